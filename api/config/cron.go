@@ -0,0 +1,10 @@
+package config
+
+import "os"
+
+// CronSecret is the bearer token that cron invocations of the job drain endpoint must
+// send, from CRON_SECRET; Vercel sends it with every cron request. The endpoint refuses
+// all requests while it is empty.
+func CronSecret() string {
+	return os.Getenv("CRON_SECRET")
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"log"
+	"os"
+)
+
+// Embedding providers, selected with EMBEDDING_PROVIDER
+const (
+	EmbeddingHash = "hash" // local feature hashing: deterministic and offline, but only lexical
+	EmbeddingHTTP = "http" // an OpenAI-compatible /embeddings endpoint
+	EmbeddingNone = "none" // semantic search disabled
+)
+
+// Embedding configures how memories and queries are turned into vectors
+type Embedding struct {
+	Provider   string // EMBEDDING_PROVIDER: none (default), hash or http
+	URL        string // EMBEDDING_URL: full URL of the embeddings endpoint
+	APIKey     string // EMBEDDING_API_KEY: sent as a bearer token when set
+	Model      string // EMBEDDING_MODEL
+	Dimensions int    // EMBEDDING_DIMENSIONS: vector size; longer vectors are truncated and renormalized
+}
+
+// LoadEmbedding reads the embedding configuration from the environment
+func LoadEmbedding() Embedding {
+	cfg := Embedding{
+		Provider:   os.Getenv("EMBEDDING_PROVIDER"),
+		URL:        os.Getenv("EMBEDDING_URL"),
+		APIKey:     os.Getenv("EMBEDDING_API_KEY"),
+		Model:      os.Getenv("EMBEDDING_MODEL"),
+		Dimensions: 768,
+	}
+	// Hash vectors only match shared words, so semantic search stays off until a provider is chosen
+	if cfg.Provider == "" {
+		cfg.Provider = EmbeddingNone
+	}
+	if cfg.Model == "" && cfg.Provider == EmbeddingHTTP {
+		cfg.Model = "text-embedding-004"
+		log.Println("EMBEDDING_MODEL not set, using default: " + cfg.Model)
+	}
+	envLimit("EMBEDDING_DIMENSIONS", &cfg.Dimensions)
+	return cfg
+}
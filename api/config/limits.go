@@ -0,0 +1,73 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// Limits bounds the size of captured content. Each value can be overridden with the
+// environment variable named in its comment; lengths are in bytes.
+type Limits struct {
+	MaxBodyBytes int64 // MAX_BODY_BYTES: larger request bodies are rejected with 413
+
+	// Exceeding these rejects the request with 422
+	MaxURLLength int // MAX_URL_LENGTH
+	MaxTags      int // MAX_TAGS: tags per memory
+	MaxLinks     int // MAX_LINKS: links per capture
+
+	// Longer values are truncated on capture and the cut is recorded on the memory;
+	// edits that exceed them are rejected with 422
+	MaxTitleLength   int // MAX_TITLE_LENGTH
+	MaxContentLength int // MAX_CONTENT_LENGTH: content and the extracted article markup
+	MaxContextLength int // MAX_CONTEXT_LENGTH: selected_text, context_before, context_after and full_context
+	MaxNotesLength   int // MAX_NOTES_LENGTH
+}
+
+// DefaultLimits leave room for the raw HTML of large pages while keeping single rows reasonable
+var DefaultLimits = Limits{
+	MaxBodyBytes:     10 << 20,
+	MaxURLLength:     8192,
+	MaxTags:          100,
+	MaxLinks:         2000,
+	MaxTitleLength:   1000,
+	MaxContentLength: 1 << 20,
+	MaxContextLength: 64 << 10,
+	MaxNotesLength:   64 << 10,
+}
+
+// LoadLimits returns DefaultLimits with any overrides from the environment.
+// Values that are not positive integers are logged and ignored.
+func LoadLimits() Limits {
+	limits := DefaultLimits
+
+	var bodyBytes int
+	if envLimit("MAX_BODY_BYTES", &bodyBytes) {
+		limits.MaxBodyBytes = int64(bodyBytes)
+	}
+	envLimit("MAX_URL_LENGTH", &limits.MaxURLLength)
+	envLimit("MAX_TAGS", &limits.MaxTags)
+	envLimit("MAX_LINKS", &limits.MaxLinks)
+	envLimit("MAX_TITLE_LENGTH", &limits.MaxTitleLength)
+	envLimit("MAX_CONTENT_LENGTH", &limits.MaxContentLength)
+	envLimit("MAX_CONTEXT_LENGTH", &limits.MaxContextLength)
+	envLimit("MAX_NOTES_LENGTH", &limits.MaxNotesLength)
+
+	return limits
+}
+
+// envLimit parses the named variable into value, reporting whether it was set and valid
+func envLimit(name string, value *int) bool {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return false
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		log.Printf("ignoring %s=%q: expected a positive integer", name, raw)
+		return false
+	}
+	*value = n
+	return true
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"log"
+	"os"
+)
+
+// LLM providers, selected with LLM_PROVIDER
+const (
+	LLMHTTP = "http" // an OpenAI-compatible /chat/completions endpoint
+	LLMStub = "stub" // a canned answer citing the first source, for tests and local development
+	LLMNone = "none" // question answering disabled
+)
+
+// LLM configures the language model that answers questions about saved memories
+type LLM struct {
+	Provider  string // LLM_PROVIDER: none (default), http or stub
+	URL       string // LLM_URL: full URL of the chat completions endpoint
+	APIKey    string // LLM_API_KEY: sent as a bearer token when set
+	Model     string // LLM_MODEL
+	MaxTokens int    // LLM_MAX_TOKENS: upper bound on the length of an answer
+}
+
+// LoadLLM reads the language model configuration from the environment
+func LoadLLM() LLM {
+	cfg := LLM{
+		Provider:  os.Getenv("LLM_PROVIDER"),
+		URL:       os.Getenv("LLM_URL"),
+		APIKey:    os.Getenv("LLM_API_KEY"),
+		Model:     os.Getenv("LLM_MODEL"),
+		MaxTokens: 1024,
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = LLMNone
+	}
+	if cfg.Model == "" && cfg.Provider == LLMHTTP {
+		cfg.Model = "gemini-1.5-flash"
+		log.Println("LLM_MODEL not set, using default: " + cfg.Model)
+	}
+	envLimit("LLM_MAX_TOKENS", &cfg.MaxTokens)
+	return cfg
+}
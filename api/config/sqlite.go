@@ -0,0 +1,8 @@
+//go:build sqlite_fts5
+
+package config
+
+// The SQLite driver needs cgo, so it is only linked into builds that ask for it:
+//
+//	go build -tags sqlite_fts5
+import _ "github.com/mattn/go-sqlite3"
@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// DefaultTrashRetentionDays is how long deleted memories stay in the trash by default
+const DefaultTrashRetentionDays = 30
+
+// TrashRetention is how long a deleted memory stays in the trash before it is purged for
+// good, from TRASH_RETENTION_DAYS
+func TrashRetention() time.Duration {
+	days := DefaultTrashRetentionDays
+	envLimit("TRASH_RETENTION_DAYS", &days)
+	return time.Duration(days) * 24 * time.Hour
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"api/config"
+	"api/embedding"
+	"api/llm"
+	"api/middleware"
+	"api/models"
+	"api/rag"
+	"api/store"
+)
+
+// AskController answers questions from the user's memories with a language model
+type AskController struct {
+	store    store.MemoryStore
+	vectors  store.EmbeddingStore
+	embedder embedding.Embedder
+	provider llm.Provider
+	limits   config.Limits
+}
+
+// NewAskController creates an AskController. Memories are retrieved by hybrid search when
+// e is set and by full-text search otherwise; a nil provider disables question answering.
+func NewAskController(s store.Store, e embedding.Embedder, p llm.Provider) *AskController {
+	return &AskController{store: s, vectors: s, embedder: e, provider: p, limits: config.LoadLimits()}
+}
+
+// Ask handles POST /api/ask. It retrieves the memories most relevant to the question, has
+// the model answer from them, and streams the answer as server-sent events:
+//
+//	event: citations  {"citations": [...]}          the numbered memories given to the model
+//	event: token      {"text": "..."}               the next piece of the answer
+//	event: done       {"answer", "cited", "model"}  the whole answer and the citations it used
+//	event: error      {"error": "..."}              generation failed after the stream started
+//
+// With "stream": false the same answer, citations and cited numbers are returned as JSON.
+func (c *AskController) Ask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if c.provider == nil {
+		middleware.ErrorResponse(w, http.StatusServiceUnavailable, "Question answering is disabled: no LLM provider is configured")
+		return
+	}
+
+	var req models.AskRequest
+	if !decodeBody(w, r, c.limits.MaxBodyBytes, &req) {
+		return
+	}
+	if err := middleware.ValidateStruct(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Question) == "" {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Question is required")
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 5
+	}
+
+	memories, err := c.retrieve(r.Context(), userID, req)
+	if err != nil {
+		var embedErr queryEmbedError
+		switch {
+		case errors.As(err, &embedErr):
+			middleware.ErrorResponse(w, http.StatusBadGateway, err.Error())
+		case errors.Is(err, store.ErrInvalidDate):
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		default:
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve memories: "+err.Error())
+		}
+		return
+	}
+	prompt, citations := rag.Prompt(req.Question, memories)
+
+	if req.Stream != nil && !*req.Stream {
+		answer, err := c.answer(r.Context(), prompt, citations, func(string) error { return nil })
+		if err != nil {
+			middleware.ErrorResponse(w, http.StatusBadGateway, "Failed to generate answer: "+err.Error())
+			return
+		}
+		middleware.SuccessResponse(w, http.StatusOK, "Answer generated", map[string]interface{}{
+			"answer":    answer,
+			"citations": citations,
+			"cited":     rag.Cited(answer, citations),
+			"model":     c.provider.Model(),
+		})
+		return
+	}
+
+	events, ok := middleware.NewEventStream(w)
+	if !ok {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+	if err := events.Send("citations", map[string]interface{}{"citations": citations}); err != nil {
+		return
+	}
+
+	answer, err := c.answer(r.Context(), prompt, citations, func(text string) error {
+		return events.Send("token", map[string]string{"text": text})
+	})
+	if err != nil {
+		// The client may be gone; the error event is best effort
+		log.Printf("ask: answer generation failed: %v", err)
+		events.Send("error", map[string]string{"error": "Failed to generate answer: " + err.Error()})
+		return
+	}
+	events.Send("done", map[string]interface{}{
+		"answer": answer,
+		"cited":  rag.Cited(answer, citations),
+		"model":  c.provider.Model(),
+	})
+}
+
+// retrieve finds the memories to answer req from, most relevant first
+func (c *AskController) retrieve(ctx context.Context, userID string, req models.AskRequest) ([]models.MemoryResponse, error) {
+	filter := models.SearchRequest{
+		Query:        rag.KeywordQuery(req.Question),
+		Tags:         req.Tags,
+		ContentType:  req.ContentType,
+		Platform:     req.Platform,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		CollectionID: req.CollectionID,
+		Limit:        req.Limit,
+	}
+
+	if c.embedder != nil {
+		fused, err := hybridRanking(ctx, c.store, c.vectors, c.embedder, userID, filter, req.Question, minHybridCandidates)
+		if err != nil {
+			return nil, err
+		}
+		return paginateFused(fused, req.Limit, 0), nil
+	}
+
+	if filter.Query == "" {
+		return []models.MemoryResponse{}, nil
+	}
+	page, err := c.store.SearchMemories(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	return page.Memories, nil
+}
+
+// answer streams the model's answer to prompt through emit and returns the whole answer.
+// Without citations there is nothing to answer from, so the model is not called.
+func (c *AskController) answer(ctx context.Context, prompt llm.Request, citations []models.Citation, emit func(string) error) (string, error) {
+	if len(citations) == 0 {
+		return rag.NoSourcesAnswer, emit(rag.NoSourcesAnswer)
+	}
+
+	var answer strings.Builder
+	err := c.provider.Stream(ctx, prompt, func(text string) error {
+		answer.WriteString(text)
+		return emit(text)
+	})
+	return answer.String(), err
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"api/jobs"
+	"api/llm"
+	"api/middleware"
+	"api/models"
+	"api/store"
+)
+
+func newTestAskController(t *testing.T) (*MemoryController, *AskController) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", testSecret)
+	s := store.NewInMemoryStore()
+	return NewMemoryController(s, jobs.NewQueue(s), nil), NewAskController(s, nil, llm.NewStub(llm.DefaultStubAnswer))
+}
+
+func TestAskAnswersWithCitations(t *testing.T) {
+	memories, ask := newTestAskController(t)
+	id := createOwnedMemory(t, memories)
+
+	body := `{"question":"What is the golang style guide?","stream":false}`
+	code, resp := serve(t, ask.Ask, authedRequest(t, http.MethodPost, "/api/ask", ownerID, body))
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", code, resp.Error)
+	}
+
+	var answer struct {
+		Answer    string            `json:"answer"`
+		Citations []models.Citation `json:"citations"`
+		Cited     []int             `json:"cited"`
+	}
+	if err := json.Unmarshal(resp.Data, &answer); err != nil {
+		t.Fatalf("invalid answer payload: %v", err)
+	}
+	if answer.Answer != llm.DefaultStubAnswer {
+		t.Errorf("expected the stub answer, got %q", answer.Answer)
+	}
+	if len(answer.Citations) != 1 || answer.Citations[0].MemoryID != id || answer.Citations[0].URL != "https://go.dev/doc" {
+		t.Errorf("expected a citation of %s, got %+v", id, answer.Citations)
+	}
+	if len(answer.Cited) != 1 || answer.Cited[0] != 1 {
+		t.Errorf("expected citation 1 to be used, got %v", answer.Cited)
+	}
+
+	// Another user's question must not see the owner's memory
+	_, resp = serve(t, ask.Ask, authedRequest(t, http.MethodPost, "/api/ask", intruderID, body))
+	if strings.Contains(string(resp.Data), id) {
+		t.Errorf("answer for %s cites another user's memory: %s", intruderID, resp.Data)
+	}
+}
+
+func TestAskStreamsServerSentEvents(t *testing.T) {
+	memories, ask := newTestAskController(t)
+	createOwnedMemory(t, memories)
+
+	rec := httptest.NewRecorder()
+	req := authedRequest(t, http.MethodPost, "/api/ask", ownerID, `{"question":"golang style"}`)
+	middleware.JWTAuth(ask.Ask)(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q: %s", ct, rec.Body.String())
+	}
+
+	events := []string{}
+	streamed := ""
+	for _, block := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n") {
+		lines := strings.SplitN(block, "\n", 2)
+		event := strings.TrimPrefix(lines[0], "event: ")
+		events = append(events, event)
+		if event == "token" {
+			var token struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &token); err != nil {
+				t.Fatalf("invalid token event %q: %v", block, err)
+			}
+			streamed += token.Text
+		}
+	}
+
+	if events[0] != "citations" || events[len(events)-1] != "done" {
+		t.Errorf("expected citations first and done last, got %v", events)
+	}
+	if streamed != llm.DefaultStubAnswer {
+		t.Errorf("expected the tokens to spell the stub answer, got %q", streamed)
+	}
+}
@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api/middleware"
+	"api/models"
+	"api/store"
+
+	"github.com/google/uuid"
+)
+
+// CollectionController serves the collection endpoints
+type CollectionController struct {
+	store store.CollectionStore
+}
+
+// NewCollectionController creates a CollectionController backed by the given store
+func NewCollectionController(s store.CollectionStore) *CollectionController {
+	return &CollectionController{store: s}
+}
+
+// CreateCollection handles POST /api/collections
+func (c *CollectionController) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.CreateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := middleware.ValidateStruct(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+
+	collection, err := c.store.CreateCollection(r.Context(), userID, req)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to create collection: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusCreated, "Collection created successfully", collection)
+}
+
+// GetAllCollections handles GET /api/collections
+func (c *CollectionController) GetAllCollections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	collections, err := c.store.ListCollections(r.Context(), userID)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch collections: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Collections retrieved successfully", map[string]interface{}{
+		"collections": collections,
+		"count":       len(collections),
+	})
+}
+
+// GetCollectionByID handles GET /api/v1/collections/{id} and returns the collection with its memories in order
+func (c *CollectionController) GetCollectionByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id, ok := collectionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := 50, 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	collection, err := c.store.GetCollection(r.Context(), userID, id)
+	if err != nil {
+		collectionError(w, err, "Failed to fetch collection")
+		return
+	}
+
+	memories, err := c.store.ListCollectionMemories(r.Context(), userID, id, limit, offset)
+	if err != nil {
+		collectionError(w, err, "Failed to fetch collection memories")
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Collection retrieved successfully", map[string]interface{}{
+		"collection": collection,
+		"memories":   memories,
+		"count":      len(memories),
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+// UpdateCollection handles PUT /api/v1/collections/{id}
+func (c *CollectionController) UpdateCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id, ok := collectionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := middleware.ValidateStruct(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+
+	collection, err := c.store.UpdateCollection(r.Context(), userID, id, req)
+	if err != nil {
+		collectionError(w, err, "Failed to update collection")
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Collection updated successfully", collection)
+}
+
+// DeleteCollection handles DELETE /api/v1/collections/{id}; the memories in it are kept
+func (c *CollectionController) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id, ok := collectionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.store.DeleteCollection(r.Context(), userID, id); err != nil {
+		collectionError(w, err, "Failed to delete collection")
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Collection deleted successfully", nil)
+}
+
+// AddMemories handles POST /api/v1/collections/{id}/memories
+func (c *CollectionController) AddMemories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id, ok := collectionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CollectionMemoriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := middleware.ValidateStruct(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+
+	if err := c.store.AddToCollection(r.Context(), userID, id, req.MemoryIDs, req.Position); err != nil {
+		collectionError(w, err, "Failed to add memories to collection")
+		return
+	}
+
+	c.respondWithCollection(w, r, userID, id, "Memories added to collection")
+}
+
+// RemoveMemory handles DELETE /api/v1/collections/{id}/memories/{memory_id}
+func (c *CollectionController) RemoveMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id, ok := collectionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	memoryID := pathParam(r, "memory_id")
+	if _, err := uuid.Parse(memoryID); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid memory ID format")
+		return
+	}
+
+	if err := c.store.RemoveFromCollection(r.Context(), userID, id, memoryID); err != nil {
+		collectionError(w, err, "Failed to remove memory from collection")
+		return
+	}
+
+	c.respondWithCollection(w, r, userID, id, "Memory removed from collection")
+}
+
+// ReorderMemories handles PUT /api/v1/collections/{id}/memories
+func (c *CollectionController) ReorderMemories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id, ok := collectionIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ReorderCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := middleware.ValidateStruct(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+
+	if err := c.store.ReorderCollection(r.Context(), userID, id, req.MemoryIDs); err != nil {
+		collectionError(w, err, "Failed to reorder collection")
+		return
+	}
+
+	c.respondWithCollection(w, r, userID, id, "Collection reordered")
+}
+
+// respondWithCollection writes the collection after a membership change
+func (c *CollectionController) respondWithCollection(w http.ResponseWriter, r *http.Request, userID, id, message string) {
+	collection, err := c.store.GetCollection(r.Context(), userID, id)
+	if err != nil {
+		collectionError(w, err, "Failed to fetch collection")
+		return
+	}
+	middleware.SuccessResponse(w, http.StatusOK, message, collection)
+}
+
+// collectionIDParam reads and validates the collection ID, writing a 400 on failure
+func collectionIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := pathParam(r, "id")
+	if id == "" {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Collection ID is required")
+		return "", false
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid collection ID format")
+		return "", false
+	}
+
+	return id, true
+}
+
+// collectionError maps store errors to responses; ErrNotFound covers unknown collections and memories alike
+func collectionError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, store.ErrNotFound) {
+		middleware.ErrorResponse(w, http.StatusNotFound, "Collection or memory not found")
+		return
+	}
+	middleware.ErrorResponse(w, http.StatusInternalServerError, message)
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"api/export"
+	"api/middleware"
+	"api/models"
+	"api/store"
+)
+
+// exportPageSize is how many memories are read from the store per query while exporting
+const exportPageSize = 100
+
+// ExportController streams a user's memories as a downloadable archive
+type ExportController struct {
+	store store.MemoryStore
+}
+
+// NewExportController creates an ExportController backed by the given store
+func NewExportController(s store.MemoryStore) *ExportController {
+	return &ExportController{store: s}
+}
+
+// Export handles GET /api/export?format=jsonl|markdown|html.
+// The list filters of GET /api/memories narrow the export; memories are read page by page
+// and written as they arrive, so only one page is held in memory at a time.
+func (c *ExportController) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatJSONL
+	}
+	if format != export.FormatJSONL && format != export.FormatMarkdown && format != export.FormatHTML {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "format must be one of jsonl, markdown, html")
+		return
+	}
+
+	params := models.MemoryQueryParams{
+		ContentType:  r.URL.Query().Get("content_type"),
+		Platform:     r.URL.Query().Get("platform"),
+		Tags:         r.URL.Query().Get("tags"),
+		Search:       r.URL.Query().Get("search"),
+		CollectionID: r.URL.Query().Get("collection_id"),
+		Limit:        exportPageSize,
+	}
+
+	// Read the first page before committing to a 200 so store errors still get a JSON error response
+	page, err := c.store.ListMemories(r.Context(), userID, params)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to export memories: "+err.Error())
+		return
+	}
+
+	mimeType, extension := export.ContentType(format)
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Disposition",
+		`attachment; filename="browsebaba-export-`+time.Now().UTC().Format("2006-01-02")+"."+extension+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		log.Printf("export for user %s failed: %v", userID, err)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	for {
+		for _, memory := range page.Memories {
+			if err := writer.Write(export.NewRecord(memory)); err != nil {
+				// The status line is already sent; stop and leave a truncated download
+				log.Printf("export for user %s failed: %v", userID, err)
+				return
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		params.Cursor = page.NextCursor
+		page, err = c.store.ListMemories(r.Context(), userID, params)
+		if err != nil {
+			log.Printf("export for user %s failed: %v", userID, err)
+			return
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Printf("export for user %s failed: %v", userID, err)
+	}
+}
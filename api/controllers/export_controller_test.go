@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api/export"
+	"api/middleware"
+)
+
+func TestExportRejectsUnknownFormats(t *testing.T) {
+	c := newTestController(t)
+	exports := NewExportController(c.store)
+
+	for _, format := range []string{"csv", "JSONL", "zip"} {
+		code, resp := serve(t, exports.Export, authedRequest(t, http.MethodGet, "/api/export?format="+format, ownerID, ""))
+		if code != http.StatusBadRequest {
+			t.Errorf("format %q: expected 400, got %d", format, code)
+		}
+		if resp.Error != "format must be one of jsonl, markdown, html" {
+			t.Errorf("format %q: unexpected error %q", format, resp.Error)
+		}
+	}
+}
+
+func TestExportSetsContentTypeAndDisposition(t *testing.T) {
+	c := newTestController(t)
+	exports := NewExportController(c.store)
+	id := createOwnedMemory(t, c)
+	date := time.Now().UTC().Format("2006-01-02")
+
+	cases := []struct {
+		query       string
+		contentType string
+		extension   string
+	}{
+		{"", "application/x-ndjson", "jsonl"},
+		{"?format=jsonl", "application/x-ndjson", "jsonl"},
+		{"?format=markdown", "application/zip", "zip"},
+		{"?format=html", "text/html; charset=utf-8", "html"},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		middleware.JWTAuth(exports.Export)(rec, authedRequest(t, http.MethodGet, "/api/export"+tc.query, ownerID, ""))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d: %s", tc.query, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Type"); got != tc.contentType {
+			t.Errorf("%q: expected Content-Type %q, got %q", tc.query, tc.contentType, got)
+		}
+		want := `attachment; filename="browsebaba-export-` + date + "." + tc.extension + `"`
+		if got := rec.Header().Get("Content-Disposition"); got != want {
+			t.Errorf("%q: expected Content-Disposition %q, got %q", tc.query, want, got)
+		}
+		// Markdown entries are deflated, so only the text formats show the title
+		if tc.extension != "zip" && !bytes.Contains(rec.Body.Bytes(), []byte("Effective Go")) {
+			t.Errorf("%q: expected the memory in the export, got %s", tc.query, rec.Body.String())
+		}
+	}
+
+	// The default export is one JSON record per memory, scoped to the caller
+	rec := httptest.NewRecorder()
+	middleware.JWTAuth(exports.Export)(rec, authedRequest(t, http.MethodGet, "/api/export", ownerID, ""))
+	var record export.Record
+	if err := json.Unmarshal(rec.Body.Bytes(), &record); err != nil || record.ID != id {
+		t.Errorf("expected a single record for %s, got %s (%v)", id, rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	middleware.JWTAuth(exports.Export)(rec, authedRequest(t, http.MethodGet, "/api/export", intruderID, ""))
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected an empty export for another user, got %s", rec.Body.String())
+	}
+}
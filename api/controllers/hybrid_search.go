@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+
+	"api/embedding"
+	"api/middleware"
+	"api/models"
+	"api/store"
+)
+
+// rrfK dampens the weight of the top positions in reciprocal rank fusion; 60 is the
+// value from the original paper and what most search engines default to
+const rrfK = 60
+
+// minHybridCandidates is the fewest results taken from each search before fusing, so a
+// memory ranked moderately by both searches can outrank one found by a single search
+const minHybridCandidates = 50
+
+// hybridSearch runs the full-text and semantic searches for req and fuses their rankings
+// with reciprocal rank fusion. Pages are selected with limit and offset; cursors are not
+// supported because the fused order is only known after both searches have run.
+func (c *MemoryController) hybridSearch(w http.ResponseWriter, r *http.Request, userID string, req models.SearchRequest) {
+	if c.embedder == nil {
+		middleware.ErrorResponse(w, http.StatusServiceUnavailable, "Hybrid search is disabled: no embedding provider is configured")
+		return
+	}
+	if req.Query == "" {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Query is required for hybrid search")
+		return
+	}
+	if req.Cursor != "" {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Hybrid search is paged with offset, not cursor")
+		return
+	}
+
+	candidates := req.Offset + req.Limit
+	if candidates < minHybridCandidates {
+		candidates = minHybridCandidates
+	}
+
+	fused, err := hybridRanking(r.Context(), c.store, c.vectors, c.embedder, userID, req, req.Query, candidates)
+	if err != nil {
+		var embedErr queryEmbedError
+		switch {
+		case errors.As(err, &embedErr):
+			middleware.ErrorResponse(w, http.StatusBadGateway, err.Error())
+		case errors.Is(err, store.ErrInvalidDate):
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		default:
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Search failed: "+err.Error())
+		}
+		return
+	}
+
+	page := models.MemoryPage{Memories: paginateFused(fused, req.Limit, req.Offset)}
+	if req.IncludeTotal {
+		total := len(fused)
+		page.Total = &total
+	}
+	middleware.SuccessResponse(w, http.StatusOK, "Search completed", pageResponse(page))
+}
+
+// queryEmbedError is a failure of the embedding provider rather than of the store
+type queryEmbedError struct{ err error }
+
+func (e queryEmbedError) Error() string { return "Failed to embed query: " + e.err.Error() }
+func (e queryEmbedError) Unwrap() error { return e.err }
+
+// hybridRanking runs the full-text search of filter.Query and the semantic search of text,
+// each for up to candidates memories passing the filters of filter, and fuses the rankings.
+// An empty filter.Query skips the full-text search. Without pgvector the full-text ranking
+// is returned alone, with keyword scores only.
+func hybridRanking(ctx context.Context, memories store.MemoryStore, vectors store.EmbeddingStore, e embedding.Embedder,
+	userID string, filter models.SearchRequest, text string, candidates int) ([]models.MemoryResponse, error) {
+	keyword := []models.MemoryResponse{}
+	if filter.Query != "" {
+		keywordReq := filter
+		keywordReq.Limit = candidates
+		keywordReq.Offset = 0
+		keywordReq.Cursor = ""
+		keywordReq.IncludeTotal = false
+		page, err := memories.SearchMemories(ctx, userID, keywordReq)
+		if err != nil {
+			return nil, err
+		}
+		keyword = page.Memories
+	}
+
+	vector, err := embedding.EmbedOne(ctx, e, text)
+	if err != nil {
+		return nil, queryEmbedError{err}
+	}
+	semantic, err := vectors.SemanticSearch(ctx, userID, models.VectorQuery{
+		Model:  e.Model(),
+		Vector: vector,
+		Filter: filter,
+		Limit:  candidates,
+	})
+	if err != nil && !errors.Is(err, store.ErrEmbeddingsUnavailable) {
+		return nil, err
+	}
+
+	return fuseRankings(keyword, semantic), nil
+}
+
+// fuseRankings merges the full-text and semantic rankings of the same memories by
+// reciprocal rank fusion: each memory scores the sum of 1/(rrfK+position) over the
+// searches that found it. Semantic results with no similarity at all are left out, since
+// the vector search returns every embedded memory that passes the filters.
+func fuseRankings(keyword, semantic []models.MemoryResponse) []models.MemoryResponse {
+	fused := []models.MemoryResponse{}
+	byID := map[string]int{}
+
+	for i, memory := range keyword {
+		score := 0.0
+		if memory.Rank != nil {
+			score = *memory.Rank
+		}
+		contribution := rankContribution(i, score)
+		memory.Scores = &models.HybridScores{Score: contribution.RRF, Keyword: contribution}
+		byID[memory.ID] = len(fused)
+		fused = append(fused, memory)
+	}
+
+	position := 0
+	for _, memory := range semantic {
+		if memory.Similarity == nil || *memory.Similarity <= 0 {
+			continue
+		}
+		contribution := rankContribution(position, *memory.Similarity)
+		position++
+
+		if i, ok := byID[memory.ID]; ok {
+			fused[i].Similarity = memory.Similarity
+			fused[i].Scores.Semantic = contribution
+			fused[i].Scores.Score += contribution.RRF
+			continue
+		}
+		memory.Scores = &models.HybridScores{Score: contribution.RRF, Semantic: contribution}
+		byID[memory.ID] = len(fused)
+		fused = append(fused, memory)
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].Scores.Score > fused[j].Scores.Score })
+	return fused
+}
+
+// rankContribution is what the result at a 0-based index of one search adds to the fused score
+func rankContribution(index int, score float64) *models.RankContribution {
+	return &models.RankContribution{
+		Position: index + 1,
+		Score:    score,
+		RRF:      1 / float64(rrfK+index+1),
+	}
+}
+
+// paginateFused returns one page of the fused results
+func paginateFused(memories []models.MemoryResponse, limit, offset int) []models.MemoryResponse {
+	if offset >= len(memories) {
+		return []models.MemoryResponse{}
+	}
+	memories = memories[offset:]
+	if len(memories) > limit {
+		memories = memories[:limit]
+	}
+	return memories
+}
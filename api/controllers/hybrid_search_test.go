@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"api/embedding"
+	"api/models"
+	"api/store"
+)
+
+// ranked returns search results with the given IDs in order, each carrying score in field
+func ranked(field string, ids ...string) []models.MemoryResponse {
+	memories := []models.MemoryResponse{}
+	for i, id := range ids {
+		score := 1 / float64(i+2)
+		memory := models.MemoryResponse{Memory: models.Memory{ID: id}}
+		switch field {
+		case "rank":
+			memory.Rank = &score
+		case "similarity":
+			memory.Similarity = &score
+		}
+		memories = append(memories, memory)
+	}
+	return memories
+}
+
+// responseIDs returns the IDs of memories in order
+func responseIDs(memories []models.MemoryResponse) []string {
+	ids := []string{}
+	for _, memory := range memories {
+		ids = append(ids, memory.ID)
+	}
+	return ids
+}
+
+func TestFuseRankings(t *testing.T) {
+	zero, negative := 0.0, -0.5
+
+	cases := []struct {
+		name     string
+		keyword  []models.MemoryResponse
+		semantic []models.MemoryResponse
+		want     []string
+	}{
+		{"keyword only", ranked("rank", "a", "b"), nil, []string{"a", "b"}},
+		{"semantic only", nil, ranked("similarity", "a", "b"), []string{"a", "b"}},
+		// Ties keep the keyword result first
+		{"disjoint", ranked("rank", "a", "b"), ranked("similarity", "c", "d"), []string{"a", "c", "b", "d"}},
+		// 1/61 + 1/63 narrowly beats 1/62 + 1/62, and both beat a single first place
+		{"overlapping", ranked("rank", "a", "b", "c"), ranked("similarity", "c", "b"), []string{"c", "b", "a"}},
+		{"same order", ranked("rank", "a", "b"), ranked("similarity", "a", "b"), []string{"a", "b"}},
+		{"unrelated vectors left out", ranked("rank", "a"), []models.MemoryResponse{
+			{Memory: models.Memory{ID: "x"}, Similarity: &zero},
+			{Memory: models.Memory{ID: "y"}, Similarity: &negative},
+			{Memory: models.Memory{ID: "z"}},
+		}, []string{"a"}},
+		{"nothing found", nil, nil, []string{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := responseIDs(fuseRankings(tc.keyword, tc.semantic)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFuseRankingsScores(t *testing.T) {
+	unrelated := 0.0
+	semantic := append([]models.MemoryResponse{{Memory: models.Memory{ID: "x"}, Similarity: &unrelated}},
+		ranked("similarity", "c", "b")...)
+	fused := fuseRankings(ranked("rank", "a", "b", "c"), semantic)
+
+	// Scores are 1/(60+position); the result without similarity takes no semantic position
+	want := map[string]models.HybridScores{
+		"a": {
+			Score:   1.0 / 61,
+			Keyword: &models.RankContribution{Position: 1, Score: 1.0 / 2, RRF: 1.0 / 61},
+		},
+		"b": {
+			Score:    1.0/62 + 1.0/62,
+			Keyword:  &models.RankContribution{Position: 2, Score: 1.0 / 3, RRF: 1.0 / 62},
+			Semantic: &models.RankContribution{Position: 2, Score: 1.0 / 3, RRF: 1.0 / 62},
+		},
+		"c": {
+			Score:    1.0/63 + 1.0/61,
+			Keyword:  &models.RankContribution{Position: 3, Score: 1.0 / 4, RRF: 1.0 / 63},
+			Semantic: &models.RankContribution{Position: 1, Score: 1.0 / 2, RRF: 1.0 / 61},
+		},
+	}
+	if len(fused) != len(want) {
+		t.Fatalf("expected %d results, got %v", len(want), responseIDs(fused))
+	}
+	for _, memory := range fused {
+		scores := want[memory.ID]
+		if memory.Scores == nil || math.Abs(memory.Scores.Score-scores.Score) > 1e-12 {
+			t.Errorf("%s: expected score %v, got %+v", memory.ID, scores.Score, memory.Scores)
+			continue
+		}
+		if !reflect.DeepEqual(memory.Scores.Keyword, scores.Keyword) {
+			t.Errorf("%s: expected keyword %+v, got %+v", memory.ID, scores.Keyword, memory.Scores.Keyword)
+		}
+		if !reflect.DeepEqual(memory.Scores.Semantic, scores.Semantic) {
+			t.Errorf("%s: expected semantic %+v, got %+v", memory.ID, scores.Semantic, memory.Scores.Semantic)
+		}
+		if scores.Semantic != nil && (memory.Similarity == nil || *memory.Similarity != scores.Semantic.Score) {
+			t.Errorf("%s: expected the similarity of the semantic search, got %v", memory.ID, memory.Similarity)
+		}
+	}
+}
+
+func TestPaginateFused(t *testing.T) {
+	fused := ranked("", "a", "b", "c", "d", "e")
+
+	cases := []struct {
+		limit, offset int
+		want          []string
+	}{
+		{2, 0, []string{"a", "b"}},
+		{2, 2, []string{"c", "d"}},
+		{2, 4, []string{"e"}},
+		{10, 0, []string{"a", "b", "c", "d", "e"}},
+		{2, 5, []string{}},
+		{2, 50, []string{}},
+	}
+	for _, tc := range cases {
+		if got := responseIDs(paginateFused(fused, tc.limit, tc.offset)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("limit %d offset %d: expected %v, got %v", tc.limit, tc.offset, tc.want, got)
+		}
+	}
+}
+
+// fixedVectors answers semantic searches with a fixed ranking and records the query
+type fixedVectors struct {
+	*store.InMemoryStore
+	ranking []models.MemoryResponse
+	err     error
+	query   models.VectorQuery
+}
+
+func (f *fixedVectors) SemanticSearch(ctx context.Context, userID string, q models.VectorQuery) ([]models.MemoryResponse, error) {
+	f.query = q
+	return f.ranking, f.err
+}
+
+func TestHybridRanking(t *testing.T) {
+	c := newTestController(t)
+	s := c.store.(*store.InMemoryStore)
+	keyword := createOwnedMemory(t, c)
+	e := embedding.NewHashEmbedder(16)
+	filter := models.SearchRequest{Query: "golang", Tags: []string{"golang"}, Limit: 1, Offset: 3, IncludeTotal: true}
+	similarity := 0.5
+
+	t.Run("fuses both searches", func(t *testing.T) {
+		vectors := &fixedVectors{InMemoryStore: s, ranking: []models.MemoryResponse{
+			{Memory: models.Memory{ID: "semantic-only"}, Similarity: &similarity},
+		}}
+		fused, err := hybridRanking(context.Background(), s, vectors, e, ownerID, filter, "style guides for go", 50)
+		if err != nil {
+			t.Fatalf("hybrid ranking failed: %v", err)
+		}
+		if got := responseIDs(fused); !reflect.DeepEqual(got, []string{keyword, "semantic-only"}) {
+			t.Errorf("expected the keyword match then the semantic match, got %v", got)
+		}
+		if vectors.query.Limit != 50 || vectors.query.Model != e.Model() || !reflect.DeepEqual(vectors.query.Filter, filter) {
+			t.Errorf("expected the semantic search to get the candidates and filters, got %+v", vectors.query)
+		}
+	})
+
+	t.Run("falls back to full-text search without pgvector", func(t *testing.T) {
+		vectors := &fixedVectors{InMemoryStore: s, err: fmt.Errorf("semantic search: %w", store.ErrEmbeddingsUnavailable)}
+		fused, err := hybridRanking(context.Background(), s, vectors, e, ownerID, filter, "style guides for go", 50)
+		if err != nil {
+			t.Fatalf("expected the full-text results, got %v", err)
+		}
+		if len(fused) != 1 || fused[0].ID != keyword || fused[0].Scores.Keyword == nil || fused[0].Scores.Semantic != nil {
+			t.Errorf("expected the keyword match with keyword scores only, got %+v", fused)
+		}
+
+		// Without a keyword query there is nothing to fall back to
+		vectorOnly := filter
+		vectorOnly.Query = ""
+		if fused, err := hybridRanking(context.Background(), s, vectors, e, ownerID, vectorOnly, "style guides for go", 50); err != nil || len(fused) != 0 {
+			t.Errorf("expected no results, got %v, %v", responseIDs(fused), err)
+		}
+	})
+
+	t.Run("other store errors fail", func(t *testing.T) {
+		vectors := &fixedVectors{InMemoryStore: s, err: context.DeadlineExceeded}
+		if _, err := hybridRanking(context.Background(), s, vectors, e, ownerID, filter, "style guides for go", 50); err != context.DeadlineExceeded {
+			t.Errorf("expected the store error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"api/config"
+	"api/importer"
+	"api/jobs"
+	"api/middleware"
+	"api/models"
+	"api/store"
+)
+
+// maxImportSize bounds an uploaded import file
+const maxImportSize = 32 << 20
+
+// maxImportErrors caps how many failures an import result lists
+const maxImportErrors = 100
+
+// ImportController imports bookmarks and exports from other services as memories
+type ImportController struct {
+	store  store.Store
+	jobs   *jobs.Queue
+	limits config.Limits
+}
+
+// NewImportController creates an ImportController backed by the given store that triggers
+// lifecycle events on q, applying the content limits configured in the environment to every entry
+func NewImportController(s store.Store, q *jobs.Queue) *ImportController {
+	return &ImportController{store: s, jobs: q, limits: config.LoadLimits()}
+}
+
+// Import handles POST /api/import?format=netscape|pocket|csv|jsonl.
+// The file is either the raw request body or the "file" field of a multipart form; without
+// a format it is detected from the content. Entries whose canonical URL the user already saved
+// are skipped, and bookmark folders become tags plus a collection named after the folder path.
+func (c *ImportController) Import(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", importer.FormatNetscape, importer.FormatPocket, importer.FormatCSV, importer.FormatJSONL:
+	default:
+		middleware.ErrorResponse(w, http.StatusBadRequest, "format must be one of netscape, pocket, csv, jsonl")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var file io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		part, _, err := r.FormFile("file")
+		if err != nil {
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Multipart import requires a file field")
+			return
+		}
+		defer part.Close()
+		file = part
+	}
+
+	existing, err := c.store.MemoryURLs(r.Context(), userID)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to import: "+err.Error())
+		return
+	}
+
+	result := models.ImportResult{Errors: []models.ImportError{}}
+	fail := func(position int, url string, err error) {
+		result.Failed++
+		if len(result.Errors) < maxImportErrors {
+			result.Errors = append(result.Errors, models.ImportError{Position: position, URL: url, Error: err.Error()})
+		}
+	}
+
+	folders := []string{}
+	members := map[string][]string{}
+	result.Format, err = importer.Read(format, file, func(entry importer.Entry) error {
+		req := entry.Request
+		if entry.Err != nil {
+			fail(entry.Position, req.URL, entry.Err)
+			return nil
+		}
+		canonical := store.CanonicalURL(req.URL)
+		if canonical != "" && existing[canonical] {
+			result.Skipped++
+			return nil
+		}
+		if err := limitCapture(&req, c.limits); err != nil {
+			fail(entry.Position, req.URL, err)
+			return nil
+		}
+		if err := middleware.ValidateStruct(req); err != nil {
+			fail(entry.Position, req.URL, err)
+			return nil
+		}
+
+		memory, err := c.store.CreateMemory(r.Context(), userID, req)
+		if err != nil {
+			fail(entry.Position, req.URL, err)
+			return r.Context().Err()
+		}
+		result.Created++
+		triggerJobs(r, c.jobs, jobs.EventMemoryCreated, userID, memory.ID)
+		if canonical != "" {
+			existing[canonical] = true
+		}
+
+		if entry.Folder != "" {
+			if _, ok := members[entry.Folder]; !ok {
+				folders = append(folders, entry.Folder)
+			}
+			members[entry.Folder] = append(members[entry.Folder], memory.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case isBodyTooLarge(err):
+			middleware.ErrorResponse(w, http.StatusRequestEntityTooLarge, "Import file is too large")
+			return
+		case result.Created == 0 && result.Skipped == 0 && result.Failed == 0:
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Failed to read import file: "+err.Error())
+			return
+		default:
+			// Keep what was saved and report where reading stopped
+			fail(0, "", err)
+		}
+	}
+
+	if err := c.fileIntoCollections(r, userID, folders, members, &result); err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to create collections: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Import completed", result)
+}
+
+// isBodyTooLarge reports whether err comes from http.MaxBytesReader; http.MaxBytesError needs Go 1.19
+func isBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "http: request body too large")
+}
+
+// fileIntoCollections appends imported memories to the collection named after their folder,
+// creating the collections that do not exist yet
+func (c *ImportController) fileIntoCollections(r *http.Request, userID string, folders []string, members map[string][]string, result *models.ImportResult) error {
+	if len(folders) == 0 {
+		return nil
+	}
+
+	collections, err := c.store.ListCollections(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+	byName := map[string]string{}
+	for _, collection := range collections {
+		byName[collection.Name] = collection.ID
+	}
+
+	for _, folder := range folders {
+		name := strings.ReplaceAll(folder, "/", " / ")
+		id, ok := byName[name]
+		if !ok {
+			collection, err := c.store.CreateCollection(r.Context(), userID, models.CreateCollectionRequest{Name: name})
+			if err != nil {
+				return err
+			}
+			id = collection.ID
+			result.CollectionsCreated++
+		}
+		if err := c.store.AddToCollection(r.Context(), userID, id, members[folder], nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
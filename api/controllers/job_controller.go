@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"api/jobs"
+	"api/middleware"
+	"api/models"
+	"api/store"
+
+	"github.com/google/uuid"
+)
+
+// JobController reports the background processing of the user's memories
+type JobController struct {
+	store store.JobStore
+}
+
+// NewJobController creates a JobController backed by the given store
+func NewJobController(s store.JobStore) *JobController {
+	return &JobController{store: s}
+}
+
+// GetJobs handles GET /api/jobs?status=&kind=&memory_id= and returns the matching jobs,
+// newest first, with the number of the user's jobs in each status
+func (c *JobController) GetJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	params := models.JobQueryParams{
+		Status:   r.URL.Query().Get("status"),
+		Kind:     r.URL.Query().Get("kind"),
+		MemoryID: r.URL.Query().Get("memory_id"),
+		Limit:    50,
+	}
+	switch params.Status {
+	case "", models.JobQueued, models.JobRunning, models.JobSucceeded, models.JobDead:
+	default:
+		middleware.ErrorResponse(w, http.StatusBadRequest, "status must be one of queued, running, succeeded, dead")
+		return
+	}
+	if params.MemoryID != "" {
+		if _, err := uuid.Parse(params.MemoryID); err != nil {
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid memory ID format")
+			return
+		}
+	}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		params.Limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		params.Offset = o
+	}
+
+	list, err := c.store.ListJobs(r.Context(), userID, params)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch jobs: "+err.Error())
+		return
+	}
+
+	counts, err := c.store.CountJobs(r.Context(), userID)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to count jobs: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Jobs retrieved successfully", map[string]interface{}{
+		"jobs":   list,
+		"count":  len(list),
+		"counts": counts,
+		"limit":  params.Limit,
+		"offset": params.Offset,
+	})
+}
+
+// triggerJobs enqueues the processing steps attached to a memory lifecycle event.
+// The memory is already saved, so a failure is logged rather than returned to the client.
+func triggerJobs(r *http.Request, queue *jobs.Queue, event, userID, memoryID string) {
+	if err := queue.Trigger(r.Context(), event, userID, memoryID); err != nil {
+		log.Printf("memory %s: %v", memoryID, err)
+	}
+}
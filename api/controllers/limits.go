@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"api/config"
+	"api/document"
+	"api/middleware"
+	"api/models"
+)
+
+// limitError reports a field over a limit that is enforced instead of truncated
+type limitError struct {
+	field string
+	limit int
+	unit  string
+}
+
+func (e *limitError) Error() string {
+	return fmt.Sprintf("%s exceeds the limit of %d %s", e.field, e.limit, e.unit)
+}
+
+// decodeBody decodes a JSON body of at most maxBytes into v, writing a 413 or 400 on failure
+func decodeBody(w http.ResponseWriter, r *http.Request, maxBytes int64, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if isBodyTooLarge(err) {
+			middleware.ErrorResponse(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Request body exceeds the limit of %d bytes", maxBytes))
+		} else {
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// limitCapture sanitizes the HTML-bearing fields of a capture and applies the length limits.
+// Long text is truncated and listed in req.Truncated; an oversized URL or too many tags
+// or links fail with a *limitError instead.
+func limitCapture(req *models.CreateMemoryRequest, limits config.Limits) error {
+	if len(req.URL) > limits.MaxURLLength {
+		return &limitError{field: "url", limit: limits.MaxURLLength, unit: "bytes"}
+	}
+	if len(req.Tags) > limits.MaxTags {
+		return &limitError{field: "tags", limit: limits.MaxTags, unit: "tags"}
+	}
+	if len(req.Links) > limits.MaxLinks {
+		return &limitError{field: "links", limit: limits.MaxLinks, unit: "links"}
+	}
+
+	fields := fieldLimiter{baseURL: req.URL}
+	req.Title = fields.text("title", req.Title, limits.MaxTitleLength)
+	req.Content = fields.markup("content", req.Content, limits.MaxContentLength)
+	req.SelectedText = fields.markup("selected_text", req.SelectedText, limits.MaxContextLength)
+	req.ContextBefore = fields.markup("context_before", req.ContextBefore, limits.MaxContextLength)
+	req.ContextAfter = fields.markup("context_after", req.ContextAfter, limits.MaxContextLength)
+	req.FullContext = fields.markup("full_context", req.FullContext, limits.MaxContextLength)
+	req.Notes = fields.markup("notes", req.Notes, limits.MaxNotesLength)
+	if req.Article != nil {
+		req.Article.HTML = fields.markup("content_html", req.Article.HTML, limits.MaxContentLength)
+	}
+	req.Truncated = fields.truncated
+	return nil
+}
+
+// limitUpdate sanitizes the notes of an edit and rejects fields over their limits;
+// unlike captures, edits are never truncated
+func limitUpdate(req *models.UpdateMemoryRequest, limits config.Limits) error {
+	if document.ContainsMarkup(req.Notes) {
+		req.Notes = document.Sanitize(req.Notes, "")
+	}
+
+	switch {
+	case len(req.Title) > limits.MaxTitleLength:
+		return &limitError{field: "title", limit: limits.MaxTitleLength, unit: "bytes"}
+	case len(req.Notes) > limits.MaxNotesLength:
+		return &limitError{field: "notes", limit: limits.MaxNotesLength, unit: "bytes"}
+	case len(req.Tags) > limits.MaxTags:
+		return &limitError{field: "tags", limit: limits.MaxTags, unit: "tags"}
+	}
+	return nil
+}
+
+// limitEdit sanitizes the markup in an edited memory and enforces the length limits. Edits
+// are typed by the user, so a field over its limit fails with a *limitError instead of
+// being truncated.
+func limitEdit(edit *models.MemoryEdit, limits config.Limits) error {
+	for _, field := range []*string{&edit.Content, &edit.SelectedText, &edit.ContextBefore, &edit.ContextAfter, &edit.Notes} {
+		if document.ContainsMarkup(*field) {
+			*field = document.Sanitize(*field, edit.URL)
+		}
+	}
+
+	switch {
+	case len(edit.URL) > limits.MaxURLLength:
+		return &limitError{field: "url", limit: limits.MaxURLLength, unit: "bytes"}
+	case len(edit.Title) > limits.MaxTitleLength:
+		return &limitError{field: "title", limit: limits.MaxTitleLength, unit: "bytes"}
+	case len(edit.Content) > limits.MaxContentLength:
+		return &limitError{field: "content", limit: limits.MaxContentLength, unit: "bytes"}
+	case len(edit.SelectedText) > limits.MaxContextLength:
+		return &limitError{field: "selected_text", limit: limits.MaxContextLength, unit: "bytes"}
+	case len(edit.ContextBefore) > limits.MaxContextLength:
+		return &limitError{field: "context_before", limit: limits.MaxContextLength, unit: "bytes"}
+	case len(edit.ContextAfter) > limits.MaxContextLength:
+		return &limitError{field: "context_after", limit: limits.MaxContextLength, unit: "bytes"}
+	case len(edit.Notes) > limits.MaxNotesLength:
+		return &limitError{field: "notes", limit: limits.MaxNotesLength, unit: "bytes"}
+	case len(edit.Tags) > limits.MaxTags:
+		return &limitError{field: "tags", limit: limits.MaxTags, unit: "tags"}
+	}
+	return nil
+}
+
+// fieldLimiter truncates the fields of one capture and records every cut
+type fieldLimiter struct {
+	baseURL   string
+	truncated models.Truncations
+}
+
+// text cuts value to limit bytes
+func (l *fieldLimiter) text(field, value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	cut := truncateUTF8(value, limit)
+	l.record(field, len(value), cut)
+	return cut
+}
+
+// markup sanitizes value if it contains HTML before applying the limit. Markup cut
+// mid-element is sanitized again so it stays well-formed, which may close a few tags
+// past the limit.
+func (l *fieldLimiter) markup(field, value string, limit int) string {
+	if !document.ContainsMarkup(value) {
+		return l.text(field, value, limit)
+	}
+
+	sanitized := document.Sanitize(value, l.baseURL)
+	if len(sanitized) <= limit {
+		return sanitized
+	}
+	cut := document.Sanitize(truncateUTF8(sanitized, limit), l.baseURL)
+	l.record(field, len(value), cut)
+	return cut
+}
+
+func (l *fieldLimiter) record(field string, originalLength int, stored string) {
+	l.truncated = append(l.truncated, models.FieldTruncation{
+		Field:          field,
+		OriginalLength: originalLength,
+		StoredLength:   len(stored),
+	})
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a multi-byte character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
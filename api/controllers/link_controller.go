@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"api/middleware"
+	"api/models"
+	"api/store"
+)
+
+// LinkController serves the links extracted from captured pages
+type LinkController struct {
+	store store.LinkStore
+}
+
+// NewLinkController creates a LinkController backed by the given store
+func NewLinkController(s store.LinkStore) *LinkController {
+	return &LinkController{store: s}
+}
+
+// GetAllLinks handles GET /api/links?domain=
+func (c *LinkController) GetAllLinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	params := linkQueryParams(r)
+	params.Domain = r.URL.Query().Get("domain")
+
+	links, err := c.store.ListLinks(r.Context(), userID, params)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch links: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Links retrieved successfully", map[string]interface{}{
+		"links":  links,
+		"count":  len(links),
+		"limit":  params.Limit,
+		"offset": params.Offset,
+	})
+}
+
+// GetBacklinks handles GET /api/links/backlinks?url= and returns every memory linking to url
+func (c *LinkController) GetBacklinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	params := linkQueryParams(r)
+	params.URL = r.URL.Query().Get("url")
+	if params.URL == "" {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "url query parameter is required")
+		return
+	}
+
+	memories, err := c.store.ListBacklinks(r.Context(), userID, params)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch backlinks: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Backlinks retrieved successfully", map[string]interface{}{
+		"url":      params.URL,
+		"memories": memories,
+		"count":    len(memories),
+	})
+}
+
+// linkQueryParams parses limit and offset with the same bounds as GetAllMemories
+func linkQueryParams(r *http.Request) models.LinkQueryParams {
+	params := models.LinkQueryParams{Limit: 50}
+
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		params.Limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		params.Offset = o
+	}
+
+	return params
+}
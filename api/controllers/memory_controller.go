@@ -1,30 +1,72 @@
 package controllers
 
 import (
-	"database/sql"
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"api/config"
+	"api/document"
+	"api/embedding"
+	"api/jobs"
 	"api/middleware"
 	"api/models"
+	"api/router"
+	"api/store"
 
 	"github.com/google/uuid"
 )
 
+// MemoryController serves the extension's memory endpoints from a MemoryStore
+type MemoryController struct {
+	store    store.MemoryStore
+	vectors  store.EmbeddingStore
+	embedder embedding.Embedder
+	jobs     *jobs.Queue
+	limits   config.Limits
+}
+
+// NewMemoryController creates a MemoryController backed by the given store that triggers
+// lifecycle events on q, with the content limits configured in the environment.
+// e embeds queries for hybrid search; nil disables it.
+func NewMemoryController(s store.Store, q *jobs.Queue, e embedding.Embedder) *MemoryController {
+	return &MemoryController{store: s, vectors: s, embedder: e, jobs: q, limits: config.LoadLimits()}
+}
+
 // CreateMemory handles POST /api/memories (from extension)
-func CreateMemory(w http.ResponseWriter, r *http.Request) {
+func (c *MemoryController) CreateMemory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
 	var req models.CreateMemoryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+	if !decodeBody(w, r, c.limits.MaxBodyBytes, &req) {
+		return
+	}
+
+	// Replace the scraped text with the readable article when the page markup was sent
+	if req.HTML != "" {
+		article := document.Extract(req.HTML, req.URL)
+		req.Article = &article
+		if article.Text != "" {
+			req.Content = article.Text
+		}
+		if req.Title == "" {
+			req.Title = article.Title
+		}
+	}
+
+	if err := limitCapture(&req, c.limits); err != nil {
+		middleware.ErrorResponse(w, http.StatusUnprocessableEntity, "Field too large: "+err.Error())
 		return
 	}
 
@@ -57,230 +99,111 @@ func CreateMemory(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	now := time.Now()
 	if req.ScrapedAt.IsZero() {
-		req.ScrapedAt = now
-	}
-
-	// Start transaction
-	tx, err := config.GetDB().Begin()
-	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to start transaction")
-		return
-	}
-	defer tx.Rollback()
-
-	// Prepare tags string
-	tagsString := strings.Join(req.Tags, ",")
-
-	// Generate UUID for the new memory
-	memoryID := uuid.New().String()
-
-	// Insert memory
-	query := `
-		INSERT INTO memories (
-			id, url, title, content_type, content, selected_text,
-			context_before, context_after, full_context,
-			element_type, page_section, xpath, tags, notes,
-			created_at, updated_at, scraped_at,
-			video_platform, video_timestamp, video_duration,
-			video_title, video_url, thumbnail_url, formatted_timestamp
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14,
-			$15, $16, $17, $18, $19, $20, $21, $22, $23, $24
-		)
-	`
-
-	var videoPlatform, videoTitle, videoURL, thumbnailURL, formattedTime sql.NullString
-	var videoTimestamp, videoDuration sql.NullInt64
-
-	if req.VideoData != nil {
-		videoPlatform = sql.NullString{String: req.VideoData.Platform, Valid: true}
-		videoTimestamp = sql.NullInt64{Int64: req.VideoData.Timestamp, Valid: true}
-		videoDuration = sql.NullInt64{Int64: req.VideoData.Duration, Valid: true}
-		videoTitle = sql.NullString{String: req.VideoData.VideoTitle, Valid: true}
-		videoURL = sql.NullString{String: req.VideoData.VideoURL, Valid: true}
-		thumbnailURL = sql.NullString{String: req.VideoData.ThumbnailURL, Valid: true}
-		formattedTime = sql.NullString{String: req.VideoData.FormattedTimestamp, Valid: true}
-	}
-
-	_, err = tx.Exec(
-		query,
-		memoryID, nullString(req.URL), req.Title, req.ContentType, nullString(req.Content), nullString(req.SelectedText),
-		nullString(req.ContextBefore), nullString(req.ContextAfter), nullString(req.FullContext),
-		nullString(req.ElementType), nullString(req.PageSection), nullString(req.XPath),
-		nullString(tagsString), nullString(req.Notes),
-		now, now, req.ScrapedAt,
-		videoPlatform, videoTimestamp, videoDuration,
-		videoTitle, videoURL, thumbnailURL, formattedTime,
-	)
-
-	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to create memory: "+err.Error())
-		return
+		req.ScrapedAt = time.Now()
 	}
 
-	// Insert links if provided
-	if len(req.Links) > 0 {
-		linkQuery := `INSERT INTO links (memory_id, text, href, link_title) VALUES ($1, $2, $3, $4)`
-		for _, link := range req.Links {
-			_, err := tx.Exec(linkQuery, memoryID, link.Text, link.Href, link.Title)
-			if err != nil {
-				middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to save links")
-				return
-			}
+	if req.OnDuplicate == models.OnDuplicateMerge {
+		memory, merged, err := c.store.CreateOrMergeMemory(r.Context(), userID, req)
+		if err != nil {
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to save memory: "+err.Error())
+			return
 		}
-	}
-
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to commit transaction")
+		if merged {
+			triggerJobs(r, c.jobs, jobs.EventMemoryUpdated, userID, memory.ID)
+			middleware.SuccessResponse(w, http.StatusOK, "Memory merged into existing memory", memory)
+			return
+		}
+		triggerJobs(r, c.jobs, jobs.EventMemoryCreated, userID, memory.ID)
+		middleware.SuccessResponse(w, http.StatusCreated, "Memory saved successfully", memory)
 		return
 	}
 
-	// Fetch the created memory
-	memory, err := getMemoryByID(memoryID)
+	memory, err := c.store.CreateMemory(r.Context(), userID, req)
 	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Memory created but failed to fetch")
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to create memory: "+err.Error())
 		return
 	}
 
+	triggerJobs(r, c.jobs, jobs.EventMemoryCreated, userID, memory.ID)
 	middleware.SuccessResponse(w, http.StatusCreated, "Memory saved successfully", memory)
 }
 
 // GetAllMemories handles GET /api/memories
-func GetAllMemories(w http.ResponseWriter, r *http.Request) {
+func (c *MemoryController) GetAllMemories(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Parse query parameters
-	contentType := r.URL.Query().Get("content_type")
-	platform := r.URL.Query().Get("platform")
-	tags := r.URL.Query().Get("tags")
-	search := r.URL.Query().Get("search")
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := 50
-	offset := 0
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
 
-	if limitStr != "" {
+	// Parse query parameters
+	params := models.MemoryQueryParams{
+		ContentType:  r.URL.Query().Get("content_type"),
+		Platform:     r.URL.Query().Get("platform"),
+		Tags:         r.URL.Query().Get("tags"),
+		Search:       r.URL.Query().Get("search"),
+		CollectionID: r.URL.Query().Get("collection_id"),
+		Limit:        50,
+		Offset:       0,
+		Cursor:       r.URL.Query().Get("cursor"),
+	}
+	params.IncludeTotal, _ = strconv.ParseBool(r.URL.Query().Get("include_total"))
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
+			params.Limit = l
 		}
 	}
-	if offsetStr != "" {
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
 		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
+			params.Offset = o
 		}
 	}
 
-	query := `
-		SELECT id, url, title, content_type, content, selected_text,
-			context_before, context_after, full_context,
-			element_type, page_section, xpath, tags, notes,
-			created_at, updated_at, scraped_at,
-			video_platform, video_timestamp, video_duration,
-			video_title, video_url, thumbnail_url, formatted_timestamp
-		FROM memories WHERE 1=1
-	`
-	args := []interface{}{}
-	argCount := 1
-
-	if contentType != "" {
-		query += " AND content_type = $" + strconv.Itoa(argCount)
-		args = append(args, contentType)
-		argCount++
-	}
-
-	if platform != "" {
-		query += " AND video_platform = $" + strconv.Itoa(argCount)
-		args = append(args, platform)
-		argCount++
-	}
-
-	if tags != "" {
-		query += " AND tags LIKE $" + strconv.Itoa(argCount)
-		args = append(args, "%"+tags+"%")
-		argCount++
-	}
-
-	if search != "" {
-		query += " AND (title ILIKE $" + strconv.Itoa(argCount) +
-			" OR content ILIKE $" + strconv.Itoa(argCount) +
-			" OR selected_text ILIKE $" + strconv.Itoa(argCount) + ")"
-		args = append(args, "%"+search+"%")
-		argCount++
-	}
-
-	query += " ORDER BY created_at DESC LIMIT $" + strconv.Itoa(argCount) + " OFFSET $" + strconv.Itoa(argCount+1)
-	args = append(args, limit, offset)
-
-	rows, err := config.GetDB().Query(query, args...)
+	page, err := c.store.ListMemories(r.Context(), userID, params)
 	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch memories: "+err.Error())
-		return
-	}
-	defer rows.Close()
-
-	var memories []models.MemoryResponse
-	for rows.Next() {
-		var memory models.Memory
-		err := rows.Scan(
-			&memory.ID, &memory.URL, &memory.Title, &memory.ContentType,
-			&memory.Content, &memory.SelectedText,
-			&memory.ContextBefore, &memory.ContextAfter, &memory.FullContext,
-			&memory.ElementType, &memory.PageSection, &memory.XPath,
-			&memory.TagsString, &memory.Notes,
-			&memory.CreatedAt, &memory.UpdatedAt, &memory.ScrapedAt,
-			&memory.VideoPlatform, &memory.VideoTimestamp, &memory.VideoDuration,
-			&memory.VideoTitle, &memory.VideoURL, &memory.ThumbnailURL, &memory.FormattedTime,
-		)
-		if err != nil {
-			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to parse memories")
-			return
+		if errors.Is(err, store.ErrInvalidCursor) {
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid cursor")
+		} else {
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch memories: "+err.Error())
 		}
-
-		response := buildMemoryResponse(memory)
-		memories = append(memories, response)
-	}
-
-	if memories == nil {
-		memories = []models.MemoryResponse{}
+		return
 	}
 
-	middleware.SuccessResponse(w, http.StatusOK, "Memories retrieved successfully", map[string]interface{}{
-		"memories": memories,
-		"count":    len(memories),
-		"limit":    limit,
-		"offset":   offset,
-	})
+	response := pageResponse(page)
+	response["limit"] = params.Limit
+	response["offset"] = params.Offset
+	middleware.CachedSuccessResponse(w, r, http.StatusOK, "Memories retrieved successfully", response)
 }
 
-// GetMemoryByID handles GET /api/memories?id=
-func GetMemoryByID(w http.ResponseWriter, r *http.Request) {
+// GetMemoryByID handles GET /api/v1/memories/{id}. The response carries the memory's ETag,
+// and If-None-Match with the current one gets 304 Not Modified.
+func (c *MemoryController) GetMemoryByID(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	id := r.URL.Query().Get("id")
-	if id == "" {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Memory ID is required")
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	// Validate UUID format
-	if _, err := uuid.Parse(id); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid memory ID format")
+	id, ok := memoryIDParam(w, r)
+	if !ok {
 		return
 	}
 
-	memory, err := getMemoryByID(id)
+	memory, err := c.store.GetMemory(r.Context(), userID, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			middleware.ErrorResponse(w, http.StatusNotFound, "Memory not found")
 		} else {
 			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch memory")
@@ -288,125 +211,190 @@ func GetMemoryByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if middleware.NotModified(w, r, memoryETag(memory)) {
+		return
+	}
 	middleware.SuccessResponse(w, http.StatusOK, "Memory retrieved successfully", memory)
 }
 
-// UpdateMemory handles PUT /api/memories?id=
-func UpdateMemory(w http.ResponseWriter, r *http.Request) {
+// UpdateMemory handles PUT /api/v1/memories/{id}; with If-Match the memory is only
+// replaced if its ETag still matches, otherwise the response is 412 Precondition Failed
+func (c *MemoryController) UpdateMemory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	id := r.URL.Query().Get("id")
-	if id == "" {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Memory ID is required")
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	// Validate UUID format
-	if _, err := uuid.Parse(id); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid memory ID format")
+	id, ok := memoryIDParam(w, r)
+	if !ok {
 		return
 	}
 
 	var req models.UpdateMemoryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeBody(w, r, c.limits.MaxBodyBytes, &req) {
 		return
 	}
-
-	updates := []string{}
-	args := []interface{}{}
-	argCount := 1
-
-	if req.Title != "" {
-		updates = append(updates, "title = $"+strconv.Itoa(argCount))
-		args = append(args, req.Title)
-		argCount++
+	if err := middleware.ValidateStruct(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+	if err := limitUpdate(&req, c.limits); err != nil {
+		middleware.ErrorResponse(w, http.StatusUnprocessableEntity, "Field too large: "+err.Error())
+		return
 	}
 
-	if len(req.Tags) > 0 {
-		updates = append(updates, "tags = $"+strconv.Itoa(argCount))
-		args = append(args, strings.Join(req.Tags, ","))
-		argCount++
+	memory, err := c.store.UpdateMemory(r.Context(), userID, id, req, ifMatch(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			middleware.ErrorResponse(w, http.StatusNotFound, "Memory not found")
+		case errors.Is(err, store.ErrPreconditionFailed):
+			memoryModified(w)
+		default:
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to update memory")
+		}
+		return
 	}
 
-	if req.Notes != "" {
-		updates = append(updates, "notes = $"+strconv.Itoa(argCount))
-		args = append(args, req.Notes)
-		argCount++
+	triggerJobs(r, c.jobs, jobs.EventMemoryUpdated, userID, memory.ID)
+	w.Header().Set("ETag", memoryETag(memory))
+	middleware.SuccessResponse(w, http.StatusOK, "Memory updated successfully", memory)
+}
+
+// PatchMemory handles PATCH /api/v1/memories/{id} with a JSON Merge Patch (RFC 7396) of
+// the editable fields of the memory: members replace fields, null clears them and
+// video_data is merged member by member. The patched memory is validated as a whole.
+// With If-Match the patch is only applied if the memory's ETag still matches. The body must
+// be sent as application/merge-patch+json; anything else is refused with a 415.
+func (c *MemoryController) PatchMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	updates = append(updates, "updated_at = $"+strconv.Itoa(argCount))
-	args = append(args, time.Now())
-	argCount++
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
 
-	args = append(args, id)
+	id, ok := memoryIDParam(w, r)
+	if !ok {
+		return
+	}
 
-	query := "UPDATE memories SET " + strings.Join(updates, ", ") + " WHERE id = $" + strconv.Itoa(argCount)
+	if !isMergePatch(r) {
+		w.Header().Set("Accept-Patch", mergePatchMediaType)
+		middleware.ErrorResponse(w, http.StatusUnsupportedMediaType, "Send the patch as "+mergePatchMediaType)
+		return
+	}
 
-	result, err := config.GetDB().Exec(query, args...)
-	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to update memory")
+	var patch interface{}
+	if !decodeBody(w, r, c.limits.MaxBodyBytes, &patch) {
+		return
+	}
+	members, isObject := patch.(map[string]interface{})
+	if !isObject {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid patch: a memory patch must be a JSON object")
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		middleware.ErrorResponse(w, http.StatusNotFound, "Memory not found")
+	precondition := ifMatch(r)
+	memory, err := c.store.PatchMemory(r.Context(), userID, id, func(current models.MemoryResponse) (models.MemoryEdit, error) {
+		if err := precondition(current); err != nil {
+			return models.MemoryEdit{}, err
+		}
+		edit, err := applyMemoryPatch(current, members)
+		if err != nil {
+			return edit, err
+		}
+		if err := limitEdit(&edit, c.limits); err != nil {
+			return edit, err
+		}
+		if err := middleware.ValidateStruct(edit); err != nil {
+			return edit, &patchError{message: "Validation error: " + err.Error()}
+		}
+		return edit, nil
+	})
+	if err != nil {
+		var invalid *patchError
+		var tooLarge *limitError
+		switch {
+		case errors.As(err, &invalid):
+			middleware.ErrorResponse(w, http.StatusBadRequest, invalid.Error())
+		case errors.As(err, &tooLarge):
+			middleware.ErrorResponse(w, http.StatusUnprocessableEntity, "Field too large: "+tooLarge.Error())
+		case errors.Is(err, store.ErrNotFound):
+			middleware.ErrorResponse(w, http.StatusNotFound, "Memory not found")
+		case errors.Is(err, store.ErrPreconditionFailed):
+			memoryModified(w)
+		default:
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to update memory")
+		}
 		return
 	}
 
-	memory, _ := getMemoryByID(id)
+	triggerJobs(r, c.jobs, jobs.EventMemoryUpdated, userID, memory.ID)
+	w.Header().Set("ETag", memoryETag(memory))
 	middleware.SuccessResponse(w, http.StatusOK, "Memory updated successfully", memory)
 }
 
-// DeleteMemory handles DELETE /api/memories?id=
-func DeleteMemory(w http.ResponseWriter, r *http.Request) {
+// DeleteMemory handles DELETE /api/v1/memories/{id} by moving the memory to the trash, from
+// where it can be restored; with If-Match it is only deleted if its ETag still matches
+func (c *MemoryController) DeleteMemory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	id := r.URL.Query().Get("id")
-	if id == "" {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Memory ID is required")
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	// Validate UUID format
-	if _, err := uuid.Parse(id); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid memory ID format")
+	id, ok := memoryIDParam(w, r)
+	if !ok {
 		return
 	}
 
-	query := "DELETE FROM memories WHERE id = $1"
-	result, err := config.GetDB().Exec(query, id)
-	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to delete memory")
-		return
-	}
-
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		middleware.ErrorResponse(w, http.StatusNotFound, "Memory not found")
+	if err := c.store.DeleteMemory(r.Context(), userID, id, ifMatch(r)); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			middleware.ErrorResponse(w, http.StatusNotFound, "Memory not found")
+		case errors.Is(err, store.ErrPreconditionFailed):
+			memoryModified(w)
+		default:
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to delete memory")
+		}
 		return
 	}
 
-	middleware.SuccessResponse(w, http.StatusOK, "Memory deleted successfully", nil)
+	middleware.SuccessResponse(w, http.StatusOK, "Memory moved to the trash", nil)
 }
 
-// SearchMemories handles POST /api/memories/search
-func SearchMemories(w http.ResponseWriter, r *http.Request) {
+// SearchMemories handles POST /api/memories/search; mode (in the body or query string)
+// selects full-text search or hybrid search
+func (c *MemoryController) SearchMemories(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
 	var req models.SearchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeBody(w, r, c.limits.MaxBodyBytes, &req) {
 		return
 	}
 
@@ -417,202 +405,264 @@ func SearchMemories(w http.ResponseWriter, r *http.Request) {
 		req.Limit = 100
 	}
 
-	query := `
-		SELECT id, url, title, content_type, content, selected_text,
-			context_before, context_after, full_context,
-			element_type, page_section, xpath, tags, notes,
-			created_at, updated_at, scraped_at,
-			video_platform, video_timestamp, video_duration,
-			video_title, video_url, thumbnail_url, formatted_timestamp
-		FROM memories WHERE 1=1
-	`
-	args := []interface{}{}
-	argCount := 1
+	if req.Mode == "" {
+		req.Mode = r.URL.Query().Get("mode")
+	}
+	switch req.Mode {
+	case "", models.SearchModeKeyword:
+	case models.SearchModeHybrid:
+		c.hybridSearch(w, r, userID, req)
+		return
+	default:
+		middleware.ErrorResponse(w, http.StatusBadRequest, "mode must be keyword or hybrid")
+		return
+	}
+
+	page, err := c.store.SearchMemories(r.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid cursor")
+		case errors.Is(err, store.ErrInvalidDate):
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		default:
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Search failed: "+err.Error())
+		}
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Search completed", pageResponse(page))
+}
 
-	if req.Query != "" {
-		query += ` AND to_tsvector('english', COALESCE(title, '') || ' ' || COALESCE(content, '') || ' ' || 
-			COALESCE(selected_text, '') || ' ' || COALESCE(tags, '')) @@ plainto_tsquery('english', $` + strconv.Itoa(argCount) + ")"
-		args = append(args, req.Query)
-		argCount++
+// BulkMemories handles POST /api/memories/bulk
+func (c *MemoryController) BulkMemories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	if req.ContentType != "" {
-		query += " AND content_type = $" + strconv.Itoa(argCount)
-		args = append(args, req.ContentType)
-		argCount++
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
 	}
 
-	if req.Platform != "" {
-		query += " AND video_platform = $" + strconv.Itoa(argCount)
-		args = append(args, req.Platform)
-		argCount++
+	var req models.BulkRequest
+	if !decodeBody(w, r, c.limits.MaxBodyBytes, &req) {
+		return
+	}
+	if err := middleware.ValidateStruct(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+	if message := validateBulkRequest(req); message != "" {
+		middleware.ErrorResponse(w, http.StatusBadRequest, message)
+		return
 	}
 
-	if len(req.Tags) > 0 {
-		for _, tag := range req.Tags {
-			query += " AND tags LIKE $" + strconv.Itoa(argCount)
-			args = append(args, "%"+tag+"%")
-			argCount++
+	result, err := c.store.ApplyBulk(r.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrBulkLimit):
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Filter matches too many memories; narrow it down or pass ids")
+		case errors.Is(err, store.ErrInvalidDate):
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		case errors.Is(err, store.ErrNotFound):
+			middleware.ErrorResponse(w, http.StatusNotFound, "Collection not found")
+		default:
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Bulk action failed: "+err.Error())
 		}
+		return
 	}
 
-	if req.StartDate != "" {
-		query += " AND created_at >= $" + strconv.Itoa(argCount)
-		args = append(args, req.StartDate)
-		argCount++
-	}
+	middleware.SuccessResponse(w, http.StatusOK, "Bulk action completed", result)
+}
 
-	if req.EndDate != "" {
-		query += " AND created_at <= $" + strconv.Itoa(argCount)
-		args = append(args, req.EndDate)
-		argCount++
+// GetStats handles GET /api/memories/stats
+func (c *MemoryController) GetStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	query += " ORDER BY created_at DESC LIMIT $" + strconv.Itoa(argCount) + " OFFSET $" + strconv.Itoa(argCount+1)
-	args = append(args, req.Limit, req.Offset)
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
 
-	rows, err := config.GetDB().Query(query, args...)
+	stats, err := c.store.MemoryStats(r.Context(), userID)
 	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Search failed: "+err.Error())
-		return
-	}
-	defer rows.Close()
-
-	var memories []models.MemoryResponse
-	for rows.Next() {
-		var memory models.Memory
-		err := rows.Scan(
-			&memory.ID, &memory.URL, &memory.Title, &memory.ContentType,
-			&memory.Content, &memory.SelectedText,
-			&memory.ContextBefore, &memory.ContextAfter, &memory.FullContext,
-			&memory.ElementType, &memory.PageSection, &memory.XPath,
-			&memory.TagsString, &memory.Notes,
-			&memory.CreatedAt, &memory.UpdatedAt, &memory.ScrapedAt,
-			&memory.VideoPlatform, &memory.VideoTimestamp, &memory.VideoDuration,
-			&memory.VideoTitle, &memory.VideoURL, &memory.ThumbnailURL, &memory.FormattedTime,
-		)
-		if err != nil {
-			continue
-		}
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch stats")
+		return
+	}
 
-		response := buildMemoryResponse(memory)
-		memories = append(memories, response)
+	middleware.SuccessResponse(w, http.StatusOK, "Stats retrieved", stats)
+}
+
+// GetDuplicates handles GET /api/memories/duplicates?limit=
+func (c *MemoryController) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	if memories == nil {
-		memories = []models.MemoryResponse{}
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
 	}
 
-	middleware.SuccessResponse(w, http.StatusOK, "Search completed", map[string]interface{}{
-		"memories": memories,
-		"count":    len(memories),
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	groups, err := c.store.ListDuplicates(r.Context(), userID, limit)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to find duplicates: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Duplicates retrieved successfully", map[string]interface{}{
+		"groups": groups,
+		"count":  len(groups),
 	})
 }
 
-// GetStats handles GET /api/memories/stats
-func GetStats(w http.ResponseWriter, r *http.Request) {
+// GetRelated handles GET /api/v1/memories/{id}/related and returns the memories sharing tags,
+// the domain, outbound links or similar text with the memory, most related first, with
+// the reasons for each
+func (c *MemoryController) GetRelated(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	stats := models.Stats{
-		ByContentType: make(map[string]int),
-		ByPlatform:    make(map[string]int),
-		MostUsedTags:  []models.TagCount{},
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
 	}
 
-	// Total memories
-	config.GetDB().QueryRow("SELECT COUNT(*) FROM memories").Scan(&stats.TotalMemories)
-
-	// By content type
-	rows, _ := config.GetDB().Query("SELECT content_type, COUNT(*) FROM memories GROUP BY content_type")
-	for rows.Next() {
-		var contentType string
-		var count int
-		rows.Scan(&contentType, &count)
-		stats.ByContentType[contentType] = count
+	id, ok := memoryIDParam(w, r)
+	if !ok {
+		return
 	}
-	rows.Close()
 
-	// By platform
-	rows, _ = config.GetDB().Query("SELECT video_platform, COUNT(*) FROM memories WHERE video_platform IS NOT NULL GROUP BY video_platform")
-	for rows.Next() {
-		var platform string
-		var count int
-		rows.Scan(&platform, &count)
-		stats.ByPlatform[platform] = count
+	query := models.RelatedQuery{Limit: 10}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 50 {
+		query.Limit = l
+	}
+	if c.embedder != nil {
+		query.Model = c.embedder.Model()
 	}
-	rows.Close()
 
-	// Recent count (last 7 days)
-	config.GetDB().QueryRow("SELECT COUNT(*) FROM memories WHERE created_at > NOW() - INTERVAL '7 days'").Scan(&stats.RecentCount)
+	related, err := c.store.RelatedMemories(r.Context(), userID, id, query)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			middleware.ErrorResponse(w, http.StatusNotFound, "Memory not found")
+		} else {
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to find related memories: "+err.Error())
+		}
+		return
+	}
 
-	middleware.SuccessResponse(w, http.StatusOK, "Stats retrieved", stats)
+	middleware.SuccessResponse(w, http.StatusOK, "Related memories retrieved successfully", map[string]interface{}{
+		"memories": related,
+		"count":    len(related),
+	})
 }
 
-// Helper functions
-func getMemoryByID(id string) (models.MemoryResponse, error) {
-	query := `
-		SELECT id, url, title, content_type, content, selected_text,
-			context_before, context_after, full_context,
-			element_type, page_section, xpath, tags, notes,
-			created_at, updated_at, scraped_at,
-			video_platform, video_timestamp, video_duration,
-			video_title, video_url, thumbnail_url, formatted_timestamp
-		FROM memories WHERE id = $1
-	`
-
-	var memory models.Memory
-	err := config.GetDB().QueryRow(query, id).Scan(
-		&memory.ID, &memory.URL, &memory.Title, &memory.ContentType,
-		&memory.Content, &memory.SelectedText,
-		&memory.ContextBefore, &memory.ContextAfter, &memory.FullContext,
-		&memory.ElementType, &memory.PageSection, &memory.XPath,
-		&memory.TagsString, &memory.Notes,
-		&memory.CreatedAt, &memory.UpdatedAt, &memory.ScrapedAt,
-		&memory.VideoPlatform, &memory.VideoTimestamp, &memory.VideoDuration,
-		&memory.VideoTitle, &memory.VideoURL, &memory.ThumbnailURL, &memory.FormattedTime,
-	)
+// validateBulkRequest checks what the struct tags cannot express, returning a message for a 400
+func validateBulkRequest(req models.BulkRequest) string {
+	if (len(req.IDs) == 0) == (req.Filter == nil) {
+		return "Provide either ids or filter"
+	}
+	if req.Filter != nil {
+		f := req.Filter
+		// An empty filter would select every memory
+		if f.Query == "" && len(f.Tags) == 0 && f.ContentType == "" && f.Platform == "" &&
+			f.StartDate == "" && f.EndDate == "" && f.CollectionID == "" {
+			return "filter must set at least one criterion"
+		}
+	}
 
-	if err != nil {
-		return models.MemoryResponse{}, err
+	switch req.Action {
+	case models.BulkAddTags, models.BulkRemoveTags:
+		if len(req.Tags) == 0 {
+			return "tags is required for " + req.Action
+		}
+	case models.BulkSetContentType:
+		if req.ContentType == "" {
+			return "content_type is required for " + req.Action
+		}
+	case models.BulkMoveToCollection:
+		if req.CollectionID == "" {
+			return "collection_id is required for " + req.Action
+		}
 	}
+	return ""
+}
 
-	return buildMemoryResponse(memory), nil
+// pageResponse renders a MemoryPage; next_cursor is null on the last page
+func pageResponse(page models.MemoryPage) map[string]interface{} {
+	response := map[string]interface{}{
+		"memories":    page.Memories,
+		"count":       len(page.Memories),
+		"next_cursor": nil,
+	}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	if page.Total != nil {
+		response["total"] = *page.Total
+	}
+	return response
 }
 
-func buildMemoryResponse(memory models.Memory) models.MemoryResponse {
-	response := models.MemoryResponse{
-		Memory: memory,
+// memoryIDParam reads and validates the memory ID, writing a 400 on failure
+func memoryIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := pathParam(r, "id")
+	if id == "" {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Memory ID is required")
+		return "", false
 	}
 
-	// Parse tags
-	if memory.TagsString.Valid && memory.TagsString.String != "" {
-		response.Tags = strings.Split(memory.TagsString.String, ",")
-	} else {
-		response.Tags = []string{}
+	// Validate UUID format
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid memory ID format")
+		return "", false
 	}
 
-	// Build video data if present
-	if memory.VideoPlatform.Valid {
-		response.VideoData = &models.VideoData{
-			Platform:           memory.VideoPlatform.String,
-			Timestamp:          memory.VideoTimestamp.Int64,
-			Duration:           memory.VideoDuration.Int64,
-			VideoTitle:         memory.VideoTitle.String,
-			VideoURL:           memory.VideoURL.String,
-			ThumbnailURL:       memory.ThumbnailURL.String,
-			FormattedTimestamp: memory.FormattedTime.String,
+	return id, true
+}
+
+// memoryETag identifies a version of a memory by when it was last changed
+func memoryETag(memory models.MemoryResponse) string {
+	return `"` + strconv.FormatInt(memory.UpdatedAt.UnixNano(), 36) + `"`
+}
+
+// ifMatch checks the memory against r's If-Match header as part of the store's change
+func ifMatch(r *http.Request) store.MemoryCheck {
+	return func(current models.MemoryResponse) error {
+		if !middleware.IfMatch(r, memoryETag(current)) {
+			return store.ErrPreconditionFailed
 		}
+		return nil
 	}
+}
 
-	return response
+// memoryModified answers a request whose If-Match no longer matches the memory
+func memoryModified(w http.ResponseWriter) {
+	middleware.ErrorResponse(w, http.StatusPreconditionFailed, "Memory was modified since it was retrieved; fetch it again and retry")
 }
 
-func nullString(s string) sql.NullString {
-	if s == "" {
-		return sql.NullString{Valid: false}
+// pathParam reads a parameter from the route path, as in /api/v1/memories/{id}, falling back
+// to the query string used by the legacy routes, as in /api/memories?id=
+func pathParam(r *http.Request, name string) string {
+	if value := router.Param(r, name); value != "" {
+		return value
 	}
-	return sql.NullString{String: s, Valid: true}
+	return r.URL.Query().Get(name)
 }
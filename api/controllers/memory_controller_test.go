@@ -0,0 +1,496 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"api/embedding"
+	"api/jobs"
+	"api/llm"
+	"api/middleware"
+	"api/models"
+	"api/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testSecret = "test-secret"
+	ownerID    = "user-a"
+	intruderID = "user-b"
+)
+
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   string          `json:"error"`
+}
+
+func newTestController(t *testing.T) *MemoryController {
+	t.Helper()
+	t.Setenv("JWT_SECRET", testSecret)
+	s := store.NewInMemoryStore()
+	return NewMemoryController(s, jobs.NewQueue(s), nil)
+}
+
+func authedRequest(t *testing.T, method, target, userID, body string) *http.Request {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userID}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func serve(t *testing.T, handler http.HandlerFunc, req *http.Request) (int, apiResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	middleware.JWTAuth(handler)(rec, req)
+
+	var resp apiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+// createOwnedMemory saves a memory as ownerID and returns its ID
+func createOwnedMemory(t *testing.T, c *MemoryController) string {
+	t.Helper()
+
+	body := `{"url":"https://go.dev/doc","title":"Effective Go","content":"golang style guide","tags":["golang"]}`
+	code, resp := serve(t, c.CreateMemory, authedRequest(t, http.MethodPost, "/api/memories", ownerID, body))
+	if code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", code, resp.Error)
+	}
+
+	var memory struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp.Data, &memory); err != nil {
+		t.Fatalf("create: invalid memory payload: %v", err)
+	}
+	return memory.ID
+}
+
+func TestMemoryEndpointsRequireAuth(t *testing.T) {
+	c := newTestController(t)
+
+	code, _ := serve(t, c.GetAllMemories, httptest.NewRequest(http.MethodGet, "/api/memories", nil))
+	if code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", code)
+	}
+}
+
+func TestCrossUserAccessReturns404(t *testing.T) {
+	c := newTestController(t)
+	id := createOwnedMemory(t, c)
+	target := "/api/memories?id=" + id
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		body    string
+	}{
+		{"get", c.GetMemoryByID, http.MethodGet, ""},
+		{"update", c.UpdateMemory, http.MethodPut, `{"title":"Hijacked"}`},
+		{"patch", c.PatchMemory, http.MethodPatch, `{"title":"Hijacked"}`},
+		{"delete", c.DeleteMemory, http.MethodDelete, ""},
+	}
+
+	for _, tc := range cases {
+		req := authedRequest(t, tc.method, target, intruderID, tc.body)
+		if tc.method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		}
+		code, resp := serve(t, tc.handler, req)
+		if code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d: %s", tc.name, code, resp.Error)
+		}
+	}
+
+	// The owner's memory must be untouched by the attempts above
+	code, resp := serve(t, c.GetMemoryByID, authedRequest(t, http.MethodGet, target, ownerID, ""))
+	if code != http.StatusOK {
+		t.Fatalf("owner get: expected 200, got %d: %s", code, resp.Error)
+	}
+	if strings.Contains(string(resp.Data), "Hijacked") {
+		t.Fatalf("owner memory was modified by another user: %s", resp.Data)
+	}
+}
+
+func TestListSearchAndStatsAreScopedToCaller(t *testing.T) {
+	c := newTestController(t)
+	createOwnedMemory(t, c)
+
+	var page struct {
+		Count int `json:"count"`
+	}
+
+	for _, user := range []struct {
+		id    string
+		count int
+	}{{ownerID, 1}, {intruderID, 0}} {
+		_, resp := serve(t, c.GetAllMemories, authedRequest(t, http.MethodGet, "/api/memories", user.id, ""))
+		if err := json.Unmarshal(resp.Data, &page); err != nil || page.Count != user.count {
+			t.Errorf("list as %s: expected %d memories, got %s", user.id, user.count, resp.Data)
+		}
+
+		_, resp = serve(t, c.SearchMemories, authedRequest(t, http.MethodPost, "/api/memories/search", user.id, `{"query":"golang"}`))
+		if err := json.Unmarshal(resp.Data, &page); err != nil || page.Count != user.count {
+			t.Errorf("search as %s: expected %d memories, got %s", user.id, user.count, resp.Data)
+		}
+	}
+
+	_, resp := serve(t, c.GetStats, authedRequest(t, http.MethodGet, "/api/memories/stats", intruderID, ""))
+	var stats struct {
+		TotalMemories int `json:"total_memories"`
+	}
+	if err := json.Unmarshal(resp.Data, &stats); err != nil || stats.TotalMemories != 0 {
+		t.Errorf("stats as %s: expected 0 memories, got %s", intruderID, resp.Data)
+	}
+}
+
+func TestInvalidCursorsAndDatesAreBadRequests(t *testing.T) {
+	c := newTestController(t)
+	createOwnedMemory(t, c)
+	createOwnedMemory(t, c)
+
+	// A ranked search cursor cannot continue a listing ordered by date
+	_, resp := serve(t, c.SearchMemories, authedRequest(t, http.MethodPost, "/api/memories/search", ownerID, `{"query":"golang","limit":1}`))
+	var page struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(resp.Data, &page); err != nil || page.NextCursor == "" {
+		t.Fatalf("search: expected a next cursor, got %s", resp.Data)
+	}
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		body    string
+	}{
+		{"garbage cursor", c.GetAllMemories, http.MethodGet, "/api/memories?cursor=not-a-cursor!", ""},
+		{"tampered cursor", c.GetAllMemories, http.MethodGet, "/api/memories?cursor=eyJjIjoxfQ", ""},
+		{"search cursor on listing", c.GetAllMemories, http.MethodGet, "/api/memories?cursor=" + page.NextCursor, ""},
+		{"garbage search cursor", c.SearchMemories, http.MethodPost, "/api/memories/search", `{"query":"golang","cursor":"%%%"}`},
+		{"invalid start date", c.SearchMemories, http.MethodPost, "/api/memories/search", `{"start_date":"yesterday"}`},
+		{"invalid end date", c.SearchMemories, http.MethodPost, "/api/memories/search", `{"query":"golang","end_date":"2024-13-45"}`},
+		{"invalid bulk filter date", c.BulkMemories, http.MethodPost, "/api/memories/bulk", `{"action":"delete","filter":{"start_date":"soon"}}`},
+	}
+	for _, tc := range cases {
+		code, resp := serve(t, tc.handler, authedRequest(t, tc.method, tc.target, ownerID, tc.body))
+		if code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tc.name, code, resp.Error)
+		}
+	}
+
+	code, resp := serve(t, c.SearchMemories, authedRequest(t, http.MethodPost, "/api/memories/search", ownerID, `{"start_date":"2020-01-02","end_date":"2999-01-01T00:00:00Z"}`))
+	if code != http.StatusOK {
+		t.Errorf("valid dates: expected 200, got %d: %s", code, resp.Error)
+	}
+}
+
+func TestPatchMemoryAppliesMergePatch(t *testing.T) {
+	c := newTestController(t)
+	id := createOwnedMemory(t, c)
+	target := "/api/memories?id=" + id
+
+	var memory struct {
+		Title        string            `json:"title"`
+		URL          sql.NullString    `json:"url"`
+		ContentType  string            `json:"content_type"`
+		Content      sql.NullString    `json:"content"`
+		SelectedText sql.NullString    `json:"selected_text"`
+		Notes        sql.NullString    `json:"notes"`
+		Tags         []string          `json:"tags"`
+		VideoData    *models.VideoData `json:"video_data"`
+	}
+	patch := func(body string) (int, apiResponse) {
+		t.Helper()
+		req := authedRequest(t, http.MethodPatch, target, ownerID, body)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		code, resp := serve(t, c.PatchMemory, req)
+		if code == http.StatusOK {
+			memory.VideoData = nil // omitted from the response when cleared
+			if err := json.Unmarshal(resp.Data, &memory); err != nil {
+				t.Fatalf("invalid memory payload: %v", err)
+			}
+		}
+		return code, resp
+	}
+
+	code, resp := patch(`{"url":"https://go.dev/blog","content":"updated","selected_text":"style","notes":"read again",
+		"content_type":"video_timestamp","video_data":{"platform":"youtube","timestamp":90}}`)
+	if code != http.StatusOK {
+		t.Fatalf("edit: expected 200, got %d: %s", code, resp.Error)
+	}
+	if memory.URL.String != "https://go.dev/blog" || memory.Content.String != "updated" || memory.SelectedText.String != "style" ||
+		memory.ContentType != "video_timestamp" || memory.Title != "Effective Go" {
+		t.Errorf("edit: expected the patched fields and the title kept, got %+v", memory)
+	}
+	if memory.VideoData == nil || memory.VideoData.Timestamp != 90 || memory.VideoData.FormattedTimestamp != "1:30" {
+		t.Errorf("edit: expected the video position at 1:30, got %+v", memory.VideoData)
+	}
+
+	// Nested objects merge; null clears a field
+	code, resp = patch(`{"notes":null,"tags":null,"selected_text":null,"video_data":{"timestamp":3725}}`)
+	if code != http.StatusOK {
+		t.Fatalf("clear: expected 200, got %d: %s", code, resp.Error)
+	}
+	if memory.Notes.Valid || memory.SelectedText.Valid || len(memory.Tags) != 0 {
+		t.Errorf("clear: expected notes, selected text and tags cleared, got %+v", memory)
+	}
+	if memory.VideoData == nil || memory.VideoData.Platform != "youtube" || memory.VideoData.FormattedTimestamp != "1:02:05" {
+		t.Errorf("clear: expected the video platform kept and the position moved, got %+v", memory.VideoData)
+	}
+
+	code, _ = patch(`{"video_data":null,"content_type":"page"}`)
+	if code != http.StatusOK || memory.VideoData != nil {
+		t.Errorf("clear video: expected no video data, got %d %+v", code, memory.VideoData)
+	}
+
+	for _, body := range []string{
+		`{"title":null}`,
+		`{"content_type":"podcast"}`,
+		`{"id":"00000000-0000-0000-0000-000000000000"}`,
+		`{"tags":"golang"}`,
+		`{"url":"javascript:alert(1)"}`,
+		`{"url":"JavaScript://example.com/%0Aalert(1)"}`,
+		`{"url":"data:text/html,<script>alert(1)</script>"}`,
+		`{"url":"/relative/path"}`,
+		`["title"]`,
+	} {
+		if code, resp := patch(body); code != http.StatusBadRequest {
+			t.Errorf("patch %s: expected 400, got %d: %s", body, code, resp.Error)
+		}
+	}
+
+	for _, contentType := range []string{"text/plain", "application/json", "application/json-patch+json", ""} {
+		req := authedRequest(t, http.MethodPatch, target, ownerID, `{"title":"x"}`)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		middleware.JWTAuth(c.PatchMemory)(rec, req)
+		if rec.Code != http.StatusUnsupportedMediaType || rec.Header().Get("Accept-Patch") != "application/merge-patch+json" {
+			t.Errorf("%q patch: expected 415 with Accept-Patch, got %d", contentType, rec.Code)
+		}
+	}
+
+	if code, resp := patch(`{"url":null}`); code != http.StatusOK || memory.URL.Valid {
+		t.Errorf("clear url: expected 200 and no url, got %d: %s", code, resp.Error)
+	}
+}
+
+func TestUpdateMemoryWritesContentType(t *testing.T) {
+	c := newTestController(t)
+	id := createOwnedMemory(t, c)
+
+	code, resp := serve(t, c.UpdateMemory, authedRequest(t, http.MethodPut, "/api/memories?id="+id, ownerID, `{"content_type":"selection"}`))
+	var memory struct {
+		ContentType string `json:"content_type"`
+	}
+	if code != http.StatusOK || json.Unmarshal(resp.Data, &memory) != nil || memory.ContentType != "selection" {
+		t.Errorf("expected content_type selection, got %d: %s%s", code, resp.Data, resp.Error)
+	}
+
+	code, _ = serve(t, c.UpdateMemory, authedRequest(t, http.MethodPut, "/api/memories?id="+id, ownerID, `{"content_type":"podcast"}`))
+	if code != http.StatusBadRequest {
+		t.Errorf("unknown content_type: expected 400, got %d", code)
+	}
+}
+
+func TestConditionalRequestsUseETags(t *testing.T) {
+	c := newTestController(t)
+	id := createOwnedMemory(t, c)
+	target := "/api/memories?id=" + id
+
+	send := func(handler http.HandlerFunc, method, target, header, etag, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := authedRequest(t, method, target, ownerID, body)
+		if header != "" {
+			req.Header.Set(header, etag)
+		}
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		}
+		rec := httptest.NewRecorder()
+		middleware.JWTAuth(handler)(rec, req)
+		return rec
+	}
+
+	rec := send(c.GetMemoryByID, http.MethodGet, target, "", "", "")
+	original := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || original == "" {
+		t.Fatalf("get: expected 200 with an ETag, got %d %q", rec.Code, original)
+	}
+	if rec := send(c.GetMemoryByID, http.MethodGet, target, "If-None-Match", original, ""); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("get with the current ETag: expected an empty 304, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = send(c.UpdateMemory, http.MethodPut, target, "If-Match", original, `{"notes":"first"}`)
+	updated := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || updated == "" || updated == original {
+		t.Fatalf("put with the current ETag: expected 200 with a new ETag, got %d %q: %s", rec.Code, updated, rec.Body)
+	}
+	if rec := send(c.GetMemoryByID, http.MethodGet, target, "If-None-Match", original, ""); rec.Code != http.StatusOK {
+		t.Errorf("get with a stale ETag: expected 200, got %d", rec.Code)
+	}
+
+	// Writes made with the stale ETag would lose the update above
+	if rec := send(c.UpdateMemory, http.MethodPut, target, "If-Match", original, `{"notes":"second"}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("put with a stale ETag: expected 412, got %d", rec.Code)
+	}
+	if rec := send(c.PatchMemory, http.MethodPatch, target, "If-Match", original, `{"notes":"second"}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("patch with a stale ETag: expected 412, got %d", rec.Code)
+	}
+	if rec := send(c.DeleteMemory, http.MethodDelete, target, "If-Match", original, ""); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("delete with a stale ETag: expected 412, got %d", rec.Code)
+	}
+	if code, resp := serve(t, c.GetMemoryByID, authedRequest(t, http.MethodGet, target, ownerID, "")); code != http.StatusOK || !strings.Contains(string(resp.Data), `"first"`) {
+		t.Errorf("expected the memory unchanged by rejected writes, got %d: %s", code, resp.Data)
+	}
+
+	rec = send(c.GetAllMemories, http.MethodGet, "/api/memories", "", "", "")
+	list := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || list == "" {
+		t.Fatalf("list: expected 200 with an ETag, got %d %q", rec.Code, list)
+	}
+	if rec := send(c.GetAllMemories, http.MethodGet, "/api/memories", "If-None-Match", list, ""); rec.Code != http.StatusNotModified {
+		t.Errorf("list with the current ETag: expected 304, got %d", rec.Code)
+	}
+
+	rec = send(c.PatchMemory, http.MethodPatch, target, "If-Match", updated, `{"notes":"second"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch with the current ETag: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := send(c.GetAllMemories, http.MethodGet, "/api/memories", "If-None-Match", list, ""); rec.Code != http.StatusOK {
+		t.Errorf("list after a change: expected 200, got %d", rec.Code)
+	}
+	if rec := send(c.DeleteMemory, http.MethodDelete, target, "If-Match", "*", ""); rec.Code != http.StatusOK {
+		t.Errorf("delete with If-Match *: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRestoringFromTheTrashChangesTheETag(t *testing.T) {
+	t.Setenv("JWT_SECRET", testSecret)
+	s := store.NewInMemoryStore()
+	c := NewMemoryController(s, jobs.NewQueue(s), nil)
+	trash := NewTrashController(s)
+	id := createOwnedMemory(t, c)
+	target := "/api/memories?id=" + id
+
+	rec := httptest.NewRecorder()
+	middleware.JWTAuth(c.GetMemoryByID)(rec, authedRequest(t, http.MethodGet, target, ownerID, ""))
+	before := rec.Header().Get("ETag")
+
+	if code, resp := serve(t, c.DeleteMemory, authedRequest(t, http.MethodDelete, target, ownerID, "")); code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d: %s", code, resp.Error)
+	}
+	rec = httptest.NewRecorder()
+	middleware.JWTAuth(trash.RestoreMemory)(rec, authedRequest(t, http.MethodPost, "/api/trash?id="+id, ownerID, ""))
+	restored := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || restored == "" || restored == before {
+		t.Fatalf("restore: expected 200 with a new ETag, got %d %q (was %q)", rec.Code, restored, before)
+	}
+
+	// A write prepared before the memory was deleted must not apply to the restored one
+	req := authedRequest(t, http.MethodPut, target, ownerID, `{"notes":"stale"}`)
+	req.Header.Set("If-Match", before)
+	if code, _ := serve(t, c.UpdateMemory, req); code != http.StatusPreconditionFailed {
+		t.Errorf("put with the ETag from before the delete: expected 412, got %d", code)
+	}
+}
+
+func TestMergingCapturesWithoutAURLCreatesSeparateMemories(t *testing.T) {
+	c := newTestController(t)
+
+	ids := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		body := `{"title":"Clipboard note","content_type":"text","content":"same words","on_duplicate":"merge"}`
+		code, resp := serve(t, c.CreateMemory, authedRequest(t, http.MethodPost, "/api/memories", ownerID, body))
+		if code != http.StatusCreated {
+			t.Fatalf("capture %d: expected 201, got %d: %s", i+1, code, resp.Error)
+		}
+		var memory struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(resp.Data, &memory); err != nil {
+			t.Fatalf("capture %d: invalid memory payload: %v", i+1, err)
+		}
+		ids[memory.ID] = true
+	}
+	if len(ids) != 2 {
+		t.Errorf("expected two memories, got %v", ids)
+	}
+}
+
+func TestBulkMemoriesRejectsMoreThan1000IDs(t *testing.T) {
+	c := newTestController(t)
+	id := createOwnedMemory(t, c)
+
+	ids := make([]string, 1001)
+	for i := range ids {
+		ids[i] = `"` + id + `"`
+	}
+	body := `{"action":"delete","ids":[` + strings.Join(ids, ",") + `]}`
+	code, resp := serve(t, c.BulkMemories, authedRequest(t, http.MethodPost, "/api/memories/bulk", ownerID, body))
+	if code != http.StatusBadRequest {
+		t.Errorf("1001 ids: expected 400, got %d: %s", code, resp.Error)
+	}
+
+	body = `{"action":"delete","ids":[` + strings.Join(ids[:1000], ",") + `]}`
+	code, resp = serve(t, c.BulkMemories, authedRequest(t, http.MethodPost, "/api/memories/bulk", ownerID, body))
+	if code != http.StatusOK {
+		t.Errorf("1000 ids: expected 200, got %d: %s", code, resp.Error)
+	}
+}
+
+// vectorlessStore behaves like a Postgres database migrated without pgvector
+type vectorlessStore struct {
+	*store.InMemoryStore
+}
+
+func (vectorlessStore) SemanticSearch(ctx context.Context, userID string, q models.VectorQuery) ([]models.MemoryResponse, error) {
+	return nil, fmt.Errorf("semantic search: %w", store.ErrEmbeddingsUnavailable)
+}
+
+func TestSearchWithoutPgvector(t *testing.T) {
+	t.Setenv("JWT_SECRET", testSecret)
+	s := vectorlessStore{store.NewInMemoryStore()}
+	e := embedding.NewHashEmbedder(16)
+	memories := NewMemoryController(s, jobs.NewQueue(s), e)
+	createOwnedMemory(t, memories)
+
+	code, resp := serve(t, NewSemanticController(s, e).SemanticSearch,
+		authedRequest(t, http.MethodPost, "/api/memories/semantic-search", ownerID, `{"query":"style guide"}`))
+	if code != http.StatusNotImplemented || !strings.Contains(resp.Error, "pgvector") {
+		t.Errorf("semantic search: expected 501 naming pgvector, got %d: %s", code, resp.Error)
+	}
+
+	// Hybrid search and asking fall back to full-text search
+	code, resp = serve(t, memories.SearchMemories,
+		authedRequest(t, http.MethodPost, "/api/memories/search", ownerID, `{"query":"golang","mode":"hybrid"}`))
+	if code != http.StatusOK || !strings.Contains(string(resp.Data), "https://go.dev/doc") {
+		t.Errorf("hybrid search: expected the full-text match, got %d: %s", code, resp.Error)
+	}
+
+	ask := NewAskController(s, e, llm.NewStub(llm.DefaultStubAnswer))
+	code, resp = serve(t, ask.Ask, authedRequest(t, http.MethodPost, "/api/ask", ownerID, `{"question":"golang style guide?","stream":false}`))
+	if code != http.StatusOK || !strings.Contains(string(resp.Data), "https://go.dev/doc") {
+		t.Errorf("ask: expected an answer citing the memory, got %d: %s", code, resp.Error)
+	}
+}
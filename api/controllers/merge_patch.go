@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"api/models"
+)
+
+// mergePatchMediaType is the media type of JSON Merge Patch documents (RFC 7396)
+const mergePatchMediaType = "application/merge-patch+json"
+
+// patchError is a merge patch that cannot be applied to the memory; it is answered with a 400
+type patchError struct {
+	message string
+}
+
+func (e *patchError) Error() string {
+	return e.message
+}
+
+// isMergePatch reports whether the request body is declared as a merge patch. Plain JSON is
+// refused so that a client expecting JSON Patch (RFC 6902) or PUT semantics finds out.
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == mergePatchMediaType
+}
+
+// isHTTPURL reports whether raw is an absolute http or https URL
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// mergePatch applies patch to target as RFC 7396 describes: members of a patch object
+// replace those of the target, null members remove them, nested objects are merged
+// recursively, and any other patch value replaces the target as a whole
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// editableMemory returns the user-editable fields of a memory
+func editableMemory(memory models.MemoryResponse) models.MemoryEdit {
+	return models.MemoryEdit{
+		Title:         memory.Title,
+		URL:           memory.URL.String,
+		ContentType:   memory.ContentType,
+		Content:       memory.Content.String,
+		SelectedText:  memory.SelectedText.String,
+		ContextBefore: memory.ContextBefore.String,
+		ContextAfter:  memory.ContextAfter.String,
+		Notes:         memory.Notes.String,
+		Tags:          memory.Tags,
+		VideoData:     memory.VideoData,
+	}
+}
+
+// applyMemoryPatch merges patch into the editable fields of memory. Members that are not
+// editable fields, or have the wrong type, fail with a *patchError.
+func applyMemoryPatch(memory models.MemoryResponse, patch map[string]interface{}) (models.MemoryEdit, error) {
+	current := editableMemory(memory)
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return models.MemoryEdit{}, err
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return models.MemoryEdit{}, err
+	}
+
+	merged, err := json.Marshal(mergePatch(document, patch))
+	if err != nil {
+		return models.MemoryEdit{}, err
+	}
+
+	var edit models.MemoryEdit
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&edit); err != nil {
+		return models.MemoryEdit{}, &patchError{message: "Invalid patch: " + err.Error()}
+	}
+
+	// Captured URLs are kept as they are, but a new one must be safe to open as a link
+	if _, patched := patch["url"]; patched && edit.URL != "" && !isHTTPURL(edit.URL) {
+		return models.MemoryEdit{}, &patchError{message: "Validation error: url must be an http or https URL"}
+	}
+
+	// Keep the displayed position in step with a new timestamp unless it was patched too
+	if video := edit.VideoData; video != nil {
+		if video.Timestamp < 0 || video.Duration < 0 {
+			return models.MemoryEdit{}, &patchError{message: "Invalid patch: video timestamp and duration cannot be negative"}
+		}
+		if current.VideoData == nil || video.Timestamp != current.VideoData.Timestamp {
+			if patched, ok := patch["video_data"].(map[string]interface{}); !ok || patched["formatted_timestamp"] == nil {
+				video.FormattedTimestamp = formatTimestamp(video.Timestamp)
+			}
+		}
+	}
+	return edit, nil
+}
+
+// formatTimestamp renders a video position in seconds as m:ss or h:mm:ss
+func formatTimestamp(seconds int64) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
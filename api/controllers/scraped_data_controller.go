@@ -1,20 +1,39 @@
 package controllers
 
 import (
-	"database/sql"
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"api/config"
 	"api/middleware"
 	"api/models"
+	"api/store"
 )
 
-// CreateScrapedData creates a new scraped data entry
-func CreateScrapedData(w http.ResponseWriter, r *http.Request) {
+// metadataFilterPrefix starts the query parameters filtering scraped data by metadata,
+// as in ?metadata.source=twitter&metadata.rating=5
+const metadataFilterPrefix = "metadata."
+
+// ScrapedDataController serves CRUD for raw pages saved by the extension with JSON metadata
+type ScrapedDataController struct {
+	store  store.ScrapedDataStore
+	limits config.Limits
+}
+
+// NewScrapedDataController creates a ScrapedDataController backed by the given store
+func NewScrapedDataController(s store.ScrapedDataStore) *ScrapedDataController {
+	return &ScrapedDataController{store: s, limits: config.LoadLimits()}
+}
+
+// CreateScrapedData handles POST /api/v1/scraped-data
+func (c *ScrapedDataController) CreateScrapedData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
 	// Get user ID from context (set by JWT middleware)
 	userID := middleware.GetUserID(r)
 	if userID == "" {
@@ -23,345 +42,195 @@ func CreateScrapedData(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.CreateScrapedDataRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeBody(w, r, c.limits.MaxBodyBytes, &req) {
 		return
 	}
 
 	// Validate request
 	if err := middleware.ValidateStruct(&req); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
 		return
 	}
 
-	// Convert metadata to JSON
-	metadataJSON, err := json.Marshal(req.Metadata)
+	data, err := c.store.CreateScrapedData(r.Context(), userID, req)
 	if err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid metadata format")
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to create scraped data: "+err.Error())
 		return
 	}
 
-	// Convert tags array to comma-separated string
-	tags := strings.Join(req.Tags, ",")
-
-	// Insert into database
-	query := `
-		INSERT INTO scraped_data (user_id, url, title, content, metadata, tags, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, created_at, updated_at
-	`
-
-	var scrapedData models.ScrapedData
-	scrapedData.UserID = userID
-	scrapedData.URL = req.URL
-	scrapedData.Title = req.Title
-	scrapedData.Content = req.Content
-	scrapedData.Tags = tags
-
-	err = config.DB.QueryRow(
-		query,
-		userID,
-		req.URL,
-		req.Title,
-		req.Content,
-		metadataJSON,
-		tags,
-		time.Now(),
-		time.Now(),
-	).Scan(&scrapedData.ID, &scrapedData.CreatedAt, &scrapedData.UpdatedAt)
+	middleware.SuccessResponse(w, http.StatusCreated, "Scraped data created successfully", data)
+}
 
-	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to create scraped data: "+err.Error())
+// GetAllScrapedData handles GET /api/v1/scraped-data?url=&tag=&metadata.<key>=&limit=&page=
+// and returns the user's scraped data, newest first. Every metadata.<key> parameter must
+// match; values are compared as text, so metadata.rating=5 matches "rating": 5.
+func (c *ScrapedDataController) GetAllScrapedData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Set metadata as JSON string for response
-	scrapedData.Metadata = string(metadataJSON)
-
-	middleware.JSONResponse(w, http.StatusCreated, map[string]interface{}{
-		"success": true,
-		"message": "Scraped data created successfully",
-		"data":    scrapedData,
-	})
-}
-
-// GetAllScrapedData retrieves all scraped data for the authenticated user
-func GetAllScrapedData(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
 	userID := middleware.GetUserID(r)
 	if userID == "" {
 		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	// Parse query parameters
-	urlFilter := r.URL.Query().Get("url")
-	tagFilter := r.URL.Query().Get("tag")
-	limitStr := r.URL.Query().Get("limit")
-	pageStr := r.URL.Query().Get("page")
-
-	// Set defaults
-	limit := 50
-	page := 1
-
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	query := r.URL.Query()
+	params := models.ScrapedDataQueryParams{
+		URL:      query.Get("url"),
+		Tag:      query.Get("tag"),
+		Metadata: map[string]string{},
+		Limit:    50,
+		Page:     1,
 	}
 
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 && l <= 100 {
+		params.Limit = l
 	}
-
-	offset := (page - 1) * limit
-
-	// Build query
-	query := `SELECT id, user_id, url, title, content, metadata, tags, created_at, updated_at
-	          FROM scraped_data WHERE user_id = $1`
-	args := []interface{}{userID}
-	argCount := 1
-
-	if urlFilter != "" {
-		argCount++
-		query += " AND url ILIKE $" + strconv.Itoa(argCount)
-		args = append(args, "%"+urlFilter+"%")
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		params.Page = p
 	}
 
-	if tagFilter != "" {
-		argCount++
-		query += " AND tags ILIKE $" + strconv.Itoa(argCount)
-		args = append(args, "%"+tagFilter+"%")
+	for name, values := range query {
+		if !strings.HasPrefix(name, metadataFilterPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(name, metadataFilterPrefix)
+		if key == "" {
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Metadata filters need a key, as in metadata.source=")
+			return
+		}
+		params.Metadata[key] = values[0]
 	}
 
-	query += " ORDER BY created_at DESC LIMIT $" + strconv.Itoa(argCount+1) + " OFFSET $" + strconv.Itoa(argCount+2)
-	args = append(args, limit, offset)
-
-	rows, err := config.DB.Query(query, args...)
+	list, err := c.store.ListScrapedData(r.Context(), userID, params)
 	if err != nil {
 		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch scraped data: "+err.Error())
 		return
 	}
-	defer rows.Close()
-
-	var scrapedDataList []models.ScrapedData
-	for rows.Next() {
-		var data models.ScrapedData
-		var metadataJSON []byte
-
-		err := rows.Scan(
-			&data.ID,
-			&data.UserID,
-			&data.URL,
-			&data.Title,
-			&data.Content,
-			&metadataJSON,
-			&data.Tags,
-			&data.CreatedAt,
-			&data.UpdatedAt,
-		)
-		if err != nil {
-			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to scan scraped data: "+err.Error())
-			return
-		}
-
-		data.Metadata = string(metadataJSON)
-		scrapedDataList = append(scrapedDataList, data)
-	}
-
-	if scrapedDataList == nil {
-		scrapedDataList = []models.ScrapedData{}
-	}
 
 	middleware.JSONResponse(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data":    scrapedDataList,
-		"page":    page,
-		"limit":   limit,
+		"data":    list,
+		"page":    params.Page,
+		"limit":   params.Limit,
 	})
 }
 
-// GetScrapedDataByID retrieves a specific scraped data entry by ID
-func GetScrapedDataByID(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
+// GetScrapedDataByID handles GET /api/v1/scraped-data/{id}
+func (c *ScrapedDataController) GetScrapedDataByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
 	userID := middleware.GetUserID(r)
 	if userID == "" {
 		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	idStr := r.URL.Query().Get("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid ID")
+	id, ok := numericIDParam(w, r, "scraped data")
+	if !ok {
 		return
 	}
 
-	query := `SELECT id, user_id, url, title, content, metadata, tags, created_at, updated_at
-	          FROM scraped_data WHERE id = $1 AND user_id = $2`
-
-	var data models.ScrapedData
-	var metadataJSON []byte
-
-	err = config.DB.QueryRow(query, id, userID).Scan(
-		&data.ID,
-		&data.UserID,
-		&data.URL,
-		&data.Title,
-		&data.Content,
-		&metadataJSON,
-		&data.Tags,
-		&data.CreatedAt,
-		&data.UpdatedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		middleware.ErrorResponse(w, http.StatusNotFound, "Scraped data not found")
-		return
-	} else if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch scraped data: "+err.Error())
+	data, err := c.store.GetScrapedData(r.Context(), userID, id)
+	if err != nil {
+		scrapedDataError(w, err, "Failed to fetch scraped data")
 		return
 	}
 
-	data.Metadata = string(metadataJSON)
-
-	middleware.JSONResponse(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"data":    data,
-	})
+	middleware.SuccessResponse(w, http.StatusOK, "Scraped data retrieved successfully", data)
 }
 
-// UpdateScrapedData updates an existing scraped data entry
-func UpdateScrapedData(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
+// UpdateScrapedData handles PUT /api/v1/scraped-data/{id}; metadata, when sent, replaces
+// the stored object
+func (c *ScrapedDataController) UpdateScrapedData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
 	userID := middleware.GetUserID(r)
 	if userID == "" {
 		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	idStr := r.URL.Query().Get("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid ID")
+	id, ok := numericIDParam(w, r, "scraped data")
+	if !ok {
 		return
 	}
 
 	var req models.UpdateScrapedDataRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeBody(w, r, c.limits.MaxBodyBytes, &req) {
 		return
 	}
 
 	// Validate request
 	if err := middleware.ValidateStruct(&req); err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
 		return
 	}
 
-	// Build update query dynamically
-	updates := []string{}
-	args := []interface{}{}
-	argCount := 0
-
-	if req.Title != "" {
-		argCount++
-		updates = append(updates, "title = $"+strconv.Itoa(argCount))
-		args = append(args, req.Title)
-	}
-
-	if req.Content != "" {
-		argCount++
-		updates = append(updates, "content = $"+strconv.Itoa(argCount))
-		args = append(args, req.Content)
-	}
-
-	if req.Metadata != nil {
-		metadataJSON, err := json.Marshal(req.Metadata)
-		if err != nil {
-			middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid metadata format")
-			return
-		}
-		argCount++
-		updates = append(updates, "metadata = $"+strconv.Itoa(argCount))
-		args = append(args, metadataJSON)
-	}
-
-	if req.Tags != nil {
-		tags := strings.Join(req.Tags, ",")
-		argCount++
-		updates = append(updates, "tags = $"+strconv.Itoa(argCount))
-		args = append(args, tags)
-	}
-
-	if len(updates) == 0 {
+	if req.Title == "" && req.Content == "" && req.Metadata == nil && req.Tags == nil {
 		middleware.ErrorResponse(w, http.StatusBadRequest, "No fields to update")
 		return
 	}
 
-	argCount++
-	updates = append(updates, "updated_at = $"+strconv.Itoa(argCount))
-	args = append(args, time.Now())
-
-	argCount++
-	args = append(args, id)
-	argCount++
-	args = append(args, userID)
-
-	query := "UPDATE scraped_data SET " + strings.Join(updates, ", ") +
-		" WHERE id = $" + strconv.Itoa(argCount-1) + " AND user_id = $" + strconv.Itoa(argCount)
-
-	result, err := config.DB.Exec(query, args...)
+	data, err := c.store.UpdateScrapedData(r.Context(), userID, id, req)
 	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to update scraped data: "+err.Error())
+		scrapedDataError(w, err, "Failed to update scraped data")
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		middleware.ErrorResponse(w, http.StatusNotFound, "Scraped data not found")
+	middleware.SuccessResponse(w, http.StatusOK, "Scraped data updated successfully", data)
+}
+
+// DeleteScrapedData handles DELETE /api/v1/scraped-data/{id}
+func (c *ScrapedDataController) DeleteScrapedData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	middleware.JSONResponse(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"message": "Scraped data updated successfully",
-	})
-}
-
-// DeleteScrapedData deletes a scraped data entry
-func DeleteScrapedData(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from context
 	userID := middleware.GetUserID(r)
 	if userID == "" {
 		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	idStr := r.URL.Query().Get("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid ID")
+	id, ok := numericIDParam(w, r, "scraped data")
+	if !ok {
 		return
 	}
 
-	query := `DELETE FROM scraped_data WHERE id = $1 AND user_id = $2`
-	result, err := config.DB.Exec(query, id, userID)
-	if err != nil {
-		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to delete scraped data: "+err.Error())
+	if err := c.store.DeleteScrapedData(r.Context(), userID, id); err != nil {
+		scrapedDataError(w, err, "Failed to delete scraped data")
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	middleware.SuccessResponse(w, http.StatusOK, "Scraped data deleted successfully", nil)
+}
+
+// numericIDParam reads and validates the integer ID of scraped data or an item, writing a
+// 400 naming resource on failure
+func numericIDParam(w http.ResponseWriter, r *http.Request, resource string) (int64, bool) {
+	id, err := strconv.ParseInt(pathParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid "+resource+" ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// scrapedDataError maps store errors to HTTP responses
+func scrapedDataError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, store.ErrNotFound) {
 		middleware.ErrorResponse(w, http.StatusNotFound, "Scraped data not found")
 		return
 	}
-
-	middleware.JSONResponse(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"message": "Scraped data deleted successfully",
-	})
+	middleware.ErrorResponse(w, http.StatusInternalServerError, message+": "+err.Error())
 }
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"api/config"
+	"api/embedding"
+	"api/middleware"
+	"api/models"
+	"api/store"
+)
+
+// SemanticController searches memories by meaning using their embeddings
+type SemanticController struct {
+	store    store.EmbeddingStore
+	embedder embedding.Embedder
+	limits   config.Limits
+}
+
+// NewSemanticController creates a SemanticController; a nil embedder disables semantic search
+func NewSemanticController(s store.EmbeddingStore, e embedding.Embedder) *SemanticController {
+	return &SemanticController{store: s, embedder: e, limits: config.LoadLimits()}
+}
+
+// SemanticSearch handles POST /api/memories/semantic-search and returns the memories
+// nearest to the query, most similar first, with their cosine similarity
+func (c *SemanticController) SemanticSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if c.embedder == nil {
+		middleware.ErrorResponse(w, http.StatusServiceUnavailable, "Semantic search is disabled")
+		return
+	}
+
+	var req models.SemanticSearchRequest
+	if !decodeBody(w, r, c.limits.MaxBodyBytes, &req) {
+		return
+	}
+	if err := middleware.ValidateStruct(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	vector, err := embedding.EmbedOne(r.Context(), c.embedder, req.Query)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusBadGateway, "Failed to embed query: "+err.Error())
+		return
+	}
+
+	memories, err := c.store.SemanticSearch(r.Context(), userID, models.VectorQuery{
+		Model:  c.embedder.Model(),
+		Vector: vector,
+		Filter: models.SearchRequest{
+			Tags:         req.Tags,
+			ContentType:  req.ContentType,
+			Platform:     req.Platform,
+			StartDate:    req.StartDate,
+			EndDate:      req.EndDate,
+			CollectionID: req.CollectionID,
+		},
+		Limit:         req.Limit,
+		MinSimilarity: req.MinSimilarity,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidDate):
+			middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		case errors.Is(err, store.ErrEmbeddingsUnavailable):
+			middleware.ErrorResponse(w, http.StatusNotImplemented, "Semantic search is not available: "+err.Error())
+		default:
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Semantic search failed: "+err.Error())
+		}
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Semantic search completed", map[string]interface{}{
+		"memories": memories,
+		"count":    len(memories),
+		"model":    c.embedder.Model(),
+	})
+}
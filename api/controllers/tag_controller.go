@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"api/middleware"
+	"api/models"
+	"api/store"
+)
+
+// TagController serves tag listing and the bulk rename, merge and delete operations
+type TagController struct {
+	store store.TagStore
+}
+
+// NewTagController creates a TagController backed by the given store
+func NewTagController(s store.TagStore) *TagController {
+	return &TagController{store: s}
+}
+
+// GetAllTags handles GET /api/tags
+func (c *TagController) GetAllTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	tags, err := c.store.ListTags(r.Context(), userID)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch tags: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Tags retrieved successfully", map[string]interface{}{
+		"tags":  tags,
+		"count": len(tags),
+	})
+}
+
+// RenameTag handles POST /api/tags/rename
+func (c *TagController) RenameTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.RenameTagRequest
+	if !decodeTagRequest(w, r, &req) {
+		return
+	}
+
+	updated, err := c.store.RenameTag(r.Context(), userID, req.From, req.To)
+	if err != nil {
+		tagError(w, err, "Failed to rename tag")
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Tag renamed successfully", map[string]interface{}{
+		"memories_updated": updated,
+	})
+}
+
+// MergeTags handles POST /api/tags/merge
+func (c *TagController) MergeTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.MergeTagsRequest
+	if !decodeTagRequest(w, r, &req) {
+		return
+	}
+
+	updated, err := c.store.MergeTags(r.Context(), userID, req.Sources, req.Target)
+	if err != nil {
+		tagError(w, err, "Failed to merge tags")
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Tags merged successfully", map[string]interface{}{
+		"memories_updated": updated,
+	})
+}
+
+// DeleteTag handles DELETE /api/v1/tags/{name}
+func (c *TagController) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	name := pathParam(r, "name")
+	if name == "" {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Tag name is required")
+		return
+	}
+
+	updated, err := c.store.DeleteTag(r.Context(), userID, name)
+	if err != nil {
+		tagError(w, err, "Failed to delete tag")
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Tag deleted successfully", map[string]interface{}{
+		"memories_updated": updated,
+	})
+}
+
+// decodeTagRequest decodes and validates a JSON body, writing a 400 on failure
+func decodeTagRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return false
+	}
+	if err := middleware.ValidateStruct(req); err != nil {
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// tagError maps the errors of the tag operations to responses
+func tagError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.ErrorResponse(w, http.StatusNotFound, "Tag not found")
+	case errors.Is(err, store.ErrConflict):
+		middleware.ErrorResponse(w, http.StatusConflict, "Tag already exists; merge the tags instead")
+	case errors.Is(err, store.ErrInvalidTag):
+		middleware.ErrorResponse(w, http.StatusBadRequest, "Invalid tag name")
+	default:
+		middleware.ErrorResponse(w, http.StatusInternalServerError, message)
+	}
+}
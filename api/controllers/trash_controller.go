@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api/middleware"
+	"api/store"
+)
+
+// TrashController lists, restores and purges the memories the user deleted
+type TrashController struct {
+	store store.TrashStore
+}
+
+// NewTrashController creates a TrashController backed by the given store
+func NewTrashController(s store.TrashStore) *TrashController {
+	return &TrashController{store: s}
+}
+
+// GetTrash handles GET /api/v1/trash and returns the memories in the trash, most recently
+// deleted first, with when each was deleted
+func (c *TrashController) GetTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	limit, offset := 50, 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	memories, err := c.store.ListTrash(r.Context(), userID, limit, offset)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch trash: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Trash retrieved successfully", map[string]interface{}{
+		"memories": memories,
+		"count":    len(memories),
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// RestoreMemory handles POST /api/v1/trash/{id}/restore and returns the restored memory
+func (c *TrashController) RestoreMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id, ok := memoryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	memory, err := c.store.RestoreMemory(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			middleware.ErrorResponse(w, http.StatusNotFound, "Memory not found in the trash")
+		} else {
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to restore memory")
+		}
+		return
+	}
+
+	w.Header().Set("ETag", memoryETag(memory))
+	middleware.SuccessResponse(w, http.StatusOK, "Memory restored successfully", memory)
+}
+
+// PurgeMemory handles DELETE /api/v1/trash/{id}, deleting a memory in the trash for good
+func (c *TrashController) PurgeMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id, ok := memoryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.store.PurgeMemory(r.Context(), userID, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			middleware.ErrorResponse(w, http.StatusNotFound, "Memory not found in the trash")
+		} else {
+			middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to purge memory")
+		}
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Memory deleted permanently", nil)
+}
+
+// EmptyTrash handles DELETE /api/v1/trash, deleting every memory in the trash for good
+func (c *TrashController) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	purged, err := c.store.EmptyTrash(r.Context(), userID)
+	if err != nil {
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to empty trash: "+err.Error())
+		return
+	}
+
+	middleware.SuccessResponse(w, http.StatusOK, "Trash emptied", map[string]int{"purged": purged})
+}
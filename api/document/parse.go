@@ -0,0 +1,227 @@
+// Package document turns captured HTML pages into readable content: a tolerant HTML parser,
+// a readability-style article extractor and an allowlist sanitizer for stored markup.
+package document
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// NodeType distinguishes the kinds of Node
+type NodeType int
+
+const (
+	DocumentNode NodeType = iota
+	ElementNode
+	TextNode
+)
+
+// Attribute is one attribute of an element, with its value unescaped
+type Attribute struct {
+	Name  string
+	Value string
+}
+
+// Node is an element, text run or the document root of a parsed page
+type Node struct {
+	Type     NodeType
+	Tag      string // lowercase element name
+	Attrs    []Attribute
+	Text     string // unescaped text of a TextNode
+	Parent   *Node
+	Children []*Node
+}
+
+// Attr returns the value of an attribute, or "" when it is not set
+func (n *Node) Attr(name string) string {
+	for _, attr := range n.Attrs {
+		if attr.Name == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// HasAttr reports whether an attribute is set, even to an empty value
+func (n *Node) HasAttr(name string) bool {
+	for _, attr := range n.Attrs {
+		if attr.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TextContent returns the text of n and its descendants with whitespace collapsed
+func (n *Node) TextContent() string {
+	var b strings.Builder
+	n.walk(func(node *Node) bool {
+		if node.Type == TextNode {
+			b.WriteString(node.Text)
+			b.WriteString(" ")
+		}
+		return true
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// FindAll returns the descendants of n with one of the given tags, in document order
+func (n *Node) FindAll(tags ...string) []*Node {
+	found := []*Node{}
+	n.walk(func(node *Node) bool {
+		if node != n && node.Type == ElementNode {
+			for _, tag := range tags {
+				if node.Tag == tag {
+					found = append(found, node)
+					break
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// Find returns the first descendant of n with one of the given tags, or nil
+func (n *Node) Find(tags ...string) *Node {
+	if found := n.FindAll(tags...); len(found) > 0 {
+		return found[0]
+	}
+	return nil
+}
+
+// Remove detaches n from its parent
+func (n *Node) Remove() {
+	if n.Parent == nil {
+		return
+	}
+	siblings := n.Parent.Children
+	for i, child := range siblings {
+		if child == n {
+			n.Parent.Children = append(siblings[:i:i], siblings[i+1:]...)
+			break
+		}
+	}
+	n.Parent = nil
+}
+
+// walk calls visit for n and its descendants depth first; returning false skips a node's children
+func (n *Node) walk(visit func(*Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for _, child := range append([]*Node{}, n.Children...) {
+		child.walk(visit)
+	}
+}
+
+func (n *Node) appendChild(child *Node) {
+	child.Parent = n
+	n.Children = append(n.Children, child)
+}
+
+// voidElements never have children or end tags
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// closesParagraph lists the start tags that end an open <p>
+var closesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "div": true, "dl": true,
+	"fieldset": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+// impliedEnds lists, for start tags that implicitly close an open element of the same kind,
+// the tags that bound the search: <li> closes an open <li> but not past its <ul> or <ol>
+var impliedEnds = map[string][]string{
+	"li":     {"ul", "ol"},
+	"dt":     {"dl"},
+	"dd":     {"dl"},
+	"tr":     {"table", "tbody", "thead", "tfoot"},
+	"td":     {"tr", "table"},
+	"th":     {"tr", "table"},
+	"option": {"select", "datalist"},
+}
+
+// Parse builds a tree from HTML without ever failing: unknown end tags are ignored,
+// unclosed elements are closed at the end and comments and doctypes are dropped.
+// Tokens are read by golang.org/x/net/html, so comments, raw text elements (<script>,
+// <style>, <title>, ...), character references and attributes split exactly where a
+// browser splits them; only the tree is built here, with the few implied-end rules
+// readability needs instead of the full HTML5 tree construction.
+func Parse(source string) *Node {
+	root := &Node{Type: DocumentNode}
+	stack := []*Node{root}
+	current := func() *Node { return stack[len(stack)-1] }
+
+	// closeTo pops the stack up to and including the innermost open tag, stopping at boundaries
+	closeTo := func(tag string, boundaries []string) bool {
+		for i := len(stack) - 1; i > 0; i-- {
+			if stack[i].Tag == tag {
+				stack = stack[:i]
+				return true
+			}
+			for _, boundary := range boundaries {
+				if stack[i].Tag == boundary {
+					return false
+				}
+			}
+		}
+		return false
+	}
+
+	tokenizer := html.NewTokenizer(strings.NewReader(source))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// io.EOF, or a read error from the strings.Reader, which cannot happen
+			return root
+
+		case html.TextToken:
+			// Browsers drop NUL characters from text, and Postgres cannot store them
+			if text := strings.ReplaceAll(string(tokenizer.Text()), "\x00", ""); text != "" {
+				current().appendChild(&Node{Type: TextNode, Text: text})
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == "p" && !closeTo("p", []string{"div", "td", "th", "li", "blockquote", "section", "article"}) {
+				// A stray </p> is an empty paragraph
+				current().appendChild(&Node{Type: ElementNode, Tag: "p"})
+			} else if tag != "p" {
+				closeTo(tag, nil)
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			tag := token.Data
+
+			if closesParagraph[tag] {
+				closeTo("p", []string{"div", "td", "th", "li", "blockquote", "section", "article", "button"})
+			}
+			if boundaries, ok := impliedEnds[tag]; ok {
+				closeTo(tag, boundaries)
+			}
+
+			node := &Node{Type: ElementNode, Tag: tag, Attrs: make([]Attribute, 0, len(token.Attr))}
+			for _, attr := range token.Attr {
+				node.Attrs = append(node.Attrs, Attribute{Name: attr.Key, Value: attr.Val})
+			}
+			current().appendChild(node)
+
+			// Like browsers, ignore the "/" of <div/>: only void elements have no content
+			if !voidElements[tag] {
+				stack = append(stack, node)
+			}
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,534 @@
+package document
+
+import (
+	"encoding/json"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"api/models"
+)
+
+// minArticleLength is the text length below which extraction is retried without
+// removing unlikely candidates, in case the article itself was removed
+const minArticleLength = 200
+
+var (
+	// unlikelyCandidates match the class and id of page chrome such as menus and comments
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote|newsletter|cookie|subscribe|share`)
+	// maybeCandidates rescue unlikely matches that probably hold the article
+	maybeCandidates = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+
+	positiveWeight = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeWeight = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	bylineClass    = regexp.MustCompile(`(?i)byline|author|dateline|writtenby|p-author`)
+
+	// titleSeparator splits "Article title | Site name" page titles
+	titleSeparator = regexp.MustCompile(`\s+[|\-–—·:»/]\s+`)
+)
+
+// chromeTags are removed before scoring; they never hold the article
+var chromeTags = []string{"nav", "aside", "footer", "form", "button", "select", "textarea", "input", "iframe", "svg", "canvas", "object", "embed", "noscript", "script", "style", "template"}
+
+// chromeRoles are ARIA roles of page chrome
+var chromeRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true, "menu": true,
+	"menubar": true, "dialog": true, "alertdialog": true, "search": true,
+}
+
+// dateLayouts are the formats accepted for publish dates in metadata
+var dateLayouts = []string{
+	time.RFC3339Nano, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04:05", "2006-01-02T15:04",
+	"2006-01-02 15:04:05", "2006-01-02", time.RFC1123Z, time.RFC1123, "January 2, 2006", "Jan 2, 2006", "2 January 2006",
+}
+
+// Extract finds the main article of an HTML page, readability style: page chrome is removed,
+// paragraphs score their ancestors by length and commas, and the best scoring container is
+// kept together with related siblings. Metadata comes from meta tags, JSON-LD and the markup.
+// pageURL resolves relative links and images; it may be empty.
+func Extract(source, pageURL string) models.Article {
+	// Neither NUL bytes nor invalid UTF-8 can be stored in a Postgres text column
+	source = strings.ToValidUTF8(strings.ReplaceAll(source, "\x00", ""), "\uFFFD")
+
+	var best models.Article
+	for _, stripUnlikely := range []bool{true, false} {
+		article := extract(source, pageURL, stripUnlikely)
+		if len(article.Text) >= minArticleLength {
+			return article
+		}
+		if len(article.Text) > len(best.Text) || best.Title == "" {
+			best = article
+		}
+	}
+	return best
+}
+
+func extract(source, pageURL string, stripUnlikely bool) models.Article {
+	doc := Parse(source)
+	article := metadata(doc)
+
+	body := doc.Find("body")
+	if body == nil {
+		body = doc
+	}
+	removeChrome(body, stripUnlikely)
+	if article.Byline == "" {
+		article.Byline = findByline(body)
+	}
+
+	content := grabArticle(body)
+	cleanArticle(content, article.Title)
+
+	article.HTML = SanitizeNode(content, pageURL)
+	article.Text = PlainText(content)
+	if article.Title == "" {
+		if h1 := content.Find("h1"); h1 != nil {
+			article.Title = h1.TextContent()
+		}
+	}
+	if article.LeadImageURL == "" {
+		if img := content.Find("img"); img != nil {
+			article.LeadImageURL = img.Attr("src")
+		}
+	}
+	article.LeadImageURL = resolveURL(article.LeadImageURL, pageURL)
+	return article
+}
+
+// metadata reads the title, byline, publish date, lead image and language of a page
+func metadata(doc *Node) models.Article {
+	meta := map[string]string{}
+	for _, node := range doc.FindAll("meta") {
+		content := strings.TrimSpace(node.Attr("content"))
+		if content == "" {
+			continue
+		}
+		for _, key := range []string{node.Attr("property"), node.Attr("name"), node.Attr("itemprop"), node.Attr("http-equiv")} {
+			key = strings.ToLower(strings.TrimSpace(key))
+			if _, seen := meta[key]; key != "" && !seen {
+				meta[key] = content
+			}
+		}
+	}
+	ld := linkedData(doc)
+
+	article := models.Article{
+		Title:        firstNonEmpty(meta["og:title"], meta["twitter:title"], ld.headline, meta["dc.title"]),
+		Byline:       firstNonEmpty(meta["author"], ld.author, meta["dc.creator"], meta["article:author"], meta["parsely-author"]),
+		LeadImageURL: firstNonEmpty(meta["og:image"], meta["og:image:url"], meta["og:image:secure_url"], meta["twitter:image"], meta["twitter:image:src"], ld.image),
+	}
+	if strings.Contains(article.Byline, "://") {
+		// article:author is often a profile URL rather than a name
+		article.Byline = ""
+	}
+
+	if article.Title == "" {
+		if title := doc.Find("title"); title != nil {
+			article.Title = cleanTitle(title.TextContent())
+		}
+	}
+
+	published := firstNonEmpty(meta["article:published_time"], ld.datePublished, meta["datepublished"],
+		meta["og:published_time"], meta["publishdate"], meta["pubdate"], meta["date"], meta["dc.date.issued"],
+		meta["dc.date"], meta["sailthru.date"])
+	if published == "" {
+		if t := doc.Find("time"); t != nil {
+			published = t.Attr("datetime")
+		}
+	}
+	if t, ok := parseDate(published); ok {
+		article.PublishedAt = &t
+	}
+
+	lang := ""
+	if root := doc.Find("html"); root != nil {
+		lang = root.Attr("lang")
+	}
+	lang = firstNonEmpty(lang, meta["content-language"], meta["og:locale"], meta["language"])
+	article.Language = strings.ReplaceAll(strings.TrimSpace(strings.Split(lang, ",")[0]), "_", "-")
+
+	return article
+}
+
+// articleData is the subset of schema.org Article fields read from JSON-LD
+type articleData struct {
+	headline, author, datePublished, image string
+}
+
+// linkedData reads the first schema.org article in the page's JSON-LD scripts
+func linkedData(doc *Node) articleData {
+	for _, script := range doc.FindAll("script") {
+		if !strings.Contains(strings.ToLower(script.Attr("type")), "ld+json") {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(script.TextContent()), &value); err != nil {
+			continue
+		}
+		if data, ok := findArticleData(value); ok {
+			return data
+		}
+	}
+	return articleData{}
+}
+
+func findArticleData(value interface{}) (articleData, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if data, ok := findArticleData(item); ok {
+				return data, true
+			}
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			if data, ok := findArticleData(graph); ok {
+				return data, true
+			}
+		}
+		if headline, ok := v["headline"].(string); ok && headline != "" {
+			return articleData{
+				headline:      headline,
+				author:        ldName(v["author"]),
+				datePublished: ldString(v["datePublished"]),
+				image:         ldURL(v["image"]),
+			}, true
+		}
+	}
+	return articleData{}, false
+}
+
+// ldName reads a person as a string, an object with a name or a list of either
+func ldName(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		return ldString(v["name"])
+	case []interface{}:
+		names := []string{}
+		for _, item := range v {
+			if name := ldName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// ldURL reads an image as a string, an ImageObject or a list of either
+func ldURL(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		return ldString(v["url"])
+	case []interface{}:
+		if len(v) > 0 {
+			return ldURL(v[0])
+		}
+	}
+	return ""
+}
+
+func ldString(value interface{}) string {
+	s, _ := value.(string)
+	return strings.TrimSpace(s)
+}
+
+// cleanTitle drops the site name from "Article title | Site name" when the rest is a real title
+func cleanTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	parts := titleSeparator.Split(title, -1)
+	if len(parts) < 2 {
+		return title
+	}
+	// Keep the longest part: the site name is usually the short one at either end
+	longest := parts[0]
+	for _, part := range parts[1:] {
+		if len(part) > len(longest) {
+			longest = part
+		}
+	}
+	if len(strings.Fields(longest)) < 3 {
+		return title
+	}
+	return longest
+}
+
+// removeChrome deletes navigation, hidden elements and, when stripUnlikely is set,
+// elements whose class or id marks them as page chrome
+func removeChrome(body *Node, stripUnlikely bool) {
+	for _, node := range body.FindAll(chromeTags...) {
+		node.Remove()
+	}
+
+	body.walk(func(node *Node) bool {
+		if node.Type != ElementNode || node == body {
+			return true
+		}
+		if isHidden(node) || chromeRoles[node.Attr("role")] {
+			node.Remove()
+			return false
+		}
+		if stripUnlikely && node.Tag != "article" && node.Tag != "main" && node.Tag != "a" && node.Tag != "body" {
+			match := node.Attr("class") + " " + node.Attr("id")
+			if unlikelyCandidates.MatchString(match) && !maybeCandidates.MatchString(match) && !hasAncestor(node, "table", "code") {
+				node.Remove()
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// isHidden reports whether an element is not rendered
+func isHidden(node *Node) bool {
+	if node.HasAttr("hidden") || node.Attr("aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(node.Attr("style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// findByline returns the text of a short element marked up as the author
+func findByline(body *Node) string {
+	byline := ""
+	body.walk(func(node *Node) bool {
+		if byline != "" {
+			return false
+		}
+		if node.Type != ElementNode {
+			return true
+		}
+		if node.Attr("rel") == "author" || strings.Contains(node.Attr("itemprop"), "author") ||
+			bylineClass.MatchString(node.Attr("class")+" "+node.Attr("id")) {
+			if text := node.TextContent(); text != "" && len(text) < 100 {
+				byline = text
+				return false
+			}
+		}
+		return true
+	})
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(byline, "By "), "by "))
+}
+
+// grabArticle scores the page and returns a container holding the best candidate and the
+// siblings that look like part of the same article
+func grabArticle(body *Node) *Node {
+	scores := map[*Node]float64{}
+	candidates := []*Node{}
+
+	for _, paragraph := range body.FindAll("p", "pre", "td", "blockquote", "div", "section", "h2", "h3") {
+		if (paragraph.Tag == "div" || paragraph.Tag == "section") && hasBlockChildren(paragraph) {
+			continue
+		}
+		text := paragraph.TextContent()
+		if len(text) < 25 {
+			continue
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		ancestor := paragraph.Parent
+		for level := 0; level < 3 && ancestor != nil && ancestor.Type == ElementNode; level++ {
+			if _, ok := scores[ancestor]; !ok {
+				scores[ancestor] = initialScore(ancestor)
+				candidates = append(candidates, ancestor)
+			}
+			divider := 1.0
+			if level == 1 {
+				divider = 2
+			} else if level > 1 {
+				divider = float64(level) * 3
+			}
+			scores[ancestor] += score / divider
+			ancestor = ancestor.Parent
+		}
+	}
+
+	var top *Node
+	topScore := 0.0
+	for _, candidate := range candidates {
+		scores[candidate] *= 1 - linkDensity(candidate)
+		if top == nil || scores[candidate] > topScore {
+			top, topScore = candidate, scores[candidate]
+		}
+	}
+
+	container := &Node{Type: ElementNode, Tag: "div"}
+	if top == nil {
+		for _, child := range append([]*Node{}, body.Children...) {
+			container.appendChild(child)
+		}
+		return container
+	}
+	if top.Parent == nil {
+		container.appendChild(top)
+		return container
+	}
+
+	threshold := math.Max(10, topScore*0.2)
+	for _, sibling := range append([]*Node{}, top.Parent.Children...) {
+		include := sibling == top
+		if !include && sibling.Type == ElementNode {
+			if score, ok := scores[sibling]; ok && score >= threshold {
+				include = true
+			} else if sibling.Tag == "p" {
+				text := sibling.TextContent()
+				density := linkDensity(sibling)
+				include = len(text) > 80 && density < 0.25 ||
+					len(text) > 0 && density == 0 && strings.HasSuffix(text, ".")
+			}
+		}
+		if include {
+			container.appendChild(sibling)
+		}
+	}
+	return container
+}
+
+// initialScore weighs a candidate container by its tag and by its class and id
+func initialScore(node *Node) float64 {
+	score := classWeight(node)
+	switch node.Tag {
+	case "div", "article", "main":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	if node.Tag == "article" {
+		score += 10
+	}
+	return score
+}
+
+// classWeight is +25 for a class or id that suggests content and -25 for one that suggests chrome
+func classWeight(node *Node) float64 {
+	weight := 0.0
+	for _, value := range []string{node.Attr("class"), node.Attr("id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of an element's text that is inside links
+func linkDensity(node *Node) float64 {
+	length := len(node.TextContent())
+	if length == 0 {
+		return 0
+	}
+	linked := 0
+	for _, a := range node.FindAll("a") {
+		linked += len(a.TextContent())
+	}
+	return float64(linked) / float64(length)
+}
+
+// cleanArticle removes what survived scoring but is not article content: a heading
+// repeating the title, link lists, empty paragraphs and containers with little text
+func cleanArticle(content *Node, title string) {
+	for _, heading := range content.FindAll("h1", "h2") {
+		if title != "" && strings.EqualFold(heading.TextContent(), title) {
+			heading.Remove()
+		}
+	}
+
+	for _, node := range content.FindAll("div", "section", "ul", "ol", "table", "header") {
+		if node.Parent == nil {
+			continue
+		}
+		text := node.TextContent()
+		if strings.Count(text, ",") >= 10 {
+			continue
+		}
+		weight := classWeight(node)
+		images := len(node.FindAll("img"))
+		paragraphs := len(node.FindAll("p"))
+		density := linkDensity(node)
+		switch {
+		case weight < 0:
+			node.Remove()
+		case weight < 25 && density > 0.3 && len(text) < 1000 || density > 0.5:
+			node.Remove()
+		case len(text) < 25 && images == 0 && node.Tag != "table":
+			node.Remove()
+		case images > 1 && float64(paragraphs)/float64(images) < 0.5 && len(text) < 200:
+			node.Remove()
+		}
+	}
+
+	for _, paragraph := range content.FindAll("p") {
+		if paragraph.TextContent() == "" && paragraph.Find("img") == nil {
+			paragraph.Remove()
+		}
+	}
+}
+
+// hasBlockChildren reports whether an element contains block-level elements
+func hasBlockChildren(node *Node) bool {
+	for _, child := range node.Children {
+		if child.Type == ElementNode && (blockTags[child.Tag] || hasBlockChildren(child)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAncestor reports whether one of node's ancestors has one of tags
+func hasAncestor(node *Node, tags ...string) bool {
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		for _, tag := range tags {
+			if parent.Tag == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseDate reads a publish date in any of dateLayouts
+func parseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// resolveURL makes ref absolute against pageURL, returning "" for unsafe schemes
+func resolveURL(ref, pageURL string) string {
+	if ref == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+	return safeURL(ref, base)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
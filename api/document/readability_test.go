@@ -0,0 +1,175 @@
+package document
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractKeepsTheArticleAndDropsBoilerplate(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("testdata", "article.html"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	article := Extract(string(source), "https://gophertimes.example/2022/03/generics")
+
+	if article.Title != "Why Go Generics Took So Long" {
+		t.Errorf("expected the site name dropped from the title, got %q", article.Title)
+	}
+	if article.Byline != "Jane Doe" {
+		t.Errorf("byline: got %q", article.Byline)
+	}
+	if want := time.Date(2022, 3, 15, 9, 30, 0, 0, time.UTC); article.PublishedAt == nil || !article.PublishedAt.Equal(want) {
+		t.Errorf("published_at: expected %v, got %v", want, article.PublishedAt)
+	}
+	if article.LeadImageURL != "https://gophertimes.example/images/lead.png" {
+		t.Errorf("expected the lead image resolved against the page, got %q", article.LeadImageURL)
+	}
+	if article.Language != "en-US" {
+		t.Errorf("language: got %q", article.Language)
+	}
+
+	for _, kept := range []string{"Type parameters were proposed", "shipped in Go 1.18", "walks through a generic map function"} {
+		if !strings.Contains(article.Text, kept) {
+			t.Errorf("expected the article text to keep %q, got %q", kept, article.Text)
+		}
+	}
+	for _, dropped := range []string{"Home", "Popular posts", "modules", "cookies", "trackReading", "Tweet this", "Great article", "Copyright"} {
+		if strings.Contains(article.Text, dropped) || strings.Contains(article.HTML, dropped) {
+			t.Errorf("expected %q to be dropped as boilerplate, got text %q", dropped, article.Text)
+		}
+	}
+	if strings.Contains(article.Text, "Why Go Generics Took So Long") {
+		t.Errorf("expected the heading repeating the title to be removed, got %q", article.Text)
+	}
+	if !strings.Contains(article.HTML, `href="https://gophertimes.example/spec#Type_parameters"`) {
+		t.Errorf("expected relative links resolved in the HTML, got %q", article.HTML)
+	}
+}
+
+func TestExtractMetadataFallbacks(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, min, sec int) *time.Time {
+		t := time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+		return &t
+	}
+	const body = `<body><article><p>Enough words in a paragraph to be picked as the article, with a comma.</p></article></body>`
+
+	cases := []struct {
+		name      string
+		source    string
+		title     string
+		byline    string
+		published *time.Time
+	}{
+		{
+			name: "open graph before twitter and the title tag",
+			source: `<head><title>Page title | Site</title><meta name="twitter:title" content="Twitter title">
+				<meta property="og:title" content="Open Graph title"></head>` + body,
+			title: "Open Graph title",
+		},
+		{
+			name:   "twitter title",
+			source: `<head><title>Page title | Site</title><meta name="twitter:title" content="Twitter title"></head>` + body,
+			title:  "Twitter title",
+		},
+		{
+			name: "JSON-LD",
+			source: `<head><script type="application/ld+json">{"@context":"https://schema.org","@graph":[{"@type":"WebSite","name":"Site"},
+				{"@type":"NewsArticle","headline":"Linked data headline","author":[{"@type":"Person","name":"Ann"},{"name":"Bob"}],
+				"datePublished":"2020-01-02T03:04:05+01:00"}]}</script></head>` + body,
+			title:     "Linked data headline",
+			byline:    "Ann, Bob",
+			published: date(2020, 1, 2, 2, 4, 5),
+		},
+		{
+			name:   "site name after the title",
+			source: `<head><title>  A long enough article title  —  Site Name</title></head>` + body,
+			title:  "A long enough article title",
+		},
+		{
+			name:   "site name before the title",
+			source: `<head><title>Site » A long enough article title</title></head>` + body,
+			title:  "A long enough article title",
+		},
+		{
+			name:   "title too short to split",
+			source: `<head><title>Go 1.18 | Blog</title></head>` + body,
+			title:  "Go 1.18 | Blog",
+		},
+		{
+			name:   "first heading without a title tag",
+			source: `<body><article><h1>Heading title</h1><p>Enough words in a paragraph to be picked as the article, with a comma.</p></article></body>`,
+			title:  "Heading title",
+		},
+		{
+			name: "byline markup when the author meta is a URL",
+			source: `<head><meta property="article:author" content="https://social.example/jane"></head>
+				<body><article><span class="author">By Sam Smith</span><p>Enough words in a paragraph to be picked, with a comma.</p></article></body>`,
+			byline: "Sam Smith",
+		},
+		{
+			name:      "date meta in words",
+			source:    `<head><meta name="date" content="March 4, 2021"></head>` + body,
+			published: date(2021, 3, 4, 0, 0, 0),
+		},
+		{
+			name:      "time element",
+			source:    `<body><article><time datetime="2019-07-04">July 4th</time><p>Enough words in a paragraph to be picked, with a comma.</p></article></body>`,
+			published: date(2019, 7, 4, 0, 0, 0),
+		},
+		{
+			name:   "unparseable date",
+			source: `<head><meta property="article:published_time" content="last Tuesday"></head>` + body,
+		},
+	}
+
+	for _, tc := range cases {
+		article := Extract(tc.source, "")
+		if article.Title != tc.title {
+			t.Errorf("%s: title %q, want %q", tc.name, article.Title, tc.title)
+		}
+		if article.Byline != tc.byline {
+			t.Errorf("%s: byline %q, want %q", tc.name, article.Byline, tc.byline)
+		}
+		switch {
+		case tc.published == nil && article.PublishedAt != nil:
+			t.Errorf("%s: expected no publish date, got %v", tc.name, article.PublishedAt)
+		case tc.published != nil && (article.PublishedAt == nil || !article.PublishedAt.Equal(*tc.published)):
+			t.Errorf("%s: published_at %v, want %v", tc.name, article.PublishedAt, tc.published)
+		}
+	}
+}
+
+func TestExtractEmptyAndInvalidHTML(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		text   string
+	}{
+		{"empty", "", ""},
+		{"whitespace", " \n\t ", ""},
+		{"head only", "<html><head></head></html>", ""},
+		{"unclosed tags", "<p>unclosed <b>bold", "unclosed bold"},
+		{"stray brackets", "<<<>>>not html", "not html"},
+		{"binary", "\x00\xff\xfe", ""},
+	}
+
+	for _, tc := range cases {
+		article := Extract(tc.source, "")
+		if !strings.Contains(article.Text, tc.text) || (tc.text == "" && strings.TrimSpace(strings.Trim(article.Text, "�")) != "") {
+			t.Errorf("%s: text %q, want %q", tc.name, article.Text, tc.text)
+		}
+		if article.PublishedAt != nil || article.Byline != "" || article.LeadImageURL != "" {
+			t.Errorf("%s: expected no metadata, got %+v", tc.name, article)
+		}
+		if strings.Contains(article.HTML, "<<") || strings.Contains(article.HTML, "\x00") {
+			t.Errorf("%s: expected escaped HTML, got %q", tc.name, article.HTML)
+		}
+	}
+
+	if got := Extract("<html><head><title>Only a title here please</title></head></html>", ""); got.Title != "Only a title here please" || got.Text != "" {
+		t.Errorf("page without a body: got %+v", got)
+	}
+}
@@ -0,0 +1,162 @@
+package document
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedTags maps every element kept by the sanitizer to the attributes it may keep
+var allowedTags = map[string][]string{
+	"a": {"href", "title"}, "abbr": {"title"}, "b": nil, "blockquote": {"cite"}, "br": nil,
+	"caption": nil, "cite": nil, "code": nil, "dd": nil, "del": nil, "dl": nil, "dt": nil, "em": nil,
+	"figcaption": nil, "figure": nil, "h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"hr": nil, "i": nil, "img": {"src", "alt", "title", "width", "height"}, "ins": nil, "kbd": nil,
+	"li": nil, "mark": nil, "ol": {"start"}, "p": nil, "pre": nil, "q": {"cite"}, "s": nil,
+	"small": nil, "strong": nil, "sub": nil, "sup": nil, "table": nil, "tbody": nil,
+	"td": {"colspan", "rowspan"}, "tfoot": nil, "th": {"colspan", "rowspan", "scope"}, "thead": nil,
+	"time": {"datetime"}, "tr": nil, "u": nil, "ul": nil,
+}
+
+// droppedTags are removed together with their content; any other element that is not
+// allowed is unwrapped, keeping its children
+var droppedTags = map[string]bool{
+	"applet": true, "audio": true, "base": true, "button": true, "canvas": true, "embed": true,
+	"form": true, "frame": true, "frameset": true, "head": true, "iframe": true, "input": true,
+	"link": true, "math": true, "meta": true, "noscript": true, "object": true, "script": true,
+	"select": true, "style": true, "svg": true, "template": true, "textarea": true, "title": true,
+	"video": true,
+}
+
+// urlAttributes hold URLs, which must resolve to one of allowedSchemes
+var urlAttributes = map[string]bool{"href": true, "src": true, "cite": true}
+
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// Sanitize rewrites untrusted HTML keeping only allowlisted elements and attributes.
+// Relative URLs are resolved against baseURL when it is an absolute URL; URLs with any
+// other scheme than http, https or mailto (javascript:, data:, ...) are dropped.
+func Sanitize(source, baseURL string) string {
+	return SanitizeNode(Parse(source), baseURL)
+}
+
+// ContainsMarkup reports whether s has anything a browser would parse as a tag or comment,
+// so plain text can be stored as is instead of being escaped by Sanitize
+func ContainsMarkup(s string) bool {
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+	read := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// A tag cut off by the end of s is dropped by the tokenizer but is still markup
+			return read < len(s)
+		case html.TextToken:
+			read += len(tokenizer.Raw())
+		default:
+			return true
+		}
+	}
+}
+
+// SanitizeNode renders the children of a parsed node as sanitized HTML
+func SanitizeNode(n *Node, baseURL string) string {
+	base, err := url.Parse(baseURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+
+	var b strings.Builder
+	for _, child := range n.Children {
+		writeSanitized(&b, child, base)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func writeSanitized(b *strings.Builder, n *Node, base *url.URL) {
+	switch n.Type {
+	case TextNode:
+		b.WriteString(html.EscapeString(n.Text))
+		return
+	case DocumentNode:
+		for _, child := range n.Children {
+			writeSanitized(b, child, base)
+		}
+		return
+	}
+
+	if droppedTags[n.Tag] {
+		return
+	}
+	allowed, ok := allowedTags[n.Tag]
+	if !ok {
+		for _, child := range n.Children {
+			writeSanitized(b, child, base)
+		}
+		return
+	}
+
+	attrs := []Attribute{}
+	for _, name := range allowed {
+		if !n.HasAttr(name) {
+			continue
+		}
+		value := strings.TrimSpace(n.Attr(name))
+		if urlAttributes[name] {
+			if value = safeURL(value, base); value == "" {
+				continue
+			}
+		}
+		attrs = append(attrs, Attribute{Name: name, Value: value})
+	}
+	if n.Tag == "img" && (len(attrs) == 0 || attrs[0].Name != "src") {
+		// An image without a usable source shows nothing
+		return
+	}
+	if n.Tag == "a" && len(attrs) > 0 && attrs[0].Name == "href" {
+		attrs = append(attrs, Attribute{Name: "rel", Value: "nofollow noopener noreferrer"})
+	}
+
+	b.WriteString("<" + n.Tag)
+	for _, attr := range attrs {
+		b.WriteString(" " + attr.Name + `="` + html.EscapeString(attr.Value) + `"`)
+	}
+	b.WriteString(">")
+	if voidElements[n.Tag] {
+		return
+	}
+	for _, child := range n.Children {
+		writeSanitized(b, child, base)
+	}
+	b.WriteString("</" + n.Tag + ">")
+}
+
+// safeURL resolves a URL attribute against base, returning "" when its scheme is not allowed
+func safeURL(value string, base *url.URL) string {
+	// Browsers ignore control characters and whitespace inside schemes ("java\tscript:")
+	cleaned := strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+
+	u, err := url.Parse(cleaned)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme == "" {
+		// Still relative: keep paths and fragments, which cannot run code
+		if strings.Contains(cleaned, ":") && !strings.HasPrefix(cleaned, "/") && !strings.HasPrefix(cleaned, "#") && !strings.HasPrefix(cleaned, "?") {
+			return ""
+		}
+		return cleaned
+	}
+	if !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return ""
+	}
+	return u.String()
+}
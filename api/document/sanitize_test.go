@@ -0,0 +1,179 @@
+package document
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// sanitizeCases are XSS payloads with the markup expected to survive them
+var sanitizeCases = []struct {
+	name   string
+	source string
+	want   string
+}{
+	{"event handler", `<img src=x onerror=alert(1)>`, `<img src="x">`},
+	{"event handler on allowed element", `<p onclick="alert(1)" ONMOUSEOVER=alert(2)>hi</p>`, `<p>hi</p>`},
+	{"entity-encoded scheme", `<a href="jaVa&#x53;cript:alert(1)">x</a>`, `<a>x</a>`},
+	{"decimal entities without semicolons", `<a href="&#106&#97&#118&#97&#115&#99&#114&#105&#112&#116&#58alert(1)">x</a>`, `<a>x</a>`},
+	{"whitespace in scheme", "<a href=\"java\tscript:alert(1)\">x</a>", `<a>x</a>`},
+	{"leading space and case", `<a href="  JAVASCRIPT:alert(1)">x</a>`, `<a>x</a>`},
+	{"data URL", `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`, `<a>x</a>`},
+	{"vbscript", `<img src="vbscript:msgbox(1)">`, ``},
+	{"script in svg", `<svg><script>alert(1)</script></svg>`, ``},
+	{"svg onload", `<svg onload=alert(1)><circle r="1"/></svg>after`, `after`},
+	{"script in math", `<math><mi xlink:href="javascript:alert(1)">x</mi></math>`, ``},
+	{"empty comment", `<!--><script>alert(1)</script>-->`, `--&gt;`},
+	{"comment ending early", `<!--x--!><img src=x onerror=alert(1)>-->`, `<img src="x">--&gt;`},
+	{"conditional comment", `<!--[if IE]><script>alert(1)</script><![endif]-->ok`, `ok`},
+	{"bogus comment", `<? <img src=x onerror=alert(1)> ?>`, `?&gt;`},
+	{"unclosed attribute", `<a href="https://ok.example/ onclick=alert(1)>x</a>`, ``},
+	{"markup in an attribute value", `<a title="x><img src=x onerror=alert(1)>" href="/ok">y</a>`,
+		`<a href="/ok" title="x&gt;&lt;img src=x onerror=alert(1)&gt;" rel="nofollow noopener noreferrer">y</a>`},
+	{"unterminated tag", `ok<img src=x onerror=alert(1)`, `ok`},
+	{"self-closing script", `<script/>alert(1)</script>ok`, `ok`},
+	{"nested script name", `<scr<script>ipt>alert(1)</script>`, `ipt&gt;alert(1)`},
+	{"end tag inside script string", `<script>var s = "</script>"; alert(1)</script>ok`, `&#34;; alert(1)ok`},
+	{"style element", `<style>body{background:url("javascript:alert(1)")}</style><p>ok</p>`, `<p>ok</p>`},
+	{"markup in style", `<style><img src=x onerror=alert(1)></style>`, ``},
+	{"style breakout", `<style></style ><img src=x onerror=alert(1)>`, `<img src="x">`},
+	{"style attribute", `<p style="background:url(javascript:alert(1))">x</p>`, `<p>x</p>`},
+	{"noscript breakout", `<noscript><p title="</noscript><img src=x onerror=alert(1)>">`, `<img src="x">&#34;&gt;`},
+	{"textarea breakout", `<textarea><script>alert(1)</script></textarea>ok`, `ok`},
+	{"title breakout", `<title><img src=x onerror=alert(1)></title>ok`, `ok`},
+	{"iframe srcdoc", `<iframe srcdoc="<script>alert(1)</script>"></iframe>`, ``},
+	{"form action", `<form action="javascript:alert(1)"><button>go</button></form>`, ``},
+	{"meta refresh", `<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`, ``},
+	{"base href", `<base href="javascript:/"><a href="x">y</a>`, `<a href="x" rel="nofollow noopener noreferrer">y</a>`},
+	{"unknown element unwrapped", `<custom-el onclick=alert(1)><b>bold</b></custom-el>`, `<b>bold</b>`},
+	{"NUL in tag name", "<scr\x00ipt>alert(1)</scr\x00ipt>", `alert(1)`},
+	{"safe link", `<a href="https://example.com/?a=1&amp;b=2" target="_blank">x</a>`,
+		`<a href="https://example.com/?a=1&amp;b=2" rel="nofollow noopener noreferrer">x</a>`},
+	{"text is escaped", `1 < 2 & "quotes"`, `1 &lt; 2 &amp; &#34;quotes&#34;`},
+}
+
+func TestSanitizeXSSPayloads(t *testing.T) {
+	for _, tc := range sanitizeCases {
+		got := Sanitize(tc.source, "")
+		if got != tc.want {
+			t.Errorf("%s: Sanitize(%q) = %q, want %q", tc.name, tc.source, got, tc.want)
+		}
+		if problem := unsafeMarkup(got); problem != "" {
+			t.Errorf("%s: output %q has %s", tc.name, got, problem)
+		}
+		// Sanitizing is idempotent, so stored markup can be sanitized again on the way out
+		if again := Sanitize(got, ""); again != got {
+			t.Errorf("%s: sanitizing %q again gave %q", tc.name, got, again)
+		}
+	}
+}
+
+// unsafeMarkup parses html as a browser would and describes the first element or attribute
+// that the allowlist should have removed, or returns ""
+func unsafeMarkup(source string) string {
+	doc, err := html.Parse(strings.NewReader("<body>" + source))
+	if err != nil {
+		return err.Error()
+	}
+
+	var check func(*html.Node) string
+	check = func(n *html.Node) string {
+		if n.Type == html.ElementNode && n.Data != "html" && n.Data != "head" && n.Data != "body" {
+			allowed, ok := allowedTags[n.Data]
+			if !ok || n.Namespace != "" {
+				return "element <" + n.Data + ">"
+			}
+			for _, attr := range n.Attr {
+				if !contains(allowed, attr.Key) && !(n.Data == "a" && attr.Key == "rel") {
+					return "attribute " + attr.Key
+				}
+				if u, err := url.Parse(attr.Val); urlAttributes[attr.Key] && (err != nil || u.Scheme != "" && !allowedSchemes[u.Scheme]) {
+					return "URL " + attr.Val
+				}
+			}
+		}
+		if n.Type == html.CommentNode {
+			return "a comment"
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if problem := check(child); problem != "" {
+				return problem
+			}
+		}
+		return ""
+	}
+	return check(doc)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSanitizeResolvesRelativeURLs(t *testing.T) {
+	got := Sanitize(`<a href="../b?c=1#d">x</a><img src="//cdn.example/i.png"><a href="#top">t</a>`, "https://example.com/a/page")
+	want := `<a href="https://example.com/b?c=1#d" rel="nofollow noopener noreferrer">x</a>` +
+		`<img src="https://cdn.example/i.png">` +
+		`<a href="https://example.com/a/page#top" rel="nofollow noopener noreferrer">t</a>`
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestContainsMarkup(t *testing.T) {
+	cases := []struct {
+		text string
+		want bool
+	}{
+		{"plain text", false},
+		{"a < b and c > d", false},
+		{"1 <2 and <3", false},
+		{"AT&T &amp; friends", false},
+		{"", false},
+		{"<b>bold</b>", true},
+		{"text <!-- comment -->", true},
+		{"</p>", true},
+		{"<!DOCTYPE html>", true},
+		{"<? processing ?>", true},
+		{"cut off <img src=x", true},
+	}
+
+	for _, tc := range cases {
+		if got := ContainsMarkup(tc.text); got != tc.want {
+			t.Errorf("ContainsMarkup(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestParseClosesImpliedElements(t *testing.T) {
+	doc := Parse(`<ul><li>one<li>two</ul><p>first<p>second<div>block</div><table><tr><td>a<td>b</table>`)
+
+	if items := doc.FindAll("li"); len(items) != 2 || items[1].Parent.Tag != "ul" {
+		t.Errorf("expected two sibling list items, got %d", len(items))
+	}
+	paragraphs := doc.FindAll("p")
+	if len(paragraphs) != 2 || paragraphs[0].TextContent() != "first" || paragraphs[1].TextContent() != "second" {
+		t.Errorf("expected <p> to close the open paragraph, got %d paragraphs", len(paragraphs))
+	}
+	if div := doc.Find("div"); div == nil || div.Parent.Tag == "p" {
+		t.Error("expected <div> to close the open paragraph")
+	}
+	if cells := doc.FindAll("td"); len(cells) != 2 || cells[1].Parent.Tag != "tr" {
+		t.Errorf("expected two sibling cells, got %d", len(cells))
+	}
+
+	title := Parse(`<title>A &amp; B <b>not a tag</b></title>`).Find("title")
+	if title == nil || title.TextContent() != "A & B <b>not a tag</b>" {
+		t.Errorf("expected the title as unescaped raw text, got %+v", title)
+	}
+	script := Parse(`<script>if (a &amp;&amp; b < c) {}</script>`).Find("script")
+	if script == nil || script.TextContent() != "if (a &amp;&amp; b < c) {}" {
+		t.Errorf("expected script text kept verbatim, got %+v", script)
+	}
+}
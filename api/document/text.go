@@ -0,0 +1,108 @@
+package document
+
+import "strings"
+
+// blockTags start and end a paragraph when rendering plain text
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// PlainText renders the text of n with paragraphs separated by blank lines,
+// list items prefixed with "- " and preformatted text kept as is
+func PlainText(n *Node) string {
+	t := &textWriter{}
+	t.node(n, false)
+
+	lines := strings.Split(t.b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// textWriter accumulates plain text, deferring line breaks and spaces until the next word
+type textWriter struct {
+	b     strings.Builder
+	lines int // line breaks owed before the next text
+	space bool
+}
+
+func (t *textWriter) node(n *Node, pre bool) {
+	switch n.Type {
+	case TextNode:
+		if pre {
+			t.write(n.Text)
+			return
+		}
+		words := strings.Fields(n.Text)
+		if len(words) == 0 {
+			t.space = t.space || n.Text != ""
+			return
+		}
+		if isSpace(n.Text[0]) {
+			t.space = true
+		}
+		t.write(strings.Join(words, " "))
+		t.space = isSpace(n.Text[len(n.Text)-1])
+		return
+	case ElementNode:
+		if droppedTags[n.Tag] {
+			return
+		}
+		switch n.Tag {
+		case "br":
+			t.breakLines(1)
+			return
+		case "td", "th":
+			t.space = true
+		}
+	}
+
+	block := n.Type == ElementNode && blockTags[n.Tag]
+	if block {
+		if n.Tag == "li" || n.Tag == "tr" || n.Tag == "dt" {
+			t.breakLines(1)
+		} else {
+			t.breakLines(2)
+		}
+	}
+	if n.Tag == "li" {
+		t.write("- ")
+		t.space = false
+	}
+	for _, child := range n.Children {
+		t.node(child, pre || n.Tag == "pre")
+	}
+	if block {
+		if n.Tag == "li" || n.Tag == "tr" || n.Tag == "dt" {
+			t.breakLines(1)
+		} else {
+			t.breakLines(2)
+		}
+	}
+}
+
+func (t *textWriter) breakLines(n int) {
+	if n > t.lines {
+		t.lines = n
+	}
+	t.space = false
+}
+
+func (t *textWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	if t.b.Len() > 0 {
+		if t.lines > 0 {
+			t.b.WriteString(strings.Repeat("\n", t.lines))
+		} else if t.space {
+			t.b.WriteString(" ")
+		}
+	}
+	t.lines, t.space = 0, false
+	t.b.WriteString(s)
+}
@@ -0,0 +1,97 @@
+// Package embedding turns memory text and search queries into vectors for semantic search
+package embedding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"api/config"
+	"api/models"
+)
+
+// maxTextLength bounds the text embedded per memory, in bytes, like the Next.js indexer does
+const maxTextLength = 5000
+
+// Embedder turns texts into vectors. Vectors from one Embedder all have Dimensions entries;
+// vectors are only comparable when they come from the same Model.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Model() string
+	Dimensions() int
+}
+
+// New returns the Embedder for cfg, or nil when semantic search is disabled
+func New(cfg config.Embedding) (Embedder, error) {
+	switch cfg.Provider {
+	case config.EmbeddingHash:
+		return NewHashEmbedder(cfg.Dimensions), nil
+	case config.EmbeddingHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("EMBEDDING_URL is required for the http embedding provider")
+		}
+		return NewHTTPEmbedder(cfg.URL, cfg.APIKey, cfg.Model, cfg.Dimensions), nil
+	case config.EmbeddingNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported EMBEDDING_PROVIDER %q (use hash, http or none)", cfg.Provider)
+	}
+}
+
+// EmbedOne embeds a single text
+func EmbedOne(ctx context.Context, e Embedder, text string) ([]float32, error) {
+	vectors, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// MemoryText is the text a memory is embedded from: its title, notes and captured text
+func MemoryText(memory models.MemoryResponse) string {
+	parts := []string{memory.Title}
+	for _, field := range []string{memory.Notes.String, memory.SelectedText.String, memory.Content.String} {
+		if strings.TrimSpace(field) != "" {
+			parts = append(parts, field)
+		}
+	}
+	if len(memory.Tags) > 0 {
+		parts = append(parts, strings.Join(memory.Tags, ", "))
+	}
+
+	text := strings.Join(parts, "\n\n")
+	if len(text) > maxTextLength {
+		cut := maxTextLength
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	return text
+}
+
+// TextHash identifies the embedded text, so unchanged memories are not embedded again
+func TextHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize scales v to unit length in place
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
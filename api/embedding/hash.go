@@ -0,0 +1,68 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// HashEmbedder embeds texts locally by feature hashing their words and word pairs.
+// It is deterministic and needs no network, which suits tests and offline installs,
+// but it only captures shared vocabulary, not meaning.
+type HashEmbedder struct {
+	dimensions int
+}
+
+// NewHashEmbedder creates a HashEmbedder producing vectors of the given size
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	return &HashEmbedder{dimensions: dimensions}
+}
+
+// Model names the hashing scheme and size, since vectors of different sizes are not comparable
+func (e *HashEmbedder) Model() string {
+	return "local-hash-" + strconv.Itoa(e.dimensions)
+}
+
+// Dimensions is the size of every vector
+func (e *HashEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+// Embed hashes each text into a unit vector
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *HashEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		e.add(vector, word, 1)
+		if i > 0 {
+			e.add(vector, words[i-1]+" "+word, 0.5)
+		}
+	}
+	return normalize(vector)
+}
+
+// add hashes a feature to a bucket; the sign comes from another bit of the hash so
+// colliding features tend to cancel out instead of piling up
+func (e *HashEmbedder) add(vector []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[sum%uint64(e.dimensions)] += weight
+}
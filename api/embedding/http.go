@@ -0,0 +1,151 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxBatchSize bounds the texts sent in one embeddings request
+const maxBatchSize = 64
+
+// StatusError is a non-2xx response from the embeddings endpoint
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("embeddings endpoint returned %d: %s", e.Code, e.Body)
+}
+
+// Retryable reports whether the request may succeed later: rate limits and server errors
+func (e *StatusError) Retryable() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code >= 500
+}
+
+// HTTPEmbedder calls an OpenAI-compatible embeddings endpoint, which OpenAI, Gemini,
+// Ollama and most hosted model servers provide
+type HTTPEmbedder struct {
+	url        string
+	apiKey     string
+	model      string
+	dimensions int
+	client     *http.Client
+}
+
+// NewHTTPEmbedder creates an HTTPEmbedder posting to url
+func NewHTTPEmbedder(url, apiKey, model string, dimensions int) *HTTPEmbedder {
+	return &HTTPEmbedder{
+		url:        url,
+		apiKey:     apiKey,
+		model:      model,
+		dimensions: dimensions,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Model is the configured model name
+func (e *HTTPEmbedder) Model() string {
+	return e.model
+}
+
+// Dimensions is the size of every vector
+func (e *HTTPEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+type embeddingsRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed requests the vectors of texts in batches of maxBatchSize
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *HTTPEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingsRequest{Model: e.model, Input: texts, Dimensions: e.dimensions})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(bytes.TrimSpace(snippet))}
+	}
+
+	var decoded embeddingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("invalid embeddings response: %w", err)
+	}
+	if len(decoded.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings response has %d vectors for %d texts", len(decoded.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range decoded.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings response has out of range index %d", item.Index)
+		}
+		vector, err := e.fit(item.Embedding)
+		if err != nil {
+			return nil, err
+		}
+		vectors[item.Index] = vector
+	}
+	return vectors, nil
+}
+
+// fit checks a vector's size. Endpoints that ignore the dimensions parameter may return
+// longer vectors; like the Next.js indexer, those are truncated and renormalized.
+func (e *HTTPEmbedder) fit(vector []float32) ([]float32, error) {
+	switch {
+	case len(vector) == e.dimensions:
+		return vector, nil
+	case len(vector) > e.dimensions:
+		return normalize(vector[:e.dimensions]), nil
+	default:
+		return nil, fmt.Errorf("embedding has %d dimensions, expected %d", len(vector), e.dimensions)
+	}
+}
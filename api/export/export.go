@@ -0,0 +1,121 @@
+// Package export writes a user's memories as a downloadable archive.
+// Every format is written incrementally so an export never holds more than one page of memories.
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"api/models"
+)
+
+// Supported formats for the format query parameter
+const (
+	FormatJSONL    = "jsonl"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Writer receives memories one at a time; Close completes the archive but does not close the underlying writer
+type Writer interface {
+	Write(record Record) error
+	Close() error
+}
+
+// Record is the flattened form of a memory shared by every export format and by JSONL import
+type Record struct {
+	ID            string            `json:"id,omitempty"`
+	URL           string            `json:"url,omitempty"`
+	Title         string            `json:"title"`
+	ContentType   string            `json:"content_type"`
+	Content       string            `json:"content,omitempty"`
+	SelectedText  string            `json:"selected_text,omitempty"`
+	ContextBefore string            `json:"context_before,omitempty"`
+	ContextAfter  string            `json:"context_after,omitempty"`
+	FullContext   string            `json:"full_context,omitempty"`
+	ElementType   string            `json:"element_type,omitempty"`
+	PageSection   string            `json:"page_section,omitempty"`
+	XPath         string            `json:"xpath,omitempty"`
+	Tags          []string          `json:"tags"`
+	Notes         string            `json:"notes,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	ScrapedAt     time.Time         `json:"scraped_at"`
+	VideoData     *models.VideoData `json:"video_data,omitempty"`
+	Links         []models.Link     `json:"links"`
+
+	// Readable article, for captures that sent the page markup
+	ContentHTML  string     `json:"content_html,omitempty"`
+	Byline       string     `json:"byline,omitempty"`
+	PublishedAt  *time.Time `json:"published_at,omitempty"`
+	LeadImageURL string     `json:"lead_image_url,omitempty"`
+	Language     string     `json:"language,omitempty"`
+}
+
+// NewRecord flattens a memory for export
+func NewRecord(m models.MemoryResponse) Record {
+	record := Record{
+		ID:            m.ID,
+		URL:           m.URL.String,
+		Title:         m.Title,
+		ContentType:   m.ContentType,
+		Content:       m.Content.String,
+		SelectedText:  m.SelectedText.String,
+		ContextBefore: m.ContextBefore.String,
+		ContextAfter:  m.ContextAfter.String,
+		FullContext:   m.FullContext.String,
+		ElementType:   m.ElementType.String,
+		PageSection:   m.PageSection.String,
+		XPath:         m.XPath.String,
+		Tags:          m.Tags,
+		Notes:         m.Notes.String,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+		ScrapedAt:     m.ScrapedAt,
+		VideoData:     m.VideoData,
+		Links:         []models.Link{},
+		ContentHTML:   m.ContentHTML.String,
+		Byline:        m.Byline.String,
+		LeadImageURL:  m.LeadImageURL.String,
+		Language:      m.Language.String,
+	}
+	if m.PublishedAt.Valid {
+		record.PublishedAt = &m.PublishedAt.Time
+	}
+	if record.Tags == nil {
+		record.Tags = []string{}
+	}
+	for _, link := range m.Links {
+		record.Links = append(record.Links, models.Link{Text: link.Text, Href: link.Href, Title: link.Title})
+	}
+	return record
+}
+
+// NewWriter returns the Writer for format writing to w
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatMarkdown:
+		return newMarkdownWriter(w), nil
+	case FormatHTML:
+		return newHTMLWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// ContentType returns the MIME type and file extension of an export format
+func ContentType(format string) (mimeType, extension string) {
+	switch format {
+	case FormatJSONL:
+		return "application/x-ndjson", "jsonl"
+	case FormatMarkdown:
+		return "application/zip", "zip"
+	case FormatHTML:
+		return "text/html; charset=utf-8", "html"
+	default:
+		return "application/octet-stream", "bin"
+	}
+}
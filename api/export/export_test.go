@@ -0,0 +1,284 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"api/models"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	created = time.Date(2024, 3, 9, 14, 30, 0, 0, time.UTC)
+	updated = created.Add(time.Hour)
+)
+
+// testRecord returns a record with every capture field set
+func testRecord(id, title string) Record {
+	return Record{
+		ID:            id,
+		URL:           "https://go.dev/doc/effective_go",
+		Title:         title,
+		ContentType:   "text",
+		Content:       "Formatting issues are the most contentious",
+		SelectedText:  "gofmt <does> it\nfor you",
+		ContextBefore: "With Go we take an unusual approach",
+		ContextAfter:  "and let the machine take care of most formatting issues",
+		FullContext:   "With Go we take an unusual approach: gofmt does it for you",
+		ElementType:   "p",
+		PageSection:   "Formatting",
+		XPath:         "/html/body/div[2]/p[3]",
+		Tags:          []string{"golang", "style \"guide\""},
+		Notes:         "Read before reviewing",
+		CreatedAt:     created,
+		UpdatedAt:     updated,
+		ScrapedAt:     created,
+		Links:         []models.Link{{Text: "gofmt", Href: "https://pkg.go.dev/cmd/gofmt"}},
+	}
+}
+
+// export writes records in format and returns the output
+func export(t *testing.T, format string, records ...Record) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	writer, err := NewWriter(format, &out)
+	if err != nil {
+		t.Fatalf("NewWriter(%q): %v", format, err)
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestJSONLRoundTripsOneRecordPerLine(t *testing.T) {
+	first := testRecord("11111111-aaaa", "Effective Go")
+	second := testRecord("22222222-bbbb", "Go <Proverbs>")
+	second.Tags = []string{}
+	second.Links = []models.Link{}
+	published := created.Add(-24 * time.Hour)
+	second.PublishedAt = &published
+	second.VideoData = &models.VideoData{Platform: "youtube", Timestamp: 90, Duration: 600, FormattedTimestamp: "1:30"}
+
+	out := export(t, FormatJSONL, first, second)
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), out)
+	}
+	if !strings.Contains(lines[1], "Go <Proverbs>") {
+		t.Errorf("expected HTML characters to be written unescaped, got %s", lines[1])
+	}
+
+	for i, want := range []Record{first, second} {
+		var got Record
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("line %d: got %+v, want %+v", i+1, got, want)
+		}
+	}
+}
+
+func TestMarkdownArchiveNamesAndFrontMatter(t *testing.T) {
+	first := testRecord("11111111-aaaa", "Effective Go: Formatting!")
+	clash := testRecord("22222222-bbbb", "Effective Go: Formatting!")
+	untitled := testRecord("33333333-cccc", "???")
+	long := testRecord("44444444-dddd", strings.Repeat("word ", 20))
+
+	out := export(t, FormatMarkdown, first, clash, untitled, long)
+	archive, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+
+	var names []string
+	files := map[string]string{}
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		body, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		files[file.Name] = string(data)
+		if !file.Modified.Equal(updated) {
+			t.Errorf("%s: expected modified time %v, got %v", file.Name, updated, file.Modified)
+		}
+	}
+
+	wantNames := []string{
+		"2024-03-09-effective-go-formatting.md",
+		"2024-03-09-effective-go-formatting-22222222.md",
+		"2024-03-09-memory.md",
+		"2024-03-09-" + strings.TrimRight(strings.Repeat("word-", 12), "-") + ".md",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("expected entries %q, got %q", wantNames, names)
+	}
+
+	content := files[wantNames[0]]
+	fields, body := parseFrontMatter(t, content)
+	wantFields := map[string]string{
+		"id":             first.ID,
+		"title":          first.Title,
+		"url":            first.URL,
+		"content_type":   first.ContentType,
+		"notes":          first.Notes,
+		"selected_text":  first.SelectedText,
+		"context_before": first.ContextBefore,
+		"context_after":  first.ContextAfter,
+		"full_context":   first.FullContext,
+		"element_type":   first.ElementType,
+		"page_section":   first.PageSection,
+		"xpath":          first.XPath,
+		"created_at":     "2024-03-09T14:30:00Z",
+		"updated_at":     "2024-03-09T15:30:00Z",
+		"scraped_at":     "2024-03-09T14:30:00Z",
+	}
+	for key, want := range wantFields {
+		if got := fields[key]; got != want {
+			t.Errorf("front matter %s: expected %q, got %q", key, want, got)
+		}
+	}
+	if tags := fields["tags"]; tags != `["golang", "style \"guide\""]` {
+		t.Errorf("front matter tags: got %s", tags)
+	}
+	if _, ok := fields["byline"]; ok {
+		t.Errorf("expected empty fields to be omitted, got byline %q", fields["byline"])
+	}
+
+	for _, section := range []string{
+		"# Effective Go: Formatting!\n\n<https://go.dev/doc/effective_go>",
+		"## Notes\n\nRead before reviewing",
+		"## Selection\n\n> gofmt <does> it\n> for you",
+		"## Content\n\nFormatting issues are the most contentious",
+		"## Links\n\n- [gofmt](<https://pkg.go.dev/cmd/gofmt>)",
+	} {
+		if !strings.Contains(body, section) {
+			t.Errorf("expected body to contain %q, got:\n%s", section, body)
+		}
+	}
+}
+
+// parseFrontMatter splits a Markdown file into its decoded top-level front matter and its body.
+// List values are returned as written.
+func parseFrontMatter(t *testing.T, content string) (map[string]string, string) {
+	t.Helper()
+
+	if !strings.HasPrefix(content, "---\n") {
+		t.Fatalf("expected front matter, got:\n%s", content)
+	}
+	end := strings.Index(content, "\n---\n")
+	if end < 0 {
+		t.Fatalf("unterminated front matter:\n%s", content)
+	}
+
+	fields := map[string]string{}
+	for _, line := range strings.Split(content[4:end], "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok || strings.HasPrefix(key, " ") {
+			continue
+		}
+		if strings.HasPrefix(value, "[") {
+			fields[key] = value
+			continue
+		}
+		var decoded string
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			t.Fatalf("front matter %s is not a quoted string: %s", key, value)
+		}
+		fields[key] = decoded
+	}
+	return fields, content[end+5:]
+}
+
+func TestHTMLEscapesCapturedText(t *testing.T) {
+	hostile := testRecord("11111111-aaaa", `<script>alert("title")</script>`)
+	hostile.URL = "javascript:alert(1)"
+	hostile.Notes = `<img src=x onerror=alert(1)>`
+	hostile.SelectedText = `</blockquote><script>alert(2)</script>`
+	hostile.Tags = []string{`"><svg onload=alert(3)>`}
+	hostile.Links = []models.Link{{Text: "<b>bold</b>", Href: "javascript:alert(4)"}}
+	article := testRecord("22222222-bbbb", "Article")
+	article.ContentHTML = `<p onclick="alert(5)">Safe <em>text</em></p><script>alert(6)</script>`
+
+	out := export(t, FormatHTML, hostile, article)
+
+	doc, err := html.Parse(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("invalid HTML: %v", err)
+	}
+
+	var text strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "img", "svg", "b":
+				t.Errorf("captured text produced a <%s> element", n.Data)
+			}
+			for _, attr := range n.Attr {
+				if strings.HasPrefix(attr.Key, "on") {
+					t.Errorf("captured text produced a %s attribute on <%s>", attr.Key, n.Data)
+				}
+				if attr.Key == "href" && strings.HasPrefix(strings.ToLower(attr.Val), "javascript:") {
+					t.Errorf("captured URL produced href %q", attr.Val)
+				}
+			}
+		case html.TextNode:
+			text.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	for _, want := range []string{
+		`<script>alert("title")</script>`,
+		`<img src=x onerror=alert(1)>`,
+		`</blockquote><script>alert(2)</script>`,
+		`"><svg onload=alert(3)>`,
+		"<b>bold</b>",
+		"Safe text",
+		"2 memories",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("expected page text to contain %q", want)
+		}
+	}
+	if strings.Contains(text.String(), "alert(6)") {
+		t.Error("expected the article's script to be removed")
+	}
+}
+
+func TestNewWriterRejectsUnknownFormats(t *testing.T) {
+	if _, err := NewWriter("csv", io.Discard); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
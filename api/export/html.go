@@ -0,0 +1,98 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"api/document"
+)
+
+// htmlWriter writes a single self-contained HTML page, one article per memory
+type htmlWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newHTMLWriter(w io.Writer) (*htmlWriter, error) {
+	if err := htmlTemplate.ExecuteTemplate(w, "header", time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return &htmlWriter{w: w}, nil
+}
+
+func (h *htmlWriter) Write(record Record) error {
+	h.count++
+	return htmlTemplate.ExecuteTemplate(h.w, "memory", record)
+}
+
+func (h *htmlWriter) Close() error {
+	return htmlTemplate.ExecuteTemplate(h.w, "footer", h.count)
+}
+
+// htmlTemplate holds the page header, one article per memory and the footer so the page can be streamed
+var htmlTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 MST") },
+	// Article markup was sanitized on save; sanitizing again keeps the page safe regardless
+	"sanitized": func(markup, pageURL string) template.HTML {
+		return template.HTML(document.Sanitize(markup, pageURL))
+	},
+}).Parse(`
+{{- define "header" -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>BrowseBaba export</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #1f2328; line-height: 1.5; }
+header, footer { color: #59636e; }
+article { border-top: 1px solid #d1d9e0; padding: 1.5rem 0; }
+h2 { margin: 0 0 .25rem; font-size: 1.25rem; }
+.meta { color: #59636e; font-size: .875rem; }
+.tag { display: inline-block; background: #ddf4ff; color: #0969da; border-radius: 1rem; padding: 0 .5rem; margin-right: .25rem; }
+blockquote { border-left: .25rem solid #d1d9e0; margin: 1rem 0; padding: 0 1rem; color: #59636e; }
+.notes { background: #fff8c5; padding: .5rem 1rem; border-radius: .375rem; }
+.content, blockquote { white-space: pre-wrap; }
+.article img { max-width: 100%; }
+</style>
+</head>
+<body>
+<header><h1>BrowseBaba export</h1><p>Exported {{date .}}</p></header>
+{{end}}
+
+{{- define "memory"}}
+<article id="{{.ID}}">
+<h2>{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</h2>
+<p class="meta">{{.ContentType}} &middot; saved {{date .CreatedAt}}{{range .Tags}} <span class="tag">{{.}}</span>{{end}}</p>
+{{- with .VideoData}}
+<p class="meta">{{.Platform}} video{{with .VideoTitle}} &ldquo;{{.}}&rdquo;{{end}} at {{.FormattedTimestamp}}{{with .VideoURL}} &middot; <a href="{{.}}">watch</a>{{end}}</p>
+{{- end}}
+{{- with .Notes}}
+<div class="notes">{{.}}</div>
+{{- end}}
+{{- with .SelectedText}}
+<blockquote>{{.}}</blockquote>
+{{- end}}
+{{- if .ContentHTML}}
+<div class="article">{{sanitized .ContentHTML .URL}}</div>
+{{- else if .Content}}
+<div class="content">{{.Content}}</div>
+{{- end}}
+{{- with .Links}}
+<ul>
+{{- range .}}
+<li><a href="{{.Href}}">{{if .Text}}{{.Text}}{{else}}{{.Href}}{{end}}</a></li>
+{{- end}}
+</ul>
+{{- end}}
+</article>
+{{end}}
+
+{{- define "footer"}}
+<footer><p>{{.}} memories</p></footer>
+</body>
+</html>
+{{end}}
+`))
@@ -0,0 +1,26 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonlWriter writes one Record per line
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &jsonlWriter{encoder: encoder}
+}
+
+func (j *jsonlWriter) Write(record Record) error {
+	// Encode terminates every value with a newline
+	return j.encoder.Encode(record)
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,159 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// markdownWriter writes a ZIP archive with one Markdown file per memory
+type markdownWriter struct {
+	archive *zip.Writer
+	names   map[string]bool
+}
+
+func newMarkdownWriter(w io.Writer) *markdownWriter {
+	return &markdownWriter{archive: zip.NewWriter(w), names: map[string]bool{}}
+}
+
+func (m *markdownWriter) Write(record Record) error {
+	file, err := m.archive.CreateHeader(&zip.FileHeader{
+		Name:     m.fileName(record),
+		Method:   zip.Deflate,
+		Modified: record.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(file, markdown(record))
+	return err
+}
+
+func (m *markdownWriter) Close() error {
+	return m.archive.Close()
+}
+
+// slugPattern matches the runs of characters replaced by a dash in file names
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// fileName names a memory file by date and title slug, suffixed with the start of the ID when the name is taken
+func (m *markdownWriter) fileName(record Record) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(record.Title), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		slug = "memory"
+	}
+
+	name := record.CreatedAt.UTC().Format("2006-01-02") + "-" + slug
+	if m.names[name] {
+		id := record.ID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		name += "-" + id
+	}
+	m.names[name] = true
+	return name + ".md"
+}
+
+// markdown renders a memory as YAML front matter followed by its text. The front matter
+// keeps the capture context; content_html is left out because its text is the Content section
+func markdown(record Record) string {
+	var b strings.Builder
+
+	b.WriteString("---\n")
+	frontMatter(&b, "id", record.ID)
+	frontMatter(&b, "title", record.Title)
+	frontMatter(&b, "url", record.URL)
+	frontMatter(&b, "content_type", record.ContentType)
+	frontMatter(&b, "byline", record.Byline)
+	if record.PublishedAt != nil {
+		frontMatter(&b, "published_at", record.PublishedAt.UTC().Format(time.RFC3339))
+	}
+	frontMatter(&b, "lead_image_url", record.LeadImageURL)
+	frontMatter(&b, "language", record.Language)
+	frontMatter(&b, "notes", record.Notes)
+	frontMatter(&b, "selected_text", record.SelectedText)
+	frontMatter(&b, "context_before", record.ContextBefore)
+	frontMatter(&b, "context_after", record.ContextAfter)
+	frontMatter(&b, "full_context", record.FullContext)
+	frontMatter(&b, "element_type", record.ElementType)
+	frontMatter(&b, "page_section", record.PageSection)
+	frontMatter(&b, "xpath", record.XPath)
+	b.WriteString("tags: [")
+	for i, tag := range record.Tags {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(yamlString(tag))
+	}
+	b.WriteString("]\n")
+	frontMatter(&b, "created_at", record.CreatedAt.UTC().Format(time.RFC3339))
+	frontMatter(&b, "updated_at", record.UpdatedAt.UTC().Format(time.RFC3339))
+	frontMatter(&b, "scraped_at", record.ScrapedAt.UTC().Format(time.RFC3339))
+	if video := record.VideoData; video != nil {
+		b.WriteString("video:\n")
+		frontMatter(&b, "  platform", video.Platform)
+		fmt.Fprintf(&b, "  timestamp: %d\n", video.Timestamp)
+		fmt.Fprintf(&b, "  duration: %d\n", video.Duration)
+		frontMatter(&b, "  formatted_timestamp", video.FormattedTimestamp)
+		frontMatter(&b, "  video_title", video.VideoTitle)
+		frontMatter(&b, "  video_url", video.VideoURL)
+		frontMatter(&b, "  thumbnail_url", video.ThumbnailURL)
+	}
+	b.WriteString("---\n\n")
+
+	fmt.Fprintf(&b, "# %s\n\n", record.Title)
+	if record.URL != "" {
+		fmt.Fprintf(&b, "<%s>\n\n", record.URL)
+	}
+	if record.Notes != "" {
+		fmt.Fprintf(&b, "## Notes\n\n%s\n\n", record.Notes)
+	}
+	if record.SelectedText != "" {
+		b.WriteString("## Selection\n\n")
+		for _, line := range strings.Split(record.SelectedText, "\n") {
+			b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+		b.WriteString("\n")
+	}
+	if record.Content != "" {
+		fmt.Fprintf(&b, "## Content\n\n%s\n\n", record.Content)
+	}
+	if len(record.Links) > 0 {
+		b.WriteString("## Links\n\n")
+		for _, link := range record.Links {
+			text := link.Text
+			if text == "" {
+				text = link.Href
+			}
+			fmt.Fprintf(&b, "- [%s](<%s>)\n", strings.ReplaceAll(text, "]", "\\]"), link.Href)
+		}
+		b.WriteString("\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// frontMatter writes a key with a quoted scalar value, skipping empty values
+func frontMatter(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	b.WriteString(key + ": " + yamlString(value) + "\n")
+}
+
+// yamlString quotes a value as a double-quoted YAML scalar; JSON strings are valid YAML
+func yamlString(value string) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(b.String(), "\n")
+}
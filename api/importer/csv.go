@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumns maps the lowercase headers of Raindrop.io and Instapaper exports to fields
+var csvColumns = map[string]string{
+	"url":         "url",
+	"link":        "url",
+	"title":       "title",
+	"note":        "notes",
+	"notes":       "notes",
+	"excerpt":     "selection",
+	"selection":   "selection",
+	"description": "selection",
+	"highlights":  "content",
+	"folder":      "folder",
+	"tags":        "tags",
+	"created":     "created",
+	"timestamp":   "created",
+}
+
+// instapaperFolders are Instapaper's built-in folders; only Archive and Starred carry meaning as tags
+var instapaperFolders = map[string]bool{"unread": true}
+
+// readCSV reads a CSV export with a header row, such as those of Raindrop.io and Instapaper
+func readCSV(r io.Reader, fn func(Entry) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := csvColumns[name]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["url"]; !ok {
+		return errors.New("CSV header has no URL column")
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return err
+			}
+			if err := fn(Entry{Position: parseErr.Line, Err: err}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		entry := newEntry(line, field("url"), field("title"))
+		if folder := field("folder"); !instapaperFolders[strings.ToLower(folder)] {
+			entry.Folder = strings.Join(folderTags(folder), "/")
+		}
+		entry.Request.Tags = append(folderTags(entry.Folder), csvTags(field("tags"))...)
+		entry.Request.Notes = field("notes")
+		entry.Request.SelectedText = field("selection")
+		entry.Request.Content = field("content")
+		if created, ok := parseTime(field("created")); ok {
+			entry.Request.ScrapedAt = created
+		}
+		if entry.Request.SelectedText != "" {
+			entry.Request.ContentType = "selection"
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// csvTags reads a tags cell: Raindrop.io separates tags with commas, Instapaper writes a JSON array
+func csvTags(value string) []string {
+	if strings.HasPrefix(value, "[") {
+		var tags []string
+		if err := json.Unmarshal([]byte(value), &tags); err == nil {
+			return tags
+		}
+	}
+	return splitList(value)
+}
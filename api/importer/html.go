@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Bookmark files are rarely well-formed (Netscape files never close <DT> or <p>),
+// so they are scanned tag by tag instead of parsed as a tree.
+var (
+	tagPattern       = regexp.MustCompile(`(?s)<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+	attributePattern = regexp.MustCompile(`([a-zA-Z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// rootFolderAttributes mark the browser's own top-level folders, which are not turned into tags
+var rootFolderAttributes = []string{"personal_toolbar_folder", "unfiled_bookmarks_folder"}
+
+// structuralTags end the description of the previous bookmark
+var structuralTags = map[string]bool{"dt": true, "dl": true, "h1": true, "h3": true, "li": true, "ul": true}
+
+// readHTML reads a Netscape bookmark file or a Pocket export.
+// Netscape folders are <H3> headings followed by a nested <DL>, and a <DD> after a
+// bookmark holds its description. Pocket lists bookmarks under <h1> sections, which are not folders.
+func readHTML(r io.Reader, fn func(Entry) error) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	doc := string(data)
+
+	var (
+		folders     []string // open folders, innermost last; "" for lists without a named heading
+		heading     string   // folder named by the last </H3>, waiting for its <DL>
+		rootHeading bool     // the open <H3> is a browser root folder
+		current     *Entry   // last bookmark, emitted once its description is complete
+		inDesc      bool
+		text        strings.Builder
+		count       int
+	)
+
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		if inDesc {
+			current.Request.Notes = cleanText(text.String())
+			inDesc = false
+		}
+		entry := *current
+		current = nil
+		return fn(entry)
+	}
+
+	end := 0
+	for _, match := range tagPattern.FindAllStringSubmatchIndex(doc, -1) {
+		text.WriteString(doc[end:match[0]])
+		end = match[1]
+
+		closing := match[3] > match[2]
+		name := strings.ToLower(doc[match[4]:match[5]])
+		attributes := parseAttributes(doc[match[6]:match[7]])
+
+		if structuralTags[name] {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case name == "a" && !closing:
+			if err := flush(); err != nil {
+				return err
+			}
+			count++
+			entry := newEntry(count, attributes["href"], "")
+			entry.Folder = strings.Join(nonEmpty(folders), "/")
+			entry.Request.Tags = append(folderTags(entry.Folder), splitList(attributes["tags"])...)
+			if added, ok := parseTime(firstOf(attributes, "add_date", "time_added")); ok {
+				entry.Request.ScrapedAt = added
+			}
+			current = &entry
+
+		case name == "a" && current != nil:
+			if title := cleanText(text.String()); title != "" {
+				current.Request.Title = title
+			}
+
+		case name == "dd" && !closing:
+			inDesc = current != nil
+
+		case name == "h3" && !closing:
+			rootHeading = hasAny(attributes, rootFolderAttributes)
+
+		case name == "h3":
+			heading = ""
+			if !rootHeading {
+				heading = strings.ReplaceAll(cleanText(text.String()), "/", "-")
+			}
+
+		case name == "dl" && !closing:
+			folders = append(folders, heading)
+			heading = ""
+
+		case name == "dl" && len(folders) > 0:
+			folders = folders[:len(folders)-1]
+		}
+
+		if !inDesc {
+			text.Reset()
+		}
+	}
+
+	text.WriteString(doc[end:])
+	return flush()
+}
+
+// parseAttributes returns the attributes of a tag keyed by lowercase name, with entities decoded
+func parseAttributes(raw string) map[string]string {
+	attributes := map[string]string{}
+	for _, match := range attributePattern.FindAllStringSubmatch(raw, -1) {
+		attributes[strings.ToLower(match[1])] = html.UnescapeString(match[2] + match[3] + match[4])
+	}
+	return attributes
+}
+
+// cleanText decodes entities and collapses whitespace in the text between tags
+func cleanText(text string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+// firstOf returns the first non-empty attribute of names
+func firstOf(attributes map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := attributes[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// hasAny reports whether any of names is set, even to an empty value
+func hasAny(attributes map[string]string, names []string) bool {
+	for _, name := range names {
+		if _, ok := attributes[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nonEmpty drops empty strings
+func nonEmpty(values []string) []string {
+	kept := []string{}
+	for _, value := range values {
+		if value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,137 @@
+// Package importer reads bookmarks exported from other services, and our own JSONL export,
+// as memory requests. Parsing only maps entries; de-duplication and saving are up to the caller.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/models"
+)
+
+// Supported formats for the format query parameter
+const (
+	FormatNetscape = "netscape" // Netscape bookmark file, as exported by every browser
+	FormatPocket   = "pocket"   // Pocket export HTML
+	FormatCSV      = "csv"      // Raindrop.io or Instapaper CSV
+	FormatJSONL    = "jsonl"    // our own JSON Lines export
+)
+
+// Entry is one bookmark read from an import file
+type Entry struct {
+	Position int    // line number for CSV and JSONL, bookmark number for HTML
+	Folder   string // folder path with "/" separators, empty when the bookmark is not in a folder
+	Request  models.CreateMemoryRequest
+	Err      error // set when the entry could not be parsed; Request is then incomplete
+}
+
+// Read parses r in the given format, calling fn for every entry in file order.
+// An empty format is detected from the start of the file. Read stops at the first error returned by fn.
+func Read(format string, r io.Reader, fn func(Entry) error) (string, error) {
+	br := bufio.NewReader(r)
+	if format == "" {
+		format = Detect(br)
+	}
+
+	var err error
+	switch format {
+	case FormatNetscape, FormatPocket:
+		err = readHTML(br, fn)
+	case FormatCSV:
+		err = readCSV(br, fn)
+	case FormatJSONL:
+		err = readJSONL(br, fn)
+	default:
+		err = fmt.Errorf("unsupported import format %q", format)
+	}
+	return format, err
+}
+
+// Detect guesses the format of an import file from its first bytes
+func Detect(br *bufio.Reader) string {
+	head, _ := br.Peek(1024)
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.ToLower(bytes.TrimSpace(head))
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return FormatJSONL
+	case bytes.Contains(trimmed, []byte("netscape-bookmark")):
+		return FormatNetscape
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return FormatPocket
+	default:
+		return FormatCSV
+	}
+}
+
+// newEntry returns an entry for a plain bookmark saved as a page
+func newEntry(position int, url, title string) Entry {
+	url, title = strings.TrimSpace(url), strings.TrimSpace(title)
+	if title == "" {
+		title = url
+	}
+	entry := Entry{Position: position, Request: models.CreateMemoryRequest{
+		URL:         url,
+		Title:       title,
+		ContentType: "page",
+		ScrapedAt:   time.Now().UTC(),
+	}}
+	if url == "" {
+		entry.Err = fmt.Errorf("bookmark has no URL")
+	}
+	return entry
+}
+
+// folderTags turns a folder path into tags, one per folder
+func folderTags(folder string) []string {
+	tags := []string{}
+	for _, name := range strings.Split(folder, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTime reads a Unix timestamp in seconds, milliseconds or microseconds, or an RFC 3339 time
+func parseTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+		switch {
+		case n > 1e15:
+			return time.UnixMicro(n).UTC(), true
+		case n > 1e12:
+			return time.UnixMilli(n).UTC(), true
+		default:
+			return time.Unix(n, 0).UTC(), true
+		}
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
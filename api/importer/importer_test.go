@@ -0,0 +1,175 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"api/models"
+)
+
+// bookmark is the part of an Entry the fixtures pin down
+type bookmark struct {
+	Position    int
+	Folder      string
+	URL         string
+	Title       string
+	ContentType string
+	Tags        []string
+	Notes       string
+	Selection   string
+	Content     string
+	ScrapedAt   time.Time // zero when the file has no date and the import time is used
+	Failed      bool
+}
+
+// readFixture imports a file from testdata, checking the format detected for it
+func readFixture(t *testing.T, name, format string) []Entry {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer file.Close()
+
+	entries := []Entry{}
+	detected, err := Read("", file, func(entry Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	if detected != format {
+		t.Errorf("%s: detected format %q, want %q", name, detected, format)
+	}
+	return entries
+}
+
+// checkBookmarks compares entries with the expected bookmarks, in order
+func checkBookmarks(t *testing.T, entries []Entry, want []bookmark) {
+	t.Helper()
+
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(want), len(entries), entries)
+	}
+	for i, entry := range entries {
+		req := entry.Request
+		got := bookmark{
+			Position:    entry.Position,
+			Folder:      entry.Folder,
+			URL:         req.URL,
+			Title:       req.Title,
+			ContentType: req.ContentType,
+			Tags:        req.Tags,
+			Notes:       req.Notes,
+			Selection:   req.SelectedText,
+			Content:     req.Content,
+			ScrapedAt:   want[i].ScrapedAt,
+			Failed:      entry.Err != nil,
+		}
+		if len(got.Tags) == 0 {
+			got.Tags = nil
+		}
+		if !want[i].ScrapedAt.IsZero() && !req.ScrapedAt.Equal(want[i].ScrapedAt) {
+			t.Errorf("entry %d: scraped_at %v, want %v", i, req.ScrapedAt, want[i].ScrapedAt)
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("entry %d:\n got %+v\nwant %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestReadNetscapeBookmarks(t *testing.T) {
+	checkBookmarks(t, readFixture(t, "bookmarks.html", FormatNetscape), []bookmark{
+		{Position: 1, URL: "https://go.dev/", Title: "The Go Programming Language", ContentType: "page",
+			ScrapedAt: time.Unix(1700000000, 0)},
+		{Position: 2, Folder: "Dev - Tools", URL: "https://example.com/?a=1&b=2", Title: "Tom & Jerry's tools", ContentType: "page",
+			Tags: []string{"Dev - Tools", "cli", "Shell"}, Notes: "Handy command line tools & tricks", ScrapedAt: time.UnixMilli(1700000200000)},
+		{Position: 3, Folder: "Dev - Tools/Nested", URL: "https://nested.example/", Title: "Deep", ContentType: "page",
+			Tags: []string{"Dev - Tools", "Nested"}},
+		{Position: 4, URL: "https://after.example/", Title: "After the folder", ContentType: "page"},
+		{Position: 5, Title: "No URL", ContentType: "page", Failed: true},
+		{Position: 6, URL: "https://untitled.example/", Title: "https://untitled.example/", ContentType: "page"},
+	})
+}
+
+func TestReadPocketExport(t *testing.T) {
+	checkBookmarks(t, readFixture(t, "pocket.html", FormatPocket), []bookmark{
+		{Position: 1, URL: "https://blog.golang.org/generics", Title: "Generics in Go", ContentType: "page",
+			Tags: []string{"go", "generics"}, ScrapedAt: time.Unix(1650000000, 0)},
+		{Position: 2, URL: "https://example.org/plain", Title: "https://example.org/plain", ContentType: "page",
+			ScrapedAt: time.Unix(1650000100, 0)},
+		{Position: 3, URL: "https://archive.example/read", Title: "Read it", ContentType: "page",
+			Tags: []string{"done"}, ScrapedAt: time.Unix(1600000000, 0)},
+	})
+}
+
+func TestReadRaindropCSV(t *testing.T) {
+	checkBookmarks(t, readFixture(t, "raindrop.csv", FormatCSV), []bookmark{
+		{Position: 2, Folder: "Programming/Go", URL: "https://go.dev/doc/effective_go", Title: "Effective Go", ContentType: "selection",
+			Tags: []string{"Programming", "Go", "go", "style"}, Notes: "My note", Selection: `Go is a new language, "concise"`,
+			Content: "Highlight: gofmt", ScrapedAt: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{Position: 3, Folder: "Unsorted", URL: "https://example.com/untitled", Title: "https://example.com/untitled", ContentType: "page",
+			Tags: []string{"Unsorted"}, ScrapedAt: time.Date(2023, 5, 2, 10, 0, 0, 0, time.UTC)},
+		{Position: 4, Folder: "Programming", Title: "Missing URL", ContentType: "page", Tags: []string{"Programming"}, Failed: true},
+	})
+}
+
+func TestReadInstapaperCSV(t *testing.T) {
+	checkBookmarks(t, readFixture(t, "instapaper.csv", FormatCSV), []bookmark{
+		{Position: 2, URL: "https://www.instapaper.com/read/1", Title: "Long read", ContentType: "selection",
+			Selection: "Quoted, selection", ScrapedAt: time.Unix(1690000000, 0)},
+		{Position: 3, Folder: "Starred", URL: "https://example.com/starred", Title: "Starred one", ContentType: "page",
+			Tags: []string{"Starred", "news", "Long Form"}, ScrapedAt: time.Unix(1690000100, 0)},
+		{Position: 4, Folder: "Archive", URL: "https://example.com/archived", Title: "Archived", ContentType: "page",
+			Tags: []string{"Archive"}, ScrapedAt: time.Unix(1690000200, 0)},
+	})
+}
+
+func TestReadJSONLExport(t *testing.T) {
+	entries := readFixture(t, "export.jsonl", FormatJSONL)
+	checkBookmarks(t, entries, []bookmark{
+		{Position: 1, URL: "https://go.dev/blog", Title: "Go blog", ContentType: "selection",
+			Tags: []string{"go", "blog"}, Notes: "keep", Selection: "chosen words", ScrapedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{Position: 3, Title: "Untitled", ContentType: "page", Notes: "a note without a URL"},
+		{Position: 4, Failed: true},
+		{Position: 5, URL: "https://example.com/article", Title: "Article", ContentType: "page", Content: "Body text"},
+	})
+	if len(entries) != 4 {
+		return
+	}
+
+	first := entries[0].Request
+	if first.ContextBefore != "before" || !reflect.DeepEqual(first.Links, []models.Link{{Text: "docs", Href: "https://go.dev/doc"}}) {
+		t.Errorf("capture context was not kept: %+v", first)
+	}
+	if first.Article != nil {
+		t.Errorf("expected no article without content_html, got %+v", first.Article)
+	}
+
+	article := entries[3].Request.Article
+	if article == nil {
+		t.Fatal("expected the article to be restored from content_html")
+	}
+	if article.Byline != "Ann Author" || article.Language != "en" || article.Text != "Body text" {
+		t.Errorf("article metadata: got %+v", article)
+	}
+	if strings.Contains(article.HTML, "script") || strings.Contains(article.HTML, "onclick") || !strings.Contains(article.HTML, "Body") {
+		t.Errorf("expected imported HTML to be sanitized, got %q", article.HTML)
+	}
+}
+
+func TestReadRejectsUnknownFormat(t *testing.T) {
+	if _, err := Read("opml", strings.NewReader("<opml/>"), func(Entry) error { return nil }); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+	err := readCSV(strings.NewReader("title,notes\nx,y\n"), func(Entry) error { return nil })
+	if err == nil {
+		t.Error("expected an error for a CSV without a URL column")
+	}
+}
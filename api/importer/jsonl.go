@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"api/document"
+	"api/export"
+	"api/models"
+)
+
+// maxJSONLLine bounds one line of a JSONL import; captured pages can be large
+const maxJSONLLine = 16 << 20
+
+// readJSONL reads the JSON Lines produced by GET /api/export?format=jsonl
+func readJSONL(r io.Reader, fn func(Entry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLine)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var record export.Record
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			if err := fn(Entry{Position: line, Err: err}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Exported notes may have no URL, so unlike bookmarks they are not rejected for it
+		if err := fn(Entry{Position: line, Request: recordRequest(record)}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// recordRequest maps an exported record back to the request that creates it
+func recordRequest(record export.Record) models.CreateMemoryRequest {
+	req := models.CreateMemoryRequest{
+		URL:           record.URL,
+		Title:         record.Title,
+		ContentType:   record.ContentType,
+		Content:       record.Content,
+		SelectedText:  record.SelectedText,
+		ContextBefore: record.ContextBefore,
+		ContextAfter:  record.ContextAfter,
+		FullContext:   record.FullContext,
+		ElementType:   record.ElementType,
+		PageSection:   record.PageSection,
+		XPath:         record.XPath,
+		Links:         record.Links,
+		Tags:          record.Tags,
+		Notes:         record.Notes,
+		ScrapedAt:     record.ScrapedAt,
+		VideoData:     record.VideoData,
+	}
+	if record.ContentHTML != "" {
+		req.Article = &models.Article{
+			Title:        record.Title,
+			Byline:       record.Byline,
+			PublishedAt:  record.PublishedAt,
+			LeadImageURL: record.LeadImageURL,
+			Language:     record.Language,
+			Text:         record.Content,
+			HTML:         document.Sanitize(record.ContentHTML, record.URL),
+		}
+	}
+	if req.Title == "" {
+		req.Title = "Untitled"
+	}
+	if req.ContentType == "" {
+		req.ContentType = "page"
+	}
+	return req
+}
@@ -1,11 +1,39 @@
 package handler
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 
 	"api/config"
+	"api/embedding"
+	"api/jobs"
+	"api/llm"
+	"api/middleware"
+	"api/migrations"
 	"api/routes"
+	"api/store"
+)
+
+const (
+	// cronPath is requested by the cron in vercel.json to run background jobs, since
+	// serverless instances have no workers of their own
+	cronPath = "/api/go/cron/jobs"
+
+	// drainBudget is how long one cron invocation keeps claiming jobs, leaving the last
+	// job time to finish before the function is stopped
+	drainBudget = 30 * time.Second
+)
+
+var (
+	schemaMu    sync.Mutex
+	schemaReady bool
+
+	backfillOnce sync.Once
 )
 
 // Handler is the main entry point for Vercel serverless function
@@ -18,5 +46,87 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	routes.SetupRoutes()(w, r)
+	if err := checkSchema(); err != nil {
+		log.Println("schema check error:", err)
+		http.Error(w, "service unavailable: database schema is out of date", http.StatusServiceUnavailable)
+		return
+	}
+
+	dataStore, err := store.New(config.GetDB(), config.Driver)
+	if err != nil {
+		log.Println("storage initialization error:", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	embedder, err := embedding.New(config.LoadEmbedding())
+	if err != nil {
+		log.Println("embedding configuration error:", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	provider, err := llm.New(config.LoadLLM())
+	if err != nil {
+		log.Println("LLM configuration error:", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Requests only enqueue jobs; the cron drains the queue, as does any long-running
+	// server sharing the database
+	queue := jobs.NewQueue(dataStore)
+	if embedder != nil {
+		jobs.RegisterEmbedding(queue, dataStore, embedder)
+	}
+	jobs.RegisterTrashPurge(queue, dataStore, config.TrashRetention())
+	jobs.RegisterDuplicateKeyBackfill(queue, dataStore)
+	if r.URL.Path == cronPath {
+		drainJobs(w, r, queue)
+		return
+	}
+	routes.SetupRoutes(dataStore, queue, embedder, provider)(w, r)
+}
+
+// checkSchema verifies migrations once per instance; failures are retried on the next request
+func checkSchema() error {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+
+	if schemaReady {
+		return nil
+	}
+	if err := migrations.Check(config.GetDB(), config.Driver); err != nil {
+		return err
+	}
+	schemaReady = true
+	return nil
+}
+
+// drainJobs runs the queued jobs and the scheduled trash purge for a cron invocation
+func drainJobs(w http.ResponseWriter, r *http.Request, queue *jobs.Queue) {
+	secret := config.CronSecret()
+	if secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+secret)) != 1 {
+		middleware.ErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), drainBudget)
+	defer cancel()
+
+	// Memories saved before duplicate detection get their keys from a job queued by the
+	// first cron invocation of each instance, keeping the scan off user requests
+	backfillOnce.Do(func() {
+		if _, err := queue.Enqueue(ctx, jobs.KindBackfillDuplicateKeys, "", "", nil); err != nil {
+			log.Println("duplicate key backfill error:", err)
+		}
+	})
+
+	ran, err := queue.Drain(ctx)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		log.Println("job drain error:", err)
+		middleware.ErrorResponse(w, http.StatusInternalServerError, "Failed to run jobs")
+		return
+	}
+	middleware.SuccessResponse(w, http.StatusOK, "Jobs run", map[string]int{"ran": ran})
 }
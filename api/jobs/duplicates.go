@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"api/models"
+	"api/store"
+)
+
+// KindBackfillDuplicateKeys fills the canonical URL and content hash of memories saved
+// before duplicate detection tracked them
+const KindBackfillDuplicateKeys = "backfill_duplicate_keys"
+
+// RegisterDuplicateKeyBackfill handles KindBackfillDuplicateKeys jobs. Nothing enqueues them
+// on its own: hosts that cannot backfill at startup enqueue one when they first drain the queue.
+func RegisterDuplicateKeyBackfill(q *Queue, s store.MaintenanceStore) {
+	q.Register(KindBackfillDuplicateKeys, func(ctx context.Context, job models.Job) error {
+		filled, err := s.BackfillDuplicateKeys(ctx)
+		if err != nil {
+			return err
+		}
+		if filled > 0 {
+			log.Printf("jobs: backfilled duplicate keys for %d memories", filled)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"api/embedding"
+	"api/models"
+	"api/store"
+)
+
+// KindEmbed embeds a memory for semantic search
+const KindEmbed = "embed_memory"
+
+// backfillBatch bounds the memories enqueued per round of BackfillEmbeddings
+const backfillBatch = 500
+
+// embedStore is what embedding memories needs from the store
+type embedStore interface {
+	store.MemoryStore
+	store.EmbeddingStore
+}
+
+// RegisterEmbedding embeds memories with e whenever they are created or updated
+func RegisterEmbedding(q *Queue, s embedStore, e embedding.Embedder) {
+	q.Register(KindEmbed, func(ctx context.Context, job models.Job) error {
+		return embedMemory(ctx, s, e, job)
+	}, EventMemoryCreated, EventMemoryUpdated)
+}
+
+func embedMemory(ctx context.Context, s embedStore, e embedding.Embedder, job models.Job) error {
+	memory, err := s.GetMemory(ctx, job.UserID, job.MemoryID)
+	if errors.Is(err, store.ErrNotFound) {
+		// Deleted since the job was enqueued
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	text := embedding.MemoryText(memory)
+	hash := embedding.TextHash(text)
+	current, err := s.EmbeddingHash(ctx, memory.ID, e.Model())
+	if errors.Is(err, store.ErrEmbeddingsUnavailable) {
+		return Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if current == hash {
+		return nil
+	}
+
+	vector, err := embedding.EmbedOne(ctx, e, text)
+	if err != nil {
+		var status *embedding.StatusError
+		if errors.As(err, &status) && !status.Retryable() {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	err = s.SaveEmbedding(ctx, memory.ID, e.Model(), vector, hash)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// BackfillEmbeddings enqueues embed jobs for memories that have no embedding by e's model,
+// such as those saved before semantic search or before the model changed. It returns the
+// number of jobs enqueued.
+func BackfillEmbeddings(ctx context.Context, q *Queue, s store.EmbeddingStore, e embedding.Embedder) (int, error) {
+	enqueued := 0
+	for {
+		refs, err := s.UnembeddedMemories(ctx, e.Model(), backfillBatch)
+		if err != nil {
+			return enqueued, err
+		}
+		for _, ref := range refs {
+			if _, err := q.Enqueue(ctx, KindEmbed, ref.UserID, ref.MemoryID, nil); err != nil {
+				return enqueued, fmt.Errorf("failed to enqueue %s: %w", KindEmbed, err)
+			}
+			enqueued++
+		}
+		if len(refs) < backfillBatch {
+			return enqueued, nil
+		}
+	}
+}
@@ -0,0 +1,301 @@
+// Package jobs runs post-capture processing in the background. Jobs are rows in the
+// jobs table: handlers are registered per kind, attached to memory lifecycle events or
+// scheduled at an interval, and run by a pool of workers that claim due jobs, retry
+// failures with exponential backoff and dead-letter jobs that keep failing. Hosts that
+// cannot keep workers running drain the queue periodically instead.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"api/models"
+	"api/store"
+)
+
+// Memory lifecycle events that processing steps attach to with Register
+const (
+	EventMemoryCreated = "memory.created"
+	EventMemoryUpdated = "memory.updated"
+)
+
+const (
+	// DefaultMaxAttempts is how often a job runs before it is dead-lettered
+	DefaultMaxAttempts = 5
+
+	// lease is how long a claimed job stays locked; a worker that has not finished by then
+	// is presumed dead and the job is claimed again. It must exceed jobTimeout.
+	lease      = 10 * time.Minute
+	jobTimeout = 5 * time.Minute
+
+	pollInterval = 2 * time.Second
+	baseBackoff  = 15 * time.Second
+	maxBackoff   = time.Hour
+
+	// Succeeded jobs are deleted after pruneAge, checked every pruneInterval
+	pruneAge      = 7 * 24 * time.Hour
+	pruneInterval = time.Hour
+)
+
+// Handler processes one claimed job. A returned error retries the job with backoff,
+// unless it is Permanent or the job has used all of its attempts.
+type Handler func(ctx context.Context, job models.Job) error
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job is dead-lettered immediately instead of retried
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Queue enqueues jobs and runs the registered handlers for them
+type Queue struct {
+	store     store.JobStore
+	handlers  map[string]Handler
+	triggers  map[string][]string      // event -> job kinds
+	schedules map[string]time.Duration // job kind -> interval between runs
+	wake      chan struct{}
+}
+
+// NewQueue creates a Queue on the given store with no handlers registered
+func NewQueue(s store.JobStore) *Queue {
+	return &Queue{
+		store:     s,
+		handlers:  map[string]Handler{},
+		triggers:  map[string][]string{},
+		schedules: map[string]time.Duration{},
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for a job kind and enqueues a job of that kind for a memory
+// whenever one of events is triggered. Register before calling Trigger or Run.
+func (q *Queue) Register(kind string, handler Handler, events ...string) {
+	q.handlers[kind] = handler
+	for _, event := range events {
+		q.triggers[event] = append(q.triggers[event], kind)
+	}
+}
+
+// Schedule enqueues a job of kind, not tied to any user or memory, when Run starts and then
+// every interval while it runs. Every instance running the queue schedules its own jobs, so
+// the handler must tolerate running more often than interval. Schedule before calling Run.
+func (q *Queue) Schedule(kind string, interval time.Duration) {
+	q.schedules[kind] = interval
+}
+
+// Enqueue adds a job of the given kind; payload, if not nil, is stored as JSON
+func (q *Queue) Enqueue(ctx context.Context, kind, userID, memoryID string, payload interface{}) (models.Job, error) {
+	job := models.Job{Kind: kind, UserID: userID, MemoryID: memoryID, MaxAttempts: DefaultMaxAttempts}
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return models.Job{}, fmt.Errorf("failed to encode %s payload: %w", kind, err)
+		}
+		job.Payload = encoded
+	}
+
+	job, err := q.store.EnqueueJob(ctx, job)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	// Let an idle worker in this process pick it up without waiting for the next poll
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Trigger enqueues the jobs registered for event on a memory
+func (q *Queue) Trigger(ctx context.Context, event, userID, memoryID string) error {
+	for _, kind := range q.triggers[event] {
+		if _, err := q.Enqueue(ctx, kind, userID, memoryID, nil); err != nil {
+			return fmt.Errorf("failed to enqueue %s for %s: %w", kind, event, err)
+		}
+	}
+	return nil
+}
+
+// Run processes jobs with the given number of workers until ctx is cancelled,
+// then waits for the jobs in progress to finish
+func (q *Queue) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.prune(ctx)
+	}()
+
+	for kind, interval := range q.schedules {
+		wg.Add(1)
+		go func(kind string, interval time.Duration) {
+			defer wg.Done()
+			q.schedule(ctx, kind, interval)
+		}(kind, interval)
+	}
+
+	wg.Wait()
+}
+
+// Drain enqueues one job of every scheduled kind, then runs due jobs one at a time until
+// none are left or ctx is done, and returns how many it ran. It stands in for Run on hosts
+// without long-running workers, such as a serverless function invoked by a cron trigger.
+// A job that is running when ctx is done still finishes so its outcome is recorded.
+func (q *Queue) Drain(ctx context.Context) (int, error) {
+	for kind := range q.schedules {
+		if _, err := q.Enqueue(ctx, kind, "", "", nil); err != nil {
+			return 0, fmt.Errorf("failed to schedule %s: %w", kind, err)
+		}
+	}
+	if _, err := q.store.PruneJobs(ctx, time.Now().Add(-pruneAge)); err != nil {
+		return 0, fmt.Errorf("failed to prune: %w", err)
+	}
+
+	ran := 0
+	for ctx.Err() == nil {
+		jobs, err := q.store.ClaimJobs(ctx, 1, lease)
+		if err != nil {
+			return ran, fmt.Errorf("failed to claim: %w", err)
+		}
+		if len(jobs) == 0 {
+			return ran, nil
+		}
+		q.process(context.Background(), jobs[0])
+		ran++
+	}
+	return ran, ctx.Err()
+}
+
+// work claims and runs one job at a time, sleeping while the queue is empty
+func (q *Queue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobs, err := q.store.ClaimJobs(ctx, 1, lease)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("jobs: failed to claim: %v", err)
+		}
+		if len(jobs) == 0 {
+			select {
+			case <-ctx.Done():
+			case <-q.wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		// The job runs to completion even during shutdown so its outcome is recorded
+		q.process(context.Background(), jobs[0])
+	}
+}
+
+// process runs the handler for a claimed job and stores the outcome
+func (q *Queue) process(ctx context.Context, job models.Job) {
+	err := q.runHandler(ctx, job)
+	if err == nil {
+		job.Status = models.JobSucceeded
+		job.LastError = ""
+	} else {
+		job.LastError = err.Error()
+		var permanent permanentError
+		if errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts {
+			job.Status = models.JobDead
+			log.Printf("jobs: %s %s dead after %d attempts: %v", job.Kind, job.ID, job.Attempts, err)
+		} else {
+			job.Status = models.JobQueued
+			job.RunAt = time.Now().Add(backoff(job.Attempts))
+		}
+	}
+
+	if err := q.store.FinishJob(ctx, job); err != nil {
+		log.Printf("jobs: failed to record outcome of %s %s: %v", job.Kind, job.ID, err)
+	}
+}
+
+// runHandler calls the handler for the job's kind with a timeout, turning panics into errors
+func (q *Queue) runHandler(ctx context.Context, job models.Job) (err error) {
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		// Possibly enqueued by a newer build; retry in case a worker that knows the kind picks it up
+		return fmt.Errorf("no handler registered for job kind %q", job.Kind)
+	}
+	if job.Attempts > job.MaxAttempts {
+		return Permanent(errors.New("lease expired on the final attempt"))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// prune deletes old succeeded jobs until ctx is cancelled
+func (q *Queue) prune(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := q.store.PruneJobs(ctx, time.Now().Add(-pruneAge)); err != nil && ctx.Err() == nil {
+				log.Printf("jobs: failed to prune: %v", err)
+			}
+		}
+	}
+}
+
+// schedule enqueues a job of kind now and every interval until ctx is cancelled
+func (q *Queue) schedule(ctx context.Context, kind string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := q.Enqueue(ctx, kind, "", "", nil); err != nil && ctx.Err() == nil {
+			log.Printf("jobs: failed to schedule %s: %v", kind, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// backoff is the delay before retrying after the given number of attempts:
+// exponential from baseBackoff up to maxBackoff, with up to 20% jitter so jobs that
+// failed together do not retry in lockstep
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
@@ -0,0 +1,298 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"api/models"
+	"api/store"
+)
+
+const userID = "user-a"
+
+// testStores lists the backends the queue is tested against; builds with the
+// sqlite_fts5 tag add a migrated SQLite database
+var testStores = map[string]func(t *testing.T) store.JobStore{
+	"memory": func(t *testing.T) store.JobStore { return store.NewInMemoryStore() },
+}
+
+// forEachStore runs test against a new store of each of testStores
+func forEachStore(t *testing.T, test func(t *testing.T, s store.JobStore)) {
+	for name, newStore := range testStores {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+// enqueue saves job for the test user, defaulting its attempts, and returns it
+func enqueue(t *testing.T, s store.JobStore, job models.Job) models.Job {
+	t.Helper()
+
+	if job.UserID == "" {
+		job.UserID = userID
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	job, err := s.EnqueueJob(context.Background(), job)
+	if err != nil {
+		t.Fatalf("enqueue %s failed: %v", job.Kind, err)
+	}
+	return job
+}
+
+// claimOne claims the next runnable job, failing the test if there is none
+func claimOne(t *testing.T, s store.JobStore, lease time.Duration) models.Job {
+	t.Helper()
+
+	jobs, err := s.ClaimJobs(context.Background(), 1, lease)
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("expected a job to claim, got %d", len(jobs))
+	}
+	return jobs[0]
+}
+
+// storedJob reads a job back from the store
+func storedJob(t *testing.T, s store.JobStore, job models.Job) models.Job {
+	t.Helper()
+
+	jobs, err := s.ListJobs(context.Background(), job.UserID, models.JobQueryParams{Kind: job.Kind, Limit: 100})
+	if err != nil {
+		t.Fatalf("list jobs failed: %v", err)
+	}
+	for _, stored := range jobs {
+		if stored.ID == job.ID {
+			return stored
+		}
+	}
+	t.Fatalf("job %s not found", job.ID)
+	return models.Job{}
+}
+
+// dueJobs counts the test user's queued jobs that are due, without claiming them
+func dueJobs(t *testing.T, s store.JobStore) int {
+	t.Helper()
+
+	jobs, err := s.ListJobs(context.Background(), userID, models.JobQueryParams{Status: models.JobQueued, Limit: 100})
+	if err != nil {
+		t.Fatalf("list jobs failed: %v", err)
+	}
+	due := 0
+	for _, job := range jobs {
+		if !job.RunAt.After(time.Now()) {
+			due++
+		}
+	}
+	return due
+}
+
+func TestClaimAndFinish(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.JobStore) {
+		ctx := context.Background()
+		later := enqueue(t, s, models.Job{Kind: "later", RunAt: time.Now().Add(time.Hour)})
+		job := enqueue(t, s, models.Job{Kind: "now"})
+
+		claimed := claimOne(t, s, time.Minute)
+		if claimed.ID != job.ID || claimed.Status != models.JobRunning || claimed.Attempts != 1 {
+			t.Fatalf("expected the due job running on its first attempt, got %+v", claimed)
+		}
+		if jobs, err := s.ClaimJobs(ctx, 10, time.Minute); err != nil || len(jobs) != 0 {
+			t.Fatalf("expected leased and future jobs not to be claimed, got %d, %v", len(jobs), err)
+		}
+
+		claimed.Status = models.JobSucceeded
+		if err := s.FinishJob(ctx, claimed); err != nil {
+			t.Fatalf("finish failed: %v", err)
+		}
+		if got := storedJob(t, s, job); got.Status != models.JobSucceeded || got.Attempts != 1 {
+			t.Errorf("expected the job to have succeeded, got %+v", got)
+		}
+		if got := storedJob(t, s, later); got.Status != models.JobQueued || got.Attempts != 0 {
+			t.Errorf("expected the future job untouched, got %+v", got)
+		}
+	})
+}
+
+func TestExpiredLeasesAreClaimedAgain(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.JobStore) {
+		ctx := context.Background()
+		job := enqueue(t, s, models.Job{Kind: "crash"})
+
+		// A worker that died holding the job leaves it running with an expired lease
+		first := claimOne(t, s, 0)
+		second := claimOne(t, s, time.Minute)
+		if second.ID != job.ID || second.Attempts != 2 {
+			t.Fatalf("expected the job claimed again on its second attempt, got %+v", second)
+		}
+
+		// The first worker's late outcome is dropped in favour of the current claim
+		first.Status = models.JobDead
+		if err := s.FinishJob(ctx, first); err != nil {
+			t.Fatalf("finish failed: %v", err)
+		}
+		if got := storedJob(t, s, job); got.Status != models.JobRunning {
+			t.Errorf("expected a stale outcome to be ignored, got %+v", got)
+		}
+
+		second.Status = models.JobSucceeded
+		if err := s.FinishJob(ctx, second); err != nil {
+			t.Fatalf("finish failed: %v", err)
+		}
+		if got := storedJob(t, s, job); got.Status != models.JobSucceeded {
+			t.Errorf("expected the current claim's outcome, got %+v", got)
+		}
+	})
+}
+
+func TestFailedJobsRetryWithBackoff(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.JobStore) {
+		q := NewQueue(s)
+		q.Register("flaky", func(ctx context.Context, job models.Job) error {
+			return errors.New("upstream unavailable")
+		})
+		q.Register("panics", func(ctx context.Context, job models.Job) error {
+			panic("nil map")
+		})
+
+		for _, kind := range []string{"flaky", "panics", "unknown"} {
+			job := enqueue(t, s, models.Job{Kind: kind})
+			before := time.Now()
+			q.process(context.Background(), claimOne(t, s, time.Minute))
+
+			got := storedJob(t, s, job)
+			if got.Status != models.JobQueued || got.LastError == "" {
+				t.Errorf("%s: expected the job queued again with its error, got %+v", kind, got)
+			}
+			if earliest, latest := before.Add(baseBackoff), time.Now().Add(baseBackoff*6/5); got.RunAt.Before(earliest) || got.RunAt.After(latest) {
+				t.Errorf("%s: expected a retry %v after the first attempt, got run_at %v", kind, baseBackoff, got.RunAt.Sub(before))
+			}
+		}
+		if n := dueJobs(t, s); n != 0 {
+			t.Errorf("expected retries to wait out their backoff, %d are runnable", n)
+		}
+	})
+}
+
+func TestJobsAreDeadLettered(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.JobStore) {
+		q := NewQueue(s)
+		q.Register("invalid", func(ctx context.Context, job models.Job) error {
+			return Permanent(errors.New("memory has no content"))
+		})
+		q.Register("flaky", func(ctx context.Context, job models.Job) error {
+			return errors.New("upstream unavailable")
+		})
+
+		permanent := enqueue(t, s, models.Job{Kind: "invalid"})
+		q.process(context.Background(), claimOne(t, s, time.Minute))
+		if got := storedJob(t, s, permanent); got.Status != models.JobDead || got.Attempts != 1 || got.LastError != "memory has no content" {
+			t.Errorf("permanent failure: expected dead after one attempt, got %+v", got)
+		}
+
+		exhausted := enqueue(t, s, models.Job{Kind: "flaky", MaxAttempts: 1})
+		q.process(context.Background(), claimOne(t, s, time.Minute))
+		if got := storedJob(t, s, exhausted); got.Status != models.JobDead {
+			t.Errorf("last attempt: expected dead, got %+v", got)
+		}
+
+		// A lease that expired on the final attempt is not run again
+		leased := enqueue(t, s, models.Job{Kind: "flaky", MaxAttempts: 1})
+		claimOne(t, s, 0)
+		q.process(context.Background(), claimOne(t, s, time.Minute))
+		if got := storedJob(t, s, leased); got.Status != models.JobDead || got.Attempts != 2 {
+			t.Errorf("expired final lease: expected dead, got %+v", got)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts := 1; attempts <= 20; attempts++ {
+		delay := baseBackoff << (attempts - 1)
+		if attempts > 10 || delay > maxBackoff {
+			delay = maxBackoff
+		}
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 50; i++ {
+			got := backoff(attempts)
+			if got < delay || got > delay+delay/5 {
+				t.Fatalf("backoff(%d) = %v, want %v plus up to 20%%", attempts, got, delay)
+			}
+			seen[got] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("backoff(%d) has no jitter: always %v", attempts, backoff(attempts))
+		}
+	}
+}
+
+func TestScheduleEnqueuesWhenRunStarts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.JobStore) {
+		q := NewQueue(s)
+		ran := make(chan models.Job, 1)
+		q.Register("tick", func(ctx context.Context, job models.Job) error {
+			ran <- job
+			return nil
+		})
+		q.Schedule("tick", time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			q.Run(ctx, 1)
+		}()
+
+		select {
+		case job := <-ran:
+			if job.UserID != "" || job.MemoryID != "" {
+				t.Errorf("expected a scheduled job without a user or memory, got %+v", job)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("expected the scheduled job to run when the queue started")
+		}
+		cancel()
+		<-done
+
+		jobs, err := s.ListJobs(context.Background(), "", models.JobQueryParams{Kind: "tick", Limit: 10})
+		if err != nil || len(jobs) != 1 || jobs[0].Status != models.JobSucceeded {
+			t.Errorf("expected one succeeded run within the interval, got %+v, %v", jobs, err)
+		}
+	})
+}
+
+func TestDrainRunsDueAndScheduledJobs(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.JobStore) {
+		q := NewQueue(s)
+		handled := map[string]int{}
+		q.Register("step", func(ctx context.Context, job models.Job) error {
+			handled[job.Kind]++
+			return nil
+		})
+		q.Register("tick", func(ctx context.Context, job models.Job) error {
+			handled[job.Kind]++
+			return nil
+		})
+		q.Schedule("tick", time.Hour)
+
+		enqueue(t, s, models.Job{Kind: "step"})
+		enqueue(t, s, models.Job{Kind: "step"})
+		later := enqueue(t, s, models.Job{Kind: "step", RunAt: time.Now().Add(time.Hour)})
+
+		ran, err := q.Drain(context.Background())
+		if err != nil {
+			t.Fatalf("drain failed: %v", err)
+		}
+		if ran != 3 || handled["step"] != 2 || handled["tick"] != 1 {
+			t.Errorf("expected the due and scheduled jobs to run once, ran %d: %v", ran, handled)
+		}
+		if got := storedJob(t, s, later); got.Status != models.JobQueued {
+			t.Errorf("expected the future job left queued, got %+v", got)
+		}
+	})
+}
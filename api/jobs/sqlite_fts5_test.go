@@ -0,0 +1,35 @@
+//go:build sqlite_fts5
+
+package jobs
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"api/config"
+	"api/migrations"
+	"api/store"
+)
+
+func init() {
+	testStores["sqlite"] = newSQLiteTestStore
+}
+
+// newSQLiteTestStore migrates a new SQLite database file and returns a store backed by it
+func newSQLiteTestStore(t *testing.T) store.JobStore {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_foreign_keys=on"
+	db, err := sql.Open(config.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := migrations.Up(db, config.DriverSQLite); err != nil {
+		t.Fatalf("failed to migrate SQLite database: %v", err)
+	}
+	return store.NewSQLiteStore(db)
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"api/models"
+	"api/store"
+)
+
+// KindPurgeTrash deletes memories that have been in the trash longer than the retention period
+const KindPurgeTrash = "purge_trash"
+
+// trashPurgeInterval is how often the trash is checked for expired memories
+const trashPurgeInterval = time.Hour
+
+// RegisterTrashPurge purges memories that have been in the trash longer than retention,
+// checking every trashPurgeInterval
+func RegisterTrashPurge(q *Queue, s store.TrashStore, retention time.Duration) {
+	q.Register(KindPurgeTrash, func(ctx context.Context, job models.Job) error {
+		purged, err := s.PurgeTrash(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		if purged > 0 {
+			log.Printf("jobs: purged %d memories from the trash", purged)
+		}
+		return nil
+	})
+	q.Schedule(KindPurgeTrash, trashPurgeInterval)
+}
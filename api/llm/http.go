@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StatusError is a non-2xx response from the chat completions endpoint
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("chat completions endpoint returned %d: %s", e.Code, e.Body)
+}
+
+// HTTPProvider streams from an OpenAI-compatible chat completions endpoint, which OpenAI,
+// Gemini, Ollama and most hosted model servers provide
+type HTTPProvider struct {
+	url       string
+	apiKey    string
+	model     string
+	maxTokens int
+	client    *http.Client
+}
+
+// NewHTTPProvider creates an HTTPProvider posting to url
+func NewHTTPProvider(url, apiKey, model string, maxTokens int) *HTTPProvider {
+	return &HTTPProvider{
+		url:       url,
+		apiKey:    apiKey,
+		model:     model,
+		maxTokens: maxTokens,
+		// No overall timeout: answers stream for as long as the request context allows
+		client: &http.Client{Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: time.Minute,
+		}},
+	}
+}
+
+// Model is the configured model name
+func (p *HTTPProvider) Model() string {
+	return p.model
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens,omitempty"`
+	Stream    bool          `json:"stream"`
+}
+
+type chatChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// Stream requests a streamed completion and emits the content of each chunk
+func (p *HTTPProvider) Stream(ctx context.Context, req Request, emit func(text string) error) error {
+	messages := []chatMessage{}
+	if req.System != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: req.System})
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: req.Prompt})
+
+	body, err := json.Marshal(chatRequest{Model: p.model, Messages: messages, MaxTokens: p.maxTokens, Stream: true})
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("chat completions request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{Code: resp.StatusCode, Body: string(bytes.TrimSpace(snippet))}
+	}
+
+	// Server-sent events: one JSON chunk per "data:" line, ending with "data: [DONE]"
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("invalid chat completions chunk: %w", err)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if err := emit(choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("chat completions stream failed: %w", err)
+	}
+	return nil
+}
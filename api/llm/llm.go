@@ -0,0 +1,39 @@
+// Package llm generates answers from a language model, streaming them as they are produced
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"api/config"
+)
+
+// Request is one completion: instructions for the model and the prompt to answer
+type Request struct {
+	System string
+	Prompt string
+}
+
+// Provider generates completions. Stream calls emit with each piece of the answer in order
+// and stops early, returning emit's error, if emit fails.
+type Provider interface {
+	Stream(ctx context.Context, req Request, emit func(text string) error) error
+	Model() string
+}
+
+// New returns the Provider for cfg, or nil when question answering is disabled
+func New(cfg config.LLM) (Provider, error) {
+	switch cfg.Provider {
+	case config.LLMHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("LLM_URL is required for the http LLM provider")
+		}
+		return NewHTTPProvider(cfg.URL, cfg.APIKey, cfg.Model, cfg.MaxTokens), nil
+	case config.LLMStub:
+		return NewStub(DefaultStubAnswer), nil
+	case config.LLMNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported LLM_PROVIDER %q (use http, stub or none)", cfg.Provider)
+	}
+}
@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// DefaultStubAnswer cites the first source, which every prompt with context has
+const DefaultStubAnswer = "This is a stub answer from your saved memories [1]."
+
+// Stub streams a fixed answer word by word without calling a model. It makes the
+// question answering pipeline testable and usable offline.
+type Stub struct {
+	answer string
+}
+
+// NewStub creates a Stub that always answers with answer
+func NewStub(answer string) *Stub {
+	return &Stub{answer: answer}
+}
+
+// Model names the stub
+func (s *Stub) Model() string {
+	return "stub"
+}
+
+// Stream emits the answer one word at a time, keeping the spacing
+func (s *Stub) Stream(ctx context.Context, req Request, emit func(text string) error) error {
+	words := strings.SplitAfter(s.answer, " ")
+	for _, word := range words {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := emit(word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,15 +1,36 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"api/config"
+	"api/embedding"
+	"api/jobs"
+	"api/llm"
+	"api/migrations"
 	"api/routes"
+	"api/store"
 )
 
 func main() {
+	// `api migrate [up|down [steps]|status]` manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := config.ConnectDB(); err != nil {
+			log.Fatal("Database connection failed:", err)
+		}
+		if err := migrations.RunCommand(config.GetDB(), config.Driver, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+		return
+	}
+
 	// Load port from environment or default to 8080
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -17,18 +38,90 @@ func main() {
 	}
 
 	// Initialize database connection
-	config.ConnectDB()
+	if err := config.ConnectDB(); err != nil {
+		log.Fatal("Database connection failed:", err)
+	}
+
+	// Refuse to serve against a schema this binary does not understand
+	if err := migrations.Check(config.GetDB(), config.Driver); err != nil {
+		log.Fatal("Schema check failed:", err)
+	}
+
+	dataStore, err := store.New(config.GetDB(), config.Driver)
+	if err != nil {
+		log.Fatal("Storage initialization failed:", err)
+	}
+
+	// Memories saved before duplicate detection need their canonical URL and content hash
+	if filled, err := dataStore.BackfillDuplicateKeys(context.Background()); err != nil {
+		log.Fatal("Duplicate key backfill failed:", err)
+	} else if filled > 0 {
+		log.Printf("Backfilled duplicate keys for %d memories", filled)
+	}
+
+	queue := jobs.NewQueue(dataStore)
+
+	// Memories are embedded in the background for semantic search
+	embedder, err := embedding.New(config.LoadEmbedding())
+	if err != nil {
+		log.Fatal("Embedding provider configuration failed:", err)
+	}
+	if embedder != nil {
+		jobs.RegisterEmbedding(queue, dataStore, embedder)
+		if queued, err := jobs.BackfillEmbeddings(context.Background(), queue, dataStore, embedder); err != nil {
+			log.Println("Embedding backfill failed:", err)
+		} else if queued > 0 {
+			log.Printf("Queued %d memories for embedding with %s", queued, embedder.Model())
+		}
+	}
+
+	// Deleted memories are purged for good once they have been in the trash for the retention period
+	jobs.RegisterTrashPurge(queue, dataStore, config.TrashRetention())
+
+	// Questions about saved memories are answered by the configured language model
+	provider, err := llm.New(config.LoadLLM())
+	if err != nil {
+		log.Fatal("LLM provider configuration failed:", err)
+	}
 
 	// Setup routes
-	http.HandleFunc("/", routes.SetupRoutes())
+	http.HandleFunc("/", routes.SetupRoutes(dataStore, queue, embedder, provider))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background workers for post-capture processing; JOB_WORKERS=0 leaves the jobs
+	// to another instance sharing the database
+	workers := 4
+	if n, err := strconv.Atoi(os.Getenv("JOB_WORKERS")); err == nil && n >= 0 {
+		workers = n
+	}
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		queue.Run(ctx, workers)
+	}()
+	log.Printf("⚙️  Running %d job workers", workers)
 
 	// Start server
+	server := &http.Server{Addr: ":" + port}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed to start:", err)
+		}
+	}()
 	log.Printf("🚀 Server starting on http://localhost:%s", port)
 	log.Printf("📡 API endpoint: http://localhost:%s/api", port)
 	log.Printf("📋 Health check: http://localhost:%s/api", port)
 	log.Println("Press Ctrl+C to stop the server")
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatal("Server failed to start:", err)
+	<-ctx.Done()
+	log.Println("Shutting down; waiting for requests and running jobs to finish")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server shutdown:", err)
 	}
+	<-workersDone
 }
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// Deprecated marks responses as coming from a deprecated endpoint with a Deprecation header
+// and links to the successor API, so clients can find and migrate the calls they still make
+func Deprecated(successor string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+			next(w, r)
+		}
+	}
+}
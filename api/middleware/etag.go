@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// IfMatch reports whether r's If-Match header is absent, is "*" or lists etag. Weak tags
+// never match, as If-Match uses strong comparison.
+func IfMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModified sets the ETag header and, if r's If-None-Match lists etag or is "*", answers
+// with 304 Not Modified and returns true. If-None-Match uses weak comparison.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// CachedSuccessResponse sends a success response tagged with a hash of its body, or
+// 304 Not Modified when the client's If-None-Match already has that body
+func CachedSuccessResponse(w http.ResponseWriter, r *http.Request, statusCode int, message string, data interface{}) {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(map[string]interface{}{
+		"success": true,
+		"message": message,
+		"data":    data,
+	})
+	if err != nil {
+		ErrorResponse(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	if NotModified(w, r, `"`+base64.RawURLEncoding.EncodeToString(sum[:16])+`"`) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// EventStream writes server-sent events, flushing each one to the client as it is sent
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewEventStream starts a text/event-stream response, or returns false if w cannot stream
+func NewEventStream(w http.ResponseWriter) (*EventStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop reverse proxies such as nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &EventStream{w: w, flusher: flusher}, true
+}
+
+// Send writes one event with data encoded as JSON
+func (s *EventStream) Send(event string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, encoded); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,315 @@
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/config"
+)
+
+// files holds the SQL migrations for every supported driver, one directory per dialect.
+// Each migration is a pair of files named NNNN_description.up.sql / NNNN_description.down.sql.
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// ErrSchemaBehind is returned by Check when migrations are pending
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes whether a migration has been applied
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+const createMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)
+`
+
+// Load returns the embedded migrations for a driver, ordered by version
+func Load(driver string) ([]Migration, error) {
+	dir, err := dialectDir(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("unexpected migration file %s", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("migration %s must be named NNNN_description", name)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has an invalid version: %w", name, err)
+		}
+
+		body, err := fs.ReadFile(files, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		} else if m.Name != parts[1] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, parts[1])
+		}
+
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction
+func Up(db *sql.DB, driver string) ([]Migration, error) {
+	statuses, err := List(db, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []Migration{}
+	for _, s := range statuses {
+		if s.AppliedAt != nil {
+			continue
+		}
+		err := inTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(s.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+				s.Version, s.Name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %04d_%s failed: %w", s.Version, s.Name, err)
+		}
+		applied = append(applied, s.Migration)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the most recently applied migrations, newest first
+func Down(db *sql.DB, driver string, steps int) ([]Migration, error) {
+	statuses, err := List(db, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := []Migration{}
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		s := statuses[i]
+		if s.AppliedAt == nil {
+			continue
+		}
+		if s.Down == "" {
+			return reverted, fmt.Errorf("migration %04d_%s cannot be reverted: no down script", s.Version, s.Name)
+		}
+		err := inTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(s.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", s.Version)
+			return err
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting %04d_%s failed: %w", s.Version, s.Name, err)
+		}
+		reverted = append(reverted, s.Migration)
+	}
+
+	return reverted, nil
+}
+
+// List reports every known migration and when it was applied
+func List(db *sql.DB, driver string) ([]Status, error) {
+	migrations, err := Load(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		s := Status{Migration: m}
+		if at, ok := appliedAt[m.Version]; ok {
+			s.AppliedAt = &at
+		}
+		statuses = append(statuses, s)
+	}
+
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind if any migration has not been applied yet
+func Check(db *sql.DB, driver string) error {
+	statuses, err := List(db, driver)
+	if err != nil {
+		return err
+	}
+
+	pending := []string{}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%04d_%s", s.Version, s.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: pending migrations %s (run `api migrate up`)", ErrSchemaBehind, strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+// RunCommand implements the `migrate` subcommand: up, down [steps] and status
+func RunCommand(db *sql.DB, driver string, args []string, out io.Writer) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := Up(db, driver)
+		for _, m := range applied {
+			fmt.Fprintf(out, "applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "database schema is up to date")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := Down(db, driver, steps)
+		for _, m := range reverted {
+			fmt.Fprintf(out, "reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := List(db, driver)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%04d_%-40s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q (use up, down [steps] or status)", command)
+	}
+}
+
+func dialectDir(driver string) (string, error) {
+	switch driver {
+	case config.DriverPostgres:
+		return "postgres", nil
+	case config.DriverSQLite:
+		return "sqlite", nil
+	default:
+		return "", fmt.Errorf("no migrations for database driver %q", driver)
+	}
+}
+
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"testing"
+
+	"api/config"
+)
+
+func TestLoadOrdersMigrationsByVersion(t *testing.T) {
+	for _, driver := range []string{config.DriverPostgres, config.DriverSQLite} {
+		migrations, err := Load(driver)
+		if err != nil {
+			t.Fatalf("%s: load failed: %v", driver, err)
+		}
+		if len(migrations) == 0 || migrations[0].Version != 1 {
+			t.Fatalf("%s: expected migrations starting at 0001, got %+v", driver, migrations)
+		}
+		for i, m := range migrations {
+			if i > 0 && m.Version <= migrations[i-1].Version {
+				t.Errorf("%s: %04d_%s is out of order", driver, m.Version, m.Name)
+			}
+			if m.Up == "" || m.Down == "" {
+				t.Errorf("%s: %04d_%s is missing a script", driver, m.Version, m.Name)
+			}
+		}
+	}
+}
+
+func TestSQLiteSkipsPostgresOnlyMigrations(t *testing.T) {
+	postgres, err := Load(config.DriverPostgres)
+	if err != nil {
+		t.Fatalf("load postgres: %v", err)
+	}
+	sqlite, err := Load(config.DriverSQLite)
+	if err != nil {
+		t.Fatalf("load sqlite: %v", err)
+	}
+
+	// The weighted tsvector has no SQLite counterpart, as FTS5 weighs columns at query time,
+	// and only the Next.js app needs memory_tags kept in step with memories.tags by triggers
+	postgresOnly := map[int]bool{4: true, 14: true}
+
+	names := map[int]string{}
+	for _, m := range sqlite {
+		names[m.Version] = m.Name
+	}
+	for _, m := range postgres {
+		name, ok := names[m.Version]
+		switch {
+		case postgresOnly[m.Version]:
+			if ok {
+				t.Errorf("sqlite unexpectedly has migration %04d_%s", m.Version, name)
+			}
+		case !ok:
+			t.Errorf("sqlite has no migration %04d_%s", m.Version, m.Name)
+		case name != m.Name:
+			t.Errorf("migration %04d is %s on postgres but %s on sqlite", m.Version, m.Name, name)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownDriver(t *testing.T) {
+	if _, err := Load("mysql"); err == nil {
+		t.Fatal("expected an error for a driver without migrations")
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS links;
+DROP TABLE IF EXISTS memories;
@@ -0,0 +1,56 @@
+-- Baseline schema shared with the Next.js app (lib/db/schema.ts).
+-- Every statement is idempotent so databases already created by Drizzle adopt it unchanged.
+
+CREATE TABLE IF NOT EXISTS memories (
+	id TEXT PRIMARY KEY,
+	user_id TEXT,
+	url TEXT,
+	title TEXT NOT NULL,
+	content_type VARCHAR(50) NOT NULL DEFAULT 'page' CHECK (content_type IN ('page', 'selection', 'video_timestamp', 'links', 'custom')),
+	content TEXT,
+	selected_text TEXT,
+	context_before TEXT,
+	context_after TEXT,
+	full_context TEXT,
+	element_type VARCHAR(50),
+	page_section VARCHAR(50),
+	xpath TEXT,
+	tags TEXT,
+	notes TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	scraped_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+	-- Video-specific fields
+	video_platform VARCHAR(50),
+	video_timestamp BIGINT,
+	video_duration BIGINT,
+	video_title TEXT,
+	video_url TEXT,
+	thumbnail_url TEXT,
+	formatted_timestamp VARCHAR(20)
+);
+
+-- Databases created before per-user ownership lack this column
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS user_id TEXT;
+
+CREATE TABLE IF NOT EXISTS links (
+	id TEXT PRIMARY KEY,
+	memory_id TEXT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+	text TEXT,
+	href TEXT NOT NULL,
+	link_title TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_memories_user_created_at ON memories(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_memories_url ON memories(url);
+CREATE INDEX IF NOT EXISTS idx_memories_content_type ON memories(content_type);
+CREATE INDEX IF NOT EXISTS idx_memories_scraped_at ON memories(scraped_at DESC);
+CREATE INDEX IF NOT EXISTS idx_memories_video_platform ON memories(video_platform) WHERE video_platform IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_links_memory_id ON links(memory_id);
+
+-- Full-text search index; replaced by the weighted search_vector in 0004
+CREATE INDEX IF NOT EXISTS idx_memories_search ON memories USING gin(
+	to_tsvector('english', COALESCE(title, '') || ' ' || COALESCE(content, '') || ' ' || COALESCE(selected_text, '') || ' ' || COALESCE(tags, ''))
+);
@@ -0,0 +1,12 @@
+-- Host of each link (lowercased, without "www.") so links can be filtered by site
+ALTER TABLE links ADD COLUMN IF NOT EXISTS domain TEXT;
+
+-- Order of the link on the captured page
+ALTER TABLE links ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE links
+SET domain = regexp_replace(lower(substring(href FROM '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^@/]*@)?([^/:?#]+)')), '^www\.', '')
+WHERE domain IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_links_domain ON links(domain);
+CREATE INDEX IF NOT EXISTS idx_links_href ON links(href);
@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS idx_memories_search ON memories USING gin(
+	to_tsvector('english', COALESCE(title, '') || ' ' || COALESCE(content, '') || ' ' || COALESCE(selected_text, '') || ' ' || COALESCE(tags, ''))
+);
+
+DROP INDEX IF EXISTS idx_memories_search_vector;
+ALTER TABLE memories DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,14 @@
+-- Weighted search document for ranked full-text search: ts_rank_cd scores title (A)
+-- above tags (B), the selected text (C) and the page content (D).
+-- Generated columns are maintained by PostgreSQL, so writers such as the Next.js app need no changes.
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+	setweight(to_tsvector('english', COALESCE(tags, '')), 'B') ||
+	setweight(to_tsvector('english', COALESCE(selected_text, '')), 'C') ||
+	setweight(to_tsvector('english', COALESCE(content, '')), 'D')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_memories_search_vector ON memories USING gin(search_vector);
+
+-- Superseded by idx_memories_search_vector
+DROP INDEX IF EXISTS idx_memories_search;
@@ -0,0 +1,43 @@
+-- Normalized tags. memories.tags keeps a comma-separated copy of the names, in the
+-- order they were given, so full-text search and the Next.js app keep working.
+CREATE TABLE IF NOT EXISTS tags (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS memory_tags (
+	memory_id TEXT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+	tag_id TEXT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (memory_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_memory_tags_tag_id ON memory_tags(tag_id);
+
+-- Backfill from memories.tags, normalizing names the way store.normalizeTags does
+CREATE TEMPORARY TABLE memory_tag_backfill ON COMMIT DROP AS
+SELECT memory_id, user_id, name, MIN(ordinal) AS ordinal
+FROM (
+	SELECT m.id AS memory_id, m.user_id,
+		lower(btrim(regexp_replace(t.name, '\s+', ' ', 'g'))) AS name,
+		t.ordinal
+	FROM memories m, unnest(string_to_array(m.tags, ',')) WITH ORDINALITY AS t(name, ordinal)
+	WHERE m.tags IS NOT NULL AND m.user_id IS NOT NULL
+) split
+WHERE name <> ''
+GROUP BY memory_id, user_id, name;
+
+INSERT INTO tags (id, user_id, name)
+SELECT DISTINCT md5(user_id || ':' || name), user_id, name FROM memory_tag_backfill
+ON CONFLICT (user_id, name) DO NOTHING;
+
+INSERT INTO memory_tags (memory_id, tag_id)
+SELECT b.memory_id, t.id FROM memory_tag_backfill b JOIN tags t ON t.user_id = b.user_id AND t.name = b.name
+ON CONFLICT DO NOTHING;
+
+UPDATE memories m SET tags = (
+	SELECT string_agg(b.name, ',' ORDER BY b.ordinal) FROM memory_tag_backfill b WHERE b.memory_id = m.id
+)
+WHERE m.tags IS NOT NULL AND m.user_id IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_memories_content_hash;
+DROP INDEX IF EXISTS idx_memories_canonical_url;
+ALTER TABLE memories DROP COLUMN IF EXISTS content_hash;
+ALTER TABLE memories DROP COLUMN IF EXISTS canonical_url;
@@ -0,0 +1,7 @@
+-- Keys for duplicate detection, computed by the API on save (see store.CanonicalURL).
+-- Rows saved before this migration are filled in when the server starts (BackfillDuplicateKeys).
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS canonical_url TEXT;
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS content_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_memories_canonical_url ON memories(user_id, canonical_url);
+CREATE INDEX IF NOT EXISTS idx_memories_content_hash ON memories(user_id, content_hash);
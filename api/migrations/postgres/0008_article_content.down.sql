@@ -0,0 +1,5 @@
+ALTER TABLE memories DROP COLUMN IF EXISTS language;
+ALTER TABLE memories DROP COLUMN IF EXISTS lead_image_url;
+ALTER TABLE memories DROP COLUMN IF EXISTS published_at;
+ALTER TABLE memories DROP COLUMN IF EXISTS byline;
+ALTER TABLE memories DROP COLUMN IF EXISTS content_html;
@@ -0,0 +1,6 @@
+-- Readable article extracted from the raw HTML of a capture; content then holds its plain text
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS content_html TEXT;
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS byline TEXT;
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS published_at TIMESTAMP;
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS lead_image_url TEXT;
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS language TEXT;
@@ -0,0 +1 @@
+ALTER TABLE memories DROP COLUMN IF EXISTS truncated_fields;
@@ -0,0 +1,2 @@
+-- JSON array of the captured fields that were cut to the configured length limits
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS truncated_fields TEXT;
@@ -0,0 +1,2 @@
+-- The vector extension is left installed; other schemas may use it
+DROP TABLE IF EXISTS memory_embeddings;
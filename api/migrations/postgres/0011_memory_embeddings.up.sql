@@ -0,0 +1,29 @@
+-- Memory embeddings for semantic search, stored with the pgvector extension.
+-- The column is unsized so the embedding model can change; the HNSW index covers
+-- 768-dimensional vectors (the default), which queries select by casting to that size.
+--
+-- Databases that do not offer pgvector skip the table, and semantic search reports that
+-- it is unavailable. This file is idempotent: after installing pgvector, run it again by
+-- hand to enable semantic search.
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+		RAISE NOTICE 'pgvector is not available; skipping memory_embeddings';
+		RETURN;
+	END IF;
+
+	CREATE EXTENSION IF NOT EXISTS vector;
+
+	CREATE TABLE IF NOT EXISTS memory_embeddings (
+		memory_id TEXT PRIMARY KEY REFERENCES memories(id) ON DELETE CASCADE,
+		model TEXT NOT NULL,
+		dimensions INTEGER NOT NULL,
+		embedding vector NOT NULL,
+		source_hash TEXT NOT NULL,
+		embedded_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_memory_embeddings_hnsw_768 ON memory_embeddings
+		USING hnsw ((embedding::vector(768)) vector_cosine_ops) WHERE dimensions = 768;
+END
+$$;
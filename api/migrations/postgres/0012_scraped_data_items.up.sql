@@ -0,0 +1,28 @@
+-- Raw page data saved by the extension with free-form JSON metadata, filtered by key with ->>
+CREATE TABLE IF NOT EXISTS scraped_data (
+	id BIGSERIAL PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	url TEXT NOT NULL,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
+	tags TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scraped_data_user_created ON scraped_data(user_id, created_at DESC);
+
+-- The user's to-do items
+CREATE TABLE IF NOT EXISTS items (
+	id BIGSERIAL PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL,
+	priority TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_items_user_created ON items(user_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- Deleting a memory moves it to the trash by setting deleted_at; it is purged for good from
+-- there, by the user or once it has been in the trash longer than the retention period
+ALTER TABLE memories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_memories_trash ON memories(user_id, deleted_at DESC) WHERE deleted_at IS NOT NULL;
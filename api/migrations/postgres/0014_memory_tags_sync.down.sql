@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS memories_prune_tags ON memories;
+DROP TRIGGER IF EXISTS memories_sync_tags ON memories;
+DROP TRIGGER IF EXISTS memories_normalize_tags ON memories;
+DROP FUNCTION IF EXISTS prune_deleted_memory_tags();
+DROP FUNCTION IF EXISTS sync_memory_tags();
+DROP FUNCTION IF EXISTS normalize_memory_tags();
@@ -0,0 +1,73 @@
+-- The Next.js app writes memories.tags directly and knows nothing of the tags tables. These
+-- triggers normalize every write to memories.tags the way store.normalizeTags does and mirror
+-- it into memory_tags, so tag filters and counts see memories saved by any writer. The API's
+-- own writes are already normalized and rewrite memory_tags the same way, so they are unaffected.
+-- SQLite has no counterpart: only the API writes to it, and its triggers cannot split strings.
+
+CREATE OR REPLACE FUNCTION normalize_memory_tags() RETURNS trigger AS $$
+BEGIN
+	NEW.tags := (
+		SELECT string_agg(name, ',' ORDER BY ordinal) FROM (
+			SELECT name, MIN(ordinal) AS ordinal FROM (
+				SELECT lower(btrim(regexp_replace(t.name, '\s+', ' ', 'g'))) AS name, t.ordinal
+				FROM unnest(string_to_array(NEW.tags, ',')) WITH ORDINALITY AS t(name, ordinal)
+			) split
+			WHERE name <> ''
+			GROUP BY name
+		) names
+	);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Tags left unused by the change are dropped, like store.pruneTags does
+CREATE OR REPLACE FUNCTION sync_memory_tags() RETURNS trigger AS $$
+BEGIN
+	DELETE FROM memory_tags WHERE memory_id = NEW.id;
+
+	IF NEW.user_id IS NOT NULL AND NEW.tags IS NOT NULL THEN
+		INSERT INTO tags (id, user_id, name)
+		SELECT md5(NEW.user_id || ':' || name), NEW.user_id, name FROM unnest(string_to_array(NEW.tags, ',')) AS name
+		ON CONFLICT (user_id, name) DO NOTHING;
+
+		INSERT INTO memory_tags (memory_id, tag_id)
+		SELECT NEW.id, t.id FROM tags t WHERE t.user_id = NEW.user_id AND t.name = ANY (string_to_array(NEW.tags, ','))
+		ON CONFLICT DO NOTHING;
+	END IF;
+
+	IF TG_OP = 'UPDATE' AND OLD.user_id IS NOT NULL AND OLD.tags IS NOT NULL THEN
+		DELETE FROM tags t
+		WHERE t.user_id = OLD.user_id AND t.name = ANY (string_to_array(OLD.tags, ','))
+			AND NOT EXISTS (SELECT 1 FROM memory_tags mt WHERE mt.tag_id = t.id);
+	END IF;
+
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Runs once per statement, after the foreign key has cascaded the deletes into memory_tags
+CREATE OR REPLACE FUNCTION prune_deleted_memory_tags() RETURNS trigger AS $$
+BEGIN
+	DELETE FROM tags t
+	WHERE t.user_id IN (SELECT DISTINCT user_id FROM removed_memories WHERE tags IS NOT NULL)
+		AND NOT EXISTS (SELECT 1 FROM memory_tags mt WHERE mt.tag_id = t.id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS memories_normalize_tags ON memories;
+CREATE TRIGGER memories_normalize_tags BEFORE INSERT OR UPDATE OF tags ON memories
+	FOR EACH ROW EXECUTE FUNCTION normalize_memory_tags();
+
+DROP TRIGGER IF EXISTS memories_sync_tags ON memories;
+CREATE TRIGGER memories_sync_tags AFTER INSERT OR UPDATE OF tags ON memories
+	FOR EACH ROW EXECUTE FUNCTION sync_memory_tags();
+
+DROP TRIGGER IF EXISTS memories_prune_tags ON memories;
+CREATE TRIGGER memories_prune_tags AFTER DELETE ON memories
+	REFERENCING OLD TABLE AS removed_memories
+	FOR EACH STATEMENT EXECUTE FUNCTION prune_deleted_memory_tags();
+
+-- Pick up the memories the Next.js app saved since 0005; rewriting tags fires the triggers
+UPDATE memories SET tags = tags
+WHERE tags IS NOT NULL AND user_id IS NOT NULL;
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS memories_fts_delete;
+DROP TRIGGER IF EXISTS memories_fts_update;
+DROP TRIGGER IF EXISTS memories_fts_insert;
+DROP TABLE IF EXISTS memories_fts;
+DROP TABLE IF EXISTS links;
+DROP TABLE IF EXISTS memories;
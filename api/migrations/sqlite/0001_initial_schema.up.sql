@@ -0,0 +1,67 @@
+-- Baseline schema mirroring the PostgreSQL one; memories_fts replaces the to_tsvector indexes
+CREATE TABLE IF NOT EXISTS memories (
+	id TEXT PRIMARY KEY,
+	user_id TEXT,
+	url TEXT,
+	title TEXT NOT NULL,
+	content_type VARCHAR(50) NOT NULL DEFAULT 'page' CHECK (content_type IN ('page', 'selection', 'video_timestamp', 'links', 'custom')),
+	content TEXT,
+	selected_text TEXT,
+	context_before TEXT,
+	context_after TEXT,
+	full_context TEXT,
+	element_type VARCHAR(50),
+	page_section VARCHAR(50),
+	xpath TEXT,
+	tags TEXT,
+	notes TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	scraped_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	-- Video-specific fields
+	video_platform VARCHAR(50),
+	video_timestamp BIGINT,
+	video_duration BIGINT,
+	video_title TEXT,
+	video_url TEXT,
+	thumbnail_url TEXT,
+	formatted_timestamp VARCHAR(20)
+);
+
+CREATE TABLE IF NOT EXISTS links (
+	id TEXT PRIMARY KEY,
+	memory_id TEXT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+	text TEXT,
+	href TEXT NOT NULL,
+	link_title TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_memories_user_created_at ON memories(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_memories_url ON memories(url);
+CREATE INDEX IF NOT EXISTS idx_memories_content_type ON memories(content_type);
+CREATE INDEX IF NOT EXISTS idx_memories_scraped_at ON memories(scraped_at DESC);
+CREATE INDEX IF NOT EXISTS idx_memories_video_platform ON memories(video_platform) WHERE video_platform IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_links_memory_id ON links(memory_id);
+
+-- Full-text search index, kept in sync by the triggers below
+CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts USING fts5(
+	memory_id UNINDEXED, title, content, selected_text, tags,
+	tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS memories_fts_insert AFTER INSERT ON memories BEGIN
+	INSERT INTO memories_fts (memory_id, title, content, selected_text, tags)
+	VALUES (new.id, new.title, new.content, new.selected_text, new.tags);
+END;
+
+CREATE TRIGGER IF NOT EXISTS memories_fts_update AFTER UPDATE ON memories BEGIN
+	DELETE FROM memories_fts WHERE memory_id = old.id;
+	INSERT INTO memories_fts (memory_id, title, content, selected_text, tags)
+	VALUES (new.id, new.title, new.content, new.selected_text, new.tags);
+END;
+
+CREATE TRIGGER IF NOT EXISTS memories_fts_delete AFTER DELETE ON memories BEGIN
+	DELETE FROM memories_fts WHERE memory_id = old.id;
+END;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_links_href;
+DROP INDEX IF EXISTS idx_links_domain;
+ALTER TABLE links DROP COLUMN position;
+ALTER TABLE links DROP COLUMN domain;
@@ -0,0 +1,22 @@
+-- Host of each link (lowercased, without "www.") so links can be filtered by site
+ALTER TABLE links ADD COLUMN domain TEXT;
+
+-- Order of the link on the captured page
+ALTER TABLE links ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+-- SQLite has no regular expressions: take the text between "://" and the next "/", then drop any port
+UPDATE links
+SET domain = (
+	SELECT CASE WHEN host LIKE 'www.%' THEN substr(host, 5) ELSE host END
+	FROM (
+		SELECT CASE WHEN instr(authority, ':') > 0 THEN substr(authority, 1, instr(authority, ':') - 1) ELSE authority END AS host
+		FROM (
+			SELECT lower(CASE WHEN instr(rest, '/') > 0 THEN substr(rest, 1, instr(rest, '/') - 1) ELSE rest END) AS authority
+			FROM (SELECT substr(href, instr(href, '://') + 3) AS rest)
+		)
+	)
+)
+WHERE domain IS NULL AND instr(href, '://') > 0;
+
+CREATE INDEX IF NOT EXISTS idx_links_domain ON links(domain);
+CREATE INDEX IF NOT EXISTS idx_links_href ON links(href);
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_memories_user_created_at ON memories(user_id, created_at DESC);
+DROP INDEX IF EXISTS idx_memories_user_created_at_id;
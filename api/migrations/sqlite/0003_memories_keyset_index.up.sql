@@ -0,0 +1,3 @@
+-- Matches the (created_at, id) keyset ordering used for cursor pagination
+CREATE INDEX IF NOT EXISTS idx_memories_user_created_at_id ON memories(user_id, created_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_memories_user_created_at;
@@ -0,0 +1,52 @@
+-- Normalized tags. memories.tags keeps a comma-separated copy of the names, in the
+-- order they were given, so full-text search keeps working.
+CREATE TABLE IF NOT EXISTS tags (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS memory_tags (
+	memory_id TEXT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+	tag_id TEXT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (memory_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_memory_tags_tag_id ON memory_tags(tag_id);
+
+-- Backfill from memories.tags, normalizing names the way store.normalizeTags does.
+-- SQLite has no regexp_replace, so runs of whitespace are collapsed by repeated replace().
+CREATE TEMPORARY TABLE memory_tag_backfill AS
+WITH RECURSIVE split(memory_id, user_id, name, rest, ordinal) AS (
+	SELECT id, user_id, '', tags || ',', 0 FROM memories WHERE tags IS NOT NULL AND user_id IS NOT NULL
+	UNION ALL
+	SELECT memory_id, user_id, substr(rest, 1, instr(rest, ',') - 1), substr(rest, instr(rest, ',') + 1), ordinal + 1
+	FROM split WHERE rest <> ''
+),
+cleaned AS (
+	SELECT memory_id, user_id, ordinal,
+		lower(trim(replace(replace(replace(replace(
+			replace(replace(replace(name, char(9), ' '), char(10), ' '), char(13), ' '),
+			'  ', ' '), '  ', ' '), '  ', ' '), '  ', ' '))) AS name
+	FROM split WHERE ordinal > 0
+)
+SELECT memory_id, user_id, name, MIN(ordinal) AS ordinal
+FROM cleaned WHERE name <> ''
+GROUP BY memory_id, user_id, name;
+
+INSERT OR IGNORE INTO tags (id, user_id, name)
+SELECT lower(hex(randomblob(16))), user_id, name FROM memory_tag_backfill GROUP BY user_id, name;
+
+INSERT OR IGNORE INTO memory_tags (memory_id, tag_id)
+SELECT b.memory_id, t.id FROM memory_tag_backfill b JOIN tags t ON t.user_id = b.user_id AND t.name = b.name;
+
+UPDATE memories SET tags = (
+	SELECT group_concat(name, ',') FROM (
+		SELECT name FROM memory_tag_backfill b WHERE b.memory_id = memories.id ORDER BY ordinal
+	)
+)
+WHERE tags IS NOT NULL AND user_id IS NOT NULL;
+
+DROP TABLE memory_tag_backfill;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS collection_memories;
+DROP TABLE IF EXISTS collections;
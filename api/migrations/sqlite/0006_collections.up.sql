@@ -0,0 +1,21 @@
+-- Named, ordered groups of memories (folders/boards); a memory may be in many collections
+CREATE TABLE IF NOT EXISTS collections (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS collection_memories (
+	collection_id TEXT NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+	memory_id TEXT NOT NULL REFERENCES memories(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	added_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (collection_id, memory_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collections_user_id ON collections(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_collection_memories_position ON collection_memories(collection_id, position);
+CREATE INDEX IF NOT EXISTS idx_collection_memories_memory_id ON collection_memories(memory_id);
@@ -0,0 +1,5 @@
+ALTER TABLE memories DROP COLUMN language;
+ALTER TABLE memories DROP COLUMN lead_image_url;
+ALTER TABLE memories DROP COLUMN published_at;
+ALTER TABLE memories DROP COLUMN byline;
+ALTER TABLE memories DROP COLUMN content_html;
@@ -0,0 +1,6 @@
+-- Readable article extracted from the raw HTML of a capture; content then holds its plain text
+ALTER TABLE memories ADD COLUMN content_html TEXT;
+ALTER TABLE memories ADD COLUMN byline TEXT;
+ALTER TABLE memories ADD COLUMN published_at TIMESTAMP;
+ALTER TABLE memories ADD COLUMN lead_image_url TEXT;
+ALTER TABLE memories ADD COLUMN language TEXT;
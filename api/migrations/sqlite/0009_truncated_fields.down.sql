@@ -0,0 +1 @@
+ALTER TABLE memories DROP COLUMN truncated_fields;
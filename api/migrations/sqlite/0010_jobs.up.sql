@@ -0,0 +1,22 @@
+-- Background processing queue. Workers claim runnable jobs (queued and due, or running with an
+-- expired lease after a crash), and a failed job is queued again with backoff until it has
+-- used max_attempts, after which it stays dead for inspection.
+CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT PRIMARY KEY,
+	kind TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	memory_id TEXT REFERENCES memories(id) ON DELETE CASCADE,
+	payload TEXT,
+	status TEXT NOT NULL DEFAULT 'queued',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL,
+	run_at TIMESTAMP NOT NULL,
+	locked_until TIMESTAMP,
+	last_error TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_runnable ON jobs(status, run_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_user_created ON jobs(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_jobs_memory_id ON jobs(memory_id);
@@ -0,0 +1,10 @@
+-- Memory embeddings for semantic search. SQLite has no vector type, so embeddings are
+-- little-endian float32 blobs and similarity is computed by the store.
+CREATE TABLE IF NOT EXISTS memory_embeddings (
+	memory_id TEXT PRIMARY KEY REFERENCES memories(id) ON DELETE CASCADE,
+	model TEXT NOT NULL,
+	dimensions INTEGER NOT NULL,
+	embedding BLOB NOT NULL,
+	source_hash TEXT NOT NULL,
+	embedded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+-- Without the column trashed memories would reappear, so they are purged
+DELETE FROM memories WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_memories_trash;
+ALTER TABLE memories DROP COLUMN deleted_at;
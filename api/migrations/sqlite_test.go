@@ -0,0 +1,142 @@
+//go:build sqlite_fts5
+
+package migrations
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"api/config"
+)
+
+// openSQLite opens a new, empty SQLite database file
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_foreign_keys=on"
+	db, err := sql.Open(config.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// run executes a migrate subcommand and returns its output
+func run(t *testing.T, db *sql.DB, args ...string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := RunCommand(db, config.DriverSQLite, args, &out); err != nil {
+		t.Fatalf("migrate %s failed: %v", strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+// pending returns the versions List reports as not applied
+func pending(t *testing.T, db *sql.DB) []int {
+	t.Helper()
+
+	statuses, err := List(db, config.DriverSQLite)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	versions := []int{}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+func TestUpDownAndStatus(t *testing.T) {
+	db := openSQLite(t)
+	all, err := Load(config.DriverSQLite)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	latest := all[len(all)-1]
+
+	if got := pending(t, db); len(got) != len(all) {
+		t.Fatalf("new database: expected %d pending migrations, got %v", len(all), got)
+	}
+
+	out := run(t, db, "up")
+	if strings.Count(out, "applied ") != len(all) || strings.Contains(out, "0004_") {
+		t.Errorf("up: unexpected output %q", out)
+	}
+	if got := pending(t, db); len(got) != 0 {
+		t.Errorf("after up: expected nothing pending, got %v", got)
+	}
+	if out := run(t, db, "up"); !strings.Contains(out, "up to date") {
+		t.Errorf("second up: expected no changes, got %q", out)
+	}
+
+	out = run(t, db, "down", "2")
+	if !strings.HasPrefix(out, "reverted "+name(latest)) || strings.Count(out, "reverted ") != 2 {
+		t.Errorf("down 2: expected the two newest migrations reverted, got %q", out)
+	}
+	if got := pending(t, db); len(got) != 2 || got[1] != latest.Version {
+		t.Errorf("after down 2: expected the two newest pending, got %v", got)
+	}
+
+	out = run(t, db, "status")
+	if !strings.Contains(out, name(latest)) || strings.Count(out, "pending") != 2 || strings.Count(out, "applied ") != len(all)-2 {
+		t.Errorf("status: unexpected output %q", out)
+	}
+
+	if out := run(t, db, "up"); strings.Count(out, "applied ") != 2 {
+		t.Errorf("up after down 2: expected two migrations re-applied, got %q", out)
+	}
+
+	// Reverting everything leaves only the bookkeeping table
+	run(t, db, "down", "100")
+	if got := pending(t, db); len(got) != len(all) {
+		t.Errorf("after down 100: expected everything pending, got %v", got)
+	}
+	if _, err := db.Exec("SELECT 1 FROM memories"); err == nil {
+		t.Error("after down 100: the memories table is still there")
+	}
+}
+
+func TestRunCommandRejectsInvalidArguments(t *testing.T) {
+	db := openSQLite(t)
+	for _, args := range [][]string{{"sideways"}, {"down", "0"}, {"down", "x"}} {
+		if err := RunCommand(db, config.DriverSQLite, args, &bytes.Buffer{}); err == nil {
+			t.Errorf("migrate %s: expected an error", strings.Join(args, " "))
+		}
+	}
+}
+
+func TestCheckRefusesPendingMigrations(t *testing.T) {
+	db := openSQLite(t)
+
+	err := Check(db, config.DriverSQLite)
+	if !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("new database: expected ErrSchemaBehind, got %v", err)
+	}
+
+	run(t, db, "up")
+	if err := Check(db, config.DriverSQLite); err != nil {
+		t.Fatalf("migrated database: expected no error, got %v", err)
+	}
+
+	run(t, db, "down")
+	all, _ := Load(config.DriverSQLite)
+	err = Check(db, config.DriverSQLite)
+	if !errors.Is(err, ErrSchemaBehind) || !strings.Contains(err.Error(), name(all[len(all)-1])) {
+		t.Fatalf("one migration behind: expected ErrSchemaBehind naming it, got %v", err)
+	}
+}
+
+// name returns the NNNN_description form the runner reports migrations in
+func name(m Migration) string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
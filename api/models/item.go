@@ -2,12 +2,16 @@ package models
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
-// Item represents a basic item in the system
+// Item represents a basic item in the system, owned by a user
 type Item struct {
 	ID          int64     `json:"id" db:"id"`
+	UserID      string    `json:"user_id" db:"user_id"`
 	Title       string    `json:"title" db:"title"`
 	Description string    `json:"description" db:"description"`
 	Status      string    `json:"status" db:"status"`
@@ -32,9 +36,16 @@ type UpdateItemRequest struct {
 	Priority    string `json:"priority"`
 }
 
+// ItemQueryParams filters the items listing; empty fields match every item
+type ItemQueryParams struct {
+	Status   string `json:"status"`
+	Priority string `json:"priority"`
+}
+
 // Memory represents saved content from the browser extension
 type Memory struct {
 	ID            string         `json:"id" db:"id"`
+	UserID        string         `json:"user_id" db:"user_id"`
 	URL           sql.NullString `json:"url,omitempty" db:"url"`
 	Title         string         `json:"title" db:"title" validate:"required"`
 	ContentType   string         `json:"content_type" db:"content_type" validate:"required,oneof=page selection video_timestamp links custom"`
@@ -61,6 +72,23 @@ type Memory struct {
 	VideoURL       sql.NullString `json:"video_url,omitempty" db:"video_url"`
 	ThumbnailURL   sql.NullString `json:"thumbnail_url,omitempty" db:"thumbnail_url"`
 	FormattedTime  sql.NullString `json:"formatted_timestamp,omitempty" db:"formatted_timestamp"`
+
+	// Duplicate detection keys, derived from URL and captured text on save
+	CanonicalURL sql.NullString `json:"canonical_url,omitempty" db:"canonical_url"`
+	ContentHash  sql.NullString `json:"-" db:"content_hash"`
+
+	// Readable article extracted from raw HTML captures; Content holds its plain text
+	ContentHTML  sql.NullString `json:"content_html,omitempty" db:"content_html"`
+	Byline       sql.NullString `json:"byline,omitempty" db:"byline"`
+	PublishedAt  sql.NullTime   `json:"published_at,omitempty" db:"published_at"`
+	LeadImageURL sql.NullString `json:"lead_image_url,omitempty" db:"lead_image_url"`
+	Language     sql.NullString `json:"language,omitempty" db:"language"`
+
+	// Captured fields that were cut to the configured length limits
+	Truncated Truncations `json:"truncated,omitempty" db:"truncated_fields"`
+
+	// When the memory was moved to the trash; only set on memories listed from the trash
+	DeletedAt sql.NullTime `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 // CreateMemoryRequest represents the request from the extension
@@ -81,8 +109,26 @@ type CreateMemoryRequest struct {
 	Notes         string     `json:"notes"`
 	ScrapedAt     time.Time  `json:"scraped_at"`
 	VideoData     *VideoData `json:"video_data"`
+
+	// HTML is the raw page markup; when set, the readable article is extracted from it
+	// and replaces Content, and an empty Title is taken from the page
+	HTML    string   `json:"html"`
+	Article *Article `json:"-"`
+
+	// Truncated is filled by the server when fields are cut to the length limits
+	Truncated Truncations `json:"-"`
+
+	// OnDuplicate is "merge" to fold the capture into an existing memory of the same page
+	// (or the same selection or video moment) instead of inserting a new one
+	OnDuplicate string `json:"on_duplicate" validate:"omitempty,oneof=insert merge"`
 }
 
+// Values of CreateMemoryRequest.OnDuplicate
+const (
+	OnDuplicateInsert = "insert"
+	OnDuplicateMerge  = "merge"
+)
+
 // VideoData represents video-specific information
 type VideoData struct {
 	Platform           string `json:"platform"`
@@ -94,6 +140,53 @@ type VideoData struct {
 	FormattedTimestamp string `json:"formatted_timestamp"`
 }
 
+// Article is the readable content extracted from the raw HTML of a captured page
+type Article struct {
+	Title        string     `json:"title"`
+	Byline       string     `json:"byline,omitempty"`
+	PublishedAt  *time.Time `json:"published_at,omitempty"`
+	LeadImageURL string     `json:"lead_image_url,omitempty"`
+	Language     string     `json:"language,omitempty"`
+	Text         string     `json:"text"`
+	HTML         string     `json:"html"` // sanitized article markup
+}
+
+// FieldTruncation records a captured field that was longer than its limit
+type FieldTruncation struct {
+	Field          string `json:"field"`
+	OriginalLength int    `json:"original_length"`
+	StoredLength   int    `json:"stored_length"`
+}
+
+// Truncations is stored as a JSON array in memories.truncated_fields
+type Truncations []FieldTruncation
+
+// Value implements driver.Valuer; an empty list is stored as NULL
+func (t Truncations) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal([]FieldTruncation(t))
+	return string(encoded), err
+}
+
+// Scan implements sql.Scanner
+func (t *Truncations) Scan(src interface{}) error {
+	var encoded []byte
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		encoded = v
+	case string:
+		encoded = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Truncations", src)
+	}
+	return json.Unmarshal(encoded, (*[]FieldTruncation)(t))
+}
+
 // Link represents a captured link from the page
 type Link struct {
 	Text  string `json:"text"`
@@ -101,30 +194,124 @@ type Link struct {
 	Title string `json:"title"`
 }
 
+// LinkResponse represents a stored link together with the memory it was captured from
+type LinkResponse struct {
+	ID        string    `json:"id" db:"id"`
+	MemoryID  string    `json:"memory_id" db:"memory_id"`
+	Text      string    `json:"text" db:"text"`
+	Href      string    `json:"href" db:"href"`
+	Title     string    `json:"title" db:"link_title"`
+	Domain    string    `json:"domain" db:"domain"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LinkQueryParams represents query parameters for the links API
+type LinkQueryParams struct {
+	Domain string `json:"domain"`
+	URL    string `json:"url"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
 // UpdateMemoryRequest represents the request for updating a memory
 type UpdateMemoryRequest struct {
 	Title       string   `json:"title" validate:"omitempty"`
 	Tags        []string `json:"tags"`
 	Notes       string   `json:"notes"`
-	ContentType string   `json:"content_type" validate:"omitempty"`
+	ContentType string   `json:"content_type" validate:"omitempty,oneof=page selection video_timestamp links custom"`
 }
 
+// MemoryEdit holds every user-editable field of a memory; it is the document a JSON Merge
+// Patch (RFC 7396) of a memory applies to. Empty strings, no tags and a nil VideoData are
+// stored as cleared fields.
+type MemoryEdit struct {
+	Title         string     `json:"title" validate:"required"`
+	URL           string     `json:"url"`
+	ContentType   string     `json:"content_type" validate:"required,oneof=page selection video_timestamp links custom"`
+	Content       string     `json:"content"`
+	SelectedText  string     `json:"selected_text"`
+	ContextBefore string     `json:"context_before"`
+	ContextAfter  string     `json:"context_after"`
+	Notes         string     `json:"notes"`
+	Tags          []string   `json:"tags"`
+	VideoData     *VideoData `json:"video_data"`
+}
+
+// MemoryQueryParams represents query parameters for listing memories
+type MemoryQueryParams struct {
+	ContentType  string `json:"content_type"`
+	Platform     string `json:"platform"`
+	Tags         string `json:"tags"`
+	Search       string `json:"search"`
+	CollectionID string `json:"collection_id"`
+	Limit        int    `json:"limit"`
+	Offset       int    `json:"offset"`
+	Cursor       string `json:"cursor"`
+	IncludeTotal bool   `json:"include_total"`
+}
+
+// Search modes of SearchRequest.Mode
+const (
+	SearchModeKeyword = "keyword" // full-text search only (the default)
+	SearchModeHybrid  = "hybrid"  // full-text and semantic search fused by reciprocal rank
+)
+
 // SearchRequest represents search parameters
 type SearchRequest struct {
-	Query       string   `json:"query"`
-	Tags        []string `json:"tags"`
-	ContentType string   `json:"content_type"`
-	Platform    string   `json:"platform"`
-	StartDate   string   `json:"start_date"`
-	EndDate     string   `json:"end_date"`
-	Limit       int      `json:"limit"`
-	Offset      int      `json:"offset"`
+	Query        string   `json:"query"`
+	Mode         string   `json:"mode"`
+	Tags         []string `json:"tags"`
+	ContentType  string   `json:"content_type"`
+	Platform     string   `json:"platform"`
+	StartDate    string   `json:"start_date"`
+	EndDate      string   `json:"end_date"`
+	CollectionID string   `json:"collection_id"`
+	Limit        int      `json:"limit"`
+	Offset       int      `json:"offset"`
+	Cursor       string   `json:"cursor"`
+	IncludeTotal bool     `json:"include_total"`
 }
 
 // MemoryResponse represents a single memory response
 type MemoryResponse struct {
 	Memory
-	VideoData *VideoData `json:"video_data,omitempty"`
+	VideoData *VideoData     `json:"video_data,omitempty"`
+	Links     []LinkResponse `json:"links"`
+
+	// Set on full-text search results only: relevance (higher is better) and
+	// the matching fragments of each field with the matched terms wrapped in <mark>
+	Rank       *float64          `json:"rank,omitempty"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+
+	// Set on semantic search results only: cosine similarity to the query, from -1 to 1
+	Similarity *float64 `json:"similarity,omitempty"`
+
+	// Set on hybrid search results only: the fused score and what each search contributed
+	Scores *HybridScores `json:"scores,omitempty"`
+}
+
+// HybridScores explains the position of a hybrid search result. Score is the sum of
+// the reciprocal rank contributions; a component is nil when that search missed the memory.
+type HybridScores struct {
+	Score    float64           `json:"score"`
+	Keyword  *RankContribution `json:"keyword,omitempty"`
+	Semantic *RankContribution `json:"semantic,omitempty"`
+}
+
+// RankContribution is one search's view of a hybrid search result: its 1-based position
+// in that search, the search's own score (text rank or similarity) and 1/(k+position)
+type RankContribution struct {
+	Position int     `json:"position"`
+	Score    float64 `json:"score"`
+	RRF      float64 `json:"rrf"`
+}
+
+// MemoryPage is one page of a memory listing or search.
+// NextCursor is empty on the last page; Total is only set when requested.
+type MemoryPage struct {
+	Memories   []MemoryResponse `json:"memories"`
+	NextCursor string           `json:"next_cursor"`
+	Total      *int             `json:"total,omitempty"`
 }
 
 // Response represents a standard API response
@@ -151,3 +338,245 @@ type TagCount struct {
 	Tag   string `json:"tag"`
 	Count int    `json:"count"`
 }
+
+// Collection is a named, ordered group of memories; a memory may belong to many collections
+type Collection struct {
+	ID          string    `json:"id" db:"id"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	MemoryCount int       `json:"memory_count"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateCollectionRequest represents the request for creating a collection
+type CreateCollectionRequest struct {
+	Name        string `json:"name" validate:"required,max=200"`
+	Description string `json:"description"`
+}
+
+// UpdateCollectionRequest represents the request for updating a collection
+type UpdateCollectionRequest struct {
+	Name        string `json:"name" validate:"omitempty,max=200"`
+	Description string `json:"description"`
+}
+
+// CollectionMemoriesRequest adds memories to a collection. Without a position they are
+// appended; memories already in the collection are moved.
+type CollectionMemoriesRequest struct {
+	MemoryIDs []string `json:"memory_ids" validate:"required,min=1,dive,uuid"`
+	Position  *int     `json:"position" validate:"omitempty,min=0"`
+}
+
+// ReorderCollectionRequest moves the listed memories to the front of a collection, in order
+type ReorderCollectionRequest struct {
+	MemoryIDs []string `json:"memory_ids" validate:"required,min=1,dive,uuid"`
+}
+
+// Bulk actions accepted by BulkRequest
+const (
+	BulkDelete           = "delete"
+	BulkAddTags          = "add_tags"
+	BulkRemoveTags       = "remove_tags"
+	BulkSetContentType   = "set_content_type"
+	BulkMoveToCollection = "move_to_collection"
+)
+
+// BulkRequest applies one action to many memories, selected either by ID or by a
+// search filter. Pagination fields of the filter are ignored.
+type BulkRequest struct {
+	IDs          []string       `json:"ids" validate:"omitempty,max=1000,dive,uuid"`
+	Filter       *SearchRequest `json:"filter"`
+	Action       string         `json:"action" validate:"required,oneof=delete add_tags remove_tags set_content_type move_to_collection"`
+	Tags         []string       `json:"tags"`
+	ContentType  string         `json:"content_type" validate:"omitempty,oneof=page selection video_timestamp links custom"`
+	CollectionID string         `json:"collection_id" validate:"omitempty,uuid"`
+}
+
+// Per-item outcomes of a bulk action
+const (
+	BulkStatusUpdated   = "updated"
+	BulkStatusDeleted   = "deleted"
+	BulkStatusUnchanged = "unchanged"
+	BulkStatusNotFound  = "not_found"
+)
+
+// BulkItemResult is the outcome of a bulk action for one memory
+type BulkItemResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// BulkResult summarizes a bulk action
+type BulkResult struct {
+	Action    string           `json:"action"`
+	Matched   int              `json:"matched"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// RenameTagRequest renames a tag across all of the user's memories
+type RenameTagRequest struct {
+	From string `json:"from" validate:"required"`
+	To   string `json:"to" validate:"required"`
+}
+
+// MergeTagsRequest replaces every source tag with the target tag
+type MergeTagsRequest struct {
+	Sources []string `json:"sources" validate:"required,min=1"`
+	Target  string   `json:"target" validate:"required"`
+}
+
+// ImportError describes an entry of an import file that could not be saved
+type ImportError struct {
+	Position int    `json:"position"`
+	URL      string `json:"url,omitempty"`
+	Error    string `json:"error"`
+}
+
+// ImportResult summarizes an import. Skipped entries have a URL the user already saved;
+// Errors lists the first failures only, Failed counts all of them.
+type ImportResult struct {
+	Format             string        `json:"format"`
+	Created            int           `json:"created"`
+	Skipped            int           `json:"skipped"`
+	Failed             int           `json:"failed"`
+	CollectionsCreated int           `json:"collections_created"`
+	Errors             []ImportError `json:"errors"`
+}
+
+// Reasons memories are grouped as likely duplicates
+const (
+	DuplicateByURL     = "url"
+	DuplicateByContent = "content"
+)
+
+// DuplicateGroup lists memories that are likely the same capture, oldest first.
+// Key is the shared canonical URL or content hash, depending on Reason.
+type DuplicateGroup struct {
+	Reason   string           `json:"reason"`
+	Key      string           `json:"key"`
+	Memories []MemoryResponse `json:"memories"`
+}
+
+// Job states. A job waiting for a retry is queued again with LastError set;
+// dead jobs used up their attempts or failed permanently and are kept for inspection.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+// Job is one unit of background processing, usually for a single memory
+type Job struct {
+	ID          string          `json:"id" db:"id"`
+	Kind        string          `json:"kind" db:"kind"`
+	UserID      string          `json:"user_id" db:"user_id"`
+	MemoryID    string          `json:"memory_id,omitempty" db:"memory_id"`
+	Payload     json.RawMessage `json:"payload,omitempty" db:"payload"`
+	Status      string          `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"max_attempts" db:"max_attempts"`
+	RunAt       time.Time       `json:"run_at" db:"run_at"`
+	LastError   string          `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// JobQueryParams filters the jobs listing; empty fields match every job
+type JobQueryParams struct {
+	Status   string `json:"status"`
+	Kind     string `json:"kind"`
+	MemoryID string `json:"memory_id"`
+	Limit    int    `json:"limit"`
+	Offset   int    `json:"offset"`
+}
+
+// SemanticSearchRequest finds the memories closest in meaning to Query; the filters
+// work as in SearchRequest
+type SemanticSearchRequest struct {
+	Query         string   `json:"query" validate:"required"`
+	Tags          []string `json:"tags"`
+	ContentType   string   `json:"content_type"`
+	Platform      string   `json:"platform"`
+	StartDate     string   `json:"start_date"`
+	EndDate       string   `json:"end_date"`
+	CollectionID  string   `json:"collection_id"`
+	Limit         int      `json:"limit" validate:"omitempty,min=1,max=50"`
+	MinSimilarity float64  `json:"min_similarity" validate:"omitempty,min=-1,max=1"`
+}
+
+// Reasons a memory is related to another, in RelatedReason.Type
+const (
+	RelatedSharedTags  = "shared_tags"
+	RelatedSameDomain  = "same_domain"
+	RelatedSharedLinks = "shared_links"
+	RelatedSimilarText = "similar_text"
+)
+
+// RelatedQuery selects the memories related to one memory. Model is the embedding model
+// whose vectors are compared for text similarity; empty leaves text similarity out.
+type RelatedQuery struct {
+	Model string
+	Limit int
+}
+
+// RelatedMemory is a memory related to another, with the combined score of the reasons
+// (from 0 to 1, higher is more related) and each reason found
+type RelatedMemory struct {
+	MemoryResponse
+	Score   float64         `json:"score"`
+	Reasons []RelatedReason `json:"reasons"`
+}
+
+// RelatedReason is one way two memories are related and what it added to the score.
+// Only the field describing the reason's Type is set.
+type RelatedReason struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Score       float64  `json:"score"`
+	Tags        []string `json:"tags,omitempty"`
+	Domain      string   `json:"domain,omitempty"`
+	Links       []string `json:"links,omitempty"`
+	Similarity  *float64 `json:"similarity,omitempty"`
+}
+
+// AskRequest is a question answered from the user's memories; the filters work as in
+// SearchRequest and select the memories the answer may draw on
+type AskRequest struct {
+	Question     string   `json:"question" validate:"required,max=2000"`
+	Tags         []string `json:"tags"`
+	ContentType  string   `json:"content_type"`
+	Platform     string   `json:"platform"`
+	StartDate    string   `json:"start_date"`
+	EndDate      string   `json:"end_date"`
+	CollectionID string   `json:"collection_id"`
+	Limit        int      `json:"limit" validate:"omitempty,min=1,max=20"`
+	Stream       *bool    `json:"stream"` // false returns the whole answer as JSON instead of server-sent events
+}
+
+// Citation is a memory given to the model as numbered context; answers cite it as [Index]
+type Citation struct {
+	Index    int    `json:"index"`
+	MemoryID string `json:"memory_id"`
+	Title    string `json:"title"`
+	URL      string `json:"url,omitempty"`
+}
+
+// VectorQuery is a semantic search after the query text has been embedded
+type VectorQuery struct {
+	Model         string
+	Vector        []float32
+	Filter        SearchRequest // only the filter fields are used
+	Limit         int
+	MinSimilarity float64
+}
+
+// MemoryRef identifies a memory of a user
+type MemoryRef struct {
+	UserID   string `json:"user_id"`
+	MemoryID string `json:"memory_id"`
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -11,33 +14,66 @@ type ScrapedData struct {
 	URL       string    `json:"url" db:"url"`
 	Title     string    `json:"title" db:"title"`
 	Content   string    `json:"content" db:"content"`
-	Metadata  string    `json:"metadata" db:"metadata"` // JSON string for flexible metadata storage
+	Metadata  Metadata  `json:"metadata" db:"metadata"` // JSON object for flexible metadata storage
 	Tags      string    `json:"tags" db:"tags"`         // Comma-separated tags
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Metadata is a free-form JSON object, stored as JSONB in PostgreSQL and as JSON text in SQLite
+type Metadata map[string]interface{}
+
+// Value implements driver.Valuer; a nil map is stored as an empty object
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(map[string]interface{}(m))
+	return string(encoded), err
+}
+
+// Scan implements sql.Scanner
+func (m *Metadata) Scan(src interface{}) error {
+	var encoded []byte
+	switch v := src.(type) {
+	case nil:
+		*m = Metadata{}
+		return nil
+	case []byte:
+		encoded = v
+	case string:
+		encoded = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Metadata", src)
+	}
+	*m = Metadata{}
+	return json.Unmarshal(encoded, (*map[string]interface{})(m))
+}
+
 // CreateScrapedDataRequest represents the request body for creating scraped data
 type CreateScrapedDataRequest struct {
-	URL      string                 `json:"url" validate:"required,url"`
-	Title    string                 `json:"title" validate:"required,max=500"`
-	Content  string                 `json:"content" validate:"required"`
-	Metadata map[string]interface{} `json:"metadata"`
-	Tags     []string               `json:"tags"`
+	URL      string   `json:"url" validate:"required,url"`
+	Title    string   `json:"title" validate:"required,max=500"`
+	Content  string   `json:"content" validate:"required"`
+	Metadata Metadata `json:"metadata"`
+	Tags     []string `json:"tags"`
 }
 
 // UpdateScrapedDataRequest represents the request body for updating scraped data
 type UpdateScrapedDataRequest struct {
-	Title    string                 `json:"title" validate:"omitempty,max=500"`
-	Content  string                 `json:"content"`
-	Metadata map[string]interface{} `json:"metadata"`
-	Tags     []string               `json:"tags"`
+	Title    string   `json:"title" validate:"omitempty,max=500"`
+	Content  string   `json:"content"`
+	Metadata Metadata `json:"metadata"` // replaces the stored metadata as a whole
+	Tags     []string `json:"tags"`
 }
 
-// ScrapedDataQueryParams represents query parameters for filtering scraped data
+// ScrapedDataQueryParams represents query parameters for filtering scraped data.
+// Metadata matches entries whose metadata has every key with the given value, compared as
+// text, so {"rating": "5"} matches both "rating": 5 and "rating": "5".
 type ScrapedDataQueryParams struct {
-	URL   string `json:"url"`
-	Tag   string `json:"tag"`
-	Limit int    `json:"limit"`
-	Page  int    `json:"page"`
+	URL      string            `json:"url"`
+	Tag      string            `json:"tag"`
+	Metadata map[string]string `json:"metadata"`
+	Limit    int               `json:"limit"`
+	Page     int               `json:"page"`
 }
@@ -0,0 +1,139 @@
+// Package rag builds the prompts that answer questions from saved memories, with numbered
+// citations back to the memories used
+package rag
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"api/llm"
+	"api/models"
+)
+
+const (
+	// maxContextLength bounds the memory text in one prompt, in bytes
+	maxContextLength = 12000
+	// maxSourceLength bounds the text of a single memory, so one long article does not
+	// crowd out the others
+	maxSourceLength = 3000
+)
+
+// SystemPrompt instructs the model to answer only from the numbered memories and cite them
+const SystemPrompt = `You are a helpful assistant for a "second brain" app that answers questions using the user's saved web memories.
+Answer using only the numbered memories provided. Cite the memories you use with their numbers in square brackets, like [1] or [2][3].
+If the memories do not contain the answer, say so instead of guessing. Be concise.`
+
+// NoSourcesAnswer is the answer when no saved memory matches the question
+const NoSourcesAnswer = "I couldn't find any saved memories about that."
+
+// stopwords are left out of the keyword query so questions match on their subject
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "were": true, "what": true,
+	"which": true, "who": true, "whom": true, "how": true, "why": true, "when": true, "where": true,
+	"did": true, "does": true, "can": true, "could": true, "should": true, "would": true, "about": true,
+	"with": true, "from": true, "that": true, "this": true, "these": true, "those": true, "have": true,
+	"has": true, "had": true, "you": true, "your": true, "any": true, "all": true, "there": true,
+	"their": true, "into": true, "saved": true, "save": true, "tell": true, "know": true, "some": true,
+}
+
+// citationPattern matches citations such as [1] and [1, 3]
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// KeywordQuery turns a natural-language question into a full-text query matching any of
+// its significant words, since a question rarely contains all the words of the answer
+func KeywordQuery(question string) string {
+	words := strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, word := range words {
+		if utf8.RuneCountInString(word) < 3 || stopwords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// Prompt builds the completion request answering question from memories, numbering the
+// memories from 1 in the given order, and returns the citation of each number
+func Prompt(question string, memories []models.MemoryResponse) (llm.Request, []models.Citation) {
+	budget := maxSourceLength
+	if len(memories) > 0 && maxContextLength/len(memories) < budget {
+		budget = maxContextLength / len(memories)
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Memories:\n\n")
+	citations := make([]models.Citation, 0, len(memories))
+	for i, memory := range memories {
+		citation := models.Citation{Index: i + 1, MemoryID: memory.ID, Title: memory.Title, URL: memory.URL.String}
+		citations = append(citations, citation)
+		writeSource(&prompt, citation, memory, budget)
+	}
+	fmt.Fprintf(&prompt, "Question: %s", strings.TrimSpace(question))
+
+	return llm.Request{System: SystemPrompt, Prompt: prompt.String()}, citations
+}
+
+// writeSource writes one numbered memory, its text cut to budget bytes
+func writeSource(prompt *strings.Builder, citation models.Citation, memory models.MemoryResponse, budget int) {
+	fmt.Fprintf(prompt, "[%d] %s\n", citation.Index, memory.Title)
+	if citation.URL != "" {
+		fmt.Fprintf(prompt, "URL: %s\n", citation.URL)
+	}
+	fmt.Fprintf(prompt, "Saved: %s\n", memory.CreatedAt.Format("2006-01-02"))
+	if len(memory.Tags) > 0 {
+		fmt.Fprintf(prompt, "Tags: %s\n", strings.Join(memory.Tags, ", "))
+	}
+
+	parts := []string{}
+	for _, field := range []string{memory.Notes.String, memory.SelectedText.String, memory.Content.String} {
+		if field = strings.TrimSpace(field); field != "" {
+			parts = append(parts, field)
+		}
+	}
+	if text := truncate(strings.Join(parts, "\n\n"), budget); text != "" {
+		prompt.WriteString(text)
+		prompt.WriteString("\n")
+	}
+	prompt.WriteString("\n")
+}
+
+// Cited returns the citation numbers used in answer that refer to one of citations, ascending
+func Cited(answer string, citations []models.Citation) []int {
+	seen := map[int]bool{}
+	cited := []int{}
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, number := range strings.Split(match[1], ",") {
+			index, err := strconv.Atoi(strings.TrimSpace(number))
+			if err != nil || index < 1 || index > len(citations) || seen[index] {
+				continue
+			}
+			seen[index] = true
+			cited = append(cited, index)
+		}
+	}
+	sort.Ints(cited)
+	return cited
+}
+
+// truncate cuts s to at most max bytes on a rune boundary, marking the cut with an ellipsis
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return strings.TrimSpace(s[:cut]) + "…"
+}
@@ -0,0 +1,255 @@
+// Package router matches requests by method and path pattern, with {name} path parameters
+// and groups of routes sharing a prefix and middleware
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Middleware wraps a handler, like middleware.JWTAuth
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Router dispatches requests to the route whose pattern matches the path. Static segments
+// take precedence over parameters, leftmost first, so /memories/search wins over
+// /memories/{id} whichever is registered first. A path that matches but not with the
+// request's method gets a 405 with an Allow header. Paths are split before they are
+// unescaped, so a parameter may contain an encoded slash: /tags/ci%2Fcd has name "ci/cd".
+type Router struct {
+	routes           []*route
+	notFound         http.HandlerFunc
+	methodNotAllowed http.HandlerFunc
+}
+
+// route is one path pattern and its handlers by method
+type route struct {
+	segments []string // a segment of the form {name} matches any one non-empty segment
+	handlers map[string]http.HandlerFunc
+}
+
+// Group registers routes under a common prefix, wrapped in the group's middleware
+type Group struct {
+	router     *Router
+	prefix     string
+	middleware []Middleware
+}
+
+type paramsKey struct{}
+
+// New creates a Router answering unmatched paths with notFound and methods a path does not
+// support with methodNotAllowed, after setting the Allow header
+func New(notFound, methodNotAllowed http.HandlerFunc) *Router {
+	return &Router{notFound: notFound, methodNotAllowed: methodNotAllowed}
+}
+
+// Group starts a group of routes under prefix wrapped in mw, outermost first
+func (rt *Router) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{router: rt, prefix: strings.TrimSuffix(prefix, "/"), middleware: mw}
+}
+
+// Group starts a nested group that adds prefix and mw to those of g
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		router:     g.router,
+		prefix:     g.prefix + strings.TrimSuffix(prefix, "/"),
+		middleware: append(append([]Middleware{}, g.middleware...), mw...),
+	}
+}
+
+// Handle registers handler for method on the group's prefix followed by pattern
+func (g *Group) Handle(method, pattern string, handler http.HandlerFunc) {
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		handler = g.middleware[i](handler)
+	}
+	g.router.handle(method, g.prefix+pattern, handler)
+}
+
+// GET registers a GET handler
+func (g *Group) GET(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodGet, pattern, handler)
+}
+
+// POST registers a POST handler
+func (g *Group) POST(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodPost, pattern, handler)
+}
+
+// PUT registers a PUT handler
+func (g *Group) PUT(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodPut, pattern, handler)
+}
+
+// PATCH registers a PATCH handler
+func (g *Group) PATCH(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodPatch, pattern, handler)
+}
+
+// DELETE registers a DELETE handler
+func (g *Group) DELETE(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodDelete, pattern, handler)
+}
+
+// handle adds handler to the route for pattern. Patterns that differ only in the names of
+// their parameters would match the same paths with no way to choose between them, so
+// registering the second one panics.
+func (rt *Router) handle(method, pattern string, handler http.HandlerFunc) {
+	segments := splitPath(pattern)
+	for _, existing := range rt.routes {
+		if !sameShape(existing.segments, segments) {
+			continue
+		}
+		if !equalSegments(existing.segments, segments) {
+			panic("router: pattern " + pattern + " conflicts with /" + strings.Join(existing.segments, "/"))
+		}
+		existing.handlers[method] = handler
+		return
+	}
+	rt.routes = append(rt.routes, &route{segments: segments, handlers: map[string]http.HandlerFunc{method: handler}})
+}
+
+// ServeHTTP dispatches r to the most specific matching route
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, err := unescapeSegments(splitPath(r.URL.EscapedPath()))
+	if err != nil {
+		rt.notFound(w, r)
+		return
+	}
+
+	var best *route
+	var bestParams map[string]string
+	for _, candidate := range rt.routes {
+		params, ok := candidate.match(path)
+		if ok && (best == nil || moreSpecific(candidate, best)) {
+			best, bestParams = candidate, params
+		}
+	}
+
+	if best == nil {
+		rt.notFound(w, r)
+		return
+	}
+
+	handler, ok := best.handlers[r.Method]
+	if !ok {
+		w.Header().Set("Allow", strings.Join(best.methods(), ", "))
+		rt.methodNotAllowed(w, r)
+		return
+	}
+
+	if len(bestParams) > 0 {
+		r = r.WithContext(context.WithValue(r.Context(), paramsKey{}, bestParams))
+	}
+	handler(w, r)
+}
+
+// Param returns the value of the path parameter name, or "" if the route has none
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+// match reports whether path fits the route, with the values of its parameters
+func (rt *route) match(path []string) (map[string]string, bool) {
+	if len(path) != len(rt.segments) {
+		return nil, false
+	}
+	var params map[string]string
+	for i, segment := range rt.segments {
+		if name, ok := paramName(segment); ok {
+			if path[i] == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = map[string]string{}
+			}
+			params[name] = path[i]
+		} else if segment != path[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// methods lists the methods the route handles, sorted
+func (rt *route) methods() []string {
+	methods := make([]string, 0, len(rt.handlers))
+	for method := range rt.handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// moreSpecific reports whether a has a static segment where b has a parameter, at the
+// first segment where they differ in kind. Routes matching the same path have the same
+// static segments, so they always differ in kind somewhere unless they have the same
+// shape, which handle rejects.
+func moreSpecific(a, b *route) bool {
+	for i := range a.segments {
+		_, aParam := paramName(a.segments[i])
+		_, bParam := paramName(b.segments[i])
+		if aParam != bParam {
+			return !aParam
+		}
+	}
+	return false
+}
+
+func paramName(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// splitPath splits a path into its segments, ignoring a trailing slash
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
+
+// unescapeSegments decodes the percent-encoding of each segment of an escaped path
+func unescapeSegments(segments []string) ([]string, error) {
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = unescaped
+	}
+	return segments, nil
+}
+
+// sameShape reports whether two patterns match the same paths: the same static segments
+// with parameters in the same places, whatever their names
+func sameShape(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		_, aParam := paramName(a[i])
+		_, bParam := paramName(b[i])
+		if aParam != bParam || !aParam && a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalSegments(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestRouter answers unmatched paths with 404 and unsupported methods with 405
+func newTestRouter() *Router {
+	return New(
+		func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+		func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusMethodNotAllowed) },
+	)
+}
+
+// reply answers with name and the request's id parameter, so tests can tell routes apart
+func reply(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name + ":" + Param(r, "id")))
+	}
+}
+
+// serve returns the status and body of a request through rt
+func serve(rt *Router, method, path string) (int, string, http.Header) {
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec.Code, rec.Body.String(), rec.Header()
+}
+
+func TestStaticSegmentsTakePrecedenceOverParameters(t *testing.T) {
+	for _, order := range []string{"param first", "static first"} {
+		rt := newTestRouter()
+		api := rt.Group("/api")
+		if order == "param first" {
+			api.GET("/memories/{id}", reply("get"))
+			api.GET("/memories/search", reply("search"))
+		} else {
+			api.GET("/memories/search", reply("search"))
+			api.GET("/memories/{id}", reply("get"))
+		}
+		api.GET("/{collection}/items", reply("items"))
+		api.GET("/memories/{id}/items", reply("memory items"))
+
+		cases := []struct{ path, want string }{
+			{"/api/memories/search", "search:"},
+			{"/api/memories/42", "get:42"},
+			{"/api/memories/42/items", "memory items:42"},
+			{"/api/memories/items", "get:items"},
+			{"/api/reading/items", "items:"},
+		}
+		for _, tc := range cases {
+			if code, body, _ := serve(rt, http.MethodGet, tc.path); code != http.StatusOK || body != tc.want {
+				t.Errorf("%s: GET %s = %d %q, want %q", order, tc.path, code, body, tc.want)
+			}
+		}
+	}
+}
+
+func TestUnsupportedMethodsListTheAllowedOnes(t *testing.T) {
+	rt := newTestRouter()
+	api := rt.Group("/api")
+	api.PUT("/memories/{id}", reply("put"))
+	api.GET("/memories/{id}", reply("get"))
+	api.DELETE("/memories/{id}", reply("delete"))
+	api.PATCH("/memories/{id}", reply("patch"))
+	api.POST("/memories/search", reply("search"))
+
+	code, _, header := serve(rt, http.MethodPost, "/api/memories/42")
+	if code != http.StatusMethodNotAllowed || header.Get("Allow") != "DELETE, GET, PATCH, PUT" {
+		t.Errorf("expected 405 allowing DELETE, GET, PATCH, PUT, got %d allowing %q", code, header.Get("Allow"))
+	}
+
+	// The most specific route decides, even if a parameter route has the method
+	code, _, header = serve(rt, http.MethodGet, "/api/memories/search")
+	if code != http.StatusMethodNotAllowed || header.Get("Allow") != "POST" {
+		t.Errorf("expected 405 allowing POST, got %d allowing %q", code, header.Get("Allow"))
+	}
+
+	if code, _, header := serve(rt, http.MethodGet, "/api/unknown"); code != http.StatusNotFound || header.Get("Allow") != "" {
+		t.Errorf("expected 404 without Allow, got %d allowing %q", code, header.Get("Allow"))
+	}
+}
+
+func TestParametersMatchOneNonEmptySegment(t *testing.T) {
+	rt := newTestRouter()
+	rt.Group("").GET("/memories/{id}/tags", reply("tags"))
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{"/memories/42/tags", http.StatusOK},
+		{"/memories//tags", http.StatusNotFound},
+		{"/memories/tags", http.StatusNotFound},
+		{"/memories/4/2/tags", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		if code, _, _ := serve(rt, http.MethodGet, tc.path); code != tc.code {
+			t.Errorf("GET %s = %d, want %d", tc.path, code, tc.code)
+		}
+	}
+}
+
+func TestEncodedSlashesStayInsideParameters(t *testing.T) {
+	rt := newTestRouter()
+	api := rt.Group("/api/v1")
+	api.DELETE("/tags/{id}", reply("delete"))
+	api.GET("/tags/{id}/memories", reply("memories"))
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/v1/tags/ci%2Fcd", http.StatusOK, "delete:ci/cd"},
+		{"/api/v1/tags/ci%2fcd", http.StatusOK, "delete:ci/cd"},
+		{"/api/v1/tags/c%2B%2B%20tips", http.StatusOK, "delete:c++ tips"},
+		{"/api/v1/tags/ci/cd", http.StatusNotFound, ""},
+		{"/api/v1/%74ags/go", http.StatusOK, "delete:go"},
+	}
+	for _, tc := range cases {
+		code, body, _ := serve(rt, http.MethodDelete, tc.path)
+		if code != tc.code || (tc.code == http.StatusOK && body != tc.body) {
+			t.Errorf("DELETE %s = %d %q, want %d %q", tc.path, code, body, tc.code, tc.body)
+		}
+	}
+
+	if code, body, _ := serve(rt, http.MethodGet, "/api/v1/tags/ci%2Fcd/memories"); code != http.StatusOK || body != "memories:ci/cd" {
+		t.Errorf("GET /api/v1/tags/ci%%2Fcd/memories = %d %q, want 200 %q", code, body, "memories:ci/cd")
+	}
+}
+
+func TestTrailingSlashesAreIgnored(t *testing.T) {
+	rt := newTestRouter()
+	api := rt.Group("/api/")
+	api.GET("/memories", reply("list"))
+	api.GET("/tags/", reply("tags"))
+	rt.Group("").GET("/", reply("root"))
+
+	cases := []struct{ path, want string }{
+		{"/api/memories", "list:"},
+		{"/api/memories/", "list:"},
+		{"/api/tags", "tags:"},
+		{"/api/tags/", "tags:"},
+		{"/", "root:"},
+	}
+	for _, tc := range cases {
+		if code, body, _ := serve(rt, http.MethodGet, tc.path); code != http.StatusOK || body != tc.want {
+			t.Errorf("GET %s = %d %q, want %q", tc.path, code, body, tc.want)
+		}
+	}
+}
+
+func TestNestedGroupsCombinePrefixesAndMiddleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next(w, r)
+			}
+		}
+	}
+
+	rt := newTestRouter()
+	api := rt.Group("/api", trace("outer"), trace("auth"))
+	v1 := api.Group("/v1/", trace("inner"))
+	v1.GET("/memories", func(w http.ResponseWriter, r *http.Request) { calls = append(calls, "handler") })
+	api.GET("/memories", func(w http.ResponseWriter, r *http.Request) { calls = append(calls, "legacy") })
+
+	if code, _, _ := serve(rt, http.MethodGet, "/api/v1/memories"); code != http.StatusOK {
+		t.Fatalf("expected the nested route at /api/v1/memories, got %d", code)
+	}
+	if got := strings.Join(calls, " > "); got != "outer > auth > inner > handler" {
+		t.Errorf("expected middleware outermost first, got %s", got)
+	}
+
+	// Adding to a nested group leaves its parent's middleware alone
+	calls = nil
+	serve(rt, http.MethodGet, "/api/memories")
+	if got := strings.Join(calls, " > "); got != "outer > auth > legacy" {
+		t.Errorf("expected the parent group's middleware only, got %s", got)
+	}
+}
+
+func TestParamWithoutParameters(t *testing.T) {
+	rt := newTestRouter()
+	rt.Group("").GET("/health", reply("health"))
+
+	if _, body, _ := serve(rt, http.MethodGet, "/health"); body != "health:" {
+		t.Errorf("expected an empty parameter, got %q", body)
+	}
+	if got := Param(httptest.NewRequest(http.MethodGet, "/", nil), "id"); got != "" {
+		t.Errorf("expected an empty parameter outside the router, got %q", got)
+	}
+}
+
+func TestPatternsDifferingOnlyInParameterNamesConflict(t *testing.T) {
+	rt := newTestRouter()
+	api := rt.Group("/api")
+	api.GET("/memories/{id}", reply("get"))
+	api.PUT("/memories/{id}", reply("put"))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering /api/memories/{memoryID} to panic")
+		}
+	}()
+	api.DELETE("/memories/{memoryID}", reply("delete"))
+}
@@ -2,56 +2,175 @@ package routes
 
 import (
 	"net/http"
-	"strings"
 
 	"api/controllers"
+	"api/embedding"
+	"api/jobs"
+	"api/llm"
 	"api/middleware"
+	"api/router"
+	"api/store"
 )
 
-// SetupRoutes configures all API routes
-func SetupRoutes() http.HandlerFunc {
+// handlers holds the controllers that requests are dispatched to
+type handlers struct {
+	memories    *controllers.MemoryController
+	links       *controllers.LinkController
+	tags        *controllers.TagController
+	collections *controllers.CollectionController
+	export      *controllers.ExportController
+	imports     *controllers.ImportController
+	jobs        *controllers.JobController
+	semantic    *controllers.SemanticController
+	ask         *controllers.AskController
+	scraped     *controllers.ScrapedDataController
+	items       *controllers.ItemController
+	trash       *controllers.TrashController
+}
+
+// SetupRoutes configures all API routes backed by the given store; memory lifecycle
+// events enqueue their processing steps on q, e embeds semantic search queries and p
+// answers questions (nil disables semantic search and question answering respectively)
+func SetupRoutes(s store.Store, q *jobs.Queue, e embedding.Embedder, p llm.Provider) http.HandlerFunc {
+	h := &handlers{
+		memories:    controllers.NewMemoryController(s, q, e),
+		links:       controllers.NewLinkController(s),
+		tags:        controllers.NewTagController(s),
+		collections: controllers.NewCollectionController(s),
+		export:      controllers.NewExportController(s),
+		imports:     controllers.NewImportController(s, q),
+		jobs:        controllers.NewJobController(s),
+		semantic:    controllers.NewSemanticController(s, e),
+		ask:         controllers.NewAskController(s, e, p),
+		scraped:     controllers.NewScrapedDataController(s),
+		items:       controllers.NewItemController(s),
+		trash:       controllers.NewTrashController(s),
+	}
+
+	mux := router.New(
+		func(w http.ResponseWriter, r *http.Request) {
+			middleware.ErrorResponse(w, http.StatusNotFound, "Endpoint not found")
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		},
+	)
+
+	// Health check endpoints
+	public := mux.Group("")
+	public.GET("/health", handleHealthCheck)
+	public.GET("/api", handleHealthCheck)
+	public.GET("/api/v1", handleHealthCheck)
+
+	h.registerV1(mux.Group("/api/v1", middleware.JWTAuth))
+	h.registerLegacy(mux.Group("/api", middleware.Deprecated("/api/v1"), middleware.JWTAuth))
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Apply CORS middleware
-		middleware.CORS(handleRoutes)(w, r)
+		// Apply CORS and logger middleware
+		middleware.CORS(middleware.Logger(mux.ServeHTTP))(w, r)
 	}
 }
 
-func handleRoutes(w http.ResponseWriter, r *http.Request) {
-	// Apply logger middleware
-	middleware.Logger(routeHandler)(w, r)
-}
+// registerV1 adds the versioned API, scoped to the authenticated user
+func (h *handlers) registerV1(api *router.Group) {
+	// Memories saved by the extension
+	api.GET("/memories", h.memories.GetAllMemories)
+	api.POST("/memories", h.memories.CreateMemory)
+	api.GET("/memories/{id}", h.memories.GetMemoryByID)
+	api.PUT("/memories/{id}", h.memories.UpdateMemory)
+	api.PATCH("/memories/{id}", h.memories.PatchMemory)
+	api.DELETE("/memories/{id}", h.memories.DeleteMemory)
+	api.GET("/memories/{id}/related", h.memories.GetRelated)
+	api.POST("/memories/search", h.memories.SearchMemories)
+	api.POST("/memories/semantic-search", h.semantic.SemanticSearch)
+	api.POST("/memories/bulk", h.memories.BulkMemories)
+	api.GET("/memories/duplicates", h.memories.GetDuplicates)
+	api.GET("/memories/stats", h.memories.GetStats)
 
-func routeHandler(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
+	// Deleted memories, kept in the trash until restored or purged
+	api.GET("/trash", h.trash.GetTrash)
+	api.DELETE("/trash", h.trash.EmptyTrash)
+	api.POST("/trash/{id}/restore", h.trash.RestoreMemory)
+	api.DELETE("/trash/{id}", h.trash.PurgeMemory)
 
-	// Health check endpoint
-	if path == "/api" || path == "/api/" || path == "/health" {
-		handleHealthCheck(w, r)
-		return
-	}
+	// Links extracted from captured pages
+	api.GET("/links", h.links.GetAllLinks)
+	api.GET("/links/backlinks", h.links.GetBacklinks)
 
-	// Extension endpoints - Main API
-	if strings.HasPrefix(path, "/api/memories") {
-		if path == "/api/memories/search" {
-			controllers.SearchMemories(w, r)
-			return
-		}
-		if path == "/api/memories/stats" {
-			controllers.GetStats(w, r)
-			return
-		}
-		handleMemoryRoutes(w, r, path)
-		return
+	// Tag management
+	api.GET("/tags", h.tags.GetAllTags)
+	api.DELETE("/tags/{name}", h.tags.DeleteTag)
+	api.POST("/tags/rename", h.tags.RenameTag)
+	api.POST("/tags/merge", h.tags.MergeTags)
+
+	// Collections of memories
+	api.GET("/collections", h.collections.GetAllCollections)
+	api.POST("/collections", h.collections.CreateCollection)
+	api.GET("/collections/{id}", h.collections.GetCollectionByID)
+	api.PUT("/collections/{id}", h.collections.UpdateCollection)
+	api.DELETE("/collections/{id}", h.collections.DeleteCollection)
+	api.POST("/collections/{id}/memories", h.collections.AddMemories)
+	api.PUT("/collections/{id}/memories", h.collections.ReorderMemories)
+	api.DELETE("/collections/{id}/memories/{memory_id}", h.collections.RemoveMemory)
+
+	// Export of all memories as a download, and import from other services
+	api.GET("/export", h.export.Export)
+	api.POST("/import", h.imports.Import)
+
+	// Status of the background processing of memories
+	api.GET("/jobs", h.jobs.GetJobs)
+
+	// Question answering over the user's memories
+	api.POST("/ask", h.ask.Ask)
+
+	// Raw scraped pages with JSON metadata, and the user's items
+	api.GET("/scraped-data", h.scraped.GetAllScrapedData)
+	api.POST("/scraped-data", h.scraped.CreateScrapedData)
+	api.GET("/scraped-data/{id}", h.scraped.GetScrapedDataByID)
+	api.PUT("/scraped-data/{id}", h.scraped.UpdateScrapedData)
+	api.DELETE("/scraped-data/{id}", h.scraped.DeleteScrapedData)
+	api.GET("/items", h.items.GetAllItems)
+	api.POST("/items", h.items.CreateItem)
+	api.GET("/items/{id}", h.items.GetItemByID)
+	api.PUT("/items/{id}", h.items.UpdateItem)
+	api.DELETE("/items/{id}", h.items.DeleteItem)
+}
+
+// registerLegacy adds the unversioned routes that take IDs in the query string. They are
+// kept for extensions that have not moved to /api/v1 and answer with a Deprecation header.
+func (h *handlers) registerLegacy(api *router.Group) {
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete} {
+		api.Handle(method, "/memories", h.handleMemoryRoutes)
 	}
+	api.POST("/memories/search", h.memories.SearchMemories)
+	api.POST("/memories/semantic-search", h.semantic.SemanticSearch)
+	api.POST("/memories/bulk", h.memories.BulkMemories)
+	api.GET("/memories/duplicates", h.memories.GetDuplicates)
+	api.GET("/memories/related", h.memories.GetRelated)
+	api.GET("/memories/stats", h.memories.GetStats)
 
-	// Legacy scrape endpoint (maps to memories)
-	if path == "/api/scrape" {
-		controllers.CreateMemory(w, r)
-		return
+	api.GET("/links", h.links.GetAllLinks)
+	api.GET("/links/backlinks", h.links.GetBacklinks)
+
+	api.GET("/tags", h.tags.GetAllTags)
+	api.DELETE("/tags", h.tags.DeleteTag)
+	api.POST("/tags/rename", h.tags.RenameTag)
+	api.POST("/tags/merge", h.tags.MergeTags)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete} {
+		api.Handle(method, "/collections", h.handleCollectionRoutes)
 	}
+	api.POST("/collections/memories", h.collections.AddMemories)
+	api.PUT("/collections/memories", h.collections.ReorderMemories)
+	api.DELETE("/collections/memories", h.collections.RemoveMemory)
 
-	// 404 - Not Found
-	middleware.ErrorResponse(w, http.StatusNotFound, "Endpoint not found")
+	api.GET("/export", h.export.Export)
+	api.POST("/import", h.imports.Import)
+	api.GET("/jobs", h.jobs.GetJobs)
+	api.POST("/ask", h.ask.Ask)
+
+	// Scrape endpoint of the first extension release (maps to memories)
+	api.POST("/scrape", h.memories.CreateMemory)
 }
 
 func handleHealthCheck(w http.ResponseWriter, r *http.Request) {
@@ -65,31 +184,84 @@ func handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 		"message": "BrowseBaba API is running",
 		"version": "2.0.0",
 		"purpose": "Browser Extension Backend",
+		"auth":    "Memory endpoints require an Authorization: Bearer <token> header",
 		"endpoints": map[string]string{
-			"GET /api":                  "Health check",
-			"GET /health":               "Health check",
-			"POST /api/scrape":          "Save content from extension (legacy)",
-			"POST /api/memories":        "Save content from extension",
-			"GET /api/memories":         "Get all saved memories",
-			"GET /api/memories?id=":     "Get memory by ID",
-			"PUT /api/memories?id=":     "Update memory by ID",
-			"DELETE /api/memories?id=":  "Delete memory by ID",
-			"POST /api/memories/search": "Full-text search memories",
-			"GET /api/memories/stats":   "Get usage statistics",
+			"GET /api/v1":                                          "Health check",
+			"GET /health":                                          "Health check",
+			"POST /api/v1/memories":                                "Save content from extension; send html to extract the readable article",
+			"GET /api/v1/memories":                                 "Get all saved memories; If-None-Match with the last ETag gets 304 when unchanged",
+			"GET /api/v1/memories/{id}":                            "Get memory by ID with its ETag; If-None-Match gets 304 when unchanged",
+			"PUT /api/v1/memories/{id}":                            "Update memory by ID; If-Match with its ETag gets 412 if it changed meanwhile",
+			"PATCH /api/v1/memories/{id}":                          "Edit memory fields with a JSON Merge Patch sent as application/merge-patch+json; null clears a field; honours If-Match",
+			"DELETE /api/v1/memories/{id}":                         "Move memory to the trash; honours If-Match",
+			"GET /api/v1/memories/{id}/related":                    "List related memories with the reasons they match",
+			"POST /api/v1/memories/search":                         "Relevance-ranked full-text search with highlights; mode=hybrid fuses it with semantic search",
+			"POST /api/v1/memories/semantic-search":                "Find memories by meaning, with cosine similarity",
+			"POST /api/v1/memories/bulk":                           "Apply one action to many memories atomically",
+			"GET /api/v1/memories/stats":                           "Get usage statistics",
+			"GET /api/v1/memories/duplicates":                      "Group likely duplicate memories by canonical URL and content",
+			"GET /api/v1/trash":                                    "List deleted memories, most recently deleted first",
+			"DELETE /api/v1/trash":                                 "Empty the trash, deleting its memories permanently",
+			"POST /api/v1/trash/{id}/restore":                      "Restore a deleted memory",
+			"DELETE /api/v1/trash/{id}":                            "Delete a memory in the trash permanently",
+			"GET /api/v1/links?domain=":                            "List captured links, optionally by domain",
+			"GET /api/v1/links/backlinks?url=":                     "List memories linking to a URL",
+			"GET /api/v1/tags":                                     "List tags with memory counts",
+			"DELETE /api/v1/tags/{name}":                           "Remove a tag from all memories",
+			"POST /api/v1/tags/rename":                             "Rename a tag across all memories",
+			"POST /api/v1/tags/merge":                              "Merge tags into one",
+			"GET /api/v1/collections":                              "List collections",
+			"GET /api/v1/collections/{id}":                         "Get a collection with its memories in order",
+			"POST /api/v1/collections":                             "Create a collection",
+			"PUT /api/v1/collections/{id}":                         "Update a collection",
+			"DELETE /api/v1/collections/{id}":                      "Delete a collection, keeping its memories",
+			"POST /api/v1/collections/{id}/memories":               "Add memories to a collection",
+			"PUT /api/v1/collections/{id}/memories":                "Reorder the memories of a collection",
+			"DELETE /api/v1/collections/{id}/memories/{memory_id}": "Remove a memory from a collection",
+			"GET /api/v1/export?format=":                           "Download all memories as jsonl, markdown (ZIP) or html",
+			"POST /api/v1/import?format=":                          "Import bookmarks (netscape, pocket, csv) or a jsonl export",
+			"GET /api/v1/jobs?status=&memory_id=":                  "List background processing jobs with counts per status",
+			"GET /api/v1/scraped-data?url=&tag=&metadata.<key>=":   "List scraped data, filtered by URL, tag and metadata values",
+			"POST /api/v1/scraped-data":                            "Save a scraped page with JSON metadata",
+			"GET /api/v1/scraped-data/{id}":                        "Get scraped data by ID",
+			"PUT /api/v1/scraped-data/{id}":                        "Update scraped data by ID",
+			"DELETE /api/v1/scraped-data/{id}":                     "Delete scraped data by ID",
+			"GET /api/v1/items?status=&priority=":                  "List items",
+			"POST /api/v1/items":                                   "Create an item",
+			"GET /api/v1/items/{id}":                               "Get item by ID",
+			"PUT /api/v1/items/{id}":                               "Update item by ID",
+			"DELETE /api/v1/items/{id}":                            "Delete item by ID",
+			"POST /api/v1/ask":                                     "Answer a question from saved memories with citations, streamed as server-sent events",
 		},
+		"deprecated": "The unversioned /api routes, which take IDs in the query string (/api/memories?id=), still work but are deprecated in favour of /api/v1",
 		"features": []string{
 			"Save web content, selections, and video timestamps",
-			"Full-text search across all saved content",
-			"Tag-based organization",
+			"Ranked full-text search with phrases, exclusions and OR",
+			"Tag-based organization with rename, merge and counts",
+			"Ordered collections of memories",
 			"Video platform support (YouTube, Netflix, etc.)",
 			"Context-aware text capture",
-			"Link extraction and storage",
+			"Link extraction, storage and backlinks",
+			"Streaming export to JSON Lines, Markdown and HTML",
+			"Import from browser bookmarks, Pocket, Raindrop.io and Instapaper",
+			"URL canonicalization, duplicate detection and merge on capture",
+			"Readable article extraction with byline, publish date and lead image",
+			"Allowlist HTML sanitization and configurable size limits on captured content",
+			"Background job queue with retries and dead-lettering for post-capture processing",
+			"Semantic search over memory embeddings from a local or OpenAI-compatible provider",
+			"Hybrid keyword and semantic search with reciprocal rank fusion",
+			"Related memories by shared tags, domain, outbound links and text similarity",
+			"Question answering over saved memories with cited sources and streamed answers",
+			"Scraped page data with JSON metadata filtering, and items",
+			"Versioned REST API with path parameters; the legacy query-string routes are deprecated",
+			"ETags on memories for conditional requests: If-Match prevents lost updates, If-None-Match saves refetching",
+			"Trash for deleted memories with restore, permanent deletion and purging after TRASH_RETENTION_DAYS",
 		},
 	}
 	middleware.JSONResponse(w, http.StatusOK, response)
 }
 
-func handleMemoryRoutes(w http.ResponseWriter, r *http.Request, path string) {
+func (h *handlers) handleMemoryRoutes(w http.ResponseWriter, r *http.Request) {
 	// Check if ID is provided in query string
 	id := r.URL.Query().Get("id")
 
@@ -97,28 +269,53 @@ func handleMemoryRoutes(w http.ResponseWriter, r *http.Request, path string) {
 	switch r.Method {
 	case http.MethodGet:
 		if id != "" {
-			controllers.GetMemoryByID(w, r)
+			h.memories.GetMemoryByID(w, r)
 		} else {
-			controllers.GetAllMemories(w, r)
+			h.memories.GetAllMemories(w, r)
 		}
 	case http.MethodPost:
 		if id != "" {
 			middleware.ErrorResponse(w, http.StatusBadRequest, "ID should not be provided for POST requests")
 			return
 		}
-		controllers.CreateMemory(w, r)
+		h.memories.CreateMemory(w, r)
 	case http.MethodPut:
 		if id == "" {
 			middleware.ErrorResponse(w, http.StatusBadRequest, "Memory ID is required")
 			return
 		}
-		controllers.UpdateMemory(w, r)
+		h.memories.UpdateMemory(w, r)
 	case http.MethodDelete:
 		if id == "" {
 			middleware.ErrorResponse(w, http.StatusBadRequest, "Memory ID is required")
 			return
 		}
-		controllers.DeleteMemory(w, r)
+		h.memories.DeleteMemory(w, r)
+	default:
+		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (h *handlers) handleCollectionRoutes(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		if id != "" {
+			h.collections.GetCollectionByID(w, r)
+		} else {
+			h.collections.GetAllCollections(w, r)
+		}
+	case http.MethodPost:
+		if id != "" {
+			middleware.ErrorResponse(w, http.StatusBadRequest, "ID should not be provided for POST requests")
+			return
+		}
+		h.collections.CreateCollection(w, r)
+	case http.MethodPut:
+		h.collections.UpdateCollection(w, r)
+	case http.MethodDelete:
+		h.collections.DeleteCollection(w, r)
 	default:
 		middleware.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 	}
@@ -0,0 +1,383 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"api/jobs"
+	"api/models"
+	"api/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testSecret = "test-secret"
+	ownerID    = "user-a"
+	intruderID = "user-b"
+)
+
+// testStores lists the backends the endpoints are tested against; builds with the
+// sqlite_fts5 tag add a migrated SQLite database
+var testStores = map[string]func(t *testing.T) store.Store{
+	"memory": func(t *testing.T) store.Store { return store.NewInMemoryStore() },
+}
+
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   string          `json:"error"`
+}
+
+// testAPI sends requests through the full router, as a client of the server would
+type testAPI struct {
+	t       *testing.T
+	handler http.HandlerFunc
+}
+
+// forEachStore runs test against the routes backed by each of testStores
+func forEachStore(t *testing.T, test func(t *testing.T, api testAPI)) {
+	for name, newStore := range testStores {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", testSecret)
+			s := newStore(t)
+			test(t, testAPI{t: t, handler: SetupRoutes(s, jobs.NewQueue(s), nil, nil)})
+		})
+	}
+}
+
+// do sends a request as userID (anonymous when empty) and decodes the response into out
+func (a testAPI) do(method, target, userID, body string, out interface{}) (int, apiResponse) {
+	a.t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if userID != "" {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userID}).SignedString([]byte(testSecret))
+		if err != nil {
+			a.t.Fatalf("failed to sign token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	a.handler(rec, req)
+
+	var resp apiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		a.t.Fatalf("%s %s: invalid JSON response %q: %v", method, target, rec.Body.String(), err)
+	}
+	if out != nil && resp.Data != nil {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			a.t.Fatalf("%s %s: invalid payload %s: %v", method, target, resp.Data, err)
+		}
+	}
+	return rec.Code, resp
+}
+
+// expect sends a request and fails the test unless it answers with status
+func (a testAPI) expect(status int, method, target, userID, body string, out interface{}) {
+	a.t.Helper()
+
+	if code, resp := a.do(method, target, userID, body, out); code != status {
+		a.t.Fatalf("%s %s: expected %d, got %d: %s", method, target, status, code, resp.Error)
+	}
+}
+
+type scrapedData struct {
+	ID       int64                  `json:"id"`
+	UserID   string                 `json:"user_id"`
+	Title    string                 `json:"title"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Tags     string                 `json:"tags"`
+}
+
+func scrapedIDs(list []scrapedData) []int64 {
+	ids := []int64{}
+	for _, data := range list {
+		ids = append(ids, data.ID)
+	}
+	return ids
+}
+
+func TestScrapedDataEndpoints(t *testing.T) {
+	forEachStore(t, func(t *testing.T, api testAPI) {
+		var tweet, post scrapedData
+		api.expect(http.StatusCreated, http.MethodPost, "/api/v1/scraped-data", ownerID,
+			`{"url":"https://twitter.com/golang/status/1","title":"Go 1.22","content":"released","metadata":{"source":"twitter","rating":5,"pinned":true,"author":{"name":"gopher"}},"tags":["go","news"]}`, &tweet)
+		api.expect(http.StatusCreated, http.MethodPost, "/api/v1/scraped-data", ownerID,
+			`{"url":"https://blog.golang.org/loops","title":"Loops","content":"range","metadata":{"source":"rss","rating":"5"}}`, &post)
+		if tweet.UserID != ownerID || tweet.Metadata["source"] != "twitter" || tweet.Tags != "go,news" {
+			t.Fatalf("create: unexpected record %+v", tweet)
+		}
+
+		var got scrapedData
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/scraped-data/"+itoa(tweet.ID), ownerID, "", &got)
+		if got.Metadata["rating"] != float64(5) || got.Metadata["pinned"] != true {
+			t.Errorf("get: metadata did not round-trip as JSON: %+v", got.Metadata)
+		}
+
+		filters := []struct {
+			query string
+			want  []int64
+		}{
+			{"", []int64{post.ID, tweet.ID}},
+			{"?metadata.source=twitter", []int64{tweet.ID}},
+			{"?metadata.rating=5", []int64{post.ID, tweet.ID}},
+			{"?metadata.rating=5&metadata.source=rss", []int64{post.ID}},
+			{"?metadata.pinned=true", []int64{tweet.ID}},
+			{"?metadata.missing=x", []int64{}},
+			{"?url=golang.org", []int64{post.ID}},
+			{"?tag=news", []int64{tweet.ID}},
+			{"?limit=1&page=2", []int64{tweet.ID}},
+		}
+		for _, f := range filters {
+			var list []scrapedData
+			api.expect(http.StatusOK, http.MethodGet, "/api/v1/scraped-data"+f.query, ownerID, "", &list)
+			if ids := scrapedIDs(list); !equalIDs(ids, f.want) {
+				t.Errorf("list%s: expected %v, got %v", f.query, f.want, ids)
+			}
+		}
+
+		var updated scrapedData
+		api.expect(http.StatusOK, http.MethodPut, "/api/v1/scraped-data/"+itoa(tweet.ID), ownerID,
+			`{"title":"Go 1.22 is out","metadata":{"source":"mastodon"}}`, &updated)
+		if updated.Title != "Go 1.22 is out" || len(updated.Metadata) != 1 || updated.Metadata["source"] != "mastodon" {
+			t.Errorf("update: expected the new title and replaced metadata, got %+v", updated)
+		}
+		api.expect(http.StatusBadRequest, http.MethodPut, "/api/v1/scraped-data/"+itoa(tweet.ID), ownerID, `{}`, nil)
+
+		// Another user's requests behave as if the record did not exist
+		api.expect(http.StatusNotFound, http.MethodGet, "/api/v1/scraped-data/"+itoa(tweet.ID), intruderID, "", nil)
+		api.expect(http.StatusNotFound, http.MethodPut, "/api/v1/scraped-data/"+itoa(tweet.ID), intruderID, `{"title":"Hijacked"}`, nil)
+		api.expect(http.StatusNotFound, http.MethodDelete, "/api/v1/scraped-data/"+itoa(tweet.ID), intruderID, "", nil)
+		var others []scrapedData
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/scraped-data", intruderID, "", &others)
+		if len(others) != 0 {
+			t.Errorf("list: another user sees %v", scrapedIDs(others))
+		}
+
+		api.expect(http.StatusOK, http.MethodDelete, "/api/v1/scraped-data/"+itoa(tweet.ID), ownerID, "", nil)
+		api.expect(http.StatusNotFound, http.MethodGet, "/api/v1/scraped-data/"+itoa(tweet.ID), ownerID, "", nil)
+
+		api.expect(http.StatusBadRequest, http.MethodPost, "/api/v1/scraped-data", ownerID, `{"url":"not a url","title":"x","content":"y"}`, nil)
+		api.expect(http.StatusBadRequest, http.MethodGet, "/api/v1/scraped-data/abc", ownerID, "", nil)
+		api.expect(http.StatusUnauthorized, http.MethodGet, "/api/v1/scraped-data", "", "", nil)
+	})
+}
+
+type item struct {
+	ID       int64  `json:"id"`
+	UserID   string `json:"user_id"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Priority string `json:"priority"`
+}
+
+func TestItemEndpoints(t *testing.T) {
+	forEachStore(t, func(t *testing.T, api testAPI) {
+		var todo, done item
+		api.expect(http.StatusCreated, http.MethodPost, "/api/v1/items", ownerID, `{"title":"Read later","status":"todo","priority":"high"}`, &todo)
+		api.expect(http.StatusCreated, http.MethodPost, "/api/v1/items", ownerID, `{"title":"Tag imports","status":"done","priority":"low"}`, &done)
+		if todo.UserID != ownerID || todo.Status != "todo" {
+			t.Fatalf("create: unexpected item %+v", todo)
+		}
+
+		var list []item
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/items?status=done", ownerID, "", &list)
+		if len(list) != 1 || list[0].ID != done.ID {
+			t.Errorf("list: expected only item %d, got %+v", done.ID, list)
+		}
+
+		var updated item
+		api.expect(http.StatusOK, http.MethodPut, "/api/v1/items/"+itoa(todo.ID), ownerID, `{"status":"done"}`, &updated)
+		if updated.Status != "done" || updated.Title != "Read later" || updated.Priority != "high" {
+			t.Errorf("update: expected only the status to change, got %+v", updated)
+		}
+
+		var got item
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/items/"+itoa(todo.ID), ownerID, "", &got)
+		if got.Status != "done" {
+			t.Errorf("get: expected the updated item, got %+v", got)
+		}
+
+		api.expect(http.StatusNotFound, http.MethodGet, "/api/v1/items/"+itoa(todo.ID), intruderID, "", nil)
+		api.expect(http.StatusNotFound, http.MethodPut, "/api/v1/items/"+itoa(todo.ID), intruderID, `{"title":"Hijacked"}`, nil)
+		api.expect(http.StatusNotFound, http.MethodDelete, "/api/v1/items/"+itoa(todo.ID), intruderID, "", nil)
+
+		api.expect(http.StatusOK, http.MethodDelete, "/api/v1/items/"+itoa(todo.ID), ownerID, "", nil)
+		api.expect(http.StatusNotFound, http.MethodDelete, "/api/v1/items/"+itoa(todo.ID), ownerID, "", nil)
+
+		api.expect(http.StatusBadRequest, http.MethodPost, "/api/v1/items", ownerID, `{"title":"No status"}`, nil)
+		api.expect(http.StatusUnauthorized, http.MethodGet, "/api/v1/items", "", "", nil)
+	})
+}
+
+type trashedMemory struct {
+	ID        string   `json:"id"`
+	Tags      []string `json:"tags"`
+	DeletedAt struct {
+		Valid bool `json:"Valid"`
+	} `json:"deleted_at"`
+}
+
+func TestTrashEndpoints(t *testing.T) {
+	forEachStore(t, func(t *testing.T, api testAPI) {
+		var kept, trashed trashedMemory
+		api.expect(http.StatusCreated, http.MethodPost, "/api/v1/memories", ownerID,
+			`{"url":"https://go.dev/doc","title":"Effective Go","content_type":"page","content":"golang style guide","tags":["golang"]}`, &kept)
+		api.expect(http.StatusCreated, http.MethodPost, "/api/v1/memories", ownerID,
+			`{"url":"https://go.dev/blog","title":"Go blog","content_type":"page","content":"golang release notes","tags":["golang","news"],
+			"links":[{"href":"https://go.dev/doc/go1.22","text":"Go 1.22"}]}`, &trashed)
+		var collection struct {
+			ID string `json:"id"`
+		}
+		api.expect(http.StatusCreated, http.MethodPost, "/api/v1/collections", ownerID, `{"name":"Reading"}`, &collection)
+		var members struct {
+			Collection struct {
+				MemoryCount int `json:"memory_count"`
+			} `json:"collection"`
+			Memories []trashedMemory `json:"memories"`
+		}
+		api.expect(http.StatusOK, http.MethodPost, "/api/v1/collections/"+collection.ID+"/memories", ownerID,
+			`{"memory_ids":["`+kept.ID+`","`+trashed.ID+`"]}`, nil)
+
+		api.expect(http.StatusNotFound, http.MethodDelete, "/api/v1/memories/"+trashed.ID, intruderID, "", nil)
+		api.expect(http.StatusOK, http.MethodDelete, "/api/v1/memories/"+trashed.ID, ownerID, "", nil)
+
+		// Every read leaves the trashed memory out
+		api.expect(http.StatusNotFound, http.MethodGet, "/api/v1/memories/"+trashed.ID, ownerID, "", nil)
+		api.expect(http.StatusNotFound, http.MethodDelete, "/api/v1/memories/"+trashed.ID, ownerID, "", nil)
+		var page struct {
+			Memories []trashedMemory `json:"memories"`
+		}
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/memories", ownerID, "", &page)
+		if len(page.Memories) != 1 || page.Memories[0].ID != kept.ID {
+			t.Errorf("list: expected only %s, got %+v", kept.ID, page.Memories)
+		}
+		api.expect(http.StatusOK, http.MethodPost, "/api/v1/memories/search", ownerID, `{"query":"golang"}`, &page)
+		if len(page.Memories) != 1 || page.Memories[0].ID != kept.ID {
+			t.Errorf("search: expected only %s, got %+v", kept.ID, page.Memories)
+		}
+		var stats struct {
+			TotalMemories int `json:"total_memories"`
+		}
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/memories/stats", ownerID, "", &stats)
+		if stats.TotalMemories != 1 {
+			t.Errorf("stats: expected 1 memory, got %d", stats.TotalMemories)
+		}
+		var tags struct {
+			Tags []struct {
+				Tag   string `json:"tag"`
+				Count int    `json:"count"`
+			} `json:"tags"`
+		}
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/tags", ownerID, "", &tags)
+		if len(tags.Tags) != 1 || tags.Tags[0].Tag != "golang" || tags.Tags[0].Count != 1 {
+			t.Errorf("tags: expected golang on 1 memory, got %+v", tags.Tags)
+		}
+		var links struct {
+			Count int `json:"count"`
+		}
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/links", ownerID, "", &links)
+		if links.Count != 0 {
+			t.Errorf("links: expected none, got %d", links.Count)
+		}
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/collections/"+collection.ID, ownerID, "", &members)
+		if members.Collection.MemoryCount != 1 || len(members.Memories) != 1 || members.Memories[0].ID != kept.ID {
+			t.Errorf("collection: expected only %s, got %d %+v", kept.ID, members.Collection.MemoryCount, members.Memories)
+		}
+
+		var trash struct {
+			Memories []trashedMemory `json:"memories"`
+		}
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/trash", ownerID, "", &trash)
+		if len(trash.Memories) != 1 || trash.Memories[0].ID != trashed.ID || !trash.Memories[0].DeletedAt.Valid {
+			t.Fatalf("trash: expected %s with deleted_at, got %+v", trashed.ID, trash.Memories)
+		}
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/trash", intruderID, "", &trash)
+		if len(trash.Memories) != 0 {
+			t.Errorf("trash of %s: expected nothing, got %+v", intruderID, trash.Memories)
+		}
+
+		// A restored memory comes back as it was
+		api.expect(http.StatusNotFound, http.MethodPost, "/api/v1/trash/"+trashed.ID+"/restore", intruderID, "", nil)
+		var restored trashedMemory
+		api.expect(http.StatusOK, http.MethodPost, "/api/v1/trash/"+trashed.ID+"/restore", ownerID, "", &restored)
+		if restored.ID != trashed.ID || len(restored.Tags) != 2 || restored.DeletedAt.Valid {
+			t.Errorf("restore: expected the memory with its tags, got %+v", restored)
+		}
+		api.expect(http.StatusNotFound, http.MethodPost, "/api/v1/trash/"+trashed.ID+"/restore", ownerID, "", nil)
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/collections/"+collection.ID, ownerID, "", &members)
+		if members.Collection.MemoryCount != 2 || len(members.Memories) != 2 {
+			t.Errorf("collection after restore: expected 2 memories, got %d", members.Collection.MemoryCount)
+		}
+
+		// Memories outside the trash cannot be purged
+		api.expect(http.StatusNotFound, http.MethodDelete, "/api/v1/trash/"+trashed.ID, ownerID, "", nil)
+		api.expect(http.StatusOK, http.MethodDelete, "/api/v1/memories/"+trashed.ID, ownerID, "", nil)
+		api.expect(http.StatusNotFound, http.MethodDelete, "/api/v1/trash/"+trashed.ID, intruderID, "", nil)
+		api.expect(http.StatusOK, http.MethodDelete, "/api/v1/trash/"+trashed.ID, ownerID, "", nil)
+		api.expect(http.StatusNotFound, http.MethodPost, "/api/v1/trash/"+trashed.ID+"/restore", ownerID, "", nil)
+
+		api.expect(http.StatusOK, http.MethodDelete, "/api/v1/memories/"+kept.ID, ownerID, "", nil)
+		var emptied struct {
+			Purged int `json:"purged"`
+		}
+		api.expect(http.StatusOK, http.MethodDelete, "/api/v1/trash", ownerID, "", &emptied)
+		if emptied.Purged != 1 {
+			t.Errorf("empty trash: expected 1 memory purged, got %d", emptied.Purged)
+		}
+		api.expect(http.StatusOK, http.MethodGet, "/api/v1/trash", ownerID, "", &trash)
+		if len(trash.Memories) != 0 {
+			t.Errorf("trash after emptying: expected nothing, got %+v", trash.Memories)
+		}
+	})
+}
+
+func TestPurgeTrashHonoursRetention(t *testing.T) {
+	for name, newStore := range testStores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			ctx := context.Background()
+			memory, err := s.CreateMemory(ctx, ownerID, models.CreateMemoryRequest{Title: "Effective Go", ContentType: "page"})
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			if err := s.DeleteMemory(ctx, ownerID, memory.ID); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+
+			if purged, err := s.PurgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+				t.Errorf("within retention: expected nothing purged, got %d, %v", purged, err)
+			}
+			if purged, err := s.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil || purged != 1 {
+				t.Errorf("past retention: expected 1 memory purged, got %d, %v", purged, err)
+			}
+			if trash, err := s.ListTrash(ctx, ownerID, 10, 0); err != nil || len(trash) != 0 {
+				t.Errorf("trash after purge: expected nothing, got %d, %v", len(trash), err)
+			}
+		})
+	}
+}
+
+func itoa(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/models"
+)
+
+// bulkLimit caps how many memories one bulk request may change
+const bulkLimit = 1000
+
+// ErrBulkLimit is returned when a bulk filter selects more than bulkLimit memories
+var ErrBulkLimit = errors.New("bulk filter matches more than " + strconv.Itoa(bulkLimit) + " memories")
+
+// ApplyBulk applies req.Action to every selected memory of the user in one transaction.
+// IDs that do not exist or belong to another user are reported as not_found;
+// any other failure rolls the whole request back.
+func (s *SQLStore) ApplyBulk(ctx context.Context, userID string, req models.BulkRequest) (models.BulkResult, error) {
+	ids, err := s.bulkTargets(ctx, userID, req)
+	if err != nil {
+		return models.BulkResult{}, err
+	}
+
+	if req.Action == models.BulkMoveToCollection {
+		if _, err := s.GetCollection(ctx, userID, req.CollectionID); err != nil {
+			return models.BulkResult{}, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.BulkResult{}, err
+	}
+	defer tx.Rollback()
+
+	owned, err := ownedTags(ctx, tx, userID, ids)
+	if err != nil {
+		return models.BulkResult{}, err
+	}
+
+	now := time.Now().UTC()
+	statuses := map[string]string{}
+	moved := []string{}
+	for _, id := range ids {
+		tags, ok := owned[id]
+		if !ok {
+			statuses[id] = models.BulkStatusNotFound
+			continue
+		}
+
+		status := models.BulkStatusUpdated
+		switch req.Action {
+		case models.BulkDelete:
+			if _, err := tx.ExecContext(ctx, "UPDATE memories SET deleted_at = $1 WHERE id = $2 AND user_id = $3", now, id, userID); err != nil {
+				return models.BulkResult{}, err
+			}
+			status = models.BulkStatusDeleted
+
+		case models.BulkAddTags, models.BulkRemoveTags:
+			updated := bulkTags(req, splitTags(tags))
+			if strings.Join(updated, ",") == tags {
+				status = models.BulkStatusUnchanged
+			} else if err := writeMemoryTags(ctx, tx, userID, id, updated, now); err != nil {
+				return models.BulkResult{}, err
+			}
+
+		case models.BulkSetContentType:
+			result, err := tx.ExecContext(ctx,
+				"UPDATE memories SET content_type = $1, updated_at = $2 WHERE id = $3 AND content_type <> $1",
+				req.ContentType, now, id)
+			if err != nil {
+				return models.BulkResult{}, err
+			}
+			if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+				status = models.BulkStatusUnchanged
+			}
+
+		case models.BulkMoveToCollection:
+			moved = append(moved, id)
+		}
+		statuses[id] = status
+	}
+
+	if len(moved) > 0 {
+		if err := s.moveToCollection(ctx, tx, userID, req.CollectionID, moved); err != nil {
+			return models.BulkResult{}, err
+		}
+	}
+
+	if req.Action != models.BulkSetContentType && req.Action != models.BulkMoveToCollection {
+		if err := pruneTags(ctx, tx, userID); err != nil {
+			return models.BulkResult{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.BulkResult{}, err
+	}
+
+	return bulkResult(req.Action, ids, statuses), nil
+}
+
+// bulkTargets resolves the memory IDs a bulk request applies to
+func (s *SQLStore) bulkTargets(ctx context.Context, userID string, req models.BulkRequest) ([]string, error) {
+	if req.Filter == nil {
+		return uniqueStrings(req.IDs), nil
+	}
+
+	sel, err := s.searchSelect(userID, *req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT memories.id FROM " + sel.from + " WHERE " + sel.where +
+		" ORDER BY memories.created_at DESC, memories.id DESC LIMIT $" + strconv.Itoa(len(sel.args)+1)
+
+	rows, err := s.db.QueryContext(ctx, query, append(sel.args, bulkLimit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > bulkLimit {
+		return nil, ErrBulkLimit
+	}
+	return ids, rows.Err()
+}
+
+// ownedTags returns the tags column of each listed memory owned by the user and not in the trash
+func ownedTags(ctx context.Context, tx *sql.Tx, userID string, ids []string) (map[string]string, error) {
+	owned := map[string]string{}
+	if len(ids) == 0 {
+		return owned, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := []interface{}{userID}
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, id)
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, COALESCE(tags, '') FROM memories WHERE user_id = $1 AND "+notTrashed+
+			" AND id IN ("+strings.Join(placeholders, ", ")+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, tags string
+		if err := rows.Scan(&id, &tags); err != nil {
+			return nil, err
+		}
+		owned[id] = tags
+	}
+	return owned, rows.Err()
+}
+
+// moveToCollection takes memories out of every other collection and appends those
+// not already there to the target collection
+func (s *SQLStore) moveToCollection(ctx context.Context, tx *sql.Tx, userID, collectionID string, memoryIDs []string) error {
+	placeholders := make([]string, len(memoryIDs))
+	args := []interface{}{collectionID}
+	for i, id := range memoryIDs {
+		placeholders[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, id)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM collection_memories WHERE collection_id <> $1 AND memory_id IN ("+strings.Join(placeholders, ", ")+")", args...); err != nil {
+		return err
+	}
+
+	return s.editCollectionTx(ctx, tx, userID, collectionID, func(tx *sql.Tx, members []string) ([]string, error) {
+		return append(members, removeMembers(memoryIDs, members)...), nil
+	})
+}
+
+// bulkTags returns the tag list of a memory after an add_tags or remove_tags action
+func bulkTags(req models.BulkRequest, tags []string) []string {
+	if req.Action == models.BulkAddTags {
+		return normalizeTags(append(tags, req.Tags...))
+	}
+
+	removed := map[string]string{}
+	for _, tag := range normalizeTags(req.Tags) {
+		removed[tag] = ""
+	}
+	return normalizeTags(replaceTags(tags, removed))
+}
+
+// bulkResult lists the outcome for each ID in request order
+func bulkResult(action string, ids []string, statuses map[string]string) models.BulkResult {
+	result := models.BulkResult{Action: action, Matched: len(ids), Results: []models.BulkItemResult{}}
+	for _, id := range ids {
+		status := statuses[id]
+		if status == models.BulkStatusNotFound {
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+		result.Results = append(result.Results, models.BulkItemResult{ID: id, Status: status})
+	}
+	return result
+}
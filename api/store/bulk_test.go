@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"api/models"
+)
+
+// applyBulk runs a bulk request that is expected to succeed
+func applyBulk(t *testing.T, s Store, req models.BulkRequest) models.BulkResult {
+	t.Helper()
+
+	result, err := s.ApplyBulk(context.Background(), ownerID, req)
+	if err != nil {
+		t.Fatalf("bulk %s failed: %v", req.Action, err)
+	}
+	return result
+}
+
+func TestBulkReportsEachIDInRequestOrder(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		tagged := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Tagged", Tags: []string{"go"}})
+		plain := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Plain"})
+		theirs := createMemory(t, s, intruderID, models.CreateMemoryRequest{Title: "Theirs"})
+		trashed := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Trashed"})
+		if err := s.DeleteMemory(ctx, ownerID, trashed.ID); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+		missing := "00000000-0000-4000-8000-000000000000"
+
+		result := applyBulk(t, s, models.BulkRequest{
+			IDs:    []string{plain.ID, theirs.ID, tagged.ID, missing, trashed.ID, plain.ID},
+			Action: models.BulkAddTags,
+			Tags:   []string{"Go"},
+		})
+
+		want := models.BulkResult{
+			Action:    models.BulkAddTags,
+			Matched:   5,
+			Succeeded: 2,
+			Failed:    3,
+			Results: []models.BulkItemResult{
+				{ID: plain.ID, Status: models.BulkStatusUpdated},
+				{ID: theirs.ID, Status: models.BulkStatusNotFound},
+				{ID: tagged.ID, Status: models.BulkStatusUnchanged},
+				{ID: missing, Status: models.BulkStatusNotFound},
+				{ID: trashed.ID, Status: models.BulkStatusNotFound},
+			},
+		}
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("expected %+v, got %+v", want, result)
+		}
+
+		if got := memoryTags(t, s, plain.ID); !reflect.DeepEqual(got, []string{"go"}) {
+			t.Errorf("add_tags: got %v", got)
+		}
+		if got := tagList(t, s, intruderID); len(got) != 0 {
+			t.Errorf("bulk changed another user's memory: %v", got)
+		}
+	})
+}
+
+func TestBulkActions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		a := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "A", Tags: []string{"draft", "go"}})
+		b := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "B", Tags: []string{"draft"}, ContentType: "custom"})
+
+		result := applyBulk(t, s, models.BulkRequest{IDs: []string{a.ID, b.ID}, Action: models.BulkRemoveTags, Tags: []string{"DRAFT"}})
+		if got := memoryTags(t, s, b.ID); result.Succeeded != 2 || len(got) != 0 {
+			t.Errorf("remove_tags: got %+v, tags %v", result, got)
+		}
+		if got := tagList(t, s, ownerID); !reflect.DeepEqual(got, []models.TagCount{{Tag: "go", Count: 1}}) {
+			t.Errorf("remove_tags left unused tags behind: %v", got)
+		}
+
+		result = applyBulk(t, s, models.BulkRequest{IDs: []string{a.ID, b.ID}, Action: models.BulkSetContentType, ContentType: "custom"})
+		statuses := []string{result.Results[0].Status, result.Results[1].Status}
+		if !reflect.DeepEqual(statuses, []string{models.BulkStatusUpdated, models.BulkStatusUnchanged}) {
+			t.Errorf("set_content_type: got %v", statuses)
+		}
+		if memory, err := s.GetMemory(ctx, ownerID, a.ID); err != nil || memory.ContentType != "custom" {
+			t.Errorf("set_content_type: got %q, %v", memory.ContentType, err)
+		}
+
+		from, err := s.CreateCollection(ctx, ownerID, models.CreateCollectionRequest{Name: "From"})
+		if err != nil {
+			t.Fatalf("create collection failed: %v", err)
+		}
+		to, err := s.CreateCollection(ctx, ownerID, models.CreateCollectionRequest{Name: "To"})
+		if err != nil {
+			t.Fatalf("create collection failed: %v", err)
+		}
+		if err := s.AddToCollection(ctx, ownerID, from.ID, []string{a.ID}, nil); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+		if err := s.AddToCollection(ctx, ownerID, to.ID, []string{b.ID}, nil); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+		applyBulk(t, s, models.BulkRequest{IDs: []string{a.ID, b.ID}, Action: models.BulkMoveToCollection, CollectionID: to.ID})
+		if got := collectionMembers(t, s, to.ID); !reflect.DeepEqual(got, []string{b.ID, a.ID}) {
+			t.Errorf("move_to_collection: expected members to keep their place and newcomers appended, got %v", got)
+		}
+		if got := collectionMembers(t, s, from.ID); len(got) != 0 {
+			t.Errorf("move_to_collection: expected the memory to leave its old collection, got %v", got)
+		}
+		if _, err := s.ApplyBulk(ctx, ownerID, models.BulkRequest{IDs: []string{a.ID}, Action: models.BulkMoveToCollection, CollectionID: "missing"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("move to a missing collection: expected ErrNotFound, got %v", err)
+		}
+
+		result = applyBulk(t, s, models.BulkRequest{IDs: []string{a.ID}, Action: models.BulkDelete})
+		if result.Results[0].Status != models.BulkStatusDeleted {
+			t.Errorf("delete: got %+v", result)
+		}
+		if _, err := s.GetMemory(ctx, ownerID, a.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("delete: expected the memory in the trash, got %v", err)
+		}
+	})
+}
+
+func TestBulkFilterIsCapped(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		var last models.MemoryResponse
+		for i := 0; i <= bulkLimit; i++ {
+			last = createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Many", Tags: []string{"many"}})
+		}
+		createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Other"})
+
+		filter := &models.SearchRequest{Tags: []string{"many"}}
+		if _, err := s.ApplyBulk(ctx, ownerID, models.BulkRequest{Filter: filter, Action: models.BulkAddTags, Tags: []string{"x"}}); !errors.Is(err, ErrBulkLimit) {
+			t.Fatalf("filter over the cap: expected ErrBulkLimit, got %v", err)
+		}
+		if got := tagList(t, s, ownerID); !reflect.DeepEqual(got, []models.TagCount{{Tag: "many", Count: bulkLimit + 1}}) {
+			t.Errorf("a capped request changed memories: %v", got)
+		}
+
+		if err := s.DeleteMemory(ctx, ownerID, last.ID); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+		result := applyBulk(t, s, models.BulkRequest{Filter: filter, Action: models.BulkAddTags, Tags: []string{"x"}})
+		if result.Matched != bulkLimit || result.Succeeded != bulkLimit {
+			t.Errorf("filter at the cap: expected %d matched, got %d matched, %d succeeded", bulkLimit, result.Matched, result.Succeeded)
+		}
+	})
+}
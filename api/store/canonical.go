@@ -0,0 +1,148 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"api/models"
+)
+
+// trackingParams are query parameters that identify a campaign or click rather than a page
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "gbraid": true, "wbraid": true, "dclid": true, "msclkid": true,
+	"yclid": true, "igshid": true, "mc_cid": true, "mc_eid": true, "_hsenc": true, "_hsmi": true,
+	"mkt_tok": true, "ref_src": true, "ref_url": true, "oly_anon_id": true, "oly_enc_id": true,
+	"vero_id": true, "s_cid": true, "spm": true,
+}
+
+// trackingPrefixes are prefixes of tracking parameter families such as utm_source
+var trackingPrefixes = []string{"utm_", "pk_", "mtm_"}
+
+// youtubeID matches a YouTube video ID
+var youtubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// CanonicalURL returns the form of raw used to detect duplicates: https, lowercase host without
+// "www." or "m.", default port, fragment, tracking parameters and trailing slash removed, and the
+// remaining parameters sorted. Video pages are reduced to the parameters that identify the video.
+// It returns "" for values that are not http(s) URLs, such as browser pages and the "unknown"
+// placeholder of URL-less captures, so they are never treated as duplicates of each other.
+func CanonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "m.", "mobile."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	if video, ok := canonicalVideoURL(host, u); ok {
+		return video
+	}
+
+	query := u.Query()
+	for name := range query {
+		if isTrackingParam(name) {
+			query.Del(name)
+		}
+	}
+
+	cleanPath := u.EscapedPath()
+	if cleanPath != "" && cleanPath != "/" {
+		cleanPath = strings.TrimRight(cleanPath, "/")
+	}
+	if cleanPath == "" {
+		cleanPath = "/"
+	}
+
+	canonical := "https://" + host + cleanPath
+	if encoded := encodeSorted(query); encoded != "" {
+		canonical += "?" + encoded
+	}
+	// Hash-bang and hash routes address different pages of single-page apps
+	if strings.HasPrefix(u.Fragment, "!") || strings.HasPrefix(u.Fragment, "/") {
+		canonical += "#" + u.Fragment
+	}
+	return canonical
+}
+
+// canonicalVideoURL maps the many URL forms of a known video platform to one per video
+func canonicalVideoURL(host string, u *url.URL) (string, bool) {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	switch host {
+	case "youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		id := u.Query().Get("v")
+		if len(segments) == 2 && (segments[0] == "shorts" || segments[0] == "embed" || segments[0] == "live" || segments[0] == "v") {
+			id = segments[1]
+		}
+		if youtubeID.MatchString(id) {
+			return "https://youtube.com/watch?v=" + id, true
+		}
+	case "youtu.be":
+		if len(segments) == 1 && youtubeID.MatchString(segments[0]) {
+			return "https://youtube.com/watch?v=" + segments[0], true
+		}
+	case "vimeo.com", "player.vimeo.com":
+		for _, segment := range segments {
+			if _, err := strconv.ParseUint(segment, 10, 64); err == nil {
+				return "https://vimeo.com/" + segment, true
+			}
+		}
+	case "netflix.com":
+		if len(segments) == 2 && segments[0] == "watch" {
+			return "https://netflix.com/watch/" + segments[1], true
+		}
+	}
+	return "", false
+}
+
+// isTrackingParam reports whether a query parameter only tracks the visit
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	if trackingParams[name] {
+		return true
+	}
+	for _, prefix := range trackingPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeSorted encodes query sorted by name and then value, so parameter order does not matter
+func encodeSorted(query url.Values) string {
+	pairs := []string{}
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, url.QueryEscape(name)+"="+url.QueryEscape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// contentHash fingerprints the captured text of a memory, ignoring case and whitespace.
+// Video captures include their timestamp so separate moments of one video differ.
+// It returns "" when the memory has no text.
+func contentHash(selectedText, content string, video *models.VideoData) string {
+	text := strings.ToLower(strings.Join(strings.Fields(selectedText+" "+content), " "))
+	if text == "" {
+		return ""
+	}
+	if video != nil {
+		text += "\x00" + strconv.FormatInt(video.Timestamp, 10)
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
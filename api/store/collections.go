@@ -0,0 +1,315 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/models"
+
+	"github.com/google/uuid"
+)
+
+// collectionColumns is the column list shared by every collection SELECT; keep it in sync with scanCollection
+const collectionColumns = `c.id, c.user_id, c.name, COALESCE(c.description, ''), c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM collection_memories cm JOIN memories ON memories.id = cm.memory_id
+		WHERE cm.collection_id = c.id AND ` + notTrashed + `)`
+
+func scanCollection(row rowScanner) (models.Collection, error) {
+	var collection models.Collection
+	err := row.Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.Description,
+		&collection.CreatedAt, &collection.UpdatedAt, &collection.MemoryCount)
+	return collection, err
+}
+
+// collectionCondition returns a WHERE fragment matching memories in the collection bound to placeholder
+func collectionCondition(placeholder string) string {
+	return "EXISTS (SELECT 1 FROM collection_memories cm WHERE cm.memory_id = memories.id AND cm.collection_id = " + placeholder + ")"
+}
+
+// CreateCollection creates an empty collection for the user
+func (s *SQLStore) CreateCollection(ctx context.Context, userID string, req models.CreateCollectionRequest) (models.Collection, error) {
+	now := time.Now().UTC()
+	id := uuid.New().String()
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO collections (id, user_id, name, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		id, userID, req.Name, nullString(req.Description), now, now)
+	if err != nil {
+		return models.Collection{}, err
+	}
+
+	return s.GetCollection(ctx, userID, id)
+}
+
+// GetCollection fetches a single collection owned by the user
+func (s *SQLStore) GetCollection(ctx context.Context, userID, id string) (models.Collection, error) {
+	query := "SELECT " + collectionColumns + " FROM collections c WHERE c.id = $1 AND c.user_id = $2"
+
+	collection, err := scanCollection(s.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return models.Collection{}, ErrNotFound
+	}
+	return collection, err
+}
+
+// ListCollections returns the user's collections, oldest first
+func (s *SQLStore) ListCollections(ctx context.Context, userID string) ([]models.Collection, error) {
+	query := "SELECT " + collectionColumns + " FROM collections c WHERE c.user_id = $1 ORDER BY c.created_at, c.id"
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.Collection{}
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	return collections, rows.Err()
+}
+
+// UpdateCollection applies the non-empty fields of req to a collection owned by the user
+func (s *SQLStore) UpdateCollection(ctx context.Context, userID, id string, req models.UpdateCollectionRequest) (models.Collection, error) {
+	updates := []string{}
+	args := []interface{}{}
+	argCount := 1
+
+	if req.Name != "" {
+		updates = append(updates, "name = $"+strconv.Itoa(argCount))
+		args = append(args, req.Name)
+		argCount++
+	}
+
+	if req.Description != "" {
+		updates = append(updates, "description = $"+strconv.Itoa(argCount))
+		args = append(args, req.Description)
+		argCount++
+	}
+
+	updates = append(updates, "updated_at = $"+strconv.Itoa(argCount))
+	args = append(args, time.Now().UTC())
+	argCount++
+
+	args = append(args, id, userID)
+
+	query := "UPDATE collections SET " + strings.Join(updates, ", ") +
+		" WHERE id = $" + strconv.Itoa(argCount) + " AND user_id = $" + strconv.Itoa(argCount+1)
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Collection{}, err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return models.Collection{}, ErrNotFound
+	}
+
+	return s.GetCollection(ctx, userID, id)
+}
+
+// DeleteCollection removes a collection owned by the user; its memories are kept
+func (s *SQLStore) DeleteCollection(ctx context.Context, userID, id string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM collections WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListCollectionMemories returns the memories of a collection in collection order. Memories in
+// the trash keep their place, to have it back when restored, but are not listed.
+func (s *SQLStore) ListCollectionMemories(ctx context.Context, userID, id string, limit, offset int) ([]models.MemoryResponse, error) {
+	if _, err := s.GetCollection(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + memoryColumns + " FROM memories JOIN collection_memories cm ON cm.memory_id = memories.id" +
+		" WHERE cm.collection_id = $1 AND memories.user_id = $2 AND " + notTrashed + " ORDER BY cm.position LIMIT $3 OFFSET $4"
+
+	return s.queryMemories(ctx, query, id, userID, limit, offset)
+}
+
+// AddToCollection inserts memories at position (or appends them when position is nil).
+// Memories already in the collection are moved; every memory must be owned by the user.
+func (s *SQLStore) AddToCollection(ctx context.Context, userID, id string, memoryIDs []string, position *int) error {
+	return s.editCollection(ctx, userID, id, func(tx *sql.Tx, members []string) ([]string, error) {
+		memoryIDs = uniqueStrings(memoryIDs)
+		if err := s.checkOwned(ctx, tx, userID, memoryIDs); err != nil {
+			return nil, err
+		}
+		return insertMembers(members, memoryIDs, position), nil
+	})
+}
+
+// RemoveFromCollection takes a memory out of a collection
+func (s *SQLStore) RemoveFromCollection(ctx context.Context, userID, id, memoryID string) error {
+	return s.editCollection(ctx, userID, id, func(tx *sql.Tx, members []string) ([]string, error) {
+		remaining := removeMembers(members, []string{memoryID})
+		if len(remaining) == len(members) {
+			return nil, ErrNotFound
+		}
+		return remaining, nil
+	})
+}
+
+// ReorderCollection moves the listed memories to the front of the collection in the given order;
+// the others keep their relative order after them
+func (s *SQLStore) ReorderCollection(ctx context.Context, userID, id string, memoryIDs []string) error {
+	return s.editCollection(ctx, userID, id, func(tx *sql.Tx, members []string) ([]string, error) {
+		return reorderMembers(members, uniqueStrings(memoryIDs))
+	})
+}
+
+// editCollection rewrites the ordered member list of a collection owned by the user in one transaction
+func (s *SQLStore) editCollection(ctx context.Context, userID, id string, edit func(*sql.Tx, []string) ([]string, error)) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.editCollectionTx(ctx, tx, userID, id, edit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// editCollectionTx is editCollection within an existing transaction
+func (s *SQLStore) editCollectionTx(ctx context.Context, tx *sql.Tx, userID, id string, edit func(*sql.Tx, []string) ([]string, error)) error {
+	now := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, "UPDATE collections SET updated_at = $1 WHERE id = $2 AND user_id = $3", now, id, userID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT memory_id, added_at FROM collection_memories WHERE collection_id = $1 ORDER BY position", id)
+	if err != nil {
+		return err
+	}
+	members := []string{}
+	addedAt := map[string]time.Time{}
+	for rows.Next() {
+		var memoryID string
+		var added time.Time
+		if err := rows.Scan(&memoryID, &added); err != nil {
+			rows.Close()
+			return err
+		}
+		members = append(members, memoryID)
+		addedAt[memoryID] = added
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	members, err = edit(tx, members)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM collection_memories WHERE collection_id = $1", id); err != nil {
+		return err
+	}
+	for position, memoryID := range members {
+		added, ok := addedAt[memoryID]
+		if !ok {
+			added = now
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO collection_memories (collection_id, memory_id, position, added_at) VALUES ($1, $2, $3, $4)",
+			id, memoryID, position, added); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkOwned returns ErrNotFound unless every memory ID belongs to the user and is not in the trash
+func (s *SQLStore) checkOwned(ctx context.Context, tx *sql.Tx, userID string, memoryIDs []string) error {
+	placeholders := make([]string, len(memoryIDs))
+	args := []interface{}{userID}
+	for i, memoryID := range memoryIDs {
+		placeholders[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, memoryID)
+	}
+
+	var owned int
+	err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM memories WHERE user_id = $1 AND "+notTrashed+" AND id IN ("+strings.Join(placeholders, ", ")+")", args...).Scan(&owned)
+	if err != nil {
+		return err
+	}
+	if owned != len(memoryIDs) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// insertMembers moves memoryIDs to position in members, appending when position is nil or past the end
+func insertMembers(members, memoryIDs []string, position *int) []string {
+	members = removeMembers(members, memoryIDs)
+
+	at := len(members)
+	if position != nil && *position < at {
+		at = *position
+	}
+
+	result := make([]string, 0, len(members)+len(memoryIDs))
+	result = append(result, members[:at]...)
+	result = append(result, memoryIDs...)
+	return append(result, members[at:]...)
+}
+
+// removeMembers returns members without memoryIDs
+func removeMembers(members, memoryIDs []string) []string {
+	drop := map[string]bool{}
+	for _, memoryID := range memoryIDs {
+		drop[memoryID] = true
+	}
+
+	kept := make([]string, 0, len(members))
+	for _, memoryID := range members {
+		if !drop[memoryID] {
+			kept = append(kept, memoryID)
+		}
+	}
+	return kept
+}
+
+// reorderMembers puts memoryIDs first, failing with ErrNotFound if one is not a member
+func reorderMembers(members, memoryIDs []string) ([]string, error) {
+	rest := removeMembers(members, memoryIDs)
+	if len(rest)+len(memoryIDs) != len(members) {
+		return nil, ErrNotFound
+	}
+	return append(append([]string{}, memoryIDs...), rest...), nil
+}
+
+// uniqueStrings drops repeated values, keeping the first occurrence
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
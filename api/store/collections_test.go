@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"api/models"
+)
+
+// collectionMembers returns the IDs of the memories listed in a collection, in order
+func collectionMembers(t *testing.T, s Store, id string) []string {
+	t.Helper()
+
+	memories, err := s.ListCollectionMemories(context.Background(), ownerID, id, 100, 0)
+	if err != nil {
+		t.Fatalf("list collection memories failed: %v", err)
+	}
+	ids := []string{}
+	for _, memory := range memories {
+		ids = append(ids, memory.ID)
+	}
+	return ids
+}
+
+// memoryCount returns the memory count reported for a collection
+func memoryCount(t *testing.T, s Store, id string) int {
+	t.Helper()
+
+	collection, err := s.GetCollection(context.Background(), ownerID, id)
+	if err != nil {
+		t.Fatalf("get collection failed: %v", err)
+	}
+	return collection.MemoryCount
+}
+
+func TestCollectionCRUD(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		reading, err := s.CreateCollection(ctx, ownerID, models.CreateCollectionRequest{Name: "Reading", Description: "Later"})
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		if reading.Name != "Reading" || reading.Description != "Later" || reading.MemoryCount != 0 {
+			t.Errorf("create: got %+v", reading)
+		}
+		work, err := s.CreateCollection(ctx, ownerID, models.CreateCollectionRequest{Name: "Work"})
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		if _, err := s.CreateCollection(ctx, intruderID, models.CreateCollectionRequest{Name: "Theirs"}); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+
+		collections, err := s.ListCollections(ctx, ownerID)
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		names := []string{}
+		for _, collection := range collections {
+			names = append(names, collection.Name)
+		}
+		if !reflect.DeepEqual(names, []string{"Reading", "Work"}) {
+			t.Errorf("list: expected the user's collections oldest first, got %v", names)
+		}
+
+		updated, err := s.UpdateCollection(ctx, ownerID, reading.ID, models.UpdateCollectionRequest{Name: "To read"})
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if updated.Name != "To read" || updated.Description != "Later" {
+			t.Errorf("update: expected the empty description to be kept, got %+v", updated)
+		}
+
+		if err := s.DeleteCollection(ctx, ownerID, work.ID); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+		if _, err := s.GetCollection(ctx, ownerID, work.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("get after delete: expected ErrNotFound, got %v", err)
+		}
+		if err := s.DeleteCollection(ctx, ownerID, work.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second delete: expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestCollectionsAreScopedToTheirOwner(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		collection, err := s.CreateCollection(ctx, ownerID, models.CreateCollectionRequest{Name: "Mine"})
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		mine := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Mine"})
+		theirs := createMemory(t, s, intruderID, models.CreateMemoryRequest{Title: "Theirs"})
+
+		if _, err := s.GetCollection(ctx, intruderID, collection.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("get: expected ErrNotFound, got %v", err)
+		}
+		if _, err := s.UpdateCollection(ctx, intruderID, collection.ID, models.UpdateCollectionRequest{Name: "Stolen"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("update: expected ErrNotFound, got %v", err)
+		}
+		if _, err := s.ListCollectionMemories(ctx, intruderID, collection.ID, 10, 0); !errors.Is(err, ErrNotFound) {
+			t.Errorf("list memories: expected ErrNotFound, got %v", err)
+		}
+		if err := s.AddToCollection(ctx, intruderID, collection.ID, []string{theirs.ID}, nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("add to another user's collection: expected ErrNotFound, got %v", err)
+		}
+		if err := s.AddToCollection(ctx, ownerID, collection.ID, []string{mine.ID, theirs.ID}, nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("add another user's memory: expected ErrNotFound, got %v", err)
+		}
+		if err := s.DeleteCollection(ctx, intruderID, collection.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("delete: expected ErrNotFound, got %v", err)
+		}
+
+		// A rejected add leaves the collection as it was
+		if got := collectionMembers(t, s, collection.ID); len(got) != 0 {
+			t.Errorf("expected an empty collection, got %v", got)
+		}
+	})
+}
+
+func TestCollectionMembershipAndOrder(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		collection, err := s.CreateCollection(ctx, ownerID, models.CreateCollectionRequest{Name: "Ordered"})
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		a := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "A"})
+		b := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "B"})
+		c := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "C"})
+		d := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "D"})
+
+		if err := s.AddToCollection(ctx, ownerID, collection.ID, []string{a.ID, b.ID, a.ID}, nil); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+		if got := collectionMembers(t, s, collection.ID); !reflect.DeepEqual(got, []string{a.ID, b.ID}) {
+			t.Errorf("append: expected repeated IDs once, got %v", got)
+		}
+
+		first := 0
+		if err := s.AddToCollection(ctx, ownerID, collection.ID, []string{c.ID}, &first); err != nil {
+			t.Fatalf("add at position failed: %v", err)
+		}
+		if got := collectionMembers(t, s, collection.ID); !reflect.DeepEqual(got, []string{c.ID, a.ID, b.ID}) {
+			t.Errorf("insert at 0: got %v", got)
+		}
+
+		// Adding a member again moves it instead of listing it twice
+		past := 10
+		if err := s.AddToCollection(ctx, ownerID, collection.ID, []string{c.ID, d.ID}, &past); err != nil {
+			t.Fatalf("move failed: %v", err)
+		}
+		if got := collectionMembers(t, s, collection.ID); !reflect.DeepEqual(got, []string{a.ID, b.ID, c.ID, d.ID}) {
+			t.Errorf("move past the end: got %v", got)
+		}
+
+		if err := s.ReorderCollection(ctx, ownerID, collection.ID, []string{d.ID, b.ID}); err != nil {
+			t.Fatalf("reorder failed: %v", err)
+		}
+		if got := collectionMembers(t, s, collection.ID); !reflect.DeepEqual(got, []string{d.ID, b.ID, a.ID, c.ID}) {
+			t.Errorf("reorder: expected the rest to keep their order, got %v", got)
+		}
+
+		outsider := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Outsider"})
+		if err := s.ReorderCollection(ctx, ownerID, collection.ID, []string{outsider.ID, a.ID}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("reorder a non-member: expected ErrNotFound, got %v", err)
+		}
+
+		if err := s.RemoveFromCollection(ctx, ownerID, collection.ID, b.ID); err != nil {
+			t.Fatalf("remove failed: %v", err)
+		}
+		if err := s.RemoveFromCollection(ctx, ownerID, collection.ID, b.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("remove a non-member: expected ErrNotFound, got %v", err)
+		}
+		if got := collectionMembers(t, s, collection.ID); !reflect.DeepEqual(got, []string{d.ID, a.ID, c.ID}) {
+			t.Errorf("remove: got %v", got)
+		}
+
+		page, err := s.ListCollectionMemories(ctx, ownerID, collection.ID, 2, 1)
+		if err != nil {
+			t.Fatalf("list page failed: %v", err)
+		}
+		got := []string{}
+		for _, memory := range page {
+			got = append(got, memory.ID)
+		}
+		if !reflect.DeepEqual(got, []string{a.ID, c.ID}) {
+			t.Errorf("limit 2 offset 1: got %v", got)
+		}
+	})
+}
+
+func TestCollectionsSkipTrashedMemories(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		collection, err := s.CreateCollection(ctx, ownerID, models.CreateCollectionRequest{Name: "Trash"})
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		kept := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Kept"})
+		trashed := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Trashed"})
+		if err := s.AddToCollection(ctx, ownerID, collection.ID, []string{trashed.ID, kept.ID}, nil); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+		if err := s.DeleteMemory(ctx, ownerID, trashed.ID); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+
+		if got := collectionMembers(t, s, collection.ID); !reflect.DeepEqual(got, []string{kept.ID}) {
+			t.Errorf("expected the trashed memory to be hidden, got %v", got)
+		}
+		if n := memoryCount(t, s, collection.ID); n != 1 {
+			t.Errorf("expected the count to exclude the trash, got %d", n)
+		}
+		if err := s.AddToCollection(ctx, ownerID, collection.ID, []string{trashed.ID}, nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("add a trashed memory: expected ErrNotFound, got %v", err)
+		}
+
+		// Restored memories come back in their old place
+		if _, err := s.RestoreMemory(ctx, ownerID, trashed.ID); err != nil {
+			t.Fatalf("restore failed: %v", err)
+		}
+		if got := collectionMembers(t, s, collection.ID); !reflect.DeepEqual(got, []string{trashed.ID, kept.ID}) {
+			t.Errorf("after restore: got %v", got)
+		}
+		if n := memoryCount(t, s, collection.ID); n != 2 {
+			t.Errorf("after restore: expected a count of 2, got %d", n)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"api/models"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the keyset position after which the next page starts.
+// Memories are ordered by (created_at DESC, id DESC); id breaks ties between equal timestamps.
+// Ranked searches order by relevance first, so their cursors also carry the rank.
+type cursor struct {
+	Rank      *float64  `json:"r,omitempty"`
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+// encodeCursor returns the opaque cursor pointing just after memory
+func encodeCursor(memory models.MemoryResponse) string {
+	payload, _ := json.Marshal(cursor{Rank: memory.Rank, CreatedAt: memory.CreatedAt, ID: memory.ID})
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// decodeCursor parses a cursor produced by encodeCursor; an empty string means the first page
+func decodeCursor(value string) (*cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil || c.ID == "" || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// before reports whether a memory sorts after the cursor position, i.e. belongs to a later page.
+// rank is ignored unless both the memory and the cursor are ranked.
+func (c *cursor) before(rank *float64, createdAt time.Time, id string) bool {
+	if c.Rank != nil && rank != nil && *rank != *c.Rank {
+		return *rank < *c.Rank
+	}
+	if createdAt.Equal(c.CreatedAt) {
+		return id < c.ID
+	}
+	return createdAt.Before(c.CreatedAt)
+}
+
+// pageRequest holds the pagination options shared by listing and search
+type pageRequest struct {
+	Limit        int
+	Offset       int
+	Cursor       string
+	IncludeTotal bool
+}
+
+// buildPage trims the extra row fetched to detect a following page and sets NextCursor
+func buildPage(memories []models.MemoryResponse, limit int) models.MemoryPage {
+	page := models.MemoryPage{Memories: memories}
+	if limit > 0 && len(memories) > limit {
+		page.Memories = memories[:limit]
+		page.NextCursor = encodeCursor(page.Memories[limit-1])
+	}
+	return page
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"api/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	rank := 0.75
+
+	for _, memory := range []models.MemoryResponse{
+		{Memory: models.Memory{ID: "b", CreatedAt: createdAt}},
+		{Memory: models.Memory{ID: "b", CreatedAt: createdAt}, Rank: &rank},
+	} {
+		c, err := decodeCursor(encodeCursor(memory))
+		if err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		if c.ID != memory.ID || !c.CreatedAt.Equal(createdAt) || (c.Rank == nil) != (memory.Rank == nil) {
+			t.Errorf("cursor %+v does not point at %+v", c, memory.Memory)
+		}
+		if c.Rank != nil && *c.Rank != rank {
+			t.Errorf("expected rank %v, got %v", rank, *c.Rank)
+		}
+	}
+
+	if c, err := decodeCursor(""); c != nil || err != nil {
+		t.Errorf("empty cursor: expected the first page, got %+v, %v", c, err)
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	encode := func(payload string) string { return base64.RawURLEncoding.EncodeToString([]byte(payload)) }
+
+	for _, value := range []string{
+		"not base64!",
+		"eyJ",
+		encode("not json"),
+		encode(`{"i":"b"}`),
+		encode(`{"c":"2024-05-01T12:00:00Z"}`),
+		encode(`{"c":"yesterday","i":"b"}`),
+		encode(`{"c":"2024-05-01T12:00:00Z","i":42}`),
+		encode(`[]`),
+	} {
+		if _, err := decodeCursor(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q): expected ErrInvalidCursor, got %v", value, err)
+		}
+	}
+}
+
+func TestCursorBreaksTiesByID(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	low, high := 0.2, 0.8
+	c := &cursor{CreatedAt: at, ID: "m"}
+	ranked := &cursor{Rank: &high, CreatedAt: at, ID: "m"}
+
+	cases := []struct {
+		name      string
+		c         *cursor
+		rank      *float64
+		createdAt time.Time
+		id        string
+		want      bool
+	}{
+		{"older", c, nil, at.Add(-time.Second), "z", true},
+		{"newer", c, nil, at.Add(time.Second), "a", false},
+		{"same time, lower id", c, nil, at, "a", true},
+		{"same time, same id", c, nil, at, "m", false},
+		{"same time, higher id", c, nil, at, "z", false},
+		{"lower rank", ranked, &low, at.Add(time.Hour), "z", true},
+		{"higher rank", ranked, &high, at, "a", true},
+		{"equal rank falls back to time", ranked, &high, at.Add(time.Second), "a", false},
+		{"unranked memory ignores rank", ranked, nil, at, "a", true},
+	}
+	for _, tc := range cases {
+		if got := tc.c.before(tc.rank, tc.createdAt, tc.id); got != tc.want {
+			t.Errorf("%s: before = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBuildPageTrimsTheLookaheadRow(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	memories := []models.MemoryResponse{
+		{Memory: models.Memory{ID: "c", CreatedAt: at}},
+		{Memory: models.Memory{ID: "b", CreatedAt: at}},
+		{Memory: models.Memory{ID: "a", CreatedAt: at}},
+	}
+
+	page := buildPage(memories, 2)
+	if got := memoryIDs(page.Memories); len(got) != 2 || got[1] != "b" {
+		t.Fatalf("expected the first 2 memories, got %v", got)
+	}
+	if c, err := decodeCursor(page.NextCursor); err != nil || c.ID != "b" {
+		t.Errorf("expected a next cursor after b, got %+v, %v", c, err)
+	}
+
+	if page := buildPage(memories, 3); page.NextCursor != "" || len(page.Memories) != 3 {
+		t.Errorf("last page: expected every memory and no cursor, got %d and %q", len(page.Memories), page.NextCursor)
+	}
+}
+
+func TestCursorsMustMatchTheListingOrder(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		for i := 0; i < 3; i++ {
+			createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "golang notes"})
+		}
+
+		search, err := s.SearchMemories(ctx, ownerID, models.SearchRequest{Query: "golang", Limit: 1})
+		if err != nil || search.NextCursor == "" {
+			t.Fatalf("search: expected a next cursor, got %+v, %v", search, err)
+		}
+		list, err := s.ListMemories(ctx, ownerID, models.MemoryQueryParams{Limit: 1})
+		if err != nil || list.NextCursor == "" {
+			t.Fatalf("list: expected a next cursor, got %+v, %v", list, err)
+		}
+
+		if _, err := s.ListMemories(ctx, ownerID, models.MemoryQueryParams{Limit: 1, Cursor: search.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("ranked cursor on a listing: expected ErrInvalidCursor, got %v", err)
+		}
+		if _, err := s.SearchMemories(ctx, ownerID, models.SearchRequest{Query: "golang", Limit: 1, Cursor: list.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("listing cursor on a search: expected ErrInvalidCursor, got %v", err)
+		}
+		if _, err := s.ListMemories(ctx, ownerID, models.MemoryQueryParams{Limit: 1, Cursor: "garbage"}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("garbage cursor: expected ErrInvalidCursor, got %v", err)
+		}
+	})
+}
+
+func TestInvalidDateFiltersAreValidationErrors(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		for _, req := range []models.SearchRequest{
+			{StartDate: "yesterday", Limit: 10},
+			{Query: "golang", EndDate: "2024-02-30", Limit: 10},
+		} {
+			if _, err := s.SearchMemories(context.Background(), ownerID, req); !errors.Is(err, ErrInvalidDate) {
+				t.Errorf("search %+v: expected ErrInvalidDate, got %v", req, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"api/models"
+
+	"github.com/google/uuid"
+)
+
+// duplicatePageTypes are the content types that capture a whole page, so two of them with
+// the same canonical URL are duplicates; selections and video moments must also share their text
+const duplicatePageTypes = "('page', 'links')"
+
+// backfillBatchSize is how many memories BackfillDuplicateKeys reads per query
+const backfillBatchSize = 500
+
+// matchesByContent reports whether duplicates of a content type must also share a content hash
+func matchesByContent(contentType string) bool {
+	return contentType == "selection" || contentType == "video_timestamp"
+}
+
+// CreateOrMergeMemory folds req into the user's newest memory it would duplicate, or creates a
+// memory when there is none, reporting whether it merged. The lookup and the write share one
+// transaction that holds a lock on the canonical URL, so concurrent captures of a page merge
+// into one memory instead of each creating their own.
+func (s *SQLStore) CreateOrMergeMemory(ctx context.Context, userID string, req models.CreateMemoryRequest) (models.MemoryResponse, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MemoryResponse{}, false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	canonical := CanonicalURL(req.URL)
+	if lock := s.dialect.advisoryLock("$1"); lock != "" && canonical != "" {
+		if _, err := tx.ExecContext(ctx, lock, "duplicate:"+userID+":"+canonical); err != nil {
+			return models.MemoryResponse{}, false, err
+		}
+	}
+
+	id, err := s.findDuplicate(ctx, tx, userID, req)
+	merged := err == nil
+	switch {
+	case merged:
+		err = s.mergeMemory(ctx, tx, userID, id, req)
+	case errors.Is(err, ErrNotFound):
+		id, err = s.insertMemory(ctx, tx, userID, req)
+	}
+	if err != nil {
+		return models.MemoryResponse{}, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.MemoryResponse{}, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	memory, err := s.GetMemory(ctx, userID, id)
+	return memory, merged, err
+}
+
+// findDuplicate returns the ID of the user's newest memory that req would duplicate, or ErrNotFound:
+// same canonical URL and content type, plus the same content hash for selections and video moments
+func (s *SQLStore) findDuplicate(ctx context.Context, tx *sql.Tx, userID string, req models.CreateMemoryRequest) (string, error) {
+	canonical := CanonicalURL(req.URL)
+	if canonical == "" {
+		return "", ErrNotFound
+	}
+
+	query := "SELECT id FROM memories WHERE user_id = $1 AND canonical_url = $2 AND content_type = $3 AND " + notTrashed
+	args := []interface{}{userID, canonical, req.ContentType}
+	if matchesByContent(req.ContentType) {
+		query += " AND content_hash = $4"
+		args = append(args, contentHash(req.SelectedText, req.Content, req.VideoData))
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT 1" + s.dialect.forUpdate()
+
+	var id string
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// mergeMemory folds a new capture into an existing memory owned by the user: tags are combined,
+// new notes are appended, non-empty captured fields (and a newly extracted article) replace
+// the stored ones and unseen links are added
+func (s *SQLStore) mergeMemory(ctx context.Context, tx *sql.Tx, userID, id string, req models.CreateMemoryRequest) error {
+	existing, err := scanMemory(tx.QueryRowContext(ctx,
+		"SELECT "+memoryColumns+" FROM memories WHERE id = $1 AND user_id = $2 AND "+notTrashed+s.dialect.forUpdate(), id, userID))
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	merged := mergeCapture(existing, req)
+	article := articleColumns(req.Article)
+	now := time.Now().UTC()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE memories SET
+			title = $1, content = $2, selected_text = $3,
+			context_before = COALESCE($4, context_before), context_after = COALESCE($5, context_after),
+			full_context = COALESCE($6, full_context), element_type = COALESCE($7, element_type),
+			page_section = COALESCE($8, page_section), xpath = COALESCE($9, xpath),
+			notes = $10, scraped_at = $11, content_hash = $12, updated_at = $13,
+			content_html = COALESCE($14, content_html), byline = COALESCE($15, byline),
+			published_at = COALESCE($16, published_at), lead_image_url = COALESCE($17, lead_image_url),
+			language = COALESCE($18, language), truncated_fields = COALESCE($19, truncated_fields)
+		WHERE id = $20`,
+		merged.Title, merged.Content, merged.SelectedText,
+		nullString(req.ContextBefore), nullString(req.ContextAfter),
+		nullString(req.FullContext), nullString(req.ElementType),
+		nullString(req.PageSection), nullString(req.XPath),
+		merged.Notes, merged.ScrapedAt, merged.ContentHash, now,
+		article.ContentHTML, article.Byline, article.PublishedAt, article.LeadImageURL, article.Language,
+		req.Truncated, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT href FROM links WHERE memory_id = $1", id)
+	if err != nil {
+		return err
+	}
+	hrefs := map[string]bool{}
+	for rows.Next() {
+		var href string
+		if err := rows.Scan(&href); err != nil {
+			rows.Close()
+			return err
+		}
+		hrefs[href] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	position := len(hrefs)
+	for _, link := range req.Links {
+		if hrefs[link.Href] {
+			continue
+		}
+		hrefs[link.Href] = true
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO links (id, memory_id, text, href, link_title, domain, position, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			uuid.New().String(), id, link.Text, link.Href, link.Title, nullString(linkDomain(link.Href)), position, now); err != nil {
+			return err
+		}
+		position++
+	}
+
+	tags := normalizeTags(append(splitTags(existing.TagsString.String), req.Tags...))
+	return writeMemoryTags(ctx, tx, userID, id, tags, now)
+}
+
+// mergeCapture returns existing with the fields mergeMemory takes from req applied;
+// context fields, tags and links are merged by the callers
+func mergeCapture(existing models.Memory, req models.CreateMemoryRequest) models.Memory {
+	merged := existing
+	if (existing.Title == "" || existing.Title == "Untitled") && req.Title != "" {
+		merged.Title = req.Title
+	}
+	if req.Content != "" {
+		merged.Content = nullString(req.Content)
+	}
+	if req.SelectedText != "" {
+		merged.SelectedText = nullString(req.SelectedText)
+	}
+
+	notes := strings.TrimSpace(req.Notes)
+	if notes != "" && !strings.Contains(existing.Notes.String, notes) {
+		if existing.Notes.String != "" {
+			notes = existing.Notes.String + "\n\n" + notes
+		}
+		merged.Notes = nullString(notes)
+	}
+
+	if req.ScrapedAt.After(existing.ScrapedAt) {
+		merged.ScrapedAt = req.ScrapedAt.UTC()
+	}
+
+	var video *models.VideoData
+	if existing.VideoPlatform.Valid {
+		video = &models.VideoData{Timestamp: existing.VideoTimestamp.Int64}
+	}
+	merged.ContentHash = nullString(contentHash(merged.SelectedText.String, merged.Content.String, video))
+	return merged
+}
+
+// ListDuplicates groups the user's likely duplicate memories, largest groups first:
+// whole-page captures sharing a canonical URL, and any memories sharing a content hash
+func (s *SQLStore) ListDuplicates(ctx context.Context, userID string, limit int) ([]models.DuplicateGroup, error) {
+	query := `
+		SELECT reason, duplicate_key FROM (
+			SELECT 'url' AS reason, canonical_url AS duplicate_key, COUNT(*) AS size, MAX(created_at) AS latest
+			FROM memories WHERE user_id = $1 AND ` + notTrashed + ` AND canonical_url IS NOT NULL AND content_type IN ` + duplicatePageTypes + `
+			GROUP BY canonical_url HAVING COUNT(*) > 1
+			UNION ALL
+			SELECT 'content', content_hash, COUNT(*), MAX(created_at)
+			FROM memories WHERE user_id = $1 AND ` + notTrashed + ` AND content_hash IS NOT NULL
+			GROUP BY content_hash HAVING COUNT(*) > 1
+		) duplicate_groups
+		ORDER BY size DESC, latest DESC, duplicate_key LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	groups := []models.DuplicateGroup{}
+	for rows.Next() {
+		var group models.DuplicateGroup
+		if err := rows.Scan(&group.Reason, &group.Key); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, group := range groups {
+		condition := "content_hash = $2"
+		if group.Reason == models.DuplicateByURL {
+			condition = "canonical_url = $2 AND content_type IN " + duplicatePageTypes
+		}
+		memories, err := s.queryMemories(ctx, "SELECT "+memoryColumns+" FROM memories WHERE user_id = $1 AND "+notTrashed+" AND "+condition+
+			" ORDER BY created_at, id", userID, group.Key)
+		if err != nil {
+			return nil, err
+		}
+		groups[i].Memories = memories
+	}
+	return groups, nil
+}
+
+// BackfillDuplicateKeys computes the canonical URL and content hash of memories saved before
+// they were tracked and returns how many were updated. Canonical URLs stored for values that
+// are not http(s) URLs are cleared. It is safe to run repeatedly.
+func (s *SQLStore) BackfillDuplicateKeys(ctx context.Context) (int, error) {
+	type pending struct {
+		id, url, selectedText, content string
+		video                          *models.VideoData
+	}
+
+	updated := 0
+	after := ""
+	for {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT id, COALESCE(url, ''), COALESCE(selected_text, ''), COALESCE(content, ''),
+				video_platform IS NOT NULL, COALESCE(video_timestamp, 0)
+			FROM memories
+			WHERE id > $1 AND ((canonical_url IS NULL AND LOWER(TRIM(url)) LIKE 'http%')
+				OR canonical_url NOT LIKE 'https://%'
+				OR (content_hash IS NULL AND (content IS NOT NULL OR selected_text IS NOT NULL)))
+			ORDER BY id LIMIT $2`, after, backfillBatchSize)
+		if err != nil {
+			return updated, err
+		}
+
+		batch := []pending{}
+		for rows.Next() {
+			var p pending
+			var isVideo bool
+			var timestamp int64
+			if err := rows.Scan(&p.id, &p.url, &p.selectedText, &p.content, &isVideo, &timestamp); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			if isVideo {
+				p.video = &models.VideoData{Timestamp: timestamp}
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+		if len(batch) == 0 {
+			return updated, nil
+		}
+
+		for _, p := range batch {
+			if _, err := s.db.ExecContext(ctx, "UPDATE memories SET canonical_url = $1, content_hash = $2 WHERE id = $3",
+				nullString(CanonicalURL(p.url)), nullString(contentHash(p.selectedText, p.content, p.video)), p.id); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+		after = batch[len(batch)-1].id
+	}
+}
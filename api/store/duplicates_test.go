@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"api/models"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	const video = "https://youtube.com/watch?v=dQw4w9WgXcQ"
+
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		// Tracking parameters and fragments
+		{"https://example.com/page?utm_source=x&utm_medium=y&id=1#section", "https://example.com/page?id=1"},
+		{"https://example.com/?UTM_Campaign=x&fbclid=y&gclid=z", "https://example.com/"},
+		{"https://example.com/a?b=2&a=1&mc_cid=3", "https://example.com/a?a=1&b=2"},
+		{"https://example.com/app#/inbox", "https://example.com/app#/inbox"},
+		{"https://example.com/page#!/route", "https://example.com/page#!/route"},
+
+		// Scheme, host and port
+		{"HTTP://WWW.Example.COM/Path", "https://example.com/Path"},
+		{"https://m.example.com/a", "https://example.com/a"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"http://example.com:80/a", "https://example.com/a"},
+		{"http://example.com:8080/a", "https://example.com:8080/a"},
+
+		// Trailing slashes
+		{"https://example.com", "https://example.com/"},
+		{"https://example.com/", "https://example.com/"},
+		{"https://example.com/docs/", "https://example.com/docs"},
+		{"https://example.com/docs//", "https://example.com/docs"},
+
+		// Video IDs
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s&list=PL1", video},
+		{"https://youtu.be/dQw4w9WgXcQ?t=42", video},
+		{"https://m.youtube.com/shorts/dQw4w9WgXcQ", video},
+		{"https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", video},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ&feature=share", video},
+		{"https://www.youtube.com/watch?v=tooshort", "https://youtube.com/watch?v=tooshort"},
+		{"https://vimeo.com/123456789?share=copy", "https://vimeo.com/123456789"},
+		{"https://player.vimeo.com/video/123456789#t=10", "https://vimeo.com/123456789"},
+		{"https://vimeo.com/channels/staffpicks/123456789", "https://vimeo.com/123456789"},
+
+		// Values that are not http(s) URLs
+		{"  chrome://settings  ", ""},
+		{"about:blank", ""},
+		{"unknown", ""},
+		{"", ""},
+	}
+
+	for _, tc := range cases {
+		if got := CanonicalURL(tc.raw); got != tc.want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", tc.raw, got, tc.want)
+		}
+	}
+}
+
+// createOrMerge saves req as the owner with on_duplicate=merge
+func createOrMerge(t *testing.T, s Store, req models.CreateMemoryRequest) (models.MemoryResponse, bool) {
+	t.Helper()
+
+	if req.ContentType == "" {
+		req.ContentType = "page"
+	}
+	if req.ScrapedAt.IsZero() {
+		req.ScrapedAt = time.Now()
+	}
+	memory, merged, err := s.CreateOrMergeMemory(context.Background(), ownerID, req)
+	if err != nil {
+		t.Fatalf("create or merge %q failed: %v", req.Title, err)
+	}
+	return memory, merged
+}
+
+func TestCreateOrMergeMemory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		first, merged := createOrMerge(t, s, models.CreateMemoryRequest{
+			URL: "https://example.com/a?utm_source=feed", Title: "A", Tags: []string{"one"}, Notes: "first",
+			Links: []models.Link{{Text: "x", Href: "https://x.example/"}},
+		})
+		if merged {
+			t.Fatal("first capture: expected a new memory")
+		}
+
+		second, merged := createOrMerge(t, s, models.CreateMemoryRequest{
+			URL: "https://www.example.com/a/", Title: "A again", Tags: []string{"Two", "one"}, Notes: "second",
+			Links: []models.Link{{Text: "x", Href: "https://x.example/"}, {Text: "y", Href: "https://y.example/"}},
+		})
+		if !merged || second.ID != first.ID {
+			t.Fatalf("same page: expected a merge into %s, got %s (merged %v)", first.ID, second.ID, merged)
+		}
+		if second.Title != "A" || second.Notes.String != "first\n\nsecond" || !reflect.DeepEqual(second.Tags, []string{"one", "two"}) {
+			t.Errorf("merge: got title %q, notes %q, tags %v", second.Title, second.Notes.String, second.Tags)
+		}
+		if len(second.Links) != 2 {
+			t.Errorf("merge: expected unseen links to be added once, got %+v", second.Links)
+		}
+
+		// Selections of a page only merge when they share their text
+		selection, merged := createOrMerge(t, s, models.CreateMemoryRequest{
+			URL: "https://example.com/a", Title: "Quote", ContentType: "selection", SelectedText: "some words",
+		})
+		if merged || selection.ID == first.ID {
+			t.Error("selection of a captured page: expected a new memory")
+		}
+		if again, merged := createOrMerge(t, s, models.CreateMemoryRequest{
+			URL: "https://example.com/a", Title: "Quote", ContentType: "selection", SelectedText: "Some   words",
+		}); !merged || again.ID != selection.ID {
+			t.Error("same selection: expected a merge")
+		}
+
+		// Another user's memories and memories in the trash are not duplicates
+		theirs, _, err := s.CreateOrMergeMemory(ctx, intruderID, models.CreateMemoryRequest{URL: "https://example.com/a", Title: "Theirs", ContentType: "page"})
+		if err != nil || theirs.ID == first.ID {
+			t.Errorf("another user: expected a memory of their own, got %s, %v", theirs.ID, err)
+		}
+		if err := s.DeleteMemory(ctx, ownerID, first.ID); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+		if _, merged := createOrMerge(t, s, models.CreateMemoryRequest{URL: "https://example.com/a", Title: "A"}); merged {
+			t.Error("page in the trash: expected a new memory")
+		}
+	})
+}
+
+func TestCapturesWithoutAPageURLAreNotMerged(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		for _, url := range []string{"unknown", "about:blank", "chrome://newtab"} {
+			req := models.CreateMemoryRequest{URL: url, Title: "Note", ContentType: "page"}
+			first, _ := createOrMerge(t, s, req)
+			second, merged := createOrMerge(t, s, req)
+			if merged || second.ID == first.ID {
+				t.Errorf("%s: expected a new memory for every capture", url)
+			}
+			if first.CanonicalURL.Valid {
+				t.Errorf("%s: expected no canonical URL, got %q", url, first.CanonicalURL.String)
+			}
+		}
+
+		groups, err := s.ListDuplicates(context.Background(), ownerID, 10)
+		if err != nil {
+			t.Fatalf("list duplicates failed: %v", err)
+		}
+		if len(groups) != 0 {
+			t.Errorf("expected no duplicate groups, got %+v", groups)
+		}
+	})
+}
+
+func TestConcurrentMergesCreateOneMemory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		const captures = 8
+		var wg sync.WaitGroup
+		errs := make(chan error, captures)
+		for i := 0; i < captures; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, err := s.CreateOrMergeMemory(context.Background(), ownerID, models.CreateMemoryRequest{
+					URL: "https://example.com/race", Title: "Race", ContentType: "page", ScrapedAt: time.Now(),
+				})
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("concurrent capture failed: %v", err)
+			}
+		}
+
+		page, err := s.ListMemories(context.Background(), ownerID, models.MemoryQueryParams{Limit: 20})
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		if len(page.Memories) != 1 {
+			t.Errorf("expected the captures to merge into one memory, got %d", len(page.Memories))
+		}
+	})
+}
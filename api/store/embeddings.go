@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"api/models"
+)
+
+// embedJobKind is jobs.KindEmbed; pending jobs of this kind keep UnembeddedMemories
+// from listing a memory twice
+const embedJobKind = "embed_memory"
+
+// scoredMemory is a memory ID ranked by similarity to a query vector
+type scoredMemory struct {
+	id         string
+	similarity float64
+}
+
+// requireEmbeddings returns ErrEmbeddingsUnavailable unless the memory_embeddings table
+// exists; migration 0011 skips it on Postgres without pgvector. Only its presence is
+// cached, so creating the table once pgvector is installed takes effect without a restart.
+func (s *SQLStore) requireEmbeddings(ctx context.Context) error {
+	if atomic.LoadInt32(&s.embeddingsReady) == 1 {
+		return nil
+	}
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, s.dialect.tableExists("$1"), "memory_embeddings").Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrEmbeddingsUnavailable
+	}
+	atomic.StoreInt32(&s.embeddingsReady, 1)
+	return nil
+}
+
+// SaveEmbedding stores the vector of a memory, replacing any previous one.
+// sourceHash identifies the embedded text; see EmbeddingHash.
+func (s *SQLStore) SaveEmbedding(ctx context.Context, memoryID, model string, vector []float32, sourceHash string) error {
+	if err := s.requireEmbeddings(ctx); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO memory_embeddings (memory_id, model, dimensions, embedding, source_hash, embedded_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (memory_id) DO UPDATE SET model = excluded.model, dimensions = excluded.dimensions,
+			embedding = excluded.embedding, source_hash = excluded.source_hash, embedded_at = excluded.embedded_at`,
+		memoryID, model, len(vector), s.dialect.encodeVector(vector), sourceHash, time.Now().UTC())
+	return err
+}
+
+// EmbeddingHash returns the source hash of a memory's embedding by model, or "" if it has none
+func (s *SQLStore) EmbeddingHash(ctx context.Context, memoryID, model string) (string, error) {
+	if err := s.requireEmbeddings(ctx); err != nil {
+		return "", err
+	}
+	var hash string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT source_hash FROM memory_embeddings WHERE memory_id = $1 AND model = $2", memoryID, model).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return hash, err
+}
+
+// UnembeddedMemories lists up to limit memories outside the trash, across all users, that have no
+// embedding by model and no embed job that is pending or dead
+func (s *SQLStore) UnembeddedMemories(ctx context.Context, model string, limit int) ([]models.MemoryRef, error) {
+	if err := s.requireEmbeddings(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT m.user_id, m.id FROM memories m
+		WHERE m.user_id IS NOT NULL AND m.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM memory_embeddings e WHERE e.memory_id = m.id AND e.model = $1)
+			AND NOT EXISTS (SELECT 1 FROM jobs j WHERE j.memory_id = m.id AND j.kind = $2 AND j.status <> $3)
+		ORDER BY m.created_at LIMIT $4`,
+		model, embedJobKind, models.JobSucceeded, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := []models.MemoryRef{}
+	for rows.Next() {
+		var ref models.MemoryRef
+		if err := rows.Scan(&ref.UserID, &ref.MemoryID); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// SemanticSearch returns the user's memories embedded by q.Model that are most similar to
+// q.Vector, best first, with their similarity set. Memories without an embedding are not found.
+func (s *SQLStore) SemanticSearch(ctx context.Context, userID string, q models.VectorQuery) ([]models.MemoryResponse, error) {
+	if err := s.requireEmbeddings(ctx); err != nil {
+		return nil, err
+	}
+	q.Filter.Query = ""
+	sel, err := s.searchSelect(userID, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append(sel.args, q.Model)
+	from := sel.from + " JOIN memory_embeddings ON memory_embeddings.memory_id = memories.id"
+	where := sel.where + " AND memory_embeddings.model = $" + strconv.Itoa(len(args)) +
+		" AND memory_embeddings.dimensions = " + strconv.Itoa(len(q.Vector))
+
+	var scored []scoredMemory
+	if distance := s.dialect.vectorDistance("$"+strconv.Itoa(len(args)+1), len(q.Vector)); distance != "" {
+		args = append(args, s.dialect.encodeVector(q.Vector), 1-q.MinSimilarity, q.Limit)
+		query := "SELECT memories.id, 1 - " + distance + " FROM " + from + " WHERE " + where +
+			" AND " + distance + " <= $" + strconv.Itoa(len(args)-1) +
+			" ORDER BY " + distance + " LIMIT $" + strconv.Itoa(len(args))
+		scored, err = s.scoredMemories(ctx, query, args...)
+	} else {
+		scored, err = s.rankInGo(ctx, "SELECT memories.id, memory_embeddings.embedding FROM "+from+" WHERE "+where, args, q)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.scoredMemoryResponses(ctx, userID, scored)
+}
+
+// scoredMemories runs a query selecting memory IDs with their similarity
+func (s *SQLStore) scoredMemories(ctx context.Context, query string, args ...interface{}) ([]scoredMemory, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scored := []scoredMemory{}
+	for rows.Next() {
+		var memory scoredMemory
+		if err := rows.Scan(&memory.id, &memory.similarity); err != nil {
+			return nil, err
+		}
+		scored = append(scored, memory)
+	}
+	return scored, rows.Err()
+}
+
+// rankInGo scores every embedding selected by query against q.Vector, for engines without
+// vector support, and keeps the q.Limit best at or above q.MinSimilarity
+func (s *SQLStore) rankInGo(ctx context.Context, query string, args []interface{}, q models.VectorQuery) ([]scoredMemory, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scored := []scoredMemory{}
+	for rows.Next() {
+		var id string
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return nil, err
+		}
+		vector, err := s.dialect.decodeVector(blob)
+		if err != nil {
+			return nil, err
+		}
+		similarity := cosineSimilarity(q.Vector, vector)
+		if similarity >= q.MinSimilarity {
+			scored = append(scored, scoredMemory{id: id, similarity: similarity})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].similarity > scored[j].similarity })
+	if len(scored) > q.Limit {
+		scored = scored[:q.Limit]
+	}
+	return scored, nil
+}
+
+// scoredMemoryResponses loads the ranked memories in rank order with their similarity set
+func (s *SQLStore) scoredMemoryResponses(ctx context.Context, userID string, scored []scoredMemory) ([]models.MemoryResponse, error) {
+	ids := make([]string, len(scored))
+	for i, memory := range scored {
+		ids[i] = memory.id
+	}
+	byID, err := s.memoriesByID(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]models.MemoryResponse, 0, len(scored))
+	for _, memory := range scored {
+		response, ok := byID[memory.id]
+		if !ok {
+			continue
+		}
+		similarity := memory.similarity
+		response.Similarity = &similarity
+		ranked = append(ranked, response)
+	}
+	return ranked, nil
+}
+
+// memoriesByID loads the user's memories with the given IDs; missing IDs are left out
+func (s *SQLStore) memoriesByID(ctx context.Context, userID string, ids []string) (map[string]models.MemoryResponse, error) {
+	byID := map[string]models.MemoryResponse{}
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := []interface{}{userID}
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, id)
+	}
+
+	memories, err := s.queryMemories(ctx,
+		"SELECT "+memoryColumns+" FROM memories WHERE user_id = $1 AND "+notTrashed+" AND id IN ("+strings.Join(placeholders, ", ")+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, memory := range memories {
+		byID[memory.ID] = memory
+	}
+	return byID, nil
+}
+
+// memoryVector returns the embedding of a memory by model, or nil if it has none or the
+// database cannot store embeddings
+func (s *SQLStore) memoryVector(ctx context.Context, memoryID, model string) ([]float32, error) {
+	err := s.requireEmbeddings(ctx)
+	if errors.Is(err, ErrEmbeddingsUnavailable) {
+		// Related memories are then found by tags, domain and links alone
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	err = s.db.QueryRowContext(ctx,
+		"SELECT embedding FROM memory_embeddings WHERE memory_id = $1 AND model = $2", memoryID, model).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.dialect.decodeVector(raw)
+}
+
+// cosineSimilarity of two vectors of the same size; 0 if either is all zeros
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/models"
+)
+
+// itemColumns is the column list shared by every item SELECT; keep it in sync with scanItem
+const itemColumns = "id, user_id, title, description, status, priority, created_at, updated_at"
+
+func scanItem(row rowScanner) (models.Item, error) {
+	var item models.Item
+	err := row.Scan(&item.ID, &item.UserID, &item.Title, &item.Description,
+		&item.Status, &item.Priority, &item.CreatedAt, &item.UpdatedAt)
+	return item, err
+}
+
+// CreateItem saves an item for the user
+func (s *SQLStore) CreateItem(ctx context.Context, userID string, req models.CreateItemRequest) (models.Item, error) {
+	now := time.Now().UTC()
+	query := `INSERT INTO items (user_id, title, description, status, priority, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + itemColumns
+
+	return scanItem(s.db.QueryRowContext(ctx, query,
+		userID, req.Title, req.Description, req.Status, req.Priority, now, now))
+}
+
+// GetItem fetches a single item owned by the user
+func (s *SQLStore) GetItem(ctx context.Context, userID string, id int64) (models.Item, error) {
+	query := "SELECT " + itemColumns + " FROM items WHERE id = $1 AND user_id = $2"
+
+	item, err := scanItem(s.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return models.Item{}, ErrNotFound
+	}
+	return item, err
+}
+
+// ListItems returns the user's items matching params, newest first
+func (s *SQLStore) ListItems(ctx context.Context, userID string, params models.ItemQueryParams) ([]models.Item, error) {
+	query := "SELECT " + itemColumns + " FROM items WHERE user_id = $1"
+	args := []interface{}{userID}
+	argCount := 1
+
+	if params.Status != "" {
+		argCount++
+		query += " AND status = $" + strconv.Itoa(argCount)
+		args = append(args, params.Status)
+	}
+	if params.Priority != "" {
+		argCount++
+		query += " AND priority = $" + strconv.Itoa(argCount)
+		args = append(args, params.Priority)
+	}
+
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.Item{}
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// UpdateItem applies the non-empty fields of req to an item owned by the user
+func (s *SQLStore) UpdateItem(ctx context.Context, userID string, id int64, req models.UpdateItemRequest) (models.Item, error) {
+	updates := []string{}
+	args := []interface{}{}
+	argCount := 1
+
+	for _, field := range []struct{ column, value string }{
+		{"title", req.Title},
+		{"description", req.Description},
+		{"status", req.Status},
+		{"priority", req.Priority},
+	} {
+		if field.value != "" {
+			updates = append(updates, field.column+" = $"+strconv.Itoa(argCount))
+			args = append(args, field.value)
+			argCount++
+		}
+	}
+
+	updates = append(updates, "updated_at = $"+strconv.Itoa(argCount))
+	args = append(args, time.Now().UTC())
+	argCount++
+
+	args = append(args, id, userID)
+
+	query := "UPDATE items SET " + strings.Join(updates, ", ") +
+		" WHERE id = $" + strconv.Itoa(argCount) + " AND user_id = $" + strconv.Itoa(argCount+1)
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Item{}, err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return models.Item{}, ErrNotFound
+	}
+
+	return s.GetItem(ctx, userID, id)
+}
+
+// DeleteItem removes an item owned by the user
+func (s *SQLStore) DeleteItem(ctx context.Context, userID string, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM items WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
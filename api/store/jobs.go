@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/models"
+
+	"github.com/google/uuid"
+)
+
+// jobColumns is the column list shared by every job SELECT; keep it in sync with scanJob
+const jobColumns = `id, kind, user_id, COALESCE(memory_id, ''), COALESCE(payload, ''), status,
+	attempts, max_attempts, run_at, COALESCE(last_error, ''), created_at, updated_at`
+
+func scanJob(row rowScanner) (models.Job, error) {
+	var job models.Job
+	var payload string
+	err := row.Scan(&job.ID, &job.Kind, &job.UserID, &job.MemoryID, &payload, &job.Status,
+		&job.Attempts, &job.MaxAttempts, &job.RunAt, &job.LastError, &job.CreatedAt, &job.UpdatedAt)
+	if payload != "" {
+		job.Payload = []byte(payload)
+	}
+	return job, err
+}
+
+// EnqueueJob saves a queued job, filling in its ID and timestamps; a zero RunAt runs it now
+func (s *SQLStore) EnqueueJob(ctx context.Context, job models.Job) (models.Job, error) {
+	now := time.Now().UTC()
+	job.ID = uuid.New().String()
+	job.Status = models.JobQueued
+	job.Attempts = 0
+	job.CreatedAt, job.UpdatedAt = now, now
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO jobs (id, kind, user_id, memory_id, payload, status, attempts, max_attempts, run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		job.ID, job.Kind, job.UserID, nullString(job.MemoryID), nullString(string(job.Payload)), job.Status,
+		job.Attempts, job.MaxAttempts, job.RunAt.UTC(), now, now)
+	if err != nil {
+		return models.Job{}, err
+	}
+	return job, nil
+}
+
+// ClaimJobs marks up to limit runnable jobs as running for the length of lease and returns them,
+// oldest due first. Jobs still running after their lease expired are claimed again, so a crashed
+// worker's jobs are retried; each claim counts as an attempt.
+func (s *SQLStore) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]models.Job, error) {
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id FROM jobs WHERE (status = $1 AND run_at <= $2) OR (status = $3 AND locked_until <= $2)"+
+			" ORDER BY run_at LIMIT $4"+s.dialect.skipLocked(),
+		models.JobQueued, now, models.JobRunning, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	jobs := []models.Job{}
+	if len(ids) == 0 {
+		return jobs, nil
+	}
+
+	updatePlaceholders := make([]string, len(ids))
+	selectPlaceholders := make([]string, len(ids))
+	updateArgs := []interface{}{models.JobRunning, now.Add(lease), now}
+	selectArgs := []interface{}{}
+	for i, id := range ids {
+		updatePlaceholders[i] = "$" + strconv.Itoa(i+4)
+		selectPlaceholders[i] = "$" + strconv.Itoa(i+1)
+		updateArgs = append(updateArgs, id)
+		selectArgs = append(selectArgs, id)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE jobs SET status = $1, attempts = attempts + 1, locked_until = $2, updated_at = $3"+
+			" WHERE id IN ("+strings.Join(updatePlaceholders, ", ")+")", updateArgs...); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx,
+		"SELECT "+jobColumns+" FROM jobs WHERE id IN ("+strings.Join(selectPlaceholders, ", ")+") ORDER BY run_at", selectArgs...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, tx.Commit()
+}
+
+// FinishJob stores the outcome of a claimed job: its new Status, RunAt and LastError.
+// Outcomes for jobs that are no longer running, because their lease expired and another
+// worker claimed them, are dropped.
+func (s *SQLStore) FinishJob(ctx context.Context, job models.Job) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE jobs SET status = $1, run_at = $2, last_error = $3, locked_until = NULL, updated_at = $4"+
+			" WHERE id = $5 AND status = $6 AND attempts = $7",
+		job.Status, job.RunAt.UTC(), nullString(job.LastError), time.Now().UTC(), job.ID, models.JobRunning, job.Attempts)
+	return err
+}
+
+// ListJobs returns the user's jobs matching params, newest first
+func (s *SQLStore) ListJobs(ctx context.Context, userID string, params models.JobQueryParams) ([]models.Job, error) {
+	where := "user_id = $1"
+	args := []interface{}{userID}
+	for _, filter := range []struct{ column, value string }{
+		{"status", params.Status},
+		{"kind", params.Kind},
+		{"memory_id", params.MemoryID},
+	} {
+		if filter.value != "" {
+			args = append(args, filter.value)
+			where += " AND " + filter.column + " = $" + strconv.Itoa(len(args))
+		}
+	}
+	args = append(args, params.Limit, params.Offset)
+
+	query := "SELECT " + jobColumns + " FROM jobs WHERE " + where +
+		" ORDER BY created_at DESC, id DESC LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// CountJobs returns how many of the user's jobs are in each status
+func (s *SQLStore) CountJobs(ctx context.Context, userID string) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM jobs WHERE user_id = $1 GROUP BY status", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// PruneJobs deletes succeeded jobs last updated before the given time; dead jobs are kept
+func (s *SQLStore) PruneJobs(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM jobs WHERE status = $1 AND updated_at < $2", models.JobSucceeded, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	pruned, _ := result.RowsAffected()
+	return int(pruned), nil
+}
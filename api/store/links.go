@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"api/models"
+)
+
+// linkColumns is the column list shared by every link SELECT; keep it in sync with scanLink
+const linkColumns = `l.id, l.memory_id, COALESCE(l.text, ''), l.href, COALESCE(l.link_title, ''), COALESCE(l.domain, ''), l.created_at`
+
+func scanLink(row rowScanner) (models.LinkResponse, error) {
+	var link models.LinkResponse
+	err := row.Scan(&link.ID, &link.MemoryID, &link.Text, &link.Href, &link.Title, &link.Domain, &link.CreatedAt)
+	return link, err
+}
+
+// attachLinks loads the links of every memory with a single query
+func (s *SQLStore) attachLinks(ctx context.Context, memories []models.MemoryResponse) error {
+	if len(memories) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(memories))
+	placeholders := make([]string, 0, len(memories))
+	args := make([]interface{}, 0, len(memories))
+	for i, memory := range memories {
+		index[memory.ID] = i
+		placeholders = append(placeholders, "$"+strconv.Itoa(i+1))
+		args = append(args, memory.ID)
+	}
+
+	query := "SELECT " + linkColumns + " FROM links l WHERE l.memory_id IN (" + strings.Join(placeholders, ", ") + ")" +
+		" ORDER BY l.position, l.id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return err
+		}
+		i := index[link.MemoryID]
+		memories[i].Links = append(memories[i].Links, link)
+	}
+
+	return rows.Err()
+}
+
+// ListLinks returns links across all of the user's memories outside the trash, newest first
+func (s *SQLStore) ListLinks(ctx context.Context, userID string, params models.LinkQueryParams) ([]models.LinkResponse, error) {
+	query := "SELECT " + linkColumns + " FROM links l JOIN memories m ON m.id = l.memory_id WHERE m.user_id = $1 AND m.deleted_at IS NULL"
+	args := []interface{}{userID}
+	argCount := 2
+
+	if params.Domain != "" {
+		domain := normalizeDomain(params.Domain)
+		query += " AND (l.domain = $" + strconv.Itoa(argCount) + " OR l.domain LIKE $" + strconv.Itoa(argCount+1) + ")"
+		args = append(args, domain, "%."+domain)
+		argCount += 2
+	}
+
+	query += " ORDER BY l.created_at DESC, l.id LIMIT $" + strconv.Itoa(argCount) + " OFFSET $" + strconv.Itoa(argCount+1)
+	args = append(args, params.Limit, params.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.LinkResponse{}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// ListBacklinks returns the user's memories containing a link to params.URL, newest first
+func (s *SQLStore) ListBacklinks(ctx context.Context, userID string, params models.LinkQueryParams) ([]models.MemoryResponse, error) {
+	withoutSlash, withSlash := urlVariants(params.URL)
+
+	query := "SELECT " + memoryColumns + " FROM memories WHERE user_id = $1 AND " + notTrashed +
+		" AND id IN (SELECT memory_id FROM links WHERE href IN ($2, $3))" +
+		" ORDER BY created_at DESC LIMIT $4 OFFSET $5"
+
+	return s.queryMemories(ctx, query, userID, withoutSlash, withSlash, params.Limit, params.Offset)
+}
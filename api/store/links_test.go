@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"api/models"
+)
+
+func TestLinksAreStoredWithMemories(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		memory := createMemory(t, s, ownerID, models.CreateMemoryRequest{
+			Title: "Reading list",
+			Links: []models.Link{
+				{Text: "Go", Href: "https://WWW.Go.dev/doc", Title: "Docs"},
+				{Text: "Blog", Href: "https://blog.golang.org/"},
+				{Text: "Relative", Href: "/about"},
+			},
+		})
+
+		got, err := s.GetMemory(ctx, ownerID, memory.ID)
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		domains := []string{}
+		for _, link := range got.Links {
+			domains = append(domains, link.Domain)
+			if link.MemoryID != memory.ID {
+				t.Errorf("link %s belongs to %s, want %s", link.Href, link.MemoryID, memory.ID)
+			}
+		}
+		if want := []string{"go.dev", "blog.golang.org", ""}; !reflect.DeepEqual(domains, want) {
+			t.Errorf("expected links in capture order with domains %v, got %v", want, domains)
+		}
+		if got.Links[0].Title != "Docs" || got.Links[0].Text != "Go" {
+			t.Errorf("link text and title did not round-trip: %+v", got.Links[0])
+		}
+	})
+}
+
+func TestListLinksFiltersByDomain(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Go", Links: []models.Link{
+			{Href: "https://go.dev/doc"},
+			{Href: "https://pkg.go.dev/net/http"},
+			{Href: "https://notgo.dev/"},
+		}})
+		createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Rust", Links: []models.Link{{Href: "https://www.rust-lang.org/"}}})
+		createMemory(t, s, intruderID, models.CreateMemoryRequest{Title: "Theirs", Links: []models.Link{{Href: "https://go.dev/blog"}}})
+
+		hrefs := func(params models.LinkQueryParams) []string {
+			t.Helper()
+			links, err := s.ListLinks(ctx, ownerID, params)
+			if err != nil {
+				t.Fatalf("list links %+v failed: %v", params, err)
+			}
+			list := []string{}
+			for _, link := range links {
+				list = append(list, link.Href)
+			}
+			return list
+		}
+
+		if got := hrefs(models.LinkQueryParams{Limit: 10}); len(got) != 4 {
+			t.Errorf("all links: expected the owner's 4 links, got %v", got)
+		}
+		for _, domain := range []string{"go.dev", "https://GO.dev/anything", "www.go.dev"} {
+			got := hrefs(models.LinkQueryParams{Domain: domain, Limit: 10})
+			if !sameIDs(got, []string{"https://go.dev/doc", "https://pkg.go.dev/net/http"}) {
+				t.Errorf("domain %q: expected go.dev and its subdomains, got %v", domain, got)
+			}
+		}
+		if got := hrefs(models.LinkQueryParams{Domain: "rust-lang.org", Limit: 10}); !reflect.DeepEqual(got, []string{"https://www.rust-lang.org/"}) {
+			t.Errorf("domain without www: got %v", got)
+		}
+		if got := hrefs(models.LinkQueryParams{Limit: 2, Offset: 3}); len(got) != 1 {
+			t.Errorf("offset past the first page: expected 1 link, got %v", got)
+		}
+	})
+}
+
+func TestListBacklinks(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		slash := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Slash", Links: []models.Link{{Href: "https://go.dev/doc/"}}})
+		bare := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Bare", Links: []models.Link{{Href: "https://go.dev/doc"}}})
+		createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Other", Links: []models.Link{{Href: "https://go.dev/doc/faq"}}})
+		createMemory(t, s, intruderID, models.CreateMemoryRequest{Title: "Theirs", Links: []models.Link{{Href: "https://go.dev/doc"}}})
+		trashed := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Trashed", Links: []models.Link{{Href: "https://go.dev/doc"}}})
+		if err := s.DeleteMemory(ctx, ownerID, trashed.ID); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+
+		for _, url := range []string{"https://go.dev/doc", "https://go.dev/doc/"} {
+			memories, err := s.ListBacklinks(ctx, ownerID, models.LinkQueryParams{URL: url, Limit: 10})
+			if err != nil {
+				t.Fatalf("backlinks of %s failed: %v", url, err)
+			}
+			if got := memoryIDs(memories); !sameIDs(got, []string{slash.ID, bare.ID}) {
+				t.Errorf("backlinks of %s: expected %v, got %v", url, []string{slash.ID, bare.ID}, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,1706 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"api/models"
+
+	"github.com/google/uuid"
+)
+
+// InMemoryStore is a MemoryStore kept entirely in process memory.
+// It is intended for tests and throwaway local runs; nothing is persisted.
+type InMemoryStore struct {
+	mu          sync.RWMutex
+	memories    map[string]models.Memory
+	trash       map[string]models.Memory // memories deleted by their user, with DeletedAt set
+	links       map[string][]models.LinkResponse
+	collections map[string]models.Collection
+	members     map[string][]string // collection ID -> memory IDs in collection order
+	jobs        map[string]models.Job
+	jobLeases   map[string]time.Time // job ID -> end of the lease of a running job
+	embeddings  map[string]memoryEmbedding
+	scraped     map[int64]models.ScrapedData
+	items       map[int64]models.Item
+	lastID      int64 // last ID given to scraped data or an item, like a database sequence
+}
+
+// memoryEmbedding is the stored vector of a memory
+type memoryEmbedding struct {
+	model      string
+	vector     []float32
+	sourceHash string
+}
+
+// NewInMemoryStore creates an empty InMemoryStore
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		memories:    make(map[string]models.Memory),
+		trash:       make(map[string]models.Memory),
+		links:       make(map[string][]models.LinkResponse),
+		collections: make(map[string]models.Collection),
+		members:     make(map[string][]string),
+		jobs:        make(map[string]models.Job),
+		jobLeases:   make(map[string]time.Time),
+		embeddings:  make(map[string]memoryEmbedding),
+		scraped:     make(map[int64]models.ScrapedData),
+		items:       make(map[int64]models.Item),
+	}
+}
+
+// CreateMemory stores a new memory for the user
+func (s *InMemoryStore) CreateMemory(ctx context.Context, userID string, req models.CreateMemoryRequest) (models.MemoryResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insertMemory(userID, req), nil
+}
+
+// insertMemory stores a new memory for the user; callers must hold s.mu for writing
+func (s *InMemoryStore) insertMemory(userID string, req models.CreateMemoryRequest) models.MemoryResponse {
+	now := time.Now()
+	memory := models.Memory{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		URL:           nullString(req.URL),
+		Title:         req.Title,
+		ContentType:   req.ContentType,
+		Content:       nullString(req.Content),
+		SelectedText:  nullString(req.SelectedText),
+		ContextBefore: nullString(req.ContextBefore),
+		ContextAfter:  nullString(req.ContextAfter),
+		FullContext:   nullString(req.FullContext),
+		ElementType:   nullString(req.ElementType),
+		PageSection:   nullString(req.PageSection),
+		XPath:         nullString(req.XPath),
+		TagsString:    nullString(strings.Join(normalizeTags(req.Tags), ",")),
+		Notes:         nullString(req.Notes),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		ScrapedAt:     req.ScrapedAt,
+		CanonicalURL:  nullString(CanonicalURL(req.URL)),
+		ContentHash:   nullString(contentHash(req.SelectedText, req.Content, req.VideoData)),
+		Truncated:     req.Truncated,
+	}
+	setArticle(&memory, req.Article)
+
+	if req.VideoData != nil {
+		memory.VideoPlatform = sql.NullString{String: req.VideoData.Platform, Valid: true}
+		memory.VideoTimestamp = sql.NullInt64{Int64: req.VideoData.Timestamp, Valid: true}
+		memory.VideoDuration = sql.NullInt64{Int64: req.VideoData.Duration, Valid: true}
+		memory.VideoTitle = sql.NullString{String: req.VideoData.VideoTitle, Valid: true}
+		memory.VideoURL = sql.NullString{String: req.VideoData.VideoURL, Valid: true}
+		memory.ThumbnailURL = sql.NullString{String: req.VideoData.ThumbnailURL, Valid: true}
+		memory.FormattedTime = sql.NullString{String: req.VideoData.FormattedTimestamp, Valid: true}
+	}
+
+	s.memories[memory.ID] = memory
+	for _, link := range req.Links {
+		s.links[memory.ID] = append(s.links[memory.ID], models.LinkResponse{
+			ID:        uuid.New().String(),
+			MemoryID:  memory.ID,
+			Text:      link.Text,
+			Href:      link.Href,
+			Title:     link.Title,
+			Domain:    linkDomain(link.Href),
+			CreatedAt: now,
+		})
+	}
+
+	return s.response(memory)
+}
+
+// GetMemory fetches a single memory owned by the user
+func (s *InMemoryStore) GetMemory(ctx context.Context, userID, id string) (models.MemoryResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	memory, ok := s.memories[id]
+	if !ok || memory.UserID != userID {
+		return models.MemoryResponse{}, ErrNotFound
+	}
+
+	return s.response(memory), nil
+}
+
+// ListMemories returns a page of the user's memories, newest first
+func (s *InMemoryStore) ListMemories(ctx context.Context, userID string, params models.MemoryQueryParams) (models.MemoryPage, error) {
+	search := strings.ToLower(params.Search)
+	filterTags := normalizeTags([]string{params.Tags})
+	page := pageRequest{Limit: params.Limit, Offset: params.Offset, Cursor: params.Cursor, IncludeTotal: params.IncludeTotal}
+
+	return s.page(userID, page, nil, func(m models.Memory) bool {
+		if params.ContentType != "" && m.ContentType != params.ContentType {
+			return false
+		}
+		if params.Platform != "" && m.VideoPlatform.String != params.Platform {
+			return false
+		}
+		if !hasTags(m, filterTags) {
+			return false
+		}
+		if params.CollectionID != "" && !s.inCollection(params.CollectionID, m.ID) {
+			return false
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(m.Title), search) &&
+			!strings.Contains(strings.ToLower(m.Content.String), search) &&
+			!strings.Contains(strings.ToLower(m.SelectedText.String), search) {
+			return false
+		}
+		return true
+	})
+}
+
+// SearchMemories matches the websearch-style query against title, content, selection and tags,
+// most relevant first
+func (s *InMemoryStore) SearchMemories(ctx context.Context, userID string, req models.SearchRequest) (models.MemoryPage, error) {
+	search, match, err := s.searchMatch(req)
+	if err != nil {
+		return models.MemoryPage{}, err
+	}
+
+	page := pageRequest{Limit: req.Limit, Offset: req.Offset, Cursor: req.Cursor, IncludeTotal: req.IncludeTotal}
+	return s.page(userID, page, search, match)
+}
+
+// searchMatch translates the filters of req into a match function and, for a query, its ranking;
+// pagination fields are ignored
+func (s *InMemoryStore) searchMatch(req models.SearchRequest) (*memorySearch, func(models.Memory) bool, error) {
+	start, err := parseDateFilter(req.StartDate)
+	if err != nil {
+		return nil, nil, err
+	}
+	end, err := parseDateFilter(req.EndDate)
+	if err != nil {
+		return nil, nil, err
+	}
+	alternatives := parseWebSearch(req.Query)
+	filterTags := normalizeTags(req.Tags)
+
+	var search *memorySearch
+	if req.Query != "" {
+		search = &memorySearch{
+			rank: func(m models.Memory) (float64, bool) {
+				return webSearchRank(alternatives, m)
+			},
+			highlights: func(m models.Memory) map[string]string {
+				return webSearchHighlights(alternatives, m)
+			},
+		}
+	}
+
+	return search, func(m models.Memory) bool {
+		if req.ContentType != "" && m.ContentType != req.ContentType {
+			return false
+		}
+		if req.Platform != "" && m.VideoPlatform.String != req.Platform {
+			return false
+		}
+		if !hasTags(m, filterTags) {
+			return false
+		}
+		if req.CollectionID != "" && !s.inCollection(req.CollectionID, m.ID) {
+			return false
+		}
+		if !start.IsZero() && m.CreatedAt.Before(start) {
+			return false
+		}
+		if !end.IsZero() && m.CreatedAt.After(end) {
+			return false
+		}
+		if search != nil {
+			_, ok := search.rank(m)
+			return ok
+		}
+		return true
+	}, nil
+}
+
+// UpdateMemory applies the non-empty fields of req to a memory owned by the user
+func (s *InMemoryStore) UpdateMemory(ctx context.Context, userID, id string, req models.UpdateMemoryRequest, checks ...MemoryCheck) (models.MemoryResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memory, ok := s.memories[id]
+	if !ok || memory.UserID != userID {
+		return models.MemoryResponse{}, ErrNotFound
+	}
+	if err := s.checkMemory(memory, checks); err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	if req.Title != "" {
+		memory.Title = req.Title
+	}
+	if len(req.Tags) > 0 {
+		memory.TagsString = nullString(strings.Join(normalizeTags(req.Tags), ","))
+	}
+	if req.Notes != "" {
+		memory.Notes = nullString(req.Notes)
+	}
+	if req.ContentType != "" {
+		memory.ContentType = req.ContentType
+	}
+	memory.UpdatedAt = time.Now()
+
+	s.memories[id] = memory
+	return s.response(memory), nil
+}
+
+// checkMemory runs checks on a memory; the caller holds the lock
+func (s *InMemoryStore) checkMemory(memory models.Memory, checks []MemoryCheck) error {
+	for _, check := range checks {
+		if err := check(s.response(memory)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PatchMemory replaces the editable fields of a memory owned by the user with those patch
+// returns for the current memory. patch runs with the store locked and must not call it.
+func (s *InMemoryStore) PatchMemory(ctx context.Context, userID, id string, patch func(models.MemoryResponse) (models.MemoryEdit, error)) (models.MemoryResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memory, ok := s.memories[id]
+	if !ok || memory.UserID != userID {
+		return models.MemoryResponse{}, ErrNotFound
+	}
+
+	edit, err := patch(s.response(memory))
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	video := videoColumns(edit.VideoData)
+	memory.Title = edit.Title
+	memory.URL = nullString(edit.URL)
+	memory.ContentType = edit.ContentType
+	memory.Content = nullString(edit.Content)
+	memory.SelectedText = nullString(edit.SelectedText)
+	memory.ContextBefore = nullString(edit.ContextBefore)
+	memory.ContextAfter = nullString(edit.ContextAfter)
+	memory.Notes = nullString(edit.Notes)
+	memory.TagsString = nullString(strings.Join(normalizeTags(edit.Tags), ","))
+	memory.VideoPlatform, memory.VideoTimestamp, memory.VideoDuration = video.Platform, video.Timestamp, video.Duration
+	memory.VideoTitle, memory.VideoURL, memory.ThumbnailURL, memory.FormattedTime = video.Title, video.URL, video.ThumbnailURL, video.FormattedTime
+	memory.CanonicalURL = nullString(CanonicalURL(edit.URL))
+	memory.ContentHash = nullString(contentHash(edit.SelectedText, edit.Content, edit.VideoData))
+	memory.UpdatedAt = time.Now()
+
+	s.memories[id] = memory
+	return s.response(memory), nil
+}
+
+// DeleteMemory moves a memory owned by the user to the trash; its links, collection places
+// and embedding are kept for a restore until it is purged
+func (s *InMemoryStore) DeleteMemory(ctx context.Context, userID, id string, checks ...MemoryCheck) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memory, ok := s.memories[id]
+	if !ok || memory.UserID != userID {
+		return ErrNotFound
+	}
+	if err := s.checkMemory(memory, checks); err != nil {
+		return err
+	}
+
+	s.moveToTrash(memory, time.Now())
+	return nil
+}
+
+// moveToTrash takes a memory out of s.memories, which every read uses; callers must hold s.mu
+func (s *InMemoryStore) moveToTrash(memory models.Memory, now time.Time) {
+	memory.DeletedAt = sql.NullTime{Time: now, Valid: true}
+	delete(s.memories, memory.ID)
+	s.trash[memory.ID] = memory
+}
+
+// ListTrash returns the user's memories in the trash, most recently deleted first
+func (s *InMemoryStore) ListTrash(ctx context.Context, userID string, limit, offset int) ([]models.MemoryResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trashed := []models.Memory{}
+	for _, memory := range s.trash {
+		if memory.UserID == userID {
+			trashed = append(trashed, memory)
+		}
+	}
+	sort.Slice(trashed, func(i, j int) bool {
+		if !trashed[i].DeletedAt.Time.Equal(trashed[j].DeletedAt.Time) {
+			return trashed[i].DeletedAt.Time.After(trashed[j].DeletedAt.Time)
+		}
+		return trashed[i].ID < trashed[j].ID
+	})
+
+	memories := []models.MemoryResponse{}
+	for _, memory := range paginate(trashed, limit, offset) {
+		memories = append(memories, s.response(memory))
+	}
+	return memories, nil
+}
+
+// RestoreMemory takes a memory owned by the user out of the trash
+func (s *InMemoryStore) RestoreMemory(ctx context.Context, userID, id string) (models.MemoryResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memory, ok := s.trash[id]
+	if !ok || memory.UserID != userID {
+		return models.MemoryResponse{}, ErrNotFound
+	}
+
+	memory.DeletedAt = sql.NullTime{}
+	memory.UpdatedAt = time.Now()
+	delete(s.trash, id)
+	s.memories[id] = memory
+	return s.response(memory), nil
+}
+
+// PurgeMemory deletes a memory in the user's trash for good, with its links, collection
+// places, embedding and jobs
+func (s *InMemoryStore) PurgeMemory(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memory, ok := s.trash[id]
+	if !ok || memory.UserID != userID {
+		return ErrNotFound
+	}
+	s.purge(id)
+	return nil
+}
+
+// EmptyTrash deletes every memory in the user's trash for good and returns how many there were
+func (s *InMemoryStore) EmptyTrash(ctx context.Context, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, memory := range s.trash {
+		if memory.UserID == userID {
+			s.purge(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// PurgeTrash deletes the memories of every user that were moved to the trash before the given
+// time for good and returns how many there were
+func (s *InMemoryStore) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, memory := range s.trash {
+		if memory.DeletedAt.Time.Before(before) {
+			s.purge(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// purge deletes a memory in the trash and everything that refers to it; callers must hold s.mu
+func (s *InMemoryStore) purge(id string) {
+	delete(s.trash, id)
+	delete(s.links, id)
+	for collectionID, members := range s.members {
+		s.members[collectionID] = removeMembers(members, []string{id})
+	}
+	delete(s.embeddings, id)
+	for jobID, job := range s.jobs {
+		if job.MemoryID == id {
+			delete(s.jobs, jobID)
+			delete(s.jobLeases, jobID)
+		}
+	}
+}
+
+// MemoryURLs returns the set of canonical URLs (see CanonicalURL) the user has saved memories for
+func (s *InMemoryStore) MemoryURLs(ctx context.Context, userID string) (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	urls := map[string]bool{}
+	for _, memory := range s.memories {
+		if memory.UserID == userID && memory.CanonicalURL.String != "" {
+			urls[memory.CanonicalURL.String] = true
+		}
+	}
+	return urls, nil
+}
+
+// CreateOrMergeMemory folds req into the user's newest memory it would duplicate, or creates a
+// memory when there is none, reporting whether it merged
+func (s *InMemoryStore) CreateOrMergeMemory(ctx context.Context, userID string, req models.CreateMemoryRequest) (models.MemoryResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.findDuplicate(userID, req); ok {
+		return s.mergeMemory(id, req), true, nil
+	}
+	return s.insertMemory(userID, req), false, nil
+}
+
+// findDuplicate returns the ID of the user's newest memory that req would duplicate; callers must hold s.mu
+func (s *InMemoryStore) findDuplicate(userID string, req models.CreateMemoryRequest) (string, bool) {
+	canonical := CanonicalURL(req.URL)
+	if canonical == "" {
+		return "", false
+	}
+	hash := contentHash(req.SelectedText, req.Content, req.VideoData)
+
+	matches := s.sorted(userID, func(m models.Memory) bool {
+		return m.CanonicalURL.String == canonical && m.ContentType == req.ContentType &&
+			(!matchesByContent(req.ContentType) || m.ContentHash.String == hash)
+	})
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[0].ID, true
+}
+
+// mergeMemory folds a new capture into an existing memory; callers must hold s.mu for writing
+func (s *InMemoryStore) mergeMemory(id string, req models.CreateMemoryRequest) models.MemoryResponse {
+	existing := s.memories[id]
+	now := time.Now()
+	merged := mergeCapture(existing, req)
+	replace := func(field *sql.NullString, value string) {
+		if value != "" {
+			*field = nullString(value)
+		}
+	}
+	replace(&merged.ContextBefore, req.ContextBefore)
+	replace(&merged.ContextAfter, req.ContextAfter)
+	replace(&merged.FullContext, req.FullContext)
+	replace(&merged.ElementType, req.ElementType)
+	replace(&merged.PageSection, req.PageSection)
+	replace(&merged.XPath, req.XPath)
+	setArticle(&merged, req.Article)
+	if len(req.Truncated) > 0 {
+		merged.Truncated = req.Truncated
+	}
+	merged.TagsString = nullString(strings.Join(normalizeTags(append(splitTags(existing.TagsString.String), req.Tags...)), ","))
+	merged.UpdatedAt = now
+	s.memories[id] = merged
+
+	hrefs := map[string]bool{}
+	for _, link := range s.links[id] {
+		hrefs[link.Href] = true
+	}
+	for _, link := range req.Links {
+		if !hrefs[link.Href] {
+			hrefs[link.Href] = true
+			s.links[id] = append(s.links[id], models.LinkResponse{
+				ID: uuid.New().String(), MemoryID: id, Text: link.Text, Href: link.Href,
+				Title: link.Title, Domain: linkDomain(link.Href), CreatedAt: now,
+			})
+		}
+	}
+
+	return s.response(merged)
+}
+
+// setArticle copies the non-empty columns of an extracted article onto a memory
+func setArticle(memory *models.Memory, article *models.Article) {
+	columns := articleColumns(article)
+	for _, field := range []struct{ to, from *sql.NullString }{
+		{&memory.ContentHTML, &columns.ContentHTML},
+		{&memory.Byline, &columns.Byline},
+		{&memory.LeadImageURL, &columns.LeadImageURL},
+		{&memory.Language, &columns.Language},
+	} {
+		if field.from.Valid {
+			*field.to = *field.from
+		}
+	}
+	if columns.PublishedAt.Valid {
+		memory.PublishedAt = columns.PublishedAt
+	}
+}
+
+// ListDuplicates groups the user's likely duplicate memories, largest groups first
+func (s *InMemoryStore) ListDuplicates(ctx context.Context, userID string, limit int) ([]models.DuplicateGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byKey := map[[2]string][]models.Memory{}
+	for _, memory := range s.sorted(userID, func(models.Memory) bool { return true }) {
+		if memory.CanonicalURL.Valid && (memory.ContentType == "page" || memory.ContentType == "links") {
+			key := [2]string{models.DuplicateByURL, memory.CanonicalURL.String}
+			byKey[key] = append(byKey[key], memory)
+		}
+		if memory.ContentHash.Valid {
+			key := [2]string{models.DuplicateByContent, memory.ContentHash.String}
+			byKey[key] = append(byKey[key], memory)
+		}
+	}
+
+	groups := []models.DuplicateGroup{}
+	sizes := map[string]int{}
+	latest := map[string]time.Time{}
+	for key, memories := range byKey {
+		if len(memories) < 2 {
+			continue
+		}
+		group := models.DuplicateGroup{Reason: key[0], Key: key[1], Memories: []models.MemoryResponse{}}
+		// sorted is newest first; groups list the oldest first
+		for i := len(memories) - 1; i >= 0; i-- {
+			group.Memories = append(group.Memories, s.response(memories[i]))
+		}
+		sizes[key[1]], latest[key[1]] = len(memories), memories[0].CreatedAt
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i].Key, groups[j].Key
+		if sizes[a] != sizes[b] {
+			return sizes[a] > sizes[b]
+		}
+		if !latest[a].Equal(latest[b]) {
+			return latest[a].After(latest[b])
+		}
+		return a < b
+	})
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
+
+// BackfillDuplicateKeys has nothing to do: every memory gets its keys when it is stored
+func (s *InMemoryStore) BackfillDuplicateKeys(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// ApplyBulk applies req.Action to every selected memory of the user while holding the write lock
+func (s *InMemoryStore) ApplyBulk(ctx context.Context, userID string, req models.BulkRequest) (models.BulkResult, error) {
+	var match func(models.Memory) bool
+	if req.Filter != nil {
+		var err error
+		if _, match, err = s.searchMatch(*req.Filter); err != nil {
+			return models.BulkResult{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := uniqueStrings(req.IDs)
+	if match != nil {
+		ids = []string{}
+		for _, m := range s.sorted(userID, match) {
+			ids = append(ids, m.ID)
+		}
+		if len(ids) > bulkLimit {
+			return models.BulkResult{}, ErrBulkLimit
+		}
+	}
+
+	if req.Action == models.BulkMoveToCollection {
+		if _, err := s.collection(userID, req.CollectionID); err != nil {
+			return models.BulkResult{}, err
+		}
+	}
+
+	now := time.Now()
+	statuses := map[string]string{}
+	for _, id := range ids {
+		memory, ok := s.memories[id]
+		if !ok || memory.UserID != userID {
+			statuses[id] = models.BulkStatusNotFound
+			continue
+		}
+
+		status := models.BulkStatusUpdated
+		switch req.Action {
+		case models.BulkDelete:
+			s.moveToTrash(memory, now)
+			status = models.BulkStatusDeleted
+
+		case models.BulkAddTags, models.BulkRemoveTags:
+			tags := strings.Join(bulkTags(req, splitTags(memory.TagsString.String)), ",")
+			if tags == memory.TagsString.String {
+				status = models.BulkStatusUnchanged
+			} else {
+				memory.TagsString = nullString(tags)
+			}
+
+		case models.BulkSetContentType:
+			if memory.ContentType == req.ContentType {
+				status = models.BulkStatusUnchanged
+			} else {
+				memory.ContentType = req.ContentType
+			}
+
+		case models.BulkMoveToCollection:
+			for collectionID, members := range s.members {
+				if collectionID != req.CollectionID {
+					s.members[collectionID] = removeMembers(members, []string{id})
+				}
+			}
+			if !s.inCollection(req.CollectionID, id) {
+				s.members[req.CollectionID] = append(s.members[req.CollectionID], id)
+			}
+		}
+
+		if status == models.BulkStatusUpdated {
+			memory.UpdatedAt = now
+			s.memories[id] = memory
+		}
+		statuses[id] = status
+	}
+
+	return bulkResult(req.Action, ids, statuses), nil
+}
+
+// MemoryStats aggregates usage statistics for the user
+func (s *InMemoryStore) MemoryStats(ctx context.Context, userID string) (models.Stats, error) {
+	stats := models.Stats{
+		ByContentType: make(map[string]int),
+		ByPlatform:    make(map[string]int),
+		MostUsedTags:  []models.TagCount{},
+	}
+	recent := time.Now().AddDate(0, 0, -7)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tags := s.tagCounts(userID)
+	stats.TotalTags = len(tags)
+	stats.MostUsedTags = paginate(tags, mostUsedTagsLimit, 0)
+
+	for _, m := range s.memories {
+		if m.UserID != userID {
+			continue
+		}
+		stats.TotalMemories++
+		stats.ByContentType[m.ContentType]++
+		if m.VideoPlatform.Valid {
+			stats.ByPlatform[m.VideoPlatform.String]++
+		}
+		if m.CreatedAt.After(recent) {
+			stats.RecentCount++
+		}
+	}
+
+	return stats, nil
+}
+
+// ListTags returns every tag in use by the user with its memory count, most used first
+func (s *InMemoryStore) ListTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.tagCounts(userID), nil
+}
+
+// RenameTag renames a tag on every memory of the user; renaming onto a tag in use fails with ErrConflict
+func (s *InMemoryStore) RenameTag(ctx context.Context, userID, from, to string) (int, error) {
+	from, to = normalizeTag(from), normalizeTag(to)
+	if from == "" || to == "" {
+		return 0, ErrInvalidTag
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.tagInUse(userID, from) {
+		return 0, ErrNotFound
+	}
+	if from != to && s.tagInUse(userID, to) {
+		return 0, ErrConflict
+	}
+	return s.editTags(userID, []string{from}, map[string]string{from: to})
+}
+
+// MergeTags replaces every source tag with target on all of the user's memories
+func (s *InMemoryStore) MergeTags(ctx context.Context, userID string, sources []string, target string) (int, error) {
+	sources, target = normalizeTags(sources), normalizeTag(target)
+	if len(sources) == 0 || target == "" {
+		return 0, ErrInvalidTag
+	}
+
+	replacements := map[string]string{}
+	for _, source := range sources {
+		replacements[source] = target
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editTags(userID, sources, replacements)
+}
+
+// DeleteTag removes a tag from all of the user's memories
+func (s *InMemoryStore) DeleteTag(ctx context.Context, userID, name string) (int, error) {
+	name = normalizeTag(name)
+	if name == "" {
+		return 0, ErrInvalidTag
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editTags(userID, []string{name}, map[string]string{name: ""})
+}
+
+// editTags applies replacements to every memory of the user tagged with one of names;
+// callers must hold s.mu for writing
+func (s *InMemoryStore) editTags(userID string, names []string, replacements map[string]string) (int, error) {
+	now := time.Now()
+	changed := 0
+	// Memories in the trash are edited too, so restoring one does not bring back an old tag
+	for _, memories := range []map[string]models.Memory{s.memories, s.trash} {
+		for id, m := range memories {
+			if m.UserID != userID || !hasAnyTag(m, names) {
+				continue
+			}
+			m.TagsString = nullString(strings.Join(normalizeTags(replaceTags(splitTags(m.TagsString.String), replacements)), ","))
+			m.UpdatedAt = now
+			memories[id] = m
+			changed++
+		}
+	}
+
+	if changed == 0 {
+		return 0, ErrNotFound
+	}
+	return changed, nil
+}
+
+// tagInUse reports whether any memory of the user, in the trash or not, carries the normalized tag;
+// callers must hold s.mu
+func (s *InMemoryStore) tagInUse(userID, tag string) bool {
+	for _, memories := range []map[string]models.Memory{s.memories, s.trash} {
+		for _, m := range memories {
+			if m.UserID == userID && hasAnyTag(m, []string{tag}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tagCounts counts the memories per tag of the user, most used first; callers must hold s.mu
+func (s *InMemoryStore) tagCounts(userID string) []models.TagCount {
+	counts := map[string]int{}
+	for _, m := range s.memories {
+		if m.UserID != userID {
+			continue
+		}
+		for _, tag := range splitTags(m.TagsString.String) {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]models.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, models.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
+
+// hasTags reports whether m carries every one of the normalized tags
+func hasTags(m models.Memory, tags []string) bool {
+	for _, tag := range tags {
+		if !hasAnyTag(m, []string{tag}) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAnyTag reports whether m carries at least one of the normalized tags
+func hasAnyTag(m models.Memory, tags []string) bool {
+	for _, own := range splitTags(m.TagsString.String) {
+		for _, tag := range tags {
+			if own == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CreateCollection creates an empty collection for the user
+func (s *InMemoryStore) CreateCollection(ctx context.Context, userID string, req models.CreateCollectionRequest) (models.Collection, error) {
+	now := time.Now()
+	collection := models.Collection{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collections[collection.ID] = collection
+	return collection, nil
+}
+
+// GetCollection fetches a single collection owned by the user
+func (s *InMemoryStore) GetCollection(ctx context.Context, userID, id string) (models.Collection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.collection(userID, id)
+}
+
+// ListCollections returns the user's collections, oldest first
+func (s *InMemoryStore) ListCollections(ctx context.Context, userID string) ([]models.Collection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	collections := []models.Collection{}
+	for id, collection := range s.collections {
+		if collection.UserID == userID {
+			collection.MemoryCount = len(s.liveMembers(id))
+			collections = append(collections, collection)
+		}
+	}
+
+	sort.Slice(collections, func(i, j int) bool {
+		if !collections[i].CreatedAt.Equal(collections[j].CreatedAt) {
+			return collections[i].CreatedAt.Before(collections[j].CreatedAt)
+		}
+		return collections[i].ID < collections[j].ID
+	})
+	return collections, nil
+}
+
+// UpdateCollection applies the non-empty fields of req to a collection owned by the user
+func (s *InMemoryStore) UpdateCollection(ctx context.Context, userID, id string, req models.UpdateCollectionRequest) (models.Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection, err := s.collection(userID, id)
+	if err != nil {
+		return models.Collection{}, err
+	}
+
+	if req.Name != "" {
+		collection.Name = req.Name
+	}
+	if req.Description != "" {
+		collection.Description = req.Description
+	}
+	collection.UpdatedAt = time.Now()
+
+	s.collections[id] = collection
+	return collection, nil
+}
+
+// DeleteCollection removes a collection owned by the user; its memories are kept
+func (s *InMemoryStore) DeleteCollection(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.collection(userID, id); err != nil {
+		return err
+	}
+
+	delete(s.collections, id)
+	delete(s.members, id)
+	return nil
+}
+
+// ListCollectionMemories returns the memories of a collection in collection order
+func (s *InMemoryStore) ListCollectionMemories(ctx context.Context, userID, id string, limit, offset int) ([]models.MemoryResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.collection(userID, id); err != nil {
+		return nil, err
+	}
+
+	memories := []models.MemoryResponse{}
+	for _, memoryID := range paginate(s.liveMembers(id), limit, offset) {
+		memories = append(memories, s.response(s.memories[memoryID]))
+	}
+	return memories, nil
+}
+
+// AddToCollection inserts memories at position (or appends them when position is nil).
+// Memories already in the collection are moved; every memory must be owned by the user.
+func (s *InMemoryStore) AddToCollection(ctx context.Context, userID, id string, memoryIDs []string, position *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editCollection(userID, id, func(members []string) ([]string, error) {
+		memoryIDs = uniqueStrings(memoryIDs)
+		for _, memoryID := range memoryIDs {
+			if memory, ok := s.memories[memoryID]; !ok || memory.UserID != userID {
+				return nil, ErrNotFound
+			}
+		}
+		return insertMembers(members, memoryIDs, position), nil
+	})
+}
+
+// RemoveFromCollection takes a memory out of a collection
+func (s *InMemoryStore) RemoveFromCollection(ctx context.Context, userID, id, memoryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editCollection(userID, id, func(members []string) ([]string, error) {
+		remaining := removeMembers(members, []string{memoryID})
+		if len(remaining) == len(members) {
+			return nil, ErrNotFound
+		}
+		return remaining, nil
+	})
+}
+
+// ReorderCollection moves the listed memories to the front of the collection in the given order
+func (s *InMemoryStore) ReorderCollection(ctx context.Context, userID, id string, memoryIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editCollection(userID, id, func(members []string) ([]string, error) {
+		return reorderMembers(members, uniqueStrings(memoryIDs))
+	})
+}
+
+// editCollection replaces the member list of a collection owned by the user; callers must hold s.mu for writing
+func (s *InMemoryStore) editCollection(userID, id string, edit func([]string) ([]string, error)) error {
+	collection, err := s.collection(userID, id)
+	if err != nil {
+		return err
+	}
+
+	members, err := edit(append([]string{}, s.members[id]...))
+	if err != nil {
+		return err
+	}
+
+	collection.UpdatedAt = time.Now()
+	s.collections[id] = collection
+	s.members[id] = members
+	return nil
+}
+
+// collection returns a collection owned by the user with its memory count; callers must hold s.mu
+func (s *InMemoryStore) collection(userID, id string) (models.Collection, error) {
+	collection, ok := s.collections[id]
+	if !ok || collection.UserID != userID {
+		return models.Collection{}, ErrNotFound
+	}
+	collection.MemoryCount = len(s.liveMembers(id))
+	return collection, nil
+}
+
+// liveMembers lists the memories of a collection that are not in the trash, in collection
+// order; callers must hold s.mu
+func (s *InMemoryStore) liveMembers(collectionID string) []string {
+	members := []string{}
+	for _, memoryID := range s.members[collectionID] {
+		if _, ok := s.memories[memoryID]; ok {
+			members = append(members, memoryID)
+		}
+	}
+	return members
+}
+
+// inCollection reports whether a memory belongs to a collection; callers must hold s.mu
+func (s *InMemoryStore) inCollection(collectionID, memoryID string) bool {
+	for _, member := range s.members[collectionID] {
+		if member == memoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// ListLinks returns links across all of the user's memories, newest first
+func (s *InMemoryStore) ListLinks(ctx context.Context, userID string, params models.LinkQueryParams) ([]models.LinkResponse, error) {
+	domain := normalizeDomain(params.Domain)
+
+	s.mu.RLock()
+	matched := []models.LinkResponse{}
+	for memoryID, links := range s.links {
+		if s.memories[memoryID].UserID != userID {
+			continue
+		}
+		for _, link := range links {
+			if params.Domain == "" || matchesDomain(link.Domain, domain) {
+				matched = append(matched, link)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	return paginate(matched, params.Limit, params.Offset), nil
+}
+
+// ListBacklinks returns the user's memories containing a link to params.URL, newest first
+func (s *InMemoryStore) ListBacklinks(ctx context.Context, userID string, params models.LinkQueryParams) ([]models.MemoryResponse, error) {
+	withoutSlash, withSlash := urlVariants(params.URL)
+
+	return s.filter(userID, params.Limit, params.Offset, func(m models.Memory) bool {
+		for _, link := range s.links[m.ID] {
+			if link.Href == withoutSlash || link.Href == withSlash {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// response builds the API shape of a memory with its links; callers must hold s.mu
+func (s *InMemoryStore) response(memory models.Memory) models.MemoryResponse {
+	response := buildMemoryResponse(memory)
+	response.Links = append(response.Links, s.links[memory.ID]...)
+	return response
+}
+
+// memorySearch ranks and highlights the memories matched by a full-text query
+type memorySearch struct {
+	rank       func(models.Memory) (float64, bool)
+	highlights func(models.Memory) map[string]string
+}
+
+// page returns one keyset page of the user's memories accepted by match,
+// most relevant first when search is set and newest first otherwise
+func (s *InMemoryStore) page(userID string, req pageRequest, search *memorySearch, match func(models.Memory) bool) (models.MemoryPage, error) {
+	after, err := decodeCursor(req.Cursor)
+	if err != nil {
+		return models.MemoryPage{}, err
+	}
+	if after != nil && (after.Rank != nil) != (search != nil) {
+		return models.MemoryPage{}, ErrInvalidCursor
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := s.sorted(userID, match)
+	total := len(matched)
+
+	ranks := make([]*float64, len(matched))
+	if search != nil {
+		for i, m := range matched {
+			rank, _ := search.rank(m)
+			ranks[i] = &rank
+		}
+		// Stable, so equal ranks stay in (created_at, id) order
+		sort.Stable(byRank{matched, ranks})
+	}
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return after.before(ranks[i], matched[i].CreatedAt, matched[i].ID)
+		})
+	} else if req.Offset < len(matched) {
+		start = req.Offset
+	} else {
+		start = len(matched)
+	}
+
+	// Take one extra memory to learn whether another page follows
+	end := len(matched)
+	if req.Limit > 0 && start+req.Limit+1 < end {
+		end = start + req.Limit + 1
+	}
+
+	memories := []models.MemoryResponse{}
+	for i := start; i < end; i++ {
+		response := s.response(matched[i])
+		if search != nil {
+			response.Rank = ranks[i]
+			response.Highlights = search.highlights(matched[i])
+		}
+		memories = append(memories, response)
+	}
+
+	page := buildPage(memories, req.Limit)
+	if req.IncludeTotal {
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// byRank sorts memories by descending rank, keeping ranks aligned
+type byRank struct {
+	memories []models.Memory
+	ranks    []*float64
+}
+
+func (b byRank) Len() int           { return len(b.memories) }
+func (b byRank) Less(i, j int) bool { return *b.ranks[i] > *b.ranks[j] }
+func (b byRank) Swap(i, j int) {
+	b.memories[i], b.memories[j] = b.memories[j], b.memories[i]
+	b.ranks[i], b.ranks[j] = b.ranks[j], b.ranks[i]
+}
+
+// filter returns the user's memories accepted by match, newest first, paginated
+func (s *InMemoryStore) filter(userID string, limit, offset int, match func(models.Memory) bool) []models.MemoryResponse {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	memories := []models.MemoryResponse{}
+	for _, m := range paginate(s.sorted(userID, match), limit, offset) {
+		memories = append(memories, s.response(m))
+	}
+	return memories
+}
+
+// sorted returns the user's memories accepted by match ordered by (created_at, id) descending;
+// callers must hold s.mu
+func (s *InMemoryStore) sorted(userID string, match func(models.Memory) bool) []models.Memory {
+	matched := []models.Memory{}
+	for _, m := range s.memories {
+		if m.UserID == userID && match(m) {
+			matched = append(matched, m)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	return matched
+}
+
+// paginate returns the window of items selected by limit and offset; a limit of 0 means no limit
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// EnqueueJob saves a queued job, filling in its ID and timestamps; a zero RunAt runs it now
+func (s *InMemoryStore) EnqueueJob(ctx context.Context, job models.Job) (models.Job, error) {
+	now := time.Now()
+	job.ID = uuid.New().String()
+	job.Status = models.JobQueued
+	job.Attempts = 0
+	job.CreatedAt, job.UpdatedAt = now, now
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = job
+	return job, nil
+}
+
+// ClaimJobs marks up to limit runnable jobs as running for the length of lease and returns them
+func (s *InMemoryStore) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	runnable := []models.Job{}
+	for id, job := range s.jobs {
+		if (job.Status == models.JobQueued && !job.RunAt.After(now)) ||
+			(job.Status == models.JobRunning && !s.jobLeases[id].After(now)) {
+			runnable = append(runnable, job)
+		}
+	}
+	sort.Slice(runnable, func(i, j int) bool { return runnable[i].RunAt.Before(runnable[j].RunAt) })
+	if len(runnable) > limit {
+		runnable = runnable[:limit]
+	}
+
+	for i := range runnable {
+		runnable[i].Status = models.JobRunning
+		runnable[i].Attempts++
+		runnable[i].UpdatedAt = now
+		s.jobs[runnable[i].ID] = runnable[i]
+		s.jobLeases[runnable[i].ID] = now.Add(lease)
+	}
+	return runnable, nil
+}
+
+// FinishJob stores the outcome of a claimed job unless another worker has claimed it since
+func (s *InMemoryStore) FinishJob(ctx context.Context, job models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.jobs[job.ID]
+	if !ok || stored.Status != models.JobRunning || stored.Attempts != job.Attempts {
+		return nil
+	}
+	stored.Status = job.Status
+	stored.RunAt = job.RunAt
+	stored.LastError = job.LastError
+	stored.UpdatedAt = time.Now()
+	s.jobs[job.ID] = stored
+	delete(s.jobLeases, job.ID)
+	return nil
+}
+
+// ListJobs returns the user's jobs matching params, newest first
+func (s *InMemoryStore) ListJobs(ctx context.Context, userID string, params models.JobQueryParams) ([]models.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := []models.Job{}
+	for _, job := range s.jobs {
+		if job.UserID != userID ||
+			(params.Status != "" && job.Status != params.Status) ||
+			(params.Kind != "" && job.Kind != params.Kind) ||
+			(params.MemoryID != "" && job.MemoryID != params.MemoryID) {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID > jobs[j].ID
+	})
+
+	if params.Offset >= len(jobs) {
+		return []models.Job{}, nil
+	}
+	jobs = jobs[params.Offset:]
+	if params.Limit > 0 && len(jobs) > params.Limit {
+		jobs = jobs[:params.Limit]
+	}
+	return jobs, nil
+}
+
+// CountJobs returns how many of the user's jobs are in each status
+func (s *InMemoryStore) CountJobs(ctx context.Context, userID string) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := map[string]int{}
+	for _, job := range s.jobs {
+		if job.UserID == userID {
+			counts[job.Status]++
+		}
+	}
+	return counts, nil
+}
+
+// PruneJobs deletes succeeded jobs last updated before the given time
+func (s *InMemoryStore) PruneJobs(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for id, job := range s.jobs {
+		if job.Status == models.JobSucceeded && job.UpdatedAt.Before(before) {
+			delete(s.jobs, id)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// SaveEmbedding stores the vector of a memory, replacing any previous one
+func (s *InMemoryStore) SaveEmbedding(ctx context.Context, memoryID, model string, vector []float32, sourceHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.memories[memoryID]; !ok {
+		return ErrNotFound
+	}
+	s.embeddings[memoryID] = memoryEmbedding{model: model, vector: vector, sourceHash: sourceHash}
+	return nil
+}
+
+// EmbeddingHash returns the source hash of a memory's embedding by model, or "" if it has none
+func (s *InMemoryStore) EmbeddingHash(ctx context.Context, memoryID, model string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if embedding, ok := s.embeddings[memoryID]; ok && embedding.model == model {
+		return embedding.sourceHash, nil
+	}
+	return "", nil
+}
+
+// UnembeddedMemories lists up to limit memories that have no embedding by model
+// and no embed job that is pending or dead, oldest first
+func (s *InMemoryStore) UnembeddedMemories(ctx context.Context, model string, limit int) ([]models.MemoryRef, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pending := map[string]bool{}
+	for _, job := range s.jobs {
+		if job.Kind == embedJobKind && job.Status != models.JobSucceeded {
+			pending[job.MemoryID] = true
+		}
+	}
+
+	memories := []models.Memory{}
+	for id, memory := range s.memories {
+		if embedding, ok := s.embeddings[id]; (!ok || embedding.model != model) && !pending[id] {
+			memories = append(memories, memory)
+		}
+	}
+	sort.Slice(memories, func(i, j int) bool { return memories[i].CreatedAt.Before(memories[j].CreatedAt) })
+
+	refs := []models.MemoryRef{}
+	for _, memory := range paginate(memories, limit, 0) {
+		refs = append(refs, models.MemoryRef{UserID: memory.UserID, MemoryID: memory.ID})
+	}
+	return refs, nil
+}
+
+// SemanticSearch returns the user's memories embedded by q.Model that are most similar to q.Vector
+func (s *InMemoryStore) SemanticSearch(ctx context.Context, userID string, q models.VectorQuery) ([]models.MemoryResponse, error) {
+	q.Filter.Query = ""
+	_, match, err := s.searchMatch(q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scored := []scoredMemory{}
+	byID := map[string]models.Memory{}
+	for _, memory := range s.sorted(userID, match) {
+		embedding, ok := s.embeddings[memory.ID]
+		if !ok || embedding.model != q.Model || len(embedding.vector) != len(q.Vector) {
+			continue
+		}
+		if similarity := cosineSimilarity(q.Vector, embedding.vector); similarity >= q.MinSimilarity {
+			scored = append(scored, scoredMemory{id: memory.ID, similarity: similarity})
+			byID[memory.ID] = memory
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].similarity > scored[j].similarity })
+
+	memories := []models.MemoryResponse{}
+	for _, memory := range paginate(scored, q.Limit, 0) {
+		response := s.response(byID[memory.id])
+		similarity := memory.similarity
+		response.Similarity = &similarity
+		memories = append(memories, response)
+	}
+	return memories, nil
+}
+
+// RelatedMemories ranks the memories related to the user's memory id, most related first
+func (s *InMemoryStore) RelatedMemories(ctx context.Context, userID, id string, q models.RelatedQuery) ([]models.RelatedMemory, error) {
+	return relatedMemories(ctx, s, userID, id, q)
+}
+
+func (s *InMemoryStore) relatedCandidates(ctx context.Context, userID string, source models.MemoryResponse, domain string) (map[string]*relatedCandidate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sourceTags := map[string]bool{}
+	for _, tag := range source.Tags {
+		sourceTags[tag] = true
+	}
+	// Links within the memory's own site are mostly navigation, so only outbound links count
+	sourceLinks := map[string]bool{}
+	for _, link := range s.links[source.ID] {
+		if link.Domain != linkDomain(domainPrefix(domain)) {
+			sourceLinks[link.Href] = true
+		}
+	}
+
+	tags := map[string][]string{}
+	links := map[string][]string{}
+	sameDomain := []models.Memory{}
+	for _, memory := range s.sorted(userID, func(m models.Memory) bool { return m.ID != source.ID }) {
+		for _, tag := range splitTags(memory.TagsString.String) {
+			if sourceTags[tag] {
+				tags[memory.ID] = append(tags[memory.ID], tag)
+			}
+		}
+		seen := map[string]bool{}
+		for _, link := range s.links[memory.ID] {
+			if sourceLinks[link.Href] && !seen[link.Href] {
+				seen[link.Href] = true
+				links[memory.ID] = append(links[memory.ID], link.Href)
+			}
+		}
+		if domain != "" && strings.HasPrefix(memory.CanonicalURL.String, domainPrefix(domain)) {
+			sameDomain = append(sameDomain, memory)
+		}
+	}
+	for _, grouped := range []map[string][]string{tags, links} {
+		for _, values := range grouped {
+			sort.Strings(values)
+		}
+	}
+
+	candidates := map[string]*relatedCandidate{}
+	addCandidates(candidates, tags, func(c *relatedCandidate, names []string) { c.sharedTags = names })
+	addCandidates(candidates, links, func(c *relatedCandidate, hrefs []string) { c.sharedLinks = hrefs })
+	for _, memory := range paginate(sameDomain, relatedCandidates, 0) {
+		if candidates[memory.ID] == nil {
+			candidates[memory.ID] = &relatedCandidate{}
+		}
+		candidates[memory.ID].sameDomain = true
+	}
+	return candidates, nil
+}
+
+// memoryVector returns the embedding of a memory by model, or nil if it has none
+func (s *InMemoryStore) memoryVector(ctx context.Context, memoryID, model string) ([]float32, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if embedding, ok := s.embeddings[memoryID]; ok && embedding.model == model {
+		return embedding.vector, nil
+	}
+	return nil, nil
+}
+
+// memoriesByID loads the user's memories with the given IDs; missing IDs are left out
+func (s *InMemoryStore) memoriesByID(ctx context.Context, userID string, ids []string) (map[string]models.MemoryResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byID := map[string]models.MemoryResponse{}
+	for _, id := range ids {
+		if memory, ok := s.memories[id]; ok && memory.UserID == userID {
+			byID[id] = s.response(memory)
+		}
+	}
+	return byID, nil
+}
+
+// CreateScrapedData saves a scraped page for the user
+func (s *InMemoryStore) CreateScrapedData(ctx context.Context, userID string, req models.CreateScrapedDataRequest) (models.ScrapedData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastID++
+	data := models.ScrapedData{
+		ID:        s.lastID,
+		UserID:    userID,
+		URL:       req.URL,
+		Title:     req.Title,
+		Content:   req.Content,
+		Metadata:  copyMetadata(req.Metadata),
+		Tags:      strings.Join(req.Tags, ","),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.scraped[data.ID] = data
+	return data, nil
+}
+
+// GetScrapedData fetches a single scraped page owned by the user
+func (s *InMemoryStore) GetScrapedData(ctx context.Context, userID string, id int64) (models.ScrapedData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.scraped[id]
+	if !ok || data.UserID != userID {
+		return models.ScrapedData{}, ErrNotFound
+	}
+	return data, nil
+}
+
+// ListScrapedData returns a page of the user's scraped pages matching params, newest first
+func (s *InMemoryStore) ListScrapedData(ctx context.Context, userID string, params models.ScrapedDataQueryParams) ([]models.ScrapedData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := []models.ScrapedData{}
+	for _, data := range s.scraped {
+		if data.UserID != userID {
+			continue
+		}
+		if params.URL != "" && !strings.Contains(strings.ToLower(data.URL), strings.ToLower(params.URL)) {
+			continue
+		}
+		if params.Tag != "" && !strings.Contains(strings.ToLower(data.Tags), strings.ToLower(params.Tag)) {
+			continue
+		}
+		if !matchesMetadata(data.Metadata, params.Metadata) {
+			continue
+		}
+		list = append(list, data)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.After(list[j].CreatedAt)
+		}
+		return list[i].ID > list[j].ID
+	})
+	return paginate(list, params.Limit, (params.Page-1)*params.Limit), nil
+}
+
+// UpdateScrapedData applies the set fields of req to a scraped page owned by the user;
+// metadata, when given, replaces the stored object
+func (s *InMemoryStore) UpdateScrapedData(ctx context.Context, userID string, id int64, req models.UpdateScrapedDataRequest) (models.ScrapedData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.scraped[id]
+	if !ok || data.UserID != userID {
+		return models.ScrapedData{}, ErrNotFound
+	}
+
+	if req.Title != "" {
+		data.Title = req.Title
+	}
+	if req.Content != "" {
+		data.Content = req.Content
+	}
+	if req.Metadata != nil {
+		data.Metadata = copyMetadata(req.Metadata)
+	}
+	if req.Tags != nil {
+		data.Tags = strings.Join(req.Tags, ",")
+	}
+	data.UpdatedAt = time.Now()
+
+	s.scraped[id] = data
+	return data, nil
+}
+
+// DeleteScrapedData removes a scraped page owned by the user
+func (s *InMemoryStore) DeleteScrapedData(ctx context.Context, userID string, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if data, ok := s.scraped[id]; !ok || data.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.scraped, id)
+	return nil
+}
+
+// CreateItem saves an item for the user
+func (s *InMemoryStore) CreateItem(ctx context.Context, userID string, req models.CreateItemRequest) (models.Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastID++
+	item := models.Item{
+		ID:          s.lastID,
+		UserID:      userID,
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      req.Status,
+		Priority:    req.Priority,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	s.items[item.ID] = item
+	return item, nil
+}
+
+// GetItem fetches a single item owned by the user
+func (s *InMemoryStore) GetItem(ctx context.Context, userID string, id int64) (models.Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[id]
+	if !ok || item.UserID != userID {
+		return models.Item{}, ErrNotFound
+	}
+	return item, nil
+}
+
+// ListItems returns the user's items matching params, newest first
+func (s *InMemoryStore) ListItems(ctx context.Context, userID string, params models.ItemQueryParams) ([]models.Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := []models.Item{}
+	for _, item := range s.items {
+		if item.UserID != userID {
+			continue
+		}
+		if (params.Status != "" && item.Status != params.Status) || (params.Priority != "" && item.Priority != params.Priority) {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	return items, nil
+}
+
+// UpdateItem applies the non-empty fields of req to an item owned by the user
+func (s *InMemoryStore) UpdateItem(ctx context.Context, userID string, id int64, req models.UpdateItemRequest) (models.Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok || item.UserID != userID {
+		return models.Item{}, ErrNotFound
+	}
+
+	if req.Title != "" {
+		item.Title = req.Title
+	}
+	if req.Description != "" {
+		item.Description = req.Description
+	}
+	if req.Status != "" {
+		item.Status = req.Status
+	}
+	if req.Priority != "" {
+		item.Priority = req.Priority
+	}
+	item.UpdatedAt = time.Now()
+
+	s.items[id] = item
+	return item, nil
+}
+
+// DeleteItem removes an item owned by the user
+func (s *InMemoryStore) DeleteItem(ctx context.Context, userID string, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if item, ok := s.items[id]; !ok || item.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.items, id)
+	return nil
+}
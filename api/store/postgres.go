@@ -0,0 +1,108 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// postgresDialect targets PostgreSQL, the schema shared with the Next.js app
+type postgresDialect struct{}
+
+// NewPostgresStore creates a SQLStore for a PostgreSQL connection pool
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: postgresDialect{}}
+}
+
+// headlineOptions keeps ts_headline fragments short; the title is always returned whole
+const headlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "`
+
+// fullTextSearch matches against the weighted search_vector column added by migration 0004
+func (postgresDialect) fullTextSearch(placeholder string) textSearch {
+	headline := func(column, options string) string {
+		return "ts_headline('english', COALESCE(" + column + ", ''), search_query, '" + options + "')"
+	}
+
+	return textSearch{
+		from:      "memories CROSS JOIN websearch_to_tsquery('english', " + placeholder + ") AS search_query",
+		condition: "search_vector @@ search_query",
+		rank:      "ts_rank_cd(search_vector, search_query)::float8",
+		highlights: []string{
+			headline("title", headlineOptions+", HighlightAll=true"),
+			headline("content", headlineOptions),
+			headline("selected_text", headlineOptions),
+		},
+	}
+}
+
+// fullTextQuery passes the input through; websearch_to_tsquery parses it and never fails on bad syntax
+func (postgresDialect) fullTextQuery(query string) string {
+	return query
+}
+
+func (postgresDialect) likeOperator() string {
+	return "ILIKE"
+}
+
+func (postgresDialect) skipLocked() string {
+	return " FOR UPDATE SKIP LOCKED"
+}
+
+func (postgresDialect) forUpdate() string {
+	return " FOR UPDATE"
+}
+
+// advisoryLock hashes the key into the 64-bit space of transaction-level advisory locks;
+// a collision only makes unrelated writers wait for each other
+func (postgresDialect) advisoryLock(placeholder string) string {
+	return "SELECT pg_advisory_xact_lock(hashtextextended(" + placeholder + ", 0))"
+}
+
+func (postgresDialect) tableExists(placeholder string) string {
+	return "SELECT to_regclass(" + placeholder + ") IS NOT NULL"
+}
+
+// vectorDistance uses pgvector's cosine distance. Casting to the exact size lets the
+// planner use the matching HNSW index.
+func (postgresDialect) vectorDistance(placeholder string, dimensions int) string {
+	size := "vector(" + strconv.Itoa(dimensions) + ")"
+	return "(memory_embeddings.embedding::" + size + " <=> " + placeholder + "::" + size + ")"
+}
+
+// encodeVector formats a vector as pgvector's text representation
+func (postgresDialect) encodeVector(vector []float32) interface{} {
+	parts := make([]string, len(vector))
+	for i, x := range vector {
+		parts[i] = strconv.FormatFloat(float64(x), 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// decodeVector parses pgvector's text representation
+func (postgresDialect) decodeVector(raw []byte) ([]float32, error) {
+	text := strings.TrimSpace(string(raw))
+	if !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+		return nil, fmt.Errorf("invalid vector %.20q", text)
+	}
+	text = text[1 : len(text)-1]
+	if text == "" {
+		return []float32{}, nil
+	}
+
+	parts := strings.Split(text, ",")
+	vector := make([]float32, len(parts))
+	for i, part := range parts {
+		x, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector component %q: %w", part, err)
+		}
+		vector[i] = float32(x)
+	}
+	return vector, nil
+}
+
+// jsonFieldEquals uses ->>, which renders numbers and booleans as their JSON text
+func (postgresDialect) jsonFieldEquals(column, keyPlaceholder, valuePlaceholder string) string {
+	return column + " ->> " + keyPlaceholder + "::text = " + valuePlaceholder
+}
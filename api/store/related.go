@@ -0,0 +1,312 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"api/models"
+)
+
+// Weights of the reasons two memories are related; they add up to 1
+const (
+	sharedTagsWeight  = 0.3
+	sameDomainWeight  = 0.15
+	sharedLinksWeight = 0.2
+	similarTextWeight = 0.35
+)
+
+const (
+	// relatedSaturation is the number of shared tags or links that counts fully
+	relatedSaturation = 3
+	// minRelatedSimilarity is the text similarity below which memories are not considered related
+	minRelatedSimilarity = 0.3
+	// relatedCandidates bounds the memories each reason contributes before ranking
+	relatedCandidates = 100
+)
+
+// relatedCandidate collects the reasons one memory is related to the source memory
+type relatedCandidate struct {
+	sharedTags  []string
+	sameDomain  bool
+	sharedLinks []string
+	similar     *models.MemoryResponse // set when the texts are similar, with Similarity
+}
+
+// relatedSource is what finding related memories needs from a store
+type relatedSource interface {
+	GetMemory(ctx context.Context, userID, id string) (models.MemoryResponse, error)
+	SemanticSearch(ctx context.Context, userID string, q models.VectorQuery) ([]models.MemoryResponse, error)
+	// relatedCandidates finds the user's other memories sharing tags, the domain or outbound
+	// links with source, keyed by memory ID
+	relatedCandidates(ctx context.Context, userID string, source models.MemoryResponse, domain string) (map[string]*relatedCandidate, error)
+	memoryVector(ctx context.Context, memoryID, model string) ([]float32, error)
+	memoriesByID(ctx context.Context, userID string, ids []string) (map[string]models.MemoryResponse, error)
+}
+
+// relatedMemories ranks the memories related to the user's memory id by shared tags, domain
+// and outbound links and, when the memory has an embedding by q.Model, text similarity
+func relatedMemories(ctx context.Context, s relatedSource, userID, id string, q models.RelatedQuery) ([]models.RelatedMemory, error) {
+	source, err := s.GetMemory(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	domain := memoryDomain(source)
+	candidates, err := s.relatedCandidates(ctx, userID, source, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Model != "" {
+		vector, err := s.memoryVector(ctx, id, q.Model)
+		if err != nil {
+			return nil, err
+		}
+		if vector != nil {
+			similar, err := s.SemanticSearch(ctx, userID, models.VectorQuery{
+				Model:         q.Model,
+				Vector:        vector,
+				Limit:         relatedCandidates + 1,
+				MinSimilarity: minRelatedSimilarity,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for i := range similar {
+				if similar[i].ID == id {
+					continue
+				}
+				candidate := candidates[similar[i].ID]
+				if candidate == nil {
+					candidate = &relatedCandidate{}
+					candidates[similar[i].ID] = candidate
+				}
+				candidate.similar = &similar[i]
+			}
+		}
+	}
+
+	related := make([]models.RelatedMemory, 0, len(candidates))
+	for memoryID, candidate := range candidates {
+		memory := models.RelatedMemory{MemoryResponse: models.MemoryResponse{Memory: models.Memory{ID: memoryID}}}
+		memory.Reasons = candidate.reasons(domain)
+		for _, reason := range memory.Reasons {
+			memory.Score += reason.Score
+		}
+		related = append(related, memory)
+	}
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Score != related[j].Score {
+			return related[i].Score > related[j].Score
+		}
+		return related[i].ID < related[j].ID
+	})
+	related = paginate(related, q.Limit, 0)
+
+	// Only the memories on the page are loaded; the ones from the vector search already are
+	missing := []string{}
+	for _, memory := range related {
+		if candidates[memory.ID].similar == nil {
+			missing = append(missing, memory.ID)
+		}
+	}
+	loaded, err := s.memoriesByID(ctx, userID, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	page := make([]models.RelatedMemory, 0, len(related))
+	for _, memory := range related {
+		if similar := candidates[memory.ID].similar; similar != nil {
+			memory.MemoryResponse = *similar
+		} else if response, ok := loaded[memory.ID]; ok {
+			memory.MemoryResponse = response
+		} else {
+			continue
+		}
+		page = append(page, memory)
+	}
+	return page, nil
+}
+
+// reasons explains and scores a candidate; domain is the source memory's domain
+func (c *relatedCandidate) reasons(domain string) []models.RelatedReason {
+	reasons := []models.RelatedReason{}
+	if len(c.sharedTags) > 0 {
+		reasons = append(reasons, models.RelatedReason{
+			Type:        models.RelatedSharedTags,
+			Description: "Shares tags: " + strings.Join(c.sharedTags, ", "),
+			Score:       sharedTagsWeight * saturate(len(c.sharedTags)),
+			Tags:        c.sharedTags,
+		})
+	}
+	if c.sameDomain {
+		reasons = append(reasons, models.RelatedReason{
+			Type:        models.RelatedSameDomain,
+			Description: "Also from " + domain,
+			Score:       sameDomainWeight,
+			Domain:      domain,
+		})
+	}
+	if len(c.sharedLinks) > 0 {
+		description := "Links to the same page"
+		if len(c.sharedLinks) > 1 {
+			description = fmt.Sprintf("Links to %d of the same pages", len(c.sharedLinks))
+		}
+		reasons = append(reasons, models.RelatedReason{
+			Type:        models.RelatedSharedLinks,
+			Description: description,
+			Score:       sharedLinksWeight * saturate(len(c.sharedLinks)),
+			Links:       c.sharedLinks,
+		})
+	}
+	if c.similar != nil {
+		similarity := *c.similar.Similarity
+		reasons = append(reasons, models.RelatedReason{
+			Type:        models.RelatedSimilarText,
+			Description: fmt.Sprintf("Similar text (%.0f%% similarity)", 100*similarity),
+			Score:       similarTextWeight * math.Max(similarity, 0),
+			Similarity:  &similarity,
+		})
+	}
+	return reasons
+}
+
+// saturate maps a count of shared items to (0, 1], reaching 1 at relatedSaturation
+func saturate(count int) float64 {
+	return math.Min(float64(count), relatedSaturation) / relatedSaturation
+}
+
+// memoryDomain is the host of a memory's canonical URL, or "" for memories without a web URL
+func memoryDomain(memory models.MemoryResponse) string {
+	canonical := memory.CanonicalURL.String
+	if canonical == "" {
+		canonical = CanonicalURL(memory.URL.String)
+	}
+	u, err := url.Parse(canonical)
+	if err != nil || u.Scheme != "https" {
+		return ""
+	}
+	return u.Host
+}
+
+// domainPrefix is how every canonical URL on domain starts
+func domainPrefix(domain string) string {
+	return "https://" + domain + "/"
+}
+
+// topCandidates keeps the relatedCandidates entries of counts with the highest counts
+func topCandidates(counts map[string][]string) map[string][]string {
+	if len(counts) <= relatedCandidates {
+		return counts
+	}
+	ids := make([]string, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if len(counts[ids[i]]) != len(counts[ids[j]]) {
+			return len(counts[ids[i]]) > len(counts[ids[j]])
+		}
+		return ids[i] < ids[j]
+	})
+
+	top := make(map[string][]string, relatedCandidates)
+	for _, id := range ids[:relatedCandidates] {
+		top[id] = counts[id]
+	}
+	return top
+}
+
+// addCandidates records the shared items of each memory in counts on its candidate
+func addCandidates(candidates map[string]*relatedCandidate, counts map[string][]string, set func(*relatedCandidate, []string)) {
+	for id, items := range topCandidates(counts) {
+		candidate := candidates[id]
+		if candidate == nil {
+			candidate = &relatedCandidate{}
+			candidates[id] = candidate
+		}
+		set(candidate, items)
+	}
+}
+
+// RelatedMemories ranks the memories related to the user's memory id, most related first
+func (s *SQLStore) RelatedMemories(ctx context.Context, userID, id string, q models.RelatedQuery) ([]models.RelatedMemory, error) {
+	return relatedMemories(ctx, s, userID, id, q)
+}
+
+func (s *SQLStore) relatedCandidates(ctx context.Context, userID string, source models.MemoryResponse, domain string) (map[string]*relatedCandidate, error) {
+	candidates := map[string]*relatedCandidate{}
+
+	tags, err := s.groupedStrings(ctx,
+		`SELECT other.memory_id, tags.name FROM memory_tags source
+		JOIN memory_tags other ON other.tag_id = source.tag_id AND other.memory_id <> source.memory_id
+		JOIN tags ON tags.id = source.tag_id
+		JOIN memories ON memories.id = other.memory_id
+		WHERE source.memory_id = $1 AND tags.user_id = $2 AND `+notTrashed+`
+		ORDER BY tags.name`, source.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	addCandidates(candidates, tags, func(c *relatedCandidate, names []string) { c.sharedTags = names })
+
+	// Links within the memory's own site are mostly navigation, so only outbound links count
+	links, err := s.groupedStrings(ctx,
+		`SELECT DISTINCT other.memory_id, other.href FROM links source
+		JOIN links other ON other.href = source.href AND other.memory_id <> source.memory_id
+		JOIN memories ON memories.id = other.memory_id
+		WHERE source.memory_id = $1 AND memories.user_id = $2 AND `+notTrashed+` AND COALESCE(source.domain, '') <> $3
+		ORDER BY other.href`, source.ID, userID, linkDomain(domainPrefix(domain)))
+	if err != nil {
+		return nil, err
+	}
+	addCandidates(candidates, links, func(c *relatedCandidate, hrefs []string) { c.sharedLinks = hrefs })
+
+	if domain != "" {
+		prefix := domainPrefix(domain)
+		rows, err := s.db.QueryContext(ctx,
+			`SELECT id FROM memories WHERE user_id = $1 AND `+notTrashed+` AND id <> $2 AND substr(canonical_url, 1, $3) = $4
+			ORDER BY created_at DESC LIMIT $5`, userID, source.ID, len(prefix), prefix, relatedCandidates)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return nil, err
+			}
+			if candidates[id] == nil {
+				candidates[id] = &relatedCandidate{}
+			}
+			candidates[id].sameDomain = true
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return candidates, nil
+}
+
+// groupedStrings runs a query selecting (memory ID, value) pairs and groups the values by memory
+func (s *SQLStore) groupedStrings(ctx context.Context, query string, args ...interface{}) (map[string][]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grouped := map[string][]string{}
+	for rows.Next() {
+		var id, value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, err
+		}
+		grouped[id] = append(grouped[id], value)
+	}
+	return grouped, rows.Err()
+}
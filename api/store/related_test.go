@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"api/models"
+)
+
+const gofmtDocs = "https://pkg.go.dev/cmd/gofmt"
+
+// relatedFixture is a source memory and the memories saved around it
+type relatedFixture struct {
+	source, everything, similar, tagged, sameSite, linking string
+}
+
+// createRelatedFixture saves a source memory and one memory for each way of relating to it,
+// plus memories that must never be related. Vectors are saved by model "m".
+func createRelatedFixture(t *testing.T, s Store) relatedFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	source := createMemory(t, s, ownerID, models.CreateMemoryRequest{
+		URL: "https://go.dev/doc/effective_go", Title: "Effective Go", Tags: []string{"golang", "style", "tooling"},
+		Links: []models.Link{{Text: "gofmt", Href: gofmtDocs}, {Text: "Blog", Href: "https://go.dev/blog"}},
+	})
+	f := relatedFixture{
+		source: source.ID,
+		// Every tag, the same site and a shared link: 0.3 + 0.15 + 0.2/3
+		everything: createMemory(t, s, ownerID, models.CreateMemoryRequest{
+			URL: "https://www.go.dev/blog/gofmt", Title: "go fmt your code", Tags: []string{"tooling", "style", "golang"},
+			Links: []models.Link{{Text: "gofmt", Href: gofmtDocs}},
+		}).ID,
+		// Cosine similarity 0.8: 0.35 * 0.8
+		similar: createMemory(t, s, ownerID, models.CreateMemoryRequest{URL: "https://example.com/idioms", Title: "Idiomatic code"}).ID,
+		// Two of three tags: 0.3 * 2/3
+		tagged: createMemory(t, s, ownerID, models.CreateMemoryRequest{
+			URL: "https://example.org/lint", Title: "Linters", Tags: []string{"style", "golang", "lint"},
+		}).ID,
+		// Same site only: 0.15
+		sameSite: createMemory(t, s, ownerID, models.CreateMemoryRequest{URL: "https://go.dev/tour", Title: "A Tour of Go"}).ID,
+		// One shared link: 0.2/3
+		linking: createMemory(t, s, ownerID, models.CreateMemoryRequest{
+			URL: "https://other.example/formatters", Title: "Formatters", Links: []models.Link{{Href: gofmtDocs}},
+		}).ID,
+	}
+
+	// Sharing only a link within the source's site, an orthogonal vector or nothing is no relation
+	navigation := createMemory(t, s, ownerID, models.CreateMemoryRequest{
+		URL: "https://news.example/go", Title: "Go news", Links: []models.Link{{Href: "https://go.dev/blog"}},
+	})
+	orthogonal := createMemory(t, s, ownerID, models.CreateMemoryRequest{URL: "https://example.net/", Title: "Orthogonal"})
+	createMemory(t, s, ownerID, models.CreateMemoryRequest{URL: "https://unrelated.example/", Title: "Unrelated", Tags: []string{"cooking"}})
+
+	// Trashed memories and other users' memories are never related
+	trashed := createMemory(t, s, ownerID, models.CreateMemoryRequest{
+		URL: "https://go.dev/doc/faq", Title: "FAQ", Tags: []string{"golang", "style", "tooling"}, Links: []models.Link{{Href: gofmtDocs}},
+	})
+	createMemory(t, s, intruderID, models.CreateMemoryRequest{
+		URL: "https://go.dev/doc/code", Title: "Theirs", Tags: []string{"golang", "style", "tooling"}, Links: []models.Link{{Href: gofmtDocs}},
+	})
+
+	vectors := map[string][]float32{
+		f.source:      {1, 0, 0},
+		f.similar:     {0.8, 0.6, 0},
+		orthogonal.ID: {0, 1, 0},
+		trashed.ID:    {1, 0, 0},
+		navigation.ID: {0, 0, 1},
+	}
+	for id, vector := range vectors {
+		if err := s.SaveEmbedding(ctx, id, "m", vector, "h"); err != nil {
+			t.Fatalf("save embedding failed: %v", err)
+		}
+	}
+	if err := s.DeleteMemory(ctx, ownerID, trashed.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	return f
+}
+
+func TestRelatedMemories(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		f := createRelatedFixture(t, s)
+
+		related, err := s.RelatedMemories(ctx, ownerID, f.source, models.RelatedQuery{Model: "m", Limit: 10})
+		if err != nil {
+			t.Fatalf("related memories failed: %v", err)
+		}
+
+		want := []struct {
+			id      string
+			score   float64
+			reasons []string
+		}{
+			{f.everything, 0.3 + 0.15 + 0.2/3, []string{models.RelatedSharedTags, models.RelatedSameDomain, models.RelatedSharedLinks}},
+			{f.similar, 0.35 * 0.8, []string{models.RelatedSimilarText}},
+			{f.tagged, 0.3 * 2 / 3, []string{models.RelatedSharedTags}},
+			{f.sameSite, 0.15, []string{models.RelatedSameDomain}},
+			{f.linking, 0.2 / 3, []string{models.RelatedSharedLinks}},
+		}
+		if len(related) != len(want) {
+			t.Fatalf("expected %d related memories, got %d: %+v", len(want), len(related), related)
+		}
+		for i, w := range want {
+			got := related[i]
+			if got.ID != w.id {
+				t.Errorf("position %d: expected %s, got %s (%q)", i+1, w.id, got.ID, got.Title)
+				continue
+			}
+			if math.Abs(got.Score-w.score) > 1e-6 {
+				t.Errorf("%q: expected score %.4f, got %.4f", got.Title, w.score, got.Score)
+			}
+			types := []string{}
+			for _, reason := range got.Reasons {
+				types = append(types, reason.Type)
+			}
+			if !reflect.DeepEqual(types, w.reasons) {
+				t.Errorf("%q: expected reasons %v, got %v", got.Title, w.reasons, types)
+			}
+			if got.Title == "" || got.URL.String == "" {
+				t.Errorf("%s: expected the memory to be loaded, got %+v", got.ID, got.MemoryResponse)
+			}
+		}
+
+		reasons := related[0].Reasons
+		if tags := reasons[0].Tags; !reflect.DeepEqual(tags, []string{"golang", "style", "tooling"}) || reasons[0].Description != "Shares tags: golang, style, tooling" {
+			t.Errorf("shared tags: got %+v", reasons[0])
+		}
+		if reasons[1].Domain != "go.dev" || reasons[1].Description != "Also from go.dev" {
+			t.Errorf("same domain: got %+v", reasons[1])
+		}
+		if !reflect.DeepEqual(reasons[2].Links, []string{gofmtDocs}) || reasons[2].Description != "Links to the same page" {
+			t.Errorf("shared links: got %+v", reasons[2])
+		}
+		similar := related[1].Reasons[0]
+		if similar.Similarity == nil || math.Abs(*similar.Similarity-0.8) > 1e-6 || similar.Description != "Similar text (80% similarity)" {
+			t.Errorf("similar text: got %+v", similar)
+		}
+
+		// The limit keeps the most related memories
+		top, err := s.RelatedMemories(ctx, ownerID, f.source, models.RelatedQuery{Model: "m", Limit: 2})
+		if err != nil || len(top) != 2 || top[0].ID != f.everything || top[1].ID != f.similar {
+			t.Errorf("limit 2: expected the two most related memories, got %+v, %v", top, err)
+		}
+
+		// Without a model, or for a memory without a vector, only the other reasons count
+		for _, q := range []models.RelatedQuery{{Limit: 10}, {Model: "other", Limit: 10}} {
+			unembedded, err := s.RelatedMemories(ctx, ownerID, f.source, q)
+			if err != nil {
+				t.Fatalf("model %q: related memories failed: %v", q.Model, err)
+			}
+			for _, memory := range unembedded {
+				if memory.ID == f.similar {
+					t.Errorf("model %q: expected no text similarity, got %+v", q.Model, memory.Reasons)
+				}
+			}
+			if len(unembedded) != len(want)-1 {
+				t.Errorf("model %q: expected %d related memories, got %d", q.Model, len(want)-1, len(unembedded))
+			}
+		}
+	})
+}
+
+func TestRelatedMemoriesAreScopedToTheOwner(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		f := createRelatedFixture(t, s)
+		if _, err := s.RelatedMemories(context.Background(), intruderID, f.source, models.RelatedQuery{Model: "m", Limit: 10}); err != ErrNotFound {
+			t.Errorf("another user's memory: expected ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/models"
+)
+
+// scrapedDataColumns is the column list shared by every scraped_data SELECT; keep it in sync with scanScrapedData
+const scrapedDataColumns = "id, user_id, url, title, content, metadata, tags, created_at, updated_at"
+
+func scanScrapedData(row rowScanner) (models.ScrapedData, error) {
+	var data models.ScrapedData
+	err := row.Scan(&data.ID, &data.UserID, &data.URL, &data.Title, &data.Content,
+		&data.Metadata, &data.Tags, &data.CreatedAt, &data.UpdatedAt)
+	return data, err
+}
+
+// CreateScrapedData saves a scraped page for the user
+func (s *SQLStore) CreateScrapedData(ctx context.Context, userID string, req models.CreateScrapedDataRequest) (models.ScrapedData, error) {
+	now := time.Now().UTC()
+	query := `INSERT INTO scraped_data (user_id, url, title, content, metadata, tags, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + scrapedDataColumns
+
+	return scanScrapedData(s.db.QueryRowContext(ctx, query,
+		userID, req.URL, req.Title, req.Content, req.Metadata, strings.Join(req.Tags, ","), now, now))
+}
+
+// GetScrapedData fetches a single scraped page owned by the user
+func (s *SQLStore) GetScrapedData(ctx context.Context, userID string, id int64) (models.ScrapedData, error) {
+	query := "SELECT " + scrapedDataColumns + " FROM scraped_data WHERE id = $1 AND user_id = $2"
+
+	data, err := scanScrapedData(s.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return models.ScrapedData{}, ErrNotFound
+	}
+	return data, err
+}
+
+// ListScrapedData returns a page of the user's scraped pages matching params, newest first
+func (s *SQLStore) ListScrapedData(ctx context.Context, userID string, params models.ScrapedDataQueryParams) ([]models.ScrapedData, error) {
+	query := "SELECT " + scrapedDataColumns + " FROM scraped_data WHERE user_id = $1"
+	args := []interface{}{userID}
+	argCount := 1
+
+	if params.URL != "" {
+		argCount++
+		query += " AND url " + s.dialect.likeOperator() + " $" + strconv.Itoa(argCount)
+		args = append(args, "%"+params.URL+"%")
+	}
+
+	if params.Tag != "" {
+		argCount++
+		query += " AND tags " + s.dialect.likeOperator() + " $" + strconv.Itoa(argCount)
+		args = append(args, "%"+params.Tag+"%")
+	}
+
+	// Sorted so the same filters always produce the same statement
+	keys := make([]string, 0, len(params.Metadata))
+	for key := range params.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query += " AND " + s.dialect.jsonFieldEquals("metadata", "$"+strconv.Itoa(argCount+1), "$"+strconv.Itoa(argCount+2))
+		args = append(args, key, params.Metadata[key])
+		argCount += 2
+	}
+
+	query += " ORDER BY created_at DESC, id DESC LIMIT $" + strconv.Itoa(argCount+1) + " OFFSET $" + strconv.Itoa(argCount+2)
+	args = append(args, params.Limit, (params.Page-1)*params.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ScrapedData{}
+	for rows.Next() {
+		data, err := scanScrapedData(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, data)
+	}
+	return list, rows.Err()
+}
+
+// UpdateScrapedData applies the set fields of req to a scraped page owned by the user;
+// metadata, when given, replaces the stored object
+func (s *SQLStore) UpdateScrapedData(ctx context.Context, userID string, id int64, req models.UpdateScrapedDataRequest) (models.ScrapedData, error) {
+	updates := []string{}
+	args := []interface{}{}
+	argCount := 1
+
+	if req.Title != "" {
+		updates = append(updates, "title = $"+strconv.Itoa(argCount))
+		args = append(args, req.Title)
+		argCount++
+	}
+
+	if req.Content != "" {
+		updates = append(updates, "content = $"+strconv.Itoa(argCount))
+		args = append(args, req.Content)
+		argCount++
+	}
+
+	if req.Metadata != nil {
+		updates = append(updates, "metadata = $"+strconv.Itoa(argCount))
+		args = append(args, req.Metadata)
+		argCount++
+	}
+
+	if req.Tags != nil {
+		updates = append(updates, "tags = $"+strconv.Itoa(argCount))
+		args = append(args, strings.Join(req.Tags, ","))
+		argCount++
+	}
+
+	updates = append(updates, "updated_at = $"+strconv.Itoa(argCount))
+	args = append(args, time.Now().UTC())
+	argCount++
+
+	args = append(args, id, userID)
+
+	query := "UPDATE scraped_data SET " + strings.Join(updates, ", ") +
+		" WHERE id = $" + strconv.Itoa(argCount) + " AND user_id = $" + strconv.Itoa(argCount+1)
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.ScrapedData{}, err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return models.ScrapedData{}, ErrNotFound
+	}
+
+	return s.GetScrapedData(ctx, userID, id)
+}
+
+// DeleteScrapedData removes a scraped page owned by the user
+func (s *SQLStore) DeleteScrapedData(ctx context.Context, userID string, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM scraped_data WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// matchesMetadata reports whether metadata has every key of filter with a value that reads
+// as the filter's text, the way jsonFieldEquals compares them in SQL
+func matchesMetadata(metadata models.Metadata, filter map[string]string) bool {
+	for key, want := range filter {
+		value, ok := metadata[key]
+		if !ok || value == nil {
+			return false
+		}
+		if metadataText(value) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// metadataText renders a decoded JSON value as PostgreSQL's ->> does
+func metadataText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// copyMetadata returns a copy of the top level of metadata, never nil
+func copyMetadata(metadata models.Metadata) models.Metadata {
+	copied := models.Metadata{}
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
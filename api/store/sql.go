@@ -0,0 +1,809 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/models"
+
+	"github.com/google/uuid"
+)
+
+// notTrashed is the WHERE fragment excluding memories in the trash from every read; only
+// the trash endpoints and purges select memories with deleted_at set
+const notTrashed = "memories.deleted_at IS NULL"
+
+// memoryColumns is the column list shared by every memory SELECT; keep it in sync with scanMemory
+const memoryColumns = `id, user_id, url, title, content_type, content, selected_text,
+	context_before, context_after, full_context,
+	element_type, page_section, xpath, tags, notes,
+	created_at, updated_at, scraped_at,
+	video_platform, video_timestamp, video_duration,
+	video_title, video_url, thumbnail_url, formatted_timestamp,
+	canonical_url, content_hash,
+	content_html, byline, published_at, lead_image_url, language,
+	truncated_fields, deleted_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMemory reads a row selected with memoryColumns
+func scanMemory(row rowScanner) (models.Memory, error) {
+	var memory models.Memory
+	err := row.Scan(
+		&memory.ID, &memory.UserID, &memory.URL, &memory.Title, &memory.ContentType,
+		&memory.Content, &memory.SelectedText,
+		&memory.ContextBefore, &memory.ContextAfter, &memory.FullContext,
+		&memory.ElementType, &memory.PageSection, &memory.XPath,
+		&memory.TagsString, &memory.Notes,
+		&memory.CreatedAt, &memory.UpdatedAt, &memory.ScrapedAt,
+		&memory.VideoPlatform, &memory.VideoTimestamp, &memory.VideoDuration,
+		&memory.VideoTitle, &memory.VideoURL, &memory.ThumbnailURL, &memory.FormattedTime,
+		&memory.CanonicalURL, &memory.ContentHash,
+		&memory.ContentHTML, &memory.Byline, &memory.PublishedAt, &memory.LeadImageURL, &memory.Language,
+		&memory.Truncated, &memory.DeletedAt,
+	)
+	return memory, err
+}
+
+// dialect isolates the SQL that differs between database engines
+type dialect interface {
+	// fullTextSearch returns the SQL ranking memories against the search query bound to placeholder
+	fullTextSearch(placeholder string) textSearch
+	// fullTextQuery converts websearch-style user input into the argument expected by fullTextSearch
+	fullTextQuery(query string) string
+	// likeOperator is the case-insensitive substring match operator
+	likeOperator() string
+	// skipLocked is appended to a SELECT so concurrent workers each lock different rows
+	skipLocked() string
+	// forUpdate is appended to a SELECT in a transaction to lock the rows until it ends
+	forUpdate() string
+	// advisoryLock returns a statement locking the text key bound to placeholder until the
+	// transaction ends, or "" when the engine already runs transactions one at a time
+	advisoryLock(placeholder string) string
+	// tableExists returns a query selecting whether the table named by placeholder exists
+	tableExists(placeholder string) string
+	// vectorDistance returns the SQL cosine distance between memory_embeddings.embedding and the
+	// vector bound to placeholder, or "" when the engine has no vector type and the store ranks in Go
+	vectorDistance(placeholder string, dimensions int) string
+	// encodeVector converts a vector to the value stored in memory_embeddings.embedding
+	encodeVector(vector []float32) interface{}
+	// decodeVector converts memory_embeddings.embedding as scanned into bytes back to a vector
+	decodeVector(raw []byte) ([]float32, error)
+	// jsonFieldEquals returns a WHERE fragment matching rows whose JSON object column has the key
+	// bound to keyPlaceholder with a scalar value that reads as the text bound to valuePlaceholder
+	jsonFieldEquals(column, keyPlaceholder, valuePlaceholder string) string
+}
+
+// textSearch is the SQL a dialect uses to find, rank and highlight full-text matches
+type textSearch struct {
+	from       string   // FROM clause joining memories to the matches
+	condition  string   // extra WHERE fragment, if from alone does not restrict to matches
+	rank       string   // relevance expression, higher is better
+	highlights []string // fragments of each highlightFields entry with matches wrapped in <mark>
+}
+
+// memorySelect describes the rows a page of memories is drawn from
+type memorySelect struct {
+	from   string
+	where  string
+	args   []interface{}
+	search *textSearch // orders by relevance and selects highlights; nil orders chronologically
+}
+
+// SQLStore is the MemoryStore backed by a database/sql connection pool
+type SQLStore struct {
+	db      *sql.DB
+	dialect dialect
+
+	embeddingsReady int32 // set to 1 once memory_embeddings is known to exist; see requireEmbeddings
+}
+
+// CreateMemory inserts a memory and its links in a single transaction
+func (s *SQLStore) CreateMemory(ctx context.Context, userID string, req models.CreateMemoryRequest) (models.MemoryResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MemoryResponse{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	memoryID, err := s.insertMemory(ctx, tx, userID, req)
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.MemoryResponse{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetMemory(ctx, userID, memoryID)
+}
+
+// insertMemory saves a new memory with its links and tags in tx and returns its ID
+func (s *SQLStore) insertMemory(ctx context.Context, tx *sql.Tx, userID string, req models.CreateMemoryRequest) (string, error) {
+	now := time.Now().UTC()
+	memoryID := uuid.New().String()
+	tags := normalizeTags(req.Tags)
+
+	query := `
+		INSERT INTO memories (
+			id, user_id, url, title, content_type, content, selected_text,
+			context_before, context_after, full_context,
+			element_type, page_section, xpath, tags, notes,
+			created_at, updated_at, scraped_at,
+			video_platform, video_timestamp, video_duration,
+			video_title, video_url, thumbnail_url, formatted_timestamp,
+			canonical_url, content_hash,
+			content_html, byline, published_at, lead_image_url, language,
+			truncated_fields
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+			$16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27,
+			$28, $29, $30, $31, $32, $33
+		)
+	`
+
+	var videoPlatform, videoTitle, videoURL, thumbnailURL, formattedTime sql.NullString
+	var videoTimestamp, videoDuration sql.NullInt64
+	article := articleColumns(req.Article)
+
+	if req.VideoData != nil {
+		videoPlatform = sql.NullString{String: req.VideoData.Platform, Valid: true}
+		videoTimestamp = sql.NullInt64{Int64: req.VideoData.Timestamp, Valid: true}
+		videoDuration = sql.NullInt64{Int64: req.VideoData.Duration, Valid: true}
+		videoTitle = sql.NullString{String: req.VideoData.VideoTitle, Valid: true}
+		videoURL = sql.NullString{String: req.VideoData.VideoURL, Valid: true}
+		thumbnailURL = sql.NullString{String: req.VideoData.ThumbnailURL, Valid: true}
+		formattedTime = sql.NullString{String: req.VideoData.FormattedTimestamp, Valid: true}
+	}
+
+	_, err := tx.ExecContext(ctx,
+		query,
+		memoryID, userID, nullString(req.URL), req.Title, req.ContentType, nullString(req.Content), nullString(req.SelectedText),
+		nullString(req.ContextBefore), nullString(req.ContextAfter), nullString(req.FullContext),
+		nullString(req.ElementType), nullString(req.PageSection), nullString(req.XPath),
+		nullString(strings.Join(tags, ",")), nullString(req.Notes),
+		now, now, req.ScrapedAt.UTC(),
+		videoPlatform, videoTimestamp, videoDuration,
+		videoTitle, videoURL, thumbnailURL, formattedTime,
+		nullString(CanonicalURL(req.URL)), nullString(contentHash(req.SelectedText, req.Content, req.VideoData)),
+		article.ContentHTML, article.Byline, article.PublishedAt, article.LeadImageURL, article.Language,
+		req.Truncated,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create memory: %w", err)
+	}
+
+	// Insert links if provided
+	linkQuery := `INSERT INTO links (id, memory_id, text, href, link_title, domain, position, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	for i, link := range req.Links {
+		if _, err := tx.ExecContext(ctx, linkQuery,
+			uuid.New().String(), memoryID, link.Text, link.Href, link.Title, nullString(linkDomain(link.Href)), i, now); err != nil {
+			return "", fmt.Errorf("failed to save links: %w", err)
+		}
+	}
+
+	if err := setMemoryTags(ctx, tx, userID, memoryID, tags, now); err != nil {
+		return "", fmt.Errorf("failed to save tags: %w", err)
+	}
+	return memoryID, nil
+}
+
+// GetMemory fetches a single memory owned by the user, unless it is in the trash
+func (s *SQLStore) GetMemory(ctx context.Context, userID, id string) (models.MemoryResponse, error) {
+	query := "SELECT " + memoryColumns + " FROM memories WHERE id = $1 AND user_id = $2 AND " + notTrashed
+
+	memory, err := scanMemory(s.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return models.MemoryResponse{}, ErrNotFound
+	}
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	memories := []models.MemoryResponse{buildMemoryResponse(memory)}
+	if err := s.attachLinks(ctx, memories); err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	return memories[0], nil
+}
+
+// ListMemories returns a page of the user's memories, newest first
+func (s *SQLStore) ListMemories(ctx context.Context, userID string, params models.MemoryQueryParams) (models.MemoryPage, error) {
+	where := "user_id = $1 AND " + notTrashed
+	args := []interface{}{userID}
+	argCount := 2
+
+	if params.ContentType != "" {
+		where += " AND content_type = $" + strconv.Itoa(argCount)
+		args = append(args, params.ContentType)
+		argCount++
+	}
+
+	if params.Platform != "" {
+		where += " AND video_platform = $" + strconv.Itoa(argCount)
+		args = append(args, params.Platform)
+		argCount++
+	}
+
+	// Every comma-separated tag must match exactly
+	for _, tag := range normalizeTags([]string{params.Tags}) {
+		where += " AND " + tagCondition("$"+strconv.Itoa(argCount))
+		args = append(args, tag)
+		argCount++
+	}
+
+	if params.CollectionID != "" {
+		where += " AND " + collectionCondition("$"+strconv.Itoa(argCount))
+		args = append(args, params.CollectionID)
+		argCount++
+	}
+
+	if params.Search != "" {
+		like := " " + s.dialect.likeOperator() + " $" + strconv.Itoa(argCount)
+		where += " AND (title" + like + " OR content" + like + " OR selected_text" + like + ")"
+		args = append(args, "%"+params.Search+"%")
+		argCount++
+	}
+
+	return s.memoryPage(ctx, memorySelect{from: "memories", where: where, args: args}, pageRequest{
+		Limit:        params.Limit,
+		Offset:       params.Offset,
+		Cursor:       params.Cursor,
+		IncludeTotal: params.IncludeTotal,
+	})
+}
+
+// SearchMemories runs a full-text search over the user's memories, most relevant first.
+// Without a query the matching memories are listed newest first.
+func (s *SQLStore) SearchMemories(ctx context.Context, userID string, req models.SearchRequest) (models.MemoryPage, error) {
+	sel, err := s.searchSelect(userID, req)
+	if err != nil {
+		return models.MemoryPage{}, err
+	}
+
+	return s.memoryPage(ctx, sel, pageRequest{
+		Limit:        req.Limit,
+		Offset:       req.Offset,
+		Cursor:       req.Cursor,
+		IncludeTotal: req.IncludeTotal,
+	})
+}
+
+// searchSelect translates the filters of req into the memories they select; pagination fields are ignored
+func (s *SQLStore) searchSelect(userID string, req models.SearchRequest) (memorySelect, error) {
+	start, err := parseDateFilter(req.StartDate)
+	if err != nil {
+		return memorySelect{}, err
+	}
+	end, err := parseDateFilter(req.EndDate)
+	if err != nil {
+		return memorySelect{}, err
+	}
+
+	sel := memorySelect{from: "memories", where: "user_id = $1 AND " + notTrashed, args: []interface{}{userID}}
+	if req.Query != "" {
+		// The query is bound first because the search joins it in the FROM clause
+		search := s.dialect.fullTextSearch("$1")
+		sel = memorySelect{
+			from:   search.from,
+			where:  "user_id = $2 AND " + notTrashed,
+			args:   []interface{}{s.dialect.fullTextQuery(req.Query), userID},
+			search: &search,
+		}
+		if search.condition != "" {
+			sel.where += " AND " + search.condition
+		}
+	}
+	where, args := sel.where, sel.args
+	argCount := len(args) + 1
+
+	if req.ContentType != "" {
+		where += " AND content_type = $" + strconv.Itoa(argCount)
+		args = append(args, req.ContentType)
+		argCount++
+	}
+
+	if req.Platform != "" {
+		where += " AND video_platform = $" + strconv.Itoa(argCount)
+		args = append(args, req.Platform)
+		argCount++
+	}
+
+	for _, tag := range normalizeTags(req.Tags) {
+		where += " AND " + tagCondition("$"+strconv.Itoa(argCount))
+		args = append(args, tag)
+		argCount++
+	}
+
+	if req.CollectionID != "" {
+		where += " AND " + collectionCondition("$"+strconv.Itoa(argCount))
+		args = append(args, req.CollectionID)
+		argCount++
+	}
+
+	if !start.IsZero() {
+		where += " AND created_at >= $" + strconv.Itoa(argCount)
+		args = append(args, start)
+		argCount++
+	}
+
+	if !end.IsZero() {
+		where += " AND created_at <= $" + strconv.Itoa(argCount)
+		args = append(args, end)
+		argCount++
+	}
+
+	sel.where, sel.args = where, args
+	return sel, nil
+}
+
+// memoryPage selects one page of the memories described by sel, ordered by (created_at, id) descending,
+// or by (rank, created_at, id) descending for a full-text search.
+// A cursor continues after the previous page; without one the legacy offset is applied.
+func (s *SQLStore) memoryPage(ctx context.Context, sel memorySelect, page pageRequest) (models.MemoryPage, error) {
+	after, err := decodeCursor(page.Cursor)
+	if err != nil {
+		return models.MemoryPage{}, err
+	}
+	if after != nil && (after.Rank != nil) != (sel.search != nil) {
+		// The cursor came from a listing ordered differently
+		return models.MemoryPage{}, ErrInvalidCursor
+	}
+
+	var total *int
+	if page.IncludeTotal {
+		var count int
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+sel.from+" WHERE "+sel.where, sel.args...).Scan(&count); err != nil {
+			return models.MemoryPage{}, err
+		}
+		total = &count
+	}
+
+	columns, order, key := memoryColumns, "created_at DESC, id DESC", "created_at, id"
+	if sel.search != nil {
+		columns += ", " + sel.search.rank + ", " + strings.Join(sel.search.highlights, ", ")
+		order = sel.search.rank + " DESC, " + order
+		key = sel.search.rank + ", " + key
+	}
+
+	where, args := sel.where, sel.args
+	argCount := len(args) + 1
+	offset := page.Offset
+	if after != nil {
+		placeholders := []string{}
+		if after.Rank != nil {
+			placeholders = append(placeholders, "$"+strconv.Itoa(argCount))
+			args = append(args, *after.Rank)
+			argCount++
+		}
+		placeholders = append(placeholders, "$"+strconv.Itoa(argCount), "$"+strconv.Itoa(argCount+1))
+		args = append(args, after.CreatedAt, after.ID)
+		argCount += 2
+		where += " AND (" + key + ") < (" + strings.Join(placeholders, ", ") + ")"
+		offset = 0
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	query := "SELECT " + columns + " FROM " + sel.from + " WHERE " + where +
+		" ORDER BY " + order + " LIMIT $" + strconv.Itoa(argCount) + " OFFSET $" + strconv.Itoa(argCount+1)
+	args = append(args, page.Limit+1, offset)
+
+	var memories []models.MemoryResponse
+	if sel.search != nil {
+		memories, err = s.scanRankedMemories(ctx, query, args...)
+	} else {
+		memories, err = s.scanMemories(ctx, query, args...)
+	}
+	if err != nil {
+		return models.MemoryPage{}, err
+	}
+
+	result := buildPage(memories, page.Limit)
+	result.Total = total
+	if err := s.attachLinks(ctx, result.Memories); err != nil {
+		return models.MemoryPage{}, err
+	}
+	return result, nil
+}
+
+// UpdateMemory applies the non-empty fields of req to a memory owned by the user
+func (s *SQLStore) UpdateMemory(ctx context.Context, userID, id string, req models.UpdateMemoryRequest, checks ...MemoryCheck) (models.MemoryResponse, error) {
+	updates := []string{}
+	args := []interface{}{}
+	argCount := 1
+
+	if req.Title != "" {
+		updates = append(updates, "title = $"+strconv.Itoa(argCount))
+		args = append(args, req.Title)
+		argCount++
+	}
+
+	tags := normalizeTags(req.Tags)
+	if len(req.Tags) > 0 {
+		updates = append(updates, "tags = $"+strconv.Itoa(argCount))
+		args = append(args, nullString(strings.Join(tags, ",")))
+		argCount++
+	}
+
+	if req.Notes != "" {
+		updates = append(updates, "notes = $"+strconv.Itoa(argCount))
+		args = append(args, req.Notes)
+		argCount++
+	}
+
+	if req.ContentType != "" {
+		updates = append(updates, "content_type = $"+strconv.Itoa(argCount))
+		args = append(args, req.ContentType)
+		argCount++
+	}
+
+	now := time.Now().UTC()
+	updates = append(updates, "updated_at = $"+strconv.Itoa(argCount))
+	args = append(args, now)
+	argCount++
+
+	args = append(args, id, userID)
+
+	query := "UPDATE memories SET " + strings.Join(updates, ", ") +
+		" WHERE id = $" + strconv.Itoa(argCount) + " AND user_id = $" + strconv.Itoa(argCount+1) + " AND " + notTrashed
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkMemory(ctx, tx, userID, id, checks); err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return models.MemoryResponse{}, ErrNotFound
+	}
+
+	if len(req.Tags) > 0 {
+		if err := setMemoryTags(ctx, tx, userID, id, tags, now); err != nil {
+			return models.MemoryResponse{}, err
+		}
+		if err := pruneTags(ctx, tx, userID); err != nil {
+			return models.MemoryResponse{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	return s.GetMemory(ctx, userID, id)
+}
+
+// PatchMemory replaces the editable fields of a memory owned by the user with those patch
+// returns for the current memory, in one transaction so concurrent edits are not lost.
+// An error from patch is returned as is, without changing the memory.
+func (s *SQLStore) PatchMemory(ctx context.Context, userID, id string, patch func(models.MemoryResponse) (models.MemoryEdit, error)) (models.MemoryResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+	defer tx.Rollback()
+
+	existing, err := s.lockMemory(ctx, tx, userID, id)
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	edit, err := patch(existing)
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	now := time.Now().UTC()
+	tags := normalizeTags(edit.Tags)
+	video := videoColumns(edit.VideoData)
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE memories SET
+			title = $1, url = $2, content_type = $3, content = $4, selected_text = $5,
+			context_before = $6, context_after = $7, notes = $8, tags = $9,
+			video_platform = $10, video_timestamp = $11, video_duration = $12, video_title = $13,
+			video_url = $14, thumbnail_url = $15, formatted_timestamp = $16,
+			canonical_url = $17, content_hash = $18, updated_at = $19
+		WHERE id = $20`,
+		edit.Title, nullString(edit.URL), edit.ContentType, nullString(edit.Content), nullString(edit.SelectedText),
+		nullString(edit.ContextBefore), nullString(edit.ContextAfter), nullString(edit.Notes), nullString(strings.Join(tags, ",")),
+		video.Platform, video.Timestamp, video.Duration, video.Title,
+		video.URL, video.ThumbnailURL, video.FormattedTime,
+		nullString(CanonicalURL(edit.URL)), nullString(contentHash(edit.SelectedText, edit.Content, edit.VideoData)), now,
+		id)
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	if err := setMemoryTags(ctx, tx, userID, id, tags, now); err != nil {
+		return models.MemoryResponse{}, err
+	}
+	if err := pruneTags(ctx, tx, userID); err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.MemoryResponse{}, err
+	}
+
+	return s.GetMemory(ctx, userID, id)
+}
+
+// videoFields holds the video columns of a memory, all NULL when it has no video data
+type videoFields struct {
+	Platform, Title, URL, ThumbnailURL, FormattedTime sql.NullString
+	Timestamp, Duration                               sql.NullInt64
+}
+
+func videoColumns(video *models.VideoData) videoFields {
+	if video == nil {
+		return videoFields{}
+	}
+	return videoFields{
+		Platform:      sql.NullString{String: video.Platform, Valid: true},
+		Title:         sql.NullString{String: video.VideoTitle, Valid: true},
+		URL:           sql.NullString{String: video.VideoURL, Valid: true},
+		ThumbnailURL:  sql.NullString{String: video.ThumbnailURL, Valid: true},
+		FormattedTime: sql.NullString{String: video.FormattedTimestamp, Valid: true},
+		Timestamp:     sql.NullInt64{Int64: video.Timestamp, Valid: true},
+		Duration:      sql.NullInt64{Int64: video.Duration, Valid: true},
+	}
+}
+
+// DeleteMemory moves a memory owned by the user to the trash, once checks accept it. Its
+// links, tags and collection places are kept for a restore until it is purged.
+func (s *SQLStore) DeleteMemory(ctx context.Context, userID, id string, checks ...MemoryCheck) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkMemory(ctx, tx, userID, id, checks); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE memories SET deleted_at = $1 WHERE id = $2 AND user_id = $3 AND "+notTrashed,
+		time.Now().UTC(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+// lockMemory reads a memory owned by the user and locks it until tx ends, so it cannot
+// change between a check of its state and the write that depends on it
+func (s *SQLStore) lockMemory(ctx context.Context, tx *sql.Tx, userID, id string) (models.MemoryResponse, error) {
+	memory, err := scanMemory(tx.QueryRowContext(ctx,
+		"SELECT "+memoryColumns+" FROM memories WHERE id = $1 AND user_id = $2 AND "+notTrashed+s.dialect.forUpdate(), id, userID))
+	if err == sql.ErrNoRows {
+		return models.MemoryResponse{}, ErrNotFound
+	}
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+	return buildMemoryResponse(memory), nil
+}
+
+// checkMemory runs checks on the locked memory; without checks it reads nothing
+func (s *SQLStore) checkMemory(ctx context.Context, tx *sql.Tx, userID, id string, checks []MemoryCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	memory, err := s.lockMemory(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+	for _, check := range checks {
+		if err := check(memory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MemoryURLs returns the set of canonical URLs (see CanonicalURL) the user has saved memories for
+func (s *SQLStore) MemoryURLs(ctx context.Context, userID string) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT DISTINCT canonical_url FROM memories WHERE user_id = $1 AND "+notTrashed+" AND canonical_url IS NOT NULL AND canonical_url <> ''", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	urls := map[string]bool{}
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		urls[url] = true
+	}
+	return urls, rows.Err()
+}
+
+// MemoryStats aggregates usage statistics for the user's memories outside the trash
+func (s *SQLStore) MemoryStats(ctx context.Context, userID string) (models.Stats, error) {
+	stats := models.Stats{
+		ByContentType: make(map[string]int),
+		ByPlatform:    make(map[string]int),
+		MostUsedTags:  []models.TagCount{},
+	}
+
+	// Total memories
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM memories WHERE user_id = $1 AND "+notTrashed, userID).Scan(&stats.TotalMemories); err != nil {
+		return stats, err
+	}
+
+	// By content type
+	if err := s.countInto(ctx, stats.ByContentType,
+		"SELECT content_type, COUNT(*) FROM memories WHERE user_id = $1 AND "+notTrashed+" GROUP BY content_type", userID); err != nil {
+		return stats, err
+	}
+
+	// By platform
+	if err := s.countInto(ctx, stats.ByPlatform,
+		"SELECT video_platform, COUNT(*) FROM memories WHERE user_id = $1 AND "+notTrashed+" AND video_platform IS NOT NULL GROUP BY video_platform", userID); err != nil {
+		return stats, err
+	}
+
+	// Recent count (last 7 days)
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM memories WHERE user_id = $1 AND "+notTrashed+" AND created_at > $2", userID, time.Now().UTC().AddDate(0, 0, -7)).Scan(&stats.RecentCount); err != nil {
+		return stats, err
+	}
+
+	// Tags in use
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(DISTINCT t.id) FROM tags t JOIN memory_tags mt ON mt.tag_id = t.id"+
+			" JOIN memories ON memories.id = mt.memory_id WHERE t.user_id = $1 AND "+notTrashed, userID).Scan(&stats.TotalTags); err != nil {
+		return stats, err
+	}
+
+	mostUsed, err := s.tagCounts(ctx, userID, mostUsedTagsLimit)
+	if err != nil {
+		return stats, err
+	}
+	stats.MostUsedTags = mostUsed
+
+	return stats, nil
+}
+
+// queryMemories runs a query selecting memoryColumns and loads the links of every result
+func (s *SQLStore) queryMemories(ctx context.Context, query string, args ...interface{}) ([]models.MemoryResponse, error) {
+	memories, err := s.scanMemories(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachLinks(ctx, memories); err != nil {
+		return nil, err
+	}
+	return memories, nil
+}
+
+// scanMemories runs a query selecting memoryColumns; the rows are closed before it returns
+// so follow-up queries work on single-connection pools such as SQLite's
+func (s *SQLStore) scanMemories(ctx context.Context, query string, args ...interface{}) ([]models.MemoryResponse, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memories := []models.MemoryResponse{}
+	for rows.Next() {
+		memory, err := scanMemory(rows)
+		if err != nil {
+			return nil, err
+		}
+		memories = append(memories, buildMemoryResponse(memory))
+	}
+
+	return memories, rows.Err()
+}
+
+// scanRankedMemories is scanMemories for a query selecting memoryColumns followed by
+// the rank and highlight columns of a textSearch
+func (s *SQLStore) scanRankedMemories(ctx context.Context, query string, args ...interface{}) ([]models.MemoryResponse, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memories := []models.MemoryResponse{}
+	for rows.Next() {
+		var rank float64
+		fragments := make([]string, len(highlightFields))
+		extra := []interface{}{&rank}
+		for i := range fragments {
+			extra = append(extra, &fragments[i])
+		}
+
+		memory, err := scanMemory(extraColumns{row: rows, extra: extra})
+		if err != nil {
+			return nil, err
+		}
+
+		response := buildMemoryResponse(memory)
+		response.Rank = &rank
+		response.Highlights = keepHighlights(fragments)
+		memories = append(memories, response)
+	}
+
+	return memories, rows.Err()
+}
+
+// extraColumns scans the columns selected after memoryColumns into extra
+type extraColumns struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func (e extraColumns) Scan(dest ...interface{}) error {
+	return e.row.Scan(append(dest, e.extra...)...)
+}
+
+func (s *SQLStore) countInto(ctx context.Context, counts map[string]int, query string, args ...interface{}) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return err
+		}
+		counts[key] = count
+	}
+
+	return rows.Err()
+}
+
+// articleColumns maps an extracted article to its memory columns; a nil article sets none
+func articleColumns(article *models.Article) models.Memory {
+	var memory models.Memory
+	if article == nil {
+		return memory
+	}
+	memory.ContentHTML = nullString(article.HTML)
+	memory.Byline = nullString(article.Byline)
+	if article.PublishedAt != nil {
+		memory.PublishedAt = sql.NullTime{Time: article.PublishedAt.UTC(), Valid: true}
+	}
+	memory.LeadImageURL = nullString(article.LeadImageURL)
+	memory.Language = nullString(article.Language)
+	return memory
+}
+
+func nullString(s string) sql.NullString {
+	if s == "" {
+		return sql.NullString{Valid: false}
+	}
+	return sql.NullString{String: s, Valid: true}
+}
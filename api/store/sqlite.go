@@ -0,0 +1,127 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// sqliteDialect targets an embedded SQLite file with the FTS5 extension
+type sqliteDialect struct{}
+
+// NewSQLiteStore creates a SQLStore for a SQLite database migrated with the sqlite migrations
+func NewSQLiteStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: sqliteDialect{}}
+}
+
+// fullTextSearch joins the FTS5 matches; bm25 weights mirror the Postgres setweight labels
+// (title, content, selected_text, tags) and is negated so that higher ranks are better
+func (sqliteDialect) fullTextSearch(placeholder string) textSearch {
+	matches := `SELECT memory_id,
+			-bm25(memories_fts, 0.0, 10.0, 1.0, 2.0, 4.0) AS search_rank,
+			highlight(memories_fts, 1, '<mark>', '</mark>') AS title_highlight,
+			snippet(memories_fts, 2, '<mark>', '</mark>', '…', 30) AS content_highlight,
+			snippet(memories_fts, 3, '<mark>', '</mark>', '…', 30) AS selected_text_highlight
+		FROM memories_fts WHERE memories_fts MATCH ` + placeholder
+
+	return textSearch{
+		from: "memories JOIN (" + matches + ") AS fts ON fts.memory_id = memories.id",
+		rank: "fts.search_rank",
+		highlights: []string{
+			"COALESCE(fts.title_highlight, '')",
+			"COALESCE(fts.content_highlight, '')",
+			"COALESCE(fts.selected_text_highlight, '')",
+		},
+	}
+}
+
+// fullTextQuery translates the websearch_to_tsquery syntax into an FTS5 query.
+// Every term is quoted so FTS5 operators in the input are never interpreted.
+func (sqliteDialect) fullTextQuery(query string) string {
+	alternatives := []string{}
+	for _, terms := range parseWebSearch(query) {
+		include, exclude := []string{}, []string{}
+		for _, term := range terms {
+			phrase := `"` + strings.Join(term.words, " ") + `"`
+			if term.exclude {
+				exclude = append(exclude, phrase)
+			} else {
+				include = append(include, phrase)
+			}
+		}
+
+		// FTS5 has no unary NOT, so an alternative made only of exclusions cannot be expressed
+		if len(include) == 0 {
+			continue
+		}
+		expr := "(" + strings.Join(include, " AND ") + ")"
+		for _, phrase := range exclude {
+			expr += " NOT " + phrase
+		}
+		alternatives = append(alternatives, "("+expr+")")
+	}
+
+	if len(alternatives) == 0 {
+		return `""`
+	}
+	return strings.Join(alternatives, " OR ")
+}
+
+func (sqliteDialect) likeOperator() string {
+	// LIKE is already case-insensitive for ASCII in SQLite
+	return "LIKE"
+}
+
+func (sqliteDialect) skipLocked() string {
+	// A SQLite database has a single writer, so claiming transactions never overlap
+	return ""
+}
+
+func (sqliteDialect) forUpdate() string {
+	// Writes are serialized on the single connection, so a read in a transaction is stable
+	return ""
+}
+
+func (sqliteDialect) advisoryLock(placeholder string) string {
+	// A transaction holds the single connection, so no other one runs until it ends
+	return ""
+}
+
+func (sqliteDialect) tableExists(placeholder string) string {
+	return "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = " + placeholder + ")"
+}
+
+func (sqliteDialect) vectorDistance(placeholder string, dimensions int) string {
+	return ""
+}
+
+// encodeVector packs a vector as little-endian float32s
+func (sqliteDialect) encodeVector(vector []float32) interface{} {
+	blob := make([]byte, 4*len(vector))
+	for i, x := range vector {
+		binary.LittleEndian.PutUint32(blob[4*i:], math.Float32bits(x))
+	}
+	return blob
+}
+
+// decodeVector unpacks a vector stored by encodeVector
+func (sqliteDialect) decodeVector(blob []byte) ([]float32, error) {
+	if len(blob)%4 != 0 {
+		return nil, fmt.Errorf("embedding of %d bytes is not a float32 vector", len(blob))
+	}
+	vector := make([]float32, len(blob)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(blob[4*i:]))
+	}
+	return vector, nil
+}
+
+// jsonFieldEquals looks the key up with json_each, so it needs no JSON path quoting, and
+// renders booleans as true/false to read like PostgreSQL's ->>
+func (sqliteDialect) jsonFieldEquals(column, keyPlaceholder, valuePlaceholder string) string {
+	return "EXISTS (SELECT 1 FROM json_each(" + column + ") AS field WHERE field.key = " + keyPlaceholder +
+		" AND (CASE field.type WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE CAST(field.value AS TEXT) END) = " +
+		valuePlaceholder + ")"
+}
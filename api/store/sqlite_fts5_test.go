@@ -0,0 +1,283 @@
+//go:build sqlite_fts5
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"api/config"
+	"api/migrations"
+	"api/models"
+)
+
+func init() {
+	testStores["sqlite"] = func(t *testing.T) Store { return newSQLiteTestStore(t) }
+}
+
+// openSQLiteTestDB opens a new SQLite database file with foreign keys enforced
+func openSQLiteTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_foreign_keys=on"
+	db, err := sql.Open(config.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newSQLiteTestStore migrates a new SQLite database file and returns a store backed by it
+func newSQLiteTestStore(t *testing.T) *SQLStore {
+	t.Helper()
+
+	db := openSQLiteTestDB(t)
+	if _, err := migrations.Up(db, config.DriverSQLite); err != nil {
+		t.Fatalf("failed to migrate SQLite database: %v", err)
+	}
+	return NewSQLiteStore(db)
+}
+
+func TestSQLiteFullTextQueriesAreValidFTS5(t *testing.T) {
+	s := newSQLiteTestStore(t)
+	ctx := context.Background()
+
+	golang := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Type parameters in Go", Content: "generics arrived in go 1.18"})
+	rust := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Rust traits", Content: "generics with trait bounds"})
+	java := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Java beans", Content: "coffee and go"})
+
+	// Every translation must be accepted by MATCH, however odd the input
+	for _, tc := range fullTextQueries {
+		if _, err := s.SearchMemories(ctx, ownerID, models.SearchRequest{Query: tc.query, Limit: 10}); err != nil {
+			t.Errorf("search %q (MATCH %s) failed: %v", tc.query, tc.want, err)
+		}
+	}
+
+	searches := []struct {
+		query string
+		want  []string
+	}{
+		{`generics`, []string{golang.ID, rust.ID}},
+		{`"type parameters"`, []string{golang.ID}},
+		{`"parameters type"`, []string{}},
+		{`generics -rust`, []string{golang.ID}},
+		{`traits OR beans`, []string{rust.ID, java.ID}},
+		{`-"java beans" go`, []string{golang.ID}},
+		{`go*`, []string{golang.ID, java.ID}},
+		{`-java`, []string{}},
+	}
+	for _, search := range searches {
+		page, err := s.SearchMemories(ctx, ownerID, models.SearchRequest{Query: search.query, Limit: 10})
+		if err != nil {
+			t.Fatalf("search %q failed: %v", search.query, err)
+		}
+		if got := memoryIDs(page.Memories); !sameIDs(got, search.want) {
+			t.Errorf("search %q: expected %v, got %v", search.query, search.want, got)
+		}
+	}
+}
+
+func TestSQLiteKeysetPagesThroughCreatedAtTies(t *testing.T) {
+	s := newSQLiteTestStore(t)
+	ctx := context.Background()
+
+	created := []string{}
+	for i := 0; i < 5; i++ {
+		created = append(created, createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "golang tie"}).ID)
+	}
+	if _, err := s.db.Exec("UPDATE memories SET created_at = $1", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("failed to tie created_at: %v", err)
+	}
+
+	for _, query := range []string{"", "golang"} {
+		seen, cursor := []string{}, ""
+		for pages := 0; pages < 10; pages++ {
+			var page models.MemoryPage
+			var err error
+			if query == "" {
+				page, err = s.ListMemories(ctx, ownerID, models.MemoryQueryParams{Limit: 2, Cursor: cursor})
+			} else {
+				page, err = s.SearchMemories(ctx, ownerID, models.SearchRequest{Query: query, Limit: 2, Cursor: cursor})
+			}
+			if err != nil {
+				t.Fatalf("query %q: page %d failed: %v", query, pages, err)
+			}
+			seen = append(seen, memoryIDs(page.Memories)...)
+			if cursor = page.NextCursor; cursor == "" {
+				break
+			}
+		}
+
+		if !sameIDs(seen, created) {
+			t.Fatalf("query %q: expected every memory exactly once, got %v", query, seen)
+		}
+		for i := 1; i < len(seen); i++ {
+			if seen[i] >= seen[i-1] {
+				t.Errorf("query %q: ties are not ordered by id descending: %v", query, seen)
+				break
+			}
+		}
+	}
+}
+
+// storedTags returns the names of the user's rows in the tags table
+func storedTags(t *testing.T, s *SQLStore, userID string) []string {
+	t.Helper()
+
+	rows, err := s.db.Query("SELECT name FROM tags WHERE user_id = $1 ORDER BY name", userID)
+	if err != nil {
+		t.Fatalf("failed to list tags: %v", err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("failed to scan tag: %v", err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestSQLiteUnusedTagsArePruned(t *testing.T) {
+	s := newSQLiteTestStore(t)
+	ctx := context.Background()
+	memory := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "One", Tags: []string{"a", "b", "c"}})
+
+	steps := []struct {
+		name string
+		edit func() error
+		want []string
+	}{
+		{"update", func() error {
+			_, err := s.UpdateMemory(ctx, ownerID, memory.ID, models.UpdateMemoryRequest{Tags: []string{"b", "c"}})
+			return err
+		}, []string{"b", "c"}},
+		{"patch", func() error {
+			_, err := s.PatchMemory(ctx, ownerID, memory.ID, func(current models.MemoryResponse) (models.MemoryEdit, error) {
+				return models.MemoryEdit{Title: current.Title, ContentType: current.ContentType, Tags: []string{"c", "d"}}, nil
+			})
+			return err
+		}, []string{"c", "d"}},
+		{"rename", func() error {
+			_, err := s.RenameTag(ctx, ownerID, "d", "e")
+			return err
+		}, []string{"c", "e"}},
+		{"delete tag", func() error {
+			_, err := s.DeleteTag(ctx, ownerID, "c")
+			return err
+		}, []string{"e"}},
+		{"purge", func() error {
+			if err := s.DeleteMemory(ctx, ownerID, memory.ID); err != nil {
+				return err
+			}
+			return s.PurgeMemory(ctx, ownerID, memory.ID)
+		}, []string{}},
+	}
+	for _, step := range steps {
+		if err := step.edit(); err != nil {
+			t.Fatalf("%s failed: %v", step.name, err)
+		}
+		if got := storedTags(t, s, ownerID); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("after %s: expected tags %v, got %v", step.name, step.want, got)
+		}
+	}
+}
+
+func TestEmbeddingsNeedTheirTable(t *testing.T) {
+	s := newSQLiteTestStore(t)
+	ctx := context.Background()
+	memory := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Embedded"})
+
+	// A Postgres database without pgvector is migrated without the table
+	if _, err := s.db.Exec("DROP TABLE memory_embeddings"); err != nil {
+		t.Fatalf("drop failed: %v", err)
+	}
+	if err := s.SaveEmbedding(ctx, memory.ID, "m", []float32{1, 0}, "h"); !errors.Is(err, ErrEmbeddingsUnavailable) {
+		t.Errorf("save: expected ErrEmbeddingsUnavailable, got %v", err)
+	}
+	if _, err := s.SemanticSearch(ctx, ownerID, models.VectorQuery{Model: "m", Vector: []float32{1, 0}, Limit: 5}); !errors.Is(err, ErrEmbeddingsUnavailable) {
+		t.Errorf("search: expected ErrEmbeddingsUnavailable, got %v", err)
+	}
+	if vector, err := s.memoryVector(ctx, memory.ID, "m"); vector != nil || err != nil {
+		t.Errorf("related memories: expected no vector and no error, got %v, %v", vector, err)
+	}
+
+	if _, err := s.db.Exec(`CREATE TABLE memory_embeddings (memory_id TEXT PRIMARY KEY, model TEXT NOT NULL,
+		dimensions INTEGER NOT NULL, embedding BLOB NOT NULL, source_hash TEXT NOT NULL, embedded_at TIMESTAMP NOT NULL)`); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := s.SaveEmbedding(ctx, memory.ID, "m", []float32{1, 0}, "h"); err != nil {
+		t.Errorf("save once the table exists: %v", err)
+	}
+}
+
+func TestBackfillClearsCanonicalURLsOfNonHTTPPages(t *testing.T) {
+	s := newSQLiteTestStore(t)
+	ctx := context.Background()
+	page := createMemory(t, s, ownerID, models.CreateMemoryRequest{URL: "https://www.example.com/a/", Title: "Page"})
+	note := createMemory(t, s, ownerID, models.CreateMemoryRequest{URL: "unknown", Title: "Note"})
+	settings := createMemory(t, s, ownerID, models.CreateMemoryRequest{URL: "chrome://settings", Title: "Settings"})
+
+	// Keys as stored before they were tracked, and as stored by earlier versions for non-http(s) values
+	if _, err := s.db.Exec("UPDATE memories SET canonical_url = NULL WHERE id = $1", page.ID); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if _, err := s.db.Exec("UPDATE memories SET canonical_url = url WHERE id IN ($1, $2)", note.ID, settings.ID); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	if updated, err := s.BackfillDuplicateKeys(ctx); err != nil || updated != 3 {
+		t.Fatalf("backfill: expected 3 updates, got %d, %v", updated, err)
+	}
+	want := map[string]sql.NullString{
+		page.ID:     {String: "https://example.com/a", Valid: true},
+		note.ID:     {},
+		settings.ID: {},
+	}
+	for id, canonical := range want {
+		var got sql.NullString
+		if err := s.db.QueryRow("SELECT canonical_url FROM memories WHERE id = $1", id).Scan(&got); err != nil {
+			t.Fatalf("select failed: %v", err)
+		}
+		if got != canonical {
+			t.Errorf("memory %s: expected canonical URL %+v, got %+v", id, canonical, got)
+		}
+	}
+
+	if updated, err := s.BackfillDuplicateKeys(ctx); err != nil || updated != 0 {
+		t.Errorf("second backfill: expected no updates, got %d, %v", updated, err)
+	}
+}
+
+func TestRelatedMemoriesWithoutPgvector(t *testing.T) {
+	s := newSQLiteTestStore(t)
+	f := createRelatedFixture(t, s)
+
+	// A Postgres database without pgvector is migrated without the table; a new store
+	// has not yet seen the table, as on an instance that started that way
+	if _, err := s.db.Exec("DROP TABLE memory_embeddings"); err != nil {
+		t.Fatalf("drop failed: %v", err)
+	}
+	related, err := NewSQLiteStore(s.db).RelatedMemories(context.Background(), ownerID, f.source, models.RelatedQuery{Model: "m", Limit: 10})
+	if err != nil {
+		t.Fatalf("expected the other reasons to count, got %v", err)
+	}
+	want := []string{f.everything, f.tagged, f.sameSite, f.linking}
+	got := []string{}
+	for _, memory := range related {
+		got = append(got, memory.ID)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,38 @@
+package store
+
+import "testing"
+
+// fullTextQueries pairs websearch-style input with its FTS5 translation
+var fullTextQueries = []struct {
+	query string
+	want  string
+}{
+	{`go generics`, `(("go" AND "generics"))`},
+	{`"type parameters" go`, `(("type parameters" AND "go"))`},
+	{`go -java`, `(("go") NOT "java")`},
+	{`-"java beans" coffee`, `(("coffee") NOT "java beans")`},
+	{`go OR rust`, `(("go")) OR (("rust"))`},
+	{`go or rust`, `(("go")) OR (("rust"))`},
+	{`OR go`, `(("go"))`},
+	{`go OR`, `(("go"))`},
+	{`go OR -rust`, `(("go"))`},
+	{`-java`, `""`},
+	{`"unterminated phrase`, `(("unterminated phrase"))`},
+	{`a"b`, `(("a b"))`},
+	{`""`, `""`},
+	{`*`, `""`},
+	{`go*`, `(("go"))`},
+	{`title:go`, `(("title go"))`},
+	{`NEAR(a b)`, `(("near a" AND "b"))`},
+	{`Go AND Rust`, `(("go" AND "and" AND "rust"))`},
+	{``, `""`},
+	{"  \t ", `""`},
+}
+
+func TestSQLiteFullTextQuery(t *testing.T) {
+	for _, tc := range fullTextQueries {
+		if got := (sqliteDialect{}).fullTextQuery(tc.query); got != tc.want {
+			t.Errorf("fullTextQuery(%q) = %s, want %s", tc.query, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"api/config"
+	"api/models"
+)
+
+// ErrNotFound is returned when a record does not exist or is not owned by the caller
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a change would collide with an existing record
+var ErrConflict = errors.New("conflict")
+
+// ErrInvalidTag is returned when a tag name is empty after normalization
+var ErrInvalidTag = errors.New("invalid tag name")
+
+// ErrInvalidDate is returned when a start or end date filter is in none of the accepted formats
+var ErrInvalidDate = errors.New("invalid date")
+
+// ErrEmbeddingsUnavailable is returned by the embedding methods of a Postgres database
+// migrated without the pgvector extension, which has nowhere to keep embeddings
+var ErrEmbeddingsUnavailable = errors.New("embeddings need the pgvector extension, which is not installed")
+
+// ErrPreconditionFailed is returned when a MemoryCheck rejects the current state of a memory
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// MemoryCheck inspects the current state of a memory before it is changed, in the same
+// transaction as the change; an error (usually ErrPreconditionFailed) aborts the change.
+// It runs with the store locked and must not call it.
+type MemoryCheck func(current models.MemoryResponse) error
+
+// MemoryStore persists memories captured by the browser extension.
+// Every method is scoped to the given user ID; records owned by other users
+// behave exactly like records that do not exist.
+type MemoryStore interface {
+	CreateMemory(ctx context.Context, userID string, req models.CreateMemoryRequest) (models.MemoryResponse, error)
+	GetMemory(ctx context.Context, userID, id string) (models.MemoryResponse, error)
+	ListMemories(ctx context.Context, userID string, params models.MemoryQueryParams) (models.MemoryPage, error)
+	SearchMemories(ctx context.Context, userID string, req models.SearchRequest) (models.MemoryPage, error)
+	UpdateMemory(ctx context.Context, userID, id string, req models.UpdateMemoryRequest, checks ...MemoryCheck) (models.MemoryResponse, error)
+	PatchMemory(ctx context.Context, userID, id string, patch func(models.MemoryResponse) (models.MemoryEdit, error)) (models.MemoryResponse, error)
+	DeleteMemory(ctx context.Context, userID, id string, checks ...MemoryCheck) error
+	MemoryURLs(ctx context.Context, userID string) (map[string]bool, error)
+	CreateOrMergeMemory(ctx context.Context, userID string, req models.CreateMemoryRequest) (models.MemoryResponse, bool, error)
+	ListDuplicates(ctx context.Context, userID string, limit int) ([]models.DuplicateGroup, error)
+	ApplyBulk(ctx context.Context, userID string, req models.BulkRequest) (models.BulkResult, error)
+	MemoryStats(ctx context.Context, userID string) (models.Stats, error)
+	RelatedMemories(ctx context.Context, userID, id string, q models.RelatedQuery) ([]models.RelatedMemory, error)
+}
+
+// LinkStore queries the links extracted from captured pages
+type LinkStore interface {
+	ListLinks(ctx context.Context, userID string, params models.LinkQueryParams) ([]models.LinkResponse, error)
+	ListBacklinks(ctx context.Context, userID string, params models.LinkQueryParams) ([]models.MemoryResponse, error)
+}
+
+// TagStore manages the user's tags. Names are normalized (lowercased, whitespace collapsed)
+// before use; every operation changes all affected memories atomically and returns how many changed.
+type TagStore interface {
+	ListTags(ctx context.Context, userID string) ([]models.TagCount, error)
+	RenameTag(ctx context.Context, userID, from, to string) (int, error)
+	MergeTags(ctx context.Context, userID string, sources []string, target string) (int, error)
+	DeleteTag(ctx context.Context, userID, name string) (int, error)
+}
+
+// CollectionStore manages named, ordered groups of the user's memories.
+// Member lists are edited atomically; memories themselves are never deleted with a collection.
+type CollectionStore interface {
+	CreateCollection(ctx context.Context, userID string, req models.CreateCollectionRequest) (models.Collection, error)
+	GetCollection(ctx context.Context, userID, id string) (models.Collection, error)
+	ListCollections(ctx context.Context, userID string) ([]models.Collection, error)
+	UpdateCollection(ctx context.Context, userID, id string, req models.UpdateCollectionRequest) (models.Collection, error)
+	DeleteCollection(ctx context.Context, userID, id string) error
+	ListCollectionMemories(ctx context.Context, userID, id string, limit, offset int) ([]models.MemoryResponse, error)
+	AddToCollection(ctx context.Context, userID, id string, memoryIDs []string, position *int) error
+	RemoveFromCollection(ctx context.Context, userID, id, memoryID string) error
+	ReorderCollection(ctx context.Context, userID, id string, memoryIDs []string) error
+}
+
+// MaintenanceStore runs housekeeping across all users; it is meant for startup and background tasks
+type MaintenanceStore interface {
+	BackfillDuplicateKeys(ctx context.Context) (int, error)
+}
+
+// JobStore persists the background job queue. Claiming and finishing jobs serves every user,
+// since workers are shared; listing is scoped to the given user.
+type JobStore interface {
+	EnqueueJob(ctx context.Context, job models.Job) (models.Job, error)
+	ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]models.Job, error)
+	FinishJob(ctx context.Context, job models.Job) error
+	ListJobs(ctx context.Context, userID string, params models.JobQueryParams) ([]models.Job, error)
+	CountJobs(ctx context.Context, userID string) (map[string]int, error)
+	PruneJobs(ctx context.Context, before time.Time) (int, error)
+}
+
+// EmbeddingStore keeps one embedding per memory for semantic search. Saving and listing
+// unembedded memories serve the background jobs of every user; searching is scoped to the user.
+type EmbeddingStore interface {
+	SaveEmbedding(ctx context.Context, memoryID, model string, vector []float32, sourceHash string) error
+	EmbeddingHash(ctx context.Context, memoryID, model string) (string, error)
+	UnembeddedMemories(ctx context.Context, model string, limit int) ([]models.MemoryRef, error)
+	SemanticSearch(ctx context.Context, userID string, q models.VectorQuery) ([]models.MemoryResponse, error)
+}
+
+// ScrapedDataStore persists raw pages saved by the extension with free-form JSON metadata.
+// Every method is scoped to the given user ID, like MemoryStore.
+type ScrapedDataStore interface {
+	CreateScrapedData(ctx context.Context, userID string, req models.CreateScrapedDataRequest) (models.ScrapedData, error)
+	GetScrapedData(ctx context.Context, userID string, id int64) (models.ScrapedData, error)
+	ListScrapedData(ctx context.Context, userID string, params models.ScrapedDataQueryParams) ([]models.ScrapedData, error)
+	UpdateScrapedData(ctx context.Context, userID string, id int64, req models.UpdateScrapedDataRequest) (models.ScrapedData, error)
+	DeleteScrapedData(ctx context.Context, userID string, id int64) error
+}
+
+// ItemStore persists the user's items. Every method is scoped to the given user ID.
+type ItemStore interface {
+	CreateItem(ctx context.Context, userID string, req models.CreateItemRequest) (models.Item, error)
+	GetItem(ctx context.Context, userID string, id int64) (models.Item, error)
+	ListItems(ctx context.Context, userID string, params models.ItemQueryParams) ([]models.Item, error)
+	UpdateItem(ctx context.Context, userID string, id int64, req models.UpdateItemRequest) (models.Item, error)
+	DeleteItem(ctx context.Context, userID string, id int64) error
+}
+
+// TrashStore manages the memories MemoryStore.DeleteMemory moves to the trash, where every
+// other read leaves them out. A memory is restored as it was, or purged for good with its
+// links, tags and collection places. PurgeTrash serves the background job of every user;
+// the other methods are scoped to the given user.
+type TrashStore interface {
+	ListTrash(ctx context.Context, userID string, limit, offset int) ([]models.MemoryResponse, error)
+	RestoreMemory(ctx context.Context, userID, id string) (models.MemoryResponse, error)
+	PurgeMemory(ctx context.Context, userID, id string) error
+	EmptyTrash(ctx context.Context, userID string) (int, error)
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+}
+
+// Store is implemented by every storage backend
+type Store interface {
+	MemoryStore
+	LinkStore
+	TagStore
+	CollectionStore
+	MaintenanceStore
+	JobStore
+	EmbeddingStore
+	ScrapedDataStore
+	ItemStore
+	TrashStore
+}
+
+// New returns the Store implementation for the configured database driver
+func New(db *sql.DB, driver string) (Store, error) {
+	switch driver {
+	case config.DriverPostgres:
+		return NewPostgresStore(db), nil
+	case config.DriverSQLite:
+		return NewSQLiteStore(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// buildMemoryResponse expands the stored representation into the API shape
+func buildMemoryResponse(memory models.Memory) models.MemoryResponse {
+	response := models.MemoryResponse{
+		Memory: memory,
+		Links:  []models.LinkResponse{},
+	}
+
+	// Parse tags
+	if memory.TagsString.Valid && memory.TagsString.String != "" {
+		response.Tags = strings.Split(memory.TagsString.String, ",")
+	} else {
+		response.Tags = []string{}
+	}
+
+	// Build video data if present
+	if memory.VideoPlatform.Valid {
+		response.VideoData = &models.VideoData{
+			Platform:           memory.VideoPlatform.String,
+			Timestamp:          memory.VideoTimestamp.Int64,
+			Duration:           memory.VideoDuration.Int64,
+			VideoTitle:         memory.VideoTitle.String,
+			VideoURL:           memory.VideoURL.String,
+			ThumbnailURL:       memory.ThumbnailURL.String,
+			FormattedTimestamp: memory.FormattedTime.String,
+		}
+	}
+
+	return response
+}
+
+// linkDomain returns the lowercased host of href without a leading "www.", or "" if it has none
+func linkDomain(href string) string {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// normalizeDomain accepts a bare host or a full URL and returns it in linkDomain form
+func normalizeDomain(input string) string {
+	if !strings.Contains(input, "://") {
+		input = "//" + input
+	}
+	return linkDomain(input)
+}
+
+// matchesDomain reports whether host is domain or one of its subdomains
+func matchesDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// urlVariants returns href with and without a trailing slash so lookups ignore the difference
+func urlVariants(href string) (string, string) {
+	trimmed := strings.TrimSuffix(strings.TrimSpace(href), "/")
+	return trimmed, trimmed + "/"
+}
+
+// parseDateFilter accepts the date formats PostgreSQL would for start/end filters
+func parseDateFilter(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w %q: use YYYY-MM-DD or RFC 3339", ErrInvalidDate, value)
+}
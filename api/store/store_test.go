@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"api/models"
+)
+
+const (
+	ownerID    = "user-a"
+	intruderID = "user-b"
+)
+
+// testStores lists the backends the store is tested against; builds with the
+// sqlite_fts5 tag add a migrated SQLite database
+var testStores = map[string]func(t *testing.T) Store{
+	"memory": func(t *testing.T) Store { return NewInMemoryStore() },
+}
+
+// forEachStore runs test against a new store of each of testStores
+func forEachStore(t *testing.T, test func(t *testing.T, s Store)) {
+	for name, newStore := range testStores {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+// createMemory saves req as userID, defaulting the required fields, and returns the memory
+func createMemory(t *testing.T, s Store, userID string, req models.CreateMemoryRequest) models.MemoryResponse {
+	t.Helper()
+
+	if req.ContentType == "" {
+		req.ContentType = "page"
+	}
+	memory, err := s.CreateMemory(context.Background(), userID, req)
+	if err != nil {
+		t.Fatalf("failed to create memory %q: %v", req.Title, err)
+	}
+	return memory
+}
+
+// memoryIDs returns the IDs of memories in order
+func memoryIDs(memories []models.MemoryResponse) []string {
+	ids := []string{}
+	for _, memory := range memories {
+		ids = append(ids, memory.ID)
+	}
+	return ids
+}
+
+// sameIDs compares two ID lists regardless of order
+func sameIDs(got, want []string) bool {
+	set := func(ids []string) map[string]bool {
+		m := map[string]bool{}
+		for _, id := range ids {
+			m[id] = true
+		}
+		return m
+	}
+	return len(got) == len(want) && reflect.DeepEqual(set(got), set(want))
+}
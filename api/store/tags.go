@@ -0,0 +1,258 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/models"
+
+	"github.com/google/uuid"
+)
+
+// mostUsedTagsLimit caps Stats.MostUsedTags
+const mostUsedTagsLimit = 10
+
+// normalizeTag lowercases a tag and collapses its whitespace; commas are not allowed
+// because memories.tags stores the names comma-separated
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(tag, ",", " ")), " "))
+}
+
+// normalizeTags normalizes every tag, splitting comma-separated input, and drops
+// empty names and duplicates while keeping the original order
+func normalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		for _, part := range strings.Split(tag, ",") {
+			name := normalizeTag(part)
+			if name != "" && !seen[name] {
+				seen[name] = true
+				normalized = append(normalized, name)
+			}
+		}
+	}
+	return normalized
+}
+
+// splitTags parses the comma-separated memories.tags column
+func splitTags(tags string) []string {
+	if tags == "" {
+		return []string{}
+	}
+	return strings.Split(tags, ",")
+}
+
+// tagCondition returns a WHERE fragment matching memories tagged exactly with the name bound to placeholder
+func tagCondition(placeholder string) string {
+	return "EXISTS (SELECT 1 FROM memory_tags mt JOIN tags t ON t.id = mt.tag_id" +
+		" WHERE mt.memory_id = memories.id AND t.name = " + placeholder + ")"
+}
+
+// setMemoryTags replaces the tag rows of a memory with names, creating missing tags
+func setMemoryTags(ctx context.Context, tx *sql.Tx, userID, memoryID string, names []string, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM memory_tags WHERE memory_id = $1", memoryID); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO tags (id, user_id, name, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, name) DO NOTHING",
+			uuid.New().String(), userID, name, now); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO memory_tags (memory_id, tag_id) SELECT $1, id FROM tags WHERE user_id = $2 AND name = $3",
+			memoryID, userID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMemoryTags stores the normalized tags of a memory in both memories.tags and memory_tags
+func writeMemoryTags(ctx context.Context, tx *sql.Tx, userID, memoryID string, tags []string, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE memories SET tags = $1, updated_at = $2 WHERE id = $3",
+		nullString(strings.Join(tags, ",")), now, memoryID); err != nil {
+		return err
+	}
+	return setMemoryTags(ctx, tx, userID, memoryID, tags, now)
+}
+
+// pruneTags drops the user's tags that no memory uses any more
+func pruneTags(ctx context.Context, tx *sql.Tx, userID string) error {
+	_, err := tx.ExecContext(ctx,
+		"DELETE FROM tags WHERE user_id = $1 AND id NOT IN (SELECT tag_id FROM memory_tags)", userID)
+	return err
+}
+
+// ListTags returns every tag in use by the user with its memory count, most used first
+func (s *SQLStore) ListTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	return s.tagCounts(ctx, userID, 0)
+}
+
+// tagCounts counts the memories outside the trash per tag of the user, most used first; a limit
+// of 0 means no limit
+func (s *SQLStore) tagCounts(ctx context.Context, userID string, limit int) ([]models.TagCount, error) {
+	query := "SELECT t.name, COUNT(*) FROM tags t JOIN memory_tags mt ON mt.tag_id = t.id" +
+		" JOIN memories ON memories.id = mt.memory_id" +
+		" WHERE t.user_id = $1 AND " + notTrashed + " GROUP BY t.name ORDER BY COUNT(*) DESC, t.name"
+	args := []interface{}{userID}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		var tag models.TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// RenameTag renames a tag on every memory of the user. Renaming onto a tag
+// that is already in use fails with ErrConflict; MergeTags combines tags instead.
+func (s *SQLStore) RenameTag(ctx context.Context, userID, from, to string) (int, error) {
+	from, to = normalizeTag(from), normalizeTag(to)
+	if from == "" || to == "" {
+		return 0, ErrInvalidTag
+	}
+
+	return s.editTags(ctx, userID, []string{from}, func(tx *sql.Tx) error {
+		if from == to {
+			return nil
+		}
+		var used int
+		err := tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM memory_tags mt JOIN tags t ON t.id = mt.tag_id WHERE t.user_id = $1 AND t.name = $2",
+			userID, to).Scan(&used)
+		if err == nil && used > 0 {
+			return ErrConflict
+		}
+		return err
+	}, func(tags []string) []string {
+		return replaceTags(tags, map[string]string{from: to})
+	})
+}
+
+// MergeTags replaces every source tag with target on all of the user's memories
+func (s *SQLStore) MergeTags(ctx context.Context, userID string, sources []string, target string) (int, error) {
+	sources, target = normalizeTags(sources), normalizeTag(target)
+	if len(sources) == 0 || target == "" {
+		return 0, ErrInvalidTag
+	}
+
+	replacements := map[string]string{}
+	for _, source := range sources {
+		replacements[source] = target
+	}
+
+	return s.editTags(ctx, userID, sources, nil, func(tags []string) []string {
+		return replaceTags(tags, replacements)
+	})
+}
+
+// DeleteTag removes a tag from all of the user's memories
+func (s *SQLStore) DeleteTag(ctx context.Context, userID, name string) (int, error) {
+	name = normalizeTag(name)
+	if name == "" {
+		return 0, ErrInvalidTag
+	}
+
+	return s.editTags(ctx, userID, []string{name}, nil, func(tags []string) []string {
+		return replaceTags(tags, map[string]string{name: ""})
+	})
+}
+
+// editTags applies edit to the tag list of every memory of the user tagged with one of names,
+// in one transaction, after running check. Memories in the trash are edited too, so restoring
+// one does not bring back a renamed or deleted tag. It returns the number of memories changed,
+// or ErrNotFound if none of names is in use.
+func (s *SQLStore) editTags(ctx context.Context, userID string, names []string, check func(*sql.Tx) error, edit func([]string) []string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	placeholders := make([]string, len(names))
+	args := []interface{}{userID}
+	for i, name := range names {
+		placeholders[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, name)
+	}
+
+	query := "SELECT id, COALESCE(tags, '') FROM memories WHERE user_id = $1 AND id IN (" +
+		"SELECT mt.memory_id FROM memory_tags mt JOIN tags t ON t.id = mt.tag_id" +
+		" WHERE t.user_id = $1 AND t.name IN (" + strings.Join(placeholders, ", ") + "))"
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	tagged := map[string]string{}
+	for rows.Next() {
+		var id, tags string
+		if err := rows.Scan(&id, &tags); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		tagged[id] = tags
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(tagged) == 0 {
+		return 0, ErrNotFound
+	}
+	if check != nil {
+		if err := check(tx); err != nil {
+			return 0, err
+		}
+	}
+
+	now := time.Now().UTC()
+	for id, tags := range tagged {
+		if err := writeMemoryTags(ctx, tx, userID, id, normalizeTags(edit(splitTags(tags))), now); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := pruneTags(ctx, tx, userID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(tagged), nil
+}
+
+// replaceTags maps tags through replacements; a replacement of "" removes the tag
+func replaceTags(tags []string, replacements map[string]string) []string {
+	replaced := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if replacement, ok := replacements[normalizeTag(tag)]; ok {
+			tag = replacement
+		}
+		if tag != "" {
+			replaced = append(replaced, tag)
+		}
+	}
+	return replaced
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"api/models"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := normalizeTags([]string{"  Go ", "GO", "machine \t  Learning", "a,b", "", " , ", "b"})
+	if want := []string{"go", "machine learning", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// tagList returns the tags of the user with their counts
+func tagList(t *testing.T, s Store, userID string) []models.TagCount {
+	t.Helper()
+
+	tags, err := s.ListTags(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("list tags failed: %v", err)
+	}
+	return tags
+}
+
+// memoryTags returns the tags of a memory as stored
+func memoryTags(t *testing.T, s Store, id string) []string {
+	t.Helper()
+
+	memory, err := s.GetMemory(context.Background(), ownerID, id)
+	if err != nil {
+		t.Fatalf("get %s failed: %v", id, err)
+	}
+	return memory.Tags
+}
+
+func TestTagsAreNormalizedAndCounted(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		first := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "One", Tags: []string{"  Go ", "GO", "Machine   Learning"}})
+		createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Two", Tags: []string{"go,rust"}})
+		createMemory(t, s, intruderID, models.CreateMemoryRequest{Title: "Theirs", Tags: []string{"go"}})
+
+		if got := memoryTags(t, s, first.ID); !reflect.DeepEqual(got, []string{"go", "machine learning"}) {
+			t.Errorf("expected normalized tags in order, got %v", got)
+		}
+
+		want := []models.TagCount{{Tag: "go", Count: 2}, {Tag: "machine learning", Count: 1}, {Tag: "rust", Count: 1}}
+		if got := tagList(t, s, ownerID); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected counts %v, got %v", want, got)
+		}
+
+		page, err := s.ListMemories(ctx, ownerID, models.MemoryQueryParams{Tags: "MACHINE learning", Limit: 10})
+		if err != nil || !reflect.DeepEqual(memoryIDs(page.Memories), []string{first.ID}) {
+			t.Errorf("filter by an unnormalized tag: expected %s, got %v, %v", first.ID, memoryIDs(page.Memories), err)
+		}
+	})
+}
+
+func TestRenameTag(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		memory := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "One", Tags: []string{"golang", "tips"}})
+		createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Two", Tags: []string{"golang"}})
+		createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Three", Tags: []string{"rust"}})
+		createMemory(t, s, intruderID, models.CreateMemoryRequest{Title: "Theirs", Tags: []string{"golang"}})
+
+		if n, err := s.RenameTag(ctx, ownerID, " GoLang ", "Go"); err != nil || n != 2 {
+			t.Fatalf("rename: expected 2 memories changed, got %d, %v", n, err)
+		}
+		if got := memoryTags(t, s, memory.ID); !reflect.DeepEqual(got, []string{"go", "tips"}) {
+			t.Errorf("rename kept the position of the tag: got %v", got)
+		}
+		if got := tagList(t, s, intruderID); !reflect.DeepEqual(got, []models.TagCount{{Tag: "golang", Count: 1}}) {
+			t.Errorf("rename changed another user's tags: %v", got)
+		}
+
+		if _, err := s.RenameTag(ctx, ownerID, "go", "rust"); !errors.Is(err, ErrConflict) {
+			t.Errorf("rename onto a tag in use: expected ErrConflict, got %v", err)
+		}
+		if _, err := s.RenameTag(ctx, ownerID, "golang", "go"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("rename of an unused tag: expected ErrNotFound, got %v", err)
+		}
+		if _, err := s.RenameTag(ctx, ownerID, "go", " , "); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("rename to an empty name: expected ErrInvalidTag, got %v", err)
+		}
+		if n, err := s.RenameTag(ctx, ownerID, "go", "GO"); err != nil || n != 2 {
+			t.Errorf("rename onto itself: expected a no-op on 2 memories, got %d, %v", n, err)
+		}
+
+		// A tag kept by a memory in the trash would come back with it on restore
+		trashed := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Trashed", Tags: []string{"archived"}})
+		if err := s.DeleteMemory(ctx, ownerID, trashed.ID); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+		if _, err := s.RenameTag(ctx, ownerID, "go", "archived"); !errors.Is(err, ErrConflict) {
+			t.Errorf("rename onto a tag used in the trash: expected ErrConflict, got %v", err)
+		}
+
+		// The old name is free again once nothing uses it
+		if _, err := s.RenameTag(ctx, ownerID, "rust", "golang"); err != nil {
+			t.Errorf("rename onto a name no longer in use: %v", err)
+		}
+	})
+}
+
+func TestMergeTags(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		both := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Both", Tags: []string{"js", "notes", "javascript"}})
+		one := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "One", Tags: []string{"ecmascript"}})
+		createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Neither", Tags: []string{"go"}})
+
+		n, err := s.MergeTags(ctx, ownerID, []string{"JS", "ecmascript", "unused"}, "JavaScript")
+		if err != nil || n != 2 {
+			t.Fatalf("merge: expected 2 memories changed, got %d, %v", n, err)
+		}
+		if got := memoryTags(t, s, both.ID); !reflect.DeepEqual(got, []string{"javascript", "notes"}) {
+			t.Errorf("merge into a tag already present: expected it once, got %v", got)
+		}
+		if got := memoryTags(t, s, one.ID); !reflect.DeepEqual(got, []string{"javascript"}) {
+			t.Errorf("merge: got %v", got)
+		}
+
+		want := []models.TagCount{{Tag: "javascript", Count: 2}, {Tag: "go", Count: 1}, {Tag: "notes", Count: 1}}
+		if got := tagList(t, s, ownerID); !reflect.DeepEqual(got, want) {
+			t.Errorf("after merge: expected %v, got %v", want, got)
+		}
+
+		if _, err := s.MergeTags(ctx, ownerID, []string{"nothing"}, "go"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("merge of unused tags: expected ErrNotFound, got %v", err)
+		}
+		if _, err := s.MergeTags(ctx, ownerID, []string{" "}, "go"); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("merge without sources: expected ErrInvalidTag, got %v", err)
+		}
+	})
+}
+
+func TestDeleteTag(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		memory := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "One", Tags: []string{"draft", "go"}})
+		createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Two", Tags: []string{"draft"}})
+
+		if n, err := s.DeleteTag(ctx, ownerID, "DRAFT"); err != nil || n != 2 {
+			t.Fatalf("delete: expected 2 memories changed, got %d, %v", n, err)
+		}
+		if got := memoryTags(t, s, memory.ID); !reflect.DeepEqual(got, []string{"go"}) {
+			t.Errorf("delete: got %v", got)
+		}
+		if got := tagList(t, s, ownerID); !reflect.DeepEqual(got, []models.TagCount{{Tag: "go", Count: 1}}) {
+			t.Errorf("after delete: got %v", got)
+		}
+		if _, err := s.DeleteTag(ctx, ownerID, "draft"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second delete: expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestUpdatesReplaceTags(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		memory := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "One", Tags: []string{"old", "kept"}})
+
+		if _, err := s.UpdateMemory(ctx, ownerID, memory.ID, models.UpdateMemoryRequest{Tags: []string{"Kept", "new"}}); err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if got := memoryTags(t, s, memory.ID); !reflect.DeepEqual(got, []string{"kept", "new"}) {
+			t.Errorf("update: got %v", got)
+		}
+		if got := tagList(t, s, ownerID); !reflect.DeepEqual(got, []models.TagCount{{Tag: "kept", Count: 1}, {Tag: "new", Count: 1}}) {
+			t.Errorf("after update: got %v", got)
+		}
+
+		// The dropped tag is gone, so renaming onto it is not a conflict
+		if _, err := s.RenameTag(ctx, ownerID, "new", "old"); err != nil {
+			t.Errorf("rename onto a dropped tag: %v", err)
+		}
+		if got := memoryTags(t, s, memory.ID); !reflect.DeepEqual(got, []string{"kept", "old"}) {
+			t.Errorf("after rename: got %v", got)
+		}
+	})
+}
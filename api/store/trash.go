@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"api/models"
+)
+
+// ListTrash returns the user's memories in the trash, most recently deleted first
+func (s *SQLStore) ListTrash(ctx context.Context, userID string, limit, offset int) ([]models.MemoryResponse, error) {
+	return s.queryMemories(ctx, "SELECT "+memoryColumns+" FROM memories WHERE user_id = $1 AND deleted_at IS NOT NULL"+
+		" ORDER BY deleted_at DESC, id LIMIT $2 OFFSET $3", userID, limit, offset)
+}
+
+// RestoreMemory takes a memory owned by the user out of the trash. It counts as a change,
+// so the memory gets a new ETag and stale If-Match requests from before the delete fail.
+func (s *SQLStore) RestoreMemory(ctx context.Context, userID, id string) (models.MemoryResponse, error) {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE memories SET deleted_at = NULL, updated_at = $1 WHERE id = $2 AND user_id = $3 AND deleted_at IS NOT NULL",
+		time.Now().UTC(), id, userID)
+	if err != nil {
+		return models.MemoryResponse{}, err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return models.MemoryResponse{}, ErrNotFound
+	}
+	return s.GetMemory(ctx, userID, id)
+}
+
+// PurgeMemory deletes a memory in the user's trash for good; its links, tags, collection
+// places and embedding cascade
+func (s *SQLStore) PurgeMemory(ctx context.Context, userID, id string) error {
+	_, err := s.purge(ctx, func(tx *sql.Tx) (int64, []string, error) {
+		result, err := tx.ExecContext(ctx,
+			"DELETE FROM memories WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL", id, userID)
+		if err != nil {
+			return 0, nil, err
+		}
+		purged, _ := result.RowsAffected()
+		if purged == 0 {
+			return 0, nil, ErrNotFound
+		}
+		return purged, []string{userID}, nil
+	})
+	return err
+}
+
+// EmptyTrash deletes every memory in the user's trash for good and returns how many there were
+func (s *SQLStore) EmptyTrash(ctx context.Context, userID string) (int, error) {
+	return s.purge(ctx, func(tx *sql.Tx) (int64, []string, error) {
+		result, err := tx.ExecContext(ctx, "DELETE FROM memories WHERE user_id = $1 AND deleted_at IS NOT NULL", userID)
+		if err != nil {
+			return 0, nil, err
+		}
+		n, _ := result.RowsAffected()
+		return n, []string{userID}, nil
+	})
+}
+
+// PurgeTrash deletes the memories of every user that were moved to the trash before the given
+// time for good and returns how many there were
+func (s *SQLStore) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	return s.purge(ctx, func(tx *sql.Tx) (int64, []string, error) {
+		rows, err := tx.QueryContext(ctx,
+			"SELECT DISTINCT user_id FROM memories WHERE deleted_at IS NOT NULL AND deleted_at < $1", before.UTC())
+		if err != nil {
+			return 0, nil, err
+		}
+		users := []string{}
+		for rows.Next() {
+			var userID sql.NullString
+			if err := rows.Scan(&userID); err != nil {
+				rows.Close()
+				return 0, nil, err
+			}
+			users = append(users, userID.String)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, nil, err
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM memories WHERE deleted_at IS NOT NULL AND deleted_at < $1", before.UTC())
+		if err != nil {
+			return 0, nil, err
+		}
+		n, _ := result.RowsAffected()
+		return n, users, nil
+	})
+}
+
+// purge runs remove, which deletes memories from the trash and returns how many and whose,
+// in a transaction that then drops the tags those users no longer use
+func (s *SQLStore) purge(ctx context.Context, remove func(*sql.Tx) (int64, []string, error)) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	purged, users, err := remove(tx)
+	if err != nil {
+		return 0, err
+	}
+	for _, userID := range users {
+		if err := pruneTags(ctx, tx, userID); err != nil {
+			return 0, err
+		}
+	}
+	return int(purged), tx.Commit()
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"api/models"
+)
+
+func TestRestoreMemory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		memory := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Restored"})
+		if err := s.DeleteMemory(ctx, ownerID, memory.ID); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+
+		if _, err := s.RestoreMemory(ctx, intruderID, memory.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("restore by another user: expected ErrNotFound, got %v", err)
+		}
+
+		restored, err := s.RestoreMemory(ctx, ownerID, memory.ID)
+		if err != nil {
+			t.Fatalf("restore failed: %v", err)
+		}
+		if restored.DeletedAt.Valid {
+			t.Errorf("expected the memory out of the trash, got deleted_at %v", restored.DeletedAt.Time)
+		}
+		// Restoring is a change, so clients holding the old version must refetch it
+		if !restored.UpdatedAt.After(memory.UpdatedAt) {
+			t.Errorf("expected updated_at to move past %v, got %v", memory.UpdatedAt, restored.UpdatedAt)
+		}
+		if got, err := s.GetMemory(ctx, ownerID, memory.ID); err != nil || !got.UpdatedAt.Equal(restored.UpdatedAt) {
+			t.Errorf("expected the restored memory readable with the new updated_at, got %v, %v", got.UpdatedAt, err)
+		}
+
+		if _, err := s.RestoreMemory(ctx, ownerID, memory.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("restore of a memory outside the trash: expected ErrNotFound, got %v", err)
+		}
+	})
+}
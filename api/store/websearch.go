@@ -0,0 +1,243 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+
+	"api/models"
+)
+
+// Highlight markers wrapped around matched terms. Field text is not HTML-escaped,
+// so clients must escape everything outside the markers before rendering.
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// highlightFields names the searchable fields that may carry highlights, in the order the dialects select them
+var highlightFields = []string{"title", "content", "selected_text"}
+
+// searchTerm is a word or quoted phrase from a websearch-style query
+type searchTerm struct {
+	words   []string
+	exclude bool
+}
+
+// parseWebSearch splits query using the websearch_to_tsquery syntax: "quoted phrases",
+// -exclusions and OR between alternatives. Each alternative lists terms that must all hold.
+func parseWebSearch(query string) [][]searchTerm {
+	alternatives := [][]searchTerm{}
+	current := []searchTerm{}
+	flush := func() {
+		if len(current) > 0 {
+			alternatives = append(alternatives, current)
+			current = []searchTerm{}
+		}
+	}
+
+	for {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		if query == "" {
+			break
+		}
+
+		exclude := strings.HasPrefix(query, "-")
+		if exclude {
+			query = query[1:]
+		}
+
+		var text string
+		if strings.HasPrefix(query, `"`) {
+			// An unterminated quote runs to the end of the query
+			end := strings.IndexByte(query[1:], '"')
+			if end < 0 {
+				text, query = query[1:], ""
+			} else {
+				text, query = query[1:end+1], query[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(query, unicode.IsSpace)
+			if end < 0 {
+				end = len(query)
+			}
+			text, query = query[:end], query[end:]
+			if !exclude && strings.EqualFold(text, "or") {
+				flush()
+				continue
+			}
+		}
+
+		if words := searchWords(text); len(words) > 0 {
+			current = append(current, searchTerm{words: words, exclude: exclude})
+		}
+	}
+	flush()
+
+	return alternatives
+}
+
+// searchWords lowercases text and splits it into words, dropping punctuation
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchField is a memory field weighted like the setweight labels of the Postgres search vector
+type searchField struct {
+	name   string
+	weight float64
+	text   func(models.Memory) string
+}
+
+var searchFields = []searchField{
+	{"title", 1.0, func(m models.Memory) string { return m.Title }},
+	{"tags", 0.4, func(m models.Memory) string { return m.TagsString.String }},
+	{"selected_text", 0.2, func(m models.Memory) string { return m.SelectedText.String }},
+	{"content", 0.1, func(m models.Memory) string { return m.Content.String }},
+}
+
+// webSearchRank scores memory against a parsed query, approximating ts_rank_cd with whole-word matching.
+// ok is false when no alternative matches.
+func webSearchRank(alternatives [][]searchTerm, memory models.Memory) (rank float64, ok bool) {
+	fields := make([]string, len(searchFields))
+	for i, field := range searchFields {
+		fields[i] = " " + strings.Join(searchWords(field.text(memory)), " ") + " "
+	}
+	document := strings.Join(fields, " ")
+
+	for _, terms := range alternatives {
+		matched, score := true, 0.0
+		for _, term := range terms {
+			phrase := " " + strings.Join(term.words, " ") + " "
+			if strings.Contains(document, phrase) == term.exclude {
+				matched = false
+				break
+			}
+			for i, field := range searchFields {
+				if !term.exclude && strings.Contains(fields[i], phrase) {
+					score += field.weight
+				}
+			}
+		}
+		if matched {
+			ok = true
+			rank += score
+		}
+	}
+
+	return rank, ok
+}
+
+// webSearchHighlights marks the query words found in each highlight field of memory
+func webSearchHighlights(alternatives [][]searchTerm, memory models.Memory) map[string]string {
+	words := map[string]bool{}
+	for _, terms := range alternatives {
+		for _, term := range terms {
+			if !term.exclude {
+				for _, word := range term.words {
+					words[word] = true
+				}
+			}
+		}
+	}
+
+	texts := map[string]string{
+		"title":         memory.Title,
+		"content":       memory.Content.String,
+		"selected_text": memory.SelectedText.String,
+	}
+
+	highlights := map[string]string{}
+	for _, name := range highlightFields {
+		maxWords := 30
+		if name == "title" {
+			maxWords = 0
+		}
+		if fragment, ok := highlightText(texts[name], words, maxWords); ok {
+			highlights[name] = fragment
+		}
+	}
+	return highlights
+}
+
+// highlightText wraps every word of text found in words with the highlight markers.
+// A positive maxWords trims the result to a window of that many words around the first match.
+func highlightText(text string, words map[string]bool, maxWords int) (string, bool) {
+	type span struct{ start, end int }
+
+	spans := []span{}
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+
+	first := -1
+	for i, s := range spans {
+		if words[strings.ToLower(text[s.start:s.end])] {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	from, to := 0, len(spans)
+	if maxWords > 0 && len(spans) > maxWords {
+		from = first - maxWords/2
+		if from < 0 {
+			from = 0
+		}
+		to = from + maxWords
+		if to > len(spans) {
+			to, from = len(spans), len(spans)-maxWords
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := spans[from].start
+	if from == 0 {
+		pos = 0
+	}
+	for _, s := range spans[from:to] {
+		b.WriteString(text[pos:s.start])
+		word := text[s.start:s.end]
+		if words[strings.ToLower(word)] {
+			b.WriteString(highlightStart + word + highlightStop)
+		} else {
+			b.WriteString(word)
+		}
+		pos = s.end
+	}
+	if to == len(spans) {
+		b.WriteString(text[pos:])
+	} else {
+		b.WriteString("…")
+	}
+
+	return b.String(), true
+}
+
+// keepHighlights returns the fragments that actually contain a match, keyed by field name
+func keepHighlights(fragments []string) map[string]string {
+	highlights := map[string]string{}
+	for i, fragment := range fragments {
+		if strings.Contains(fragment, highlightStart) {
+			highlights[highlightFields[i]] = fragment
+		}
+	}
+	return highlights
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"api/models"
+)
+
+// term builds a searchTerm for the expected parses below
+func term(exclude bool, words ...string) searchTerm {
+	return searchTerm{words: words, exclude: exclude}
+}
+
+func TestParseWebSearch(t *testing.T) {
+	cases := []struct {
+		query string
+		want  [][]searchTerm
+	}{
+		{`go generics`, [][]searchTerm{{term(false, "go"), term(false, "generics")}}},
+		{`"Type Parameters" go`, [][]searchTerm{{term(false, "type", "parameters"), term(false, "go")}}},
+		{`go -java`, [][]searchTerm{{term(false, "go"), term(true, "java")}}},
+		{`-"java beans"`, [][]searchTerm{{term(true, "java", "beans")}}},
+		{`go OR rust`, [][]searchTerm{{term(false, "go")}, {term(false, "rust")}}},
+		{`go or rust -c`, [][]searchTerm{{term(false, "go")}, {term(false, "rust"), term(true, "c")}}},
+		{`go -or`, [][]searchTerm{{term(false, "go"), term(true, "or")}}},
+		{`"or" go`, [][]searchTerm{{term(false, "or"), term(false, "go")}}},
+		{`OR OR go OR`, [][]searchTerm{{term(false, "go")}}},
+		{`"unterminated phrase`, [][]searchTerm{{term(false, "unterminated", "phrase")}}},
+		{`net/http`, [][]searchTerm{{term(false, "net", "http")}}},
+		{`'; DROP TABLE memories; --`, [][]searchTerm{{term(false, "drop"), term(false, "table"), term(false, "memories")}}},
+		{`title:go* NEAR(x)`, [][]searchTerm{{term(false, "title", "go"), term(false, "near", "x")}}},
+		{`- -- "" *`, [][]searchTerm{}},
+		{``, [][]searchTerm{}},
+	}
+
+	for _, tc := range cases {
+		if got := parseWebSearch(tc.query); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseWebSearch(%q) = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestWebSearchRank(t *testing.T) {
+	memory := models.Memory{
+		Title:        "Go generics",
+		Content:      sql.NullString{String: "Type parameters arrived in Go 1.18, unlike Java.", Valid: true},
+		SelectedText: sql.NullString{String: "type parameters", Valid: true},
+		TagsString:   sql.NullString{String: "golang,programming", Valid: true},
+	}
+
+	cases := []struct {
+		query string
+		ok    bool
+		rank  float64
+	}{
+		{`generics`, true, 1.0},
+		{`golang`, true, 0.4},
+		{`"type parameters"`, true, 0.3},
+		{`"parameters type"`, false, 0},
+		{`go -java`, false, 0},
+		{`go -rust`, true, 1.1},
+		{`rust OR golang`, true, 0.4},
+		{`generics OR golang`, true, 1.4},
+		{`gener`, false, 0},
+		{`-rust`, true, 0},
+	}
+
+	for _, tc := range cases {
+		rank, ok := webSearchRank(parseWebSearch(tc.query), memory)
+		if ok != tc.ok || (ok && !almostEqual(rank, tc.rank)) {
+			t.Errorf("webSearchRank(%q) = %v, %v, want %v, %v", tc.query, rank, ok, tc.rank, tc.ok)
+		}
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
+
+func TestHighlightText(t *testing.T) {
+	words := map[string]bool{"go": true, "generics": true}
+
+	cases := []struct {
+		text     string
+		maxWords int
+		want     string
+		ok       bool
+	}{
+		{"Go generics, finally!", 0, "<mark>Go</mark> <mark>generics</mark>, finally!", true},
+		{"gopher going to Go-land", 0, "gopher going to <mark>Go</mark>-land", true},
+		{"nothing here", 0, "", false},
+		{"", 0, "", false},
+		{"one two three go five six seven", 3, "…three <mark>go</mark> five…", true},
+		{"go two three four", 2, "<mark>go</mark> two…", true},
+		{"one two three go", 2, "…three <mark>go</mark>", true},
+		{"<script>go</script>", 0, "<script><mark>go</mark></script>", true},
+	}
+
+	for _, tc := range cases {
+		got, ok := highlightText(tc.text, words, tc.maxWords)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("highlightText(%q, %d) = %q, %v, want %q, %v", tc.text, tc.maxWords, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestWebSearchHighlightsSkipExclusions(t *testing.T) {
+	memory := models.Memory{
+		Title:   "Go and Java",
+		Content: sql.NullString{String: "go is not java", Valid: true},
+	}
+
+	got := webSearchHighlights(parseWebSearch(`go -java`), memory)
+	want := map[string]string{
+		"title":   "<mark>Go</mark> and Java",
+		"content": "<mark>go</mark> is not java",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestKeepHighlights(t *testing.T) {
+	got := keepHighlights([]string{"<mark>Go</mark>", "no match", ""})
+	if want := map[string]string{"title": "<mark>Go</mark>"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSearchTreatsOperatorsAndSQLAsText(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		drop := createMemory(t, s, ownerID, models.CreateMemoryRequest{Title: "Never DROP TABLE memories in production"})
+
+		for _, query := range []string{`'; DROP TABLE memories; --`, `drop" OR 1=1 --`, `"table" -`, `*:drop`} {
+			page, err := s.SearchMemories(ctx, ownerID, models.SearchRequest{Query: query, Limit: 10})
+			if err != nil {
+				t.Fatalf("search %q failed: %v", query, err)
+			}
+			if got := memoryIDs(page.Memories); !reflect.DeepEqual(got, []string{drop.ID}) {
+				t.Errorf("search %q: expected %v, got %v", query, []string{drop.ID}, got)
+			}
+		}
+		if _, err := s.GetMemory(ctx, ownerID, drop.ID); err != nil {
+			t.Errorf("memory lost after the searches: %v", err)
+		}
+	})
+}
@@ -35,7 +35,7 @@ export async function GET(request: NextRequest) {
     const type = searchParams.get('type') as MemoryType | 'all' | null;
     const search = searchParams.get('search');
 
-    // Always filter by the authenticated user's ID
+    // Always filter by the authenticated user's ID; getMemories leaves out memories in the trash
     const memories = await getMemories({
       userId: session.user.id,
       type: type || 'all',
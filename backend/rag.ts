@@ -1,7 +1,7 @@
 import { GoogleGenerativeAI } from '@google/generative-ai';
 import { db } from '@/lib/db';
 import { memories, type Memory } from '@/lib/db/schema';
-import { and, gte, lte, eq, like, or } from 'drizzle-orm';
+import { and, gte, lte, eq, like, or, isNull } from 'drizzle-orm';
 
 const genAI = new GoogleGenerativeAI(process.env.GEMINI_API_KEY || '');
 
@@ -119,8 +119,8 @@ export async function semanticSearch({
     const parsed = parseQuery(query);
     const searchQuery = parsed.cleanQuery || query;
 
-    // Build SQL conditions
-    const conditions = [];
+    // Build SQL conditions, leaving out memories in the trash
+    const conditions = [isNull(memories.deletedAt)];
 
     // Apply type filter (using contentType from schema)
     const typeValue = filters?.type || parsed.type;
@@ -158,7 +158,7 @@ export async function semanticSearch({
     const results = await db
       .select()
       .from(memories)
-      .where(conditions.length > 0 ? and(...conditions) : undefined)
+      .where(and(...conditions))
       .limit(limit);
 
     // Calculate similarity scores and generate reasons
@@ -7,6 +7,9 @@ export default defineConfig({
   schema: "./lib/db/schema.ts",
   out: "./migrations",
   dialect: "postgresql",
+  // Only the tables declared in lib/db/schema.ts; the Go API's migrations create and own
+  // the rest (tags, collections, jobs, memory_embeddings, schema_migrations, ...)
+  tablesFilter: ["memories", "links", "user", "session", "account", "verification"],
   dbCredentials: {
     url: process.env.DATABASE_URL!,
   },
@@ -2,7 +2,7 @@
 
 import { db } from '@/lib/db';
 import { memories } from '@/lib/db/schema';
-import { eq, desc, and, like, or } from 'drizzle-orm';
+import { eq, desc, and, like, or, isNull, type SQL } from 'drizzle-orm';
 import { revalidatePath } from 'next/cache';
 import type { Memory, NewMemory, MemoryType } from '@/lib/types';
 import type { Memory as DbMemory } from '@/lib/db/schema';
@@ -36,7 +36,8 @@ export async function getMemories(filters?: {
   userId?: string;
 }): Promise<Memory[]> {
   try {
-    const conditions = [];
+    // Memories in the trash (deleted_at set by the Go API) are hidden until restored
+    const conditions: (SQL | undefined)[] = [isNull(memories.deletedAt)];
 
     if (filters?.userId) {
       conditions.push(eq(memories.userId, filters.userId));
@@ -59,7 +60,7 @@ export async function getMemories(filters?: {
     let result = await db
       .select()
       .from(memories)
-      .where(conditions.length > 0 ? and(...conditions) : undefined)
+      .where(and(...conditions))
       .orderBy(desc(memories.createdAt));
 
     if (filters?.limit) {
@@ -75,7 +76,7 @@ export async function getMemories(filters?: {
 
 export async function getMemoryById(id: string, userId?: string): Promise<Memory | null> {
   try {
-    const conditions = [eq(memories.id, id)];
+    const conditions = [eq(memories.id, id), isNull(memories.deletedAt)];
     
     // If userId is provided, ensure the memory belongs to that user
     if (userId) {
@@ -139,12 +140,15 @@ export async function deleteMemory(id: string): Promise<void> {
       throw new Error('Unauthorized');
     }
 
-    // Delete only if the memory belongs to the authenticated user
+    // Move the memory to the trash, like the Go API, only if it belongs to the authenticated user;
+    // the Go API purges it once the retention period has passed
     const result = await db
-      .delete(memories)
+      .update(memories)
+      .set({ deletedAt: new Date() })
       .where(and(
         eq(memories.id, id),
-        eq(memories.userId, session.user.id)
+        eq(memories.userId, session.user.id),
+        isNull(memories.deletedAt)
       ))
       .returning();
 
@@ -1,4 +1,16 @@
-import { pgTable, text, timestamp, boolean, bigint, varchar } from "drizzle-orm/pg-core";
+import { sql } from "drizzle-orm";
+import { pgTable, text, timestamp, boolean, bigint, integer, varchar, check, index, customType } from "drizzle-orm/pg-core";
+
+// The Go API's migrations (api/migrations/postgres) own the schema of memories and links.
+// Their columns and indexes are mirrored here so drizzle-kit leaves them alone; the tables
+// only the API uses are excluded from drizzle-kit by tablesFilter in drizzle.config.ts.
+
+// PostgreSQL full-text search document, maintained by the database
+const tsvector = customType<{ data: string }>({
+  dataType() {
+    return 'tsvector';
+  },
+});
 
 // Extension memories table - compatible with existing UUID-based schema
 export const memories = pgTable('memories', {
@@ -29,7 +41,41 @@ export const memories = pgTable('memories', {
   videoUrl: text('video_url'),
   thumbnailUrl: text('thumbnail_url'),
   formattedTimestamp: varchar('formatted_timestamp', { length: 20 }),
-});
+
+  // Weighted search document (migration 0004); read-only
+  searchVector: tsvector('search_vector').generatedAlwaysAs(sql`
+    setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(tags, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(selected_text, '')), 'C') ||
+    setweight(to_tsvector('english', COALESCE(content, '')), 'D')`),
+
+  // Duplicate detection keys computed by the API on save (migration 0007)
+  canonicalUrl: text('canonical_url'),
+  contentHash: text('content_hash'),
+
+  // Readable article extracted from the page (migrations 0008 and 0009)
+  contentHtml: text('content_html'),
+  byline: text('byline'),
+  publishedAt: timestamp('published_at'),
+  leadImageUrl: text('lead_image_url'),
+  language: text('language'),
+  truncatedFields: text('truncated_fields'),
+
+  // Set while the memory is in the trash (migration 0013); every reader must skip these rows
+  deletedAt: timestamp('deleted_at'),
+}, (table) => [
+  // Mirrors the constraint of the Go API's 0001 migration, under PostgreSQL's default name
+  check('memories_content_type_check', sql`${table.contentType} IN ('page', 'selection', 'video_timestamp', 'links', 'custom')`),
+  index('idx_memories_url').on(table.url),
+  index('idx_memories_content_type').on(table.contentType),
+  index('idx_memories_scraped_at').on(table.scrapedAt.desc()),
+  index('idx_memories_video_platform').on(table.videoPlatform).where(sql`${table.videoPlatform} IS NOT NULL`),
+  index('idx_memories_user_created_at_id').on(table.userId, table.createdAt.desc(), table.id.desc()),
+  index('idx_memories_search_vector').using('gin', table.searchVector),
+  index('idx_memories_canonical_url').on(table.userId, table.canonicalUrl),
+  index('idx_memories_content_hash').on(table.userId, table.contentHash),
+  index('idx_memories_trash').on(table.userId, table.deletedAt.desc()).where(sql`${table.deletedAt} IS NOT NULL`),
+]);
 
 // Links table for extracted links from pages
 export const links = pgTable('links', {
@@ -39,7 +85,15 @@ export const links = pgTable('links', {
   href: text('href').notNull(),
   linkTitle: text('link_title'),
   createdAt: timestamp('created_at').defaultNow().notNull(),
-});
+
+  // Host and order on the page (migration 0002), filled in by the API
+  domain: text('domain'),
+  position: integer('position').notNull().default(0),
+}, (table) => [
+  index('idx_links_memory_id').on(table.memoryId),
+  index('idx_links_domain').on(table.domain),
+  index('idx_links_href').on(table.href),
+]);
 
 // Better-auth compatible user table
 export const user = pgTable('user', {
@@ -41,12 +41,13 @@ async function generateEmbeddingsForAllMemories() {
     ]);
 
     // Use drizzle-orm's helpers from the imported module
-    const { desc } = drizzleOrm;
+    const { desc, isNull } = drizzleOrm;
 
-    // Get all memories from PostgreSQL
+    // Get all memories from PostgreSQL, except those in the trash
     const allMemories = await db
       .select()
       .from(memories)
+      .where(isNull(memories.deletedAt))
       .orderBy(desc(memories.createdAt));
 
     console.log(`📊 Found ${allMemories.length} memories to process\n`);
@@ -2,7 +2,7 @@ import { GoogleGenerativeAI } from '@google/generative-ai';
 import { MongoClient, Collection } from 'mongodb';
 import { db } from './db';
 import { memories, type Memory as DbMemory } from './db/schema';
-import { eq, and, like, or, inArray } from 'drizzle-orm';
+import { eq, and, like, or, inArray, isNull } from 'drizzle-orm';
 
 // Initialize Gemini API
 const genAI = new GoogleGenerativeAI(process.env.GEMINI_API_KEY || '');
@@ -161,7 +161,8 @@ export async function semanticSearch(query: string, userId: string, limit: numbe
       .where(
         and(
           eq(memories.userId, userId),
-          inArray(memories.id, memoryIds)
+          inArray(memories.id, memoryIds),
+          isNull(memories.deletedAt)
         )
       );
     
@@ -191,6 +192,7 @@ async function keywordSearch(query: string, userId: string, limit: number) {
     .where(
       and(
         eq(memories.userId, userId),
+        isNull(memories.deletedAt),
         or(
           like(memories.title, `%${query}%`),
           like(memories.content, `%${query}%`),
@@ -277,14 +279,15 @@ export async function batchGenerateEmbeddings(userId: string, memoryIds?: string
         .where(
           and(
             eq(memories.userId, userId),
-            inArray(memories.id, memoryIds)
+            inArray(memories.id, memoryIds),
+            isNull(memories.deletedAt)
           )
         );
     } else {
       memoriesToProcess = await db
         .select()
         .from(memories)
-        .where(eq(memories.userId, userId));
+        .where(and(eq(memories.userId, userId), isNull(memories.deletedAt)));
     }
     
     // Process each memory